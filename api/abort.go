@@ -0,0 +1,44 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/ONSdigital/dp-net/v3/request"
+	"github.com/ONSdigital/dp-upload-service/config"
+	"github.com/ONSdigital/dp-upload-service/files"
+	"github.com/ONSdigital/log.go/v2/log"
+	"github.com/gorilla/mux"
+)
+
+type AbortUpload func(ctx context.Context, path string) error
+
+// AbortUploadHandler abandons the in progress upload for the file path, responding with 204 once it has been aborted
+func AbortUploadHandler(abortUpload AbortUpload) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		authHeaderValue := req.Header.Get(request.AuthHeaderKey)
+		augmentedContext := context.WithValue(req.Context(), config.AuthContextKey, authHeaderValue)
+
+		path := mux.Vars(req)["path"]
+		if err := abortUpload(augmentedContext, path); err != nil {
+			log.Error(augmentedContext, "error aborting upload", err, log.Data{"path": path})
+			switch err {
+			case files.ErrUploadNotFound:
+				writeError(w, buildErrors(err, "NotFound"), http.StatusNotFound)
+			case files.ErrUploadComplete:
+				writeError(w, buildErrors(err, "UploadComplete"), http.StatusConflict)
+			case files.ErrFilesServer:
+				writeError(w, buildErrors(err, "RemoteServerError"), http.StatusInternalServerError)
+			case files.ErrFilesUnauthorised:
+				writeError(w, buildErrors(err, "Unauthorised"), http.StatusUnauthorized)
+			case files.ErrFilesForbidden:
+				writeError(w, buildErrors(err, "Forbidden"), http.StatusForbidden)
+			default:
+				writeError(w, buildErrors(err, "InternalError"), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,86 @@
+package api_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ONSdigital/dp-upload-service/api"
+	"github.com/ONSdigital/dp-upload-service/files"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/suite"
+)
+
+type AbortTestSuite struct {
+	suite.Suite
+
+	rec *httptest.ResponseRecorder
+}
+
+func TestAbortTestSuite(t *testing.T) {
+	suite.Run(t, new(AbortTestSuite))
+}
+
+func (s *AbortTestSuite) SetupTest() {
+	s.rec = httptest.NewRecorder()
+}
+
+func (s *AbortTestSuite) serve(abortUpload api.AbortUpload) {
+	r := mux.NewRouter()
+	r.Path("/upload-new/files/{path:.*}").Methods(http.MethodDelete).HandlerFunc(api.AbortUploadHandler(abortUpload))
+
+	req := httptest.NewRequest(http.MethodDelete, "/upload-new/files/data/file.csv", nil)
+	r.ServeHTTP(s.rec, req)
+}
+
+func (s *AbortTestSuite) TestAbortedUploadReturns204() {
+	var capturedPath string
+	s.serve(func(ctx context.Context, path string) error {
+		capturedPath = path
+		return nil
+	})
+
+	s.Equal(http.StatusNoContent, s.rec.Code)
+	s.Equal("data/file.csv", capturedPath)
+}
+
+func (s *AbortTestSuite) TestUploadNotFoundReturns404() {
+	s.serve(func(ctx context.Context, path string) error {
+		return files.ErrUploadNotFound
+	})
+
+	s.Equal(http.StatusNotFound, s.rec.Code)
+	response, _ := io.ReadAll(s.rec.Body)
+	s.Contains(string(response), "NotFound")
+}
+
+func (s *AbortTestSuite) TestCompletedUploadReturns409() {
+	s.serve(func(ctx context.Context, path string) error {
+		return files.ErrUploadComplete
+	})
+
+	s.Equal(http.StatusConflict, s.rec.Code)
+	response, _ := io.ReadAll(s.rec.Body)
+	s.Contains(string(response), "UploadComplete")
+}
+
+func (s *AbortTestSuite) TestFilesAPIForbiddenReturns403() {
+	s.serve(func(ctx context.Context, path string) error {
+		return files.ErrFilesForbidden
+	})
+
+	s.Equal(http.StatusForbidden, s.rec.Code)
+}
+
+func (s *AbortTestSuite) TestUnexpectedErrorReturns500() {
+	s.serve(func(ctx context.Context, path string) error {
+		return errors.New("broken")
+	})
+
+	s.Equal(http.StatusInternalServerError, s.rec.Code)
+	response, _ := io.ReadAll(s.rec.Body)
+	s.Contains(string(response), "InternalError")
+}
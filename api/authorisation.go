@@ -0,0 +1,136 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/ONSdigital/dp-net/v3/request"
+	"github.com/ONSdigital/dp-upload-service/config"
+	"github.com/ONSdigital/dp-upload-service/files"
+	"github.com/ONSdigital/log.go/v2/log"
+	"github.com/gorilla/mux"
+)
+
+const (
+	// collectionIDAttribute is the attribute that permissions are scoped to collections with
+	collectionIDAttribute = "collection_id"
+	// bundleIDAttribute is the attribute that permissions are scoped to bundles with
+	bundleIDAttribute = "bundle_id"
+)
+
+// GetFileOwner returns the collection and bundle that the file with the path is registered in, or nil for either that
+// it is not in
+type GetFileOwner func(ctx context.Context, path string) (collectionID, bundleID *string, err error)
+
+var (
+	ErrCollectionMismatch = errors.New("collectionId does not match the collection the request was authorised for")
+	ErrFileOwnerUnknown   = errors.New("owner of the file has not been looked up")
+)
+
+// CollectionIDAttributes returns the collection that a request is authorised for, from the Collection-Id header or, if
+// there isn't one, the collectionId query parameter. It can be used with authorisation.Middleware RequireWithAttributes
+// so that permissions scoped to a collection are checked. If no collection is given, the attributes are empty and only
+// permissions that are not scoped to a collection allow the request.
+func CollectionIDAttributes(req *http.Request) (map[string]string, error) {
+	attributes := map[string]string{}
+	if collectionID := authorisedCollectionID(req); collectionID != "" {
+		attributes[collectionIDAttribute] = collectionID
+	}
+	return attributes, nil
+}
+
+// CollectionPathAttributes returns the collection in the id path variable as the collection that a request is
+// authorised for
+func CollectionPathAttributes(req *http.Request) (map[string]string, error) {
+	return map[string]string{collectionIDAttribute: mux.Vars(req)["id"]}, nil
+}
+
+// BundlePathAttributes returns the bundle in the id path variable as the bundle that a request is authorised for
+func BundlePathAttributes(req *http.Request) (map[string]string, error) {
+	return map[string]string{bundleIDAttribute: mux.Vars(req)["id"]}, nil
+}
+
+// fileOwnerKey is the context key of the owner of the file a request is for, once it has been looked up
+type fileOwnerKey struct{}
+
+type fileOwner struct {
+	collectionID *string
+	bundleID     *string
+}
+
+// ResolveFileOwner looks up the collection and bundle of the file in the path variable, so that FileAttributes can
+// authorise the request against them. It is called before the request is authorised, so that a failed lookup is reported
+// in the same way as by the handlers for the file, rather than as an error in the authorisation middleware.
+func ResolveFileOwner(getOwner GetFileOwner, handlerFunc http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		ctx := context.WithValue(req.Context(), config.AuthContextKey, req.Header.Get(request.AuthHeaderKey))
+
+		path := mux.Vars(req)["path"]
+		collectionID, bundleID, err := getOwner(ctx, path)
+		if err != nil {
+			log.Error(ctx, "error getting owner of file", err, log.Data{"path": path})
+			switch err {
+			case files.ErrFilesServer:
+				writeError(w, buildErrors(err, "RemoteServerError"), http.StatusInternalServerError)
+			case files.ErrFilesUnauthorised:
+				writeError(w, buildErrors(err, "Unauthorised"), http.StatusUnauthorized)
+			case files.ErrFilesForbidden:
+				writeError(w, buildErrors(err, "Forbidden"), http.StatusForbidden)
+			default:
+				writeError(w, buildErrors(err, "InternalError"), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		owner := fileOwner{collectionID: collectionID, bundleID: bundleID}
+		handlerFunc(w, req.WithContext(context.WithValue(req.Context(), fileOwnerKey{}, owner)))
+	}
+}
+
+// FileAttributes gives the collection or bundle that the file is in, as looked up by ResolveFileOwner, as the
+// attributes a request is authorised for, so that a caller cannot reach a file in another collection by naming their
+// own. A file is in the collection or bundle it is registered in, or, while its upload is in progress, that its upload
+// was started for. Files in neither have no attributes, so only permissions that are not scoped to a collection or
+// bundle allow requests for them.
+func FileAttributes(req *http.Request) (map[string]string, error) {
+	owner, ok := req.Context().Value(fileOwnerKey{}).(fileOwner)
+	if !ok {
+		return nil, ErrFileOwnerUnknown
+	}
+
+	attributes := map[string]string{}
+	if owner.collectionID != nil {
+		attributes[collectionIDAttribute] = *owner.collectionID
+	}
+	if owner.bundleID != nil {
+		attributes[bundleIDAttribute] = *owner.bundleID
+	}
+	return attributes, nil
+}
+
+// authorisedCollectionID returns the collection that CollectionIDAttributes authorises the request for
+func authorisedCollectionID(req *http.Request) string {
+	if collectionID := req.Header.Get(request.CollectionIDHeaderKey); collectionID != "" {
+		return collectionID
+	}
+	return req.URL.Query().Get("collectionId")
+}
+
+// checkAuthorisedCollection checks that the file is in the collection that the request was authorised for, as the
+// form fields are read after the request has been authorised. If the request was not authorised for a collection, the
+// file can be in any collection. If the file is in another collection the error response is written and false is
+// returned.
+func checkAuthorisedCollection(w http.ResponseWriter, req *http.Request, metadata Metadata) bool {
+	collectionID := authorisedCollectionID(req)
+	if collectionID == "" {
+		return true
+	}
+
+	if metadata.CollectionId == nil || *metadata.CollectionId != collectionID {
+		writeError(w, buildErrors(ErrCollectionMismatch, "CollectionMismatch"), http.StatusForbidden)
+		return false
+	}
+
+	return true
+}
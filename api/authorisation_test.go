@@ -0,0 +1,261 @@
+package api_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	filesAPITypes "github.com/ONSdigital/dp-files-api/files"
+	filesSDK "github.com/ONSdigital/dp-files-api/sdk"
+	"github.com/ONSdigital/dp-upload-service/api"
+	"github.com/ONSdigital/dp-upload-service/config"
+	"github.com/ONSdigital/dp-upload-service/files"
+	mock_files "github.com/ONSdigital/dp-upload-service/files/mock"
+	"github.com/ONSdigital/dp-upload-service/storage"
+	mock_storage "github.com/ONSdigital/dp-upload-service/storage/mock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/suite"
+)
+
+type AuthorisationTestSuite struct {
+	suite.Suite
+
+	rec *httptest.ResponseRecorder
+}
+
+func TestAuthorisationTestSuite(t *testing.T) {
+	suite.Run(t, new(AuthorisationTestSuite))
+}
+
+func (s *AuthorisationTestSuite) SetupTest() {
+	s.rec = httptest.NewRecorder()
+}
+
+func (s *AuthorisationTestSuite) TestCollectionIDAttributesFromHeader() {
+	req := httptest.NewRequest(http.MethodPost, "/upload-new?collectionId=query-collection", nil)
+	req.Header.Set("Collection-Id", "header-collection")
+
+	attributes, err := api.CollectionIDAttributes(req)
+
+	s.NoError(err)
+	s.Equal(map[string]string{"collection_id": "header-collection"}, attributes)
+}
+
+func (s *AuthorisationTestSuite) TestCollectionIDAttributesFromQuery() {
+	req := httptest.NewRequest(http.MethodGet, "/upload-new?collectionId=query-collection", nil)
+
+	attributes, err := api.CollectionIDAttributes(req)
+
+	s.NoError(err)
+	s.Equal(map[string]string{"collection_id": "query-collection"}, attributes)
+}
+
+func (s *AuthorisationTestSuite) TestCollectionIDAttributesWithoutACollection() {
+	req := httptest.NewRequest(http.MethodGet, "/upload-new/files/data/file.csv/status", nil)
+
+	attributes, err := api.CollectionIDAttributes(req)
+
+	s.NoError(err)
+	s.Empty(attributes)
+}
+
+func (s *AuthorisationTestSuite) TestCollectionPathAttributes() {
+	req := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/upload-new/collections/path-collection/status", nil), map[string]string{"id": "path-collection"})
+
+	attributes, err := api.CollectionPathAttributes(req)
+
+	s.NoError(err)
+	s.Equal(map[string]string{"collection_id": "path-collection"}, attributes)
+}
+
+func (s *AuthorisationTestSuite) TestBundlePathAttributes() {
+	req := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/upload-new/bundles/path-bundle/status", nil), map[string]string{"id": "path-bundle"})
+
+	attributes, err := api.BundlePathAttributes(req)
+
+	s.NoError(err)
+	s.Equal(map[string]string{"bundle_id": "path-bundle"}, attributes)
+}
+
+// fileRequest is a request for the file data/file.csv that names a collection the file is not in
+func fileRequest() *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/upload-new/files/data/file.csv/status", nil)
+	req.Header.Set("Collection-Id", "caller-collection")
+	req.Header.Set("Authorization", "Bearer caller-token")
+	return mux.SetURLVars(req, map[string]string{"path": "data/file.csv"})
+}
+
+// fileAttributes serves the request for the file, with the owner given by getOwner, returning the attributes it would
+// be authorised against, or nil if the request did not reach authorisation
+func (s *AuthorisationTestSuite) fileAttributes(getOwner api.GetFileOwner, req *http.Request) map[string]string {
+	var attributes map[string]string
+	api.ResolveFileOwner(getOwner, func(w http.ResponseWriter, req *http.Request) {
+		var err error
+		attributes, err = api.FileAttributes(req)
+		s.NoError(err)
+	})(s.rec, req)
+	return attributes
+}
+
+func (s *AuthorisationTestSuite) TestFileAttributesAreTheCollectionTheFileIsRegisteredIn() {
+	collectionID := "file-collection"
+	getOwner := func(ctx context.Context, path string) (*string, *string, error) {
+		s.Equal("data/file.csv", path)
+		s.Equal("Bearer caller-token", ctx.Value(config.AuthContextKey))
+		return &collectionID, nil, nil
+	}
+
+	attributes := s.fileAttributes(getOwner, fileRequest())
+
+	s.Equal(map[string]string{"collection_id": "file-collection"}, attributes)
+}
+
+func (s *AuthorisationTestSuite) TestFileAttributesAreTheBundleTheFileIsRegisteredIn() {
+	bundleID := "file-bundle"
+	getOwner := func(ctx context.Context, path string) (*string, *string, error) {
+		return nil, &bundleID, nil
+	}
+
+	attributes := s.fileAttributes(getOwner, fileRequest())
+
+	s.Equal(map[string]string{"bundle_id": "file-bundle"}, attributes)
+}
+
+func (s *AuthorisationTestSuite) TestFileAttributesOfAFileInNoCollectionOrBundleAreEmpty() {
+	getOwner := func(ctx context.Context, path string) (*string, *string, error) {
+		return nil, nil, nil
+	}
+
+	attributes := s.fileAttributes(getOwner, fileRequest())
+
+	s.NotNil(attributes)
+	s.Empty(attributes)
+}
+
+func (s *AuthorisationTestSuite) TestFileAttributesOfAnUploadInProgressAreTheCollectionItWasStartedFor() {
+	collectionID := "caller-collection"
+	store := files.NewStore(&mock_files.FilesClienterMock{
+		GetFileFunc: func(ctx context.Context, path string, headers filesSDK.Headers) (*filesAPITypes.StoredRegisteredMetaData, error) {
+			return nil, &filesSDK.APIError{StatusCode: http.StatusNotFound}
+		},
+	}, storage.NewBucket("name", &mock_storage.DriverMock{
+		GetFunc: func(ctx context.Context, key string) (io.ReadCloser, *int64, error) {
+			s.Equal(".upload-owners/data/file.csv", key)
+			return io.NopCloser(strings.NewReader(`{"collection_id":"` + collectionID + `"}`)), nil, nil
+		},
+	}), &config.Config{})
+
+	attributes := s.fileAttributes(store.FileOwner, fileRequest())
+
+	s.Equal(map[string]string{"collection_id": "caller-collection"}, attributes)
+}
+
+func (s *AuthorisationTestSuite) TestFileOwnerErrors() {
+	tests := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{files.ErrFilesUnauthorised, http.StatusUnauthorized, "Unauthorised"},
+		{files.ErrFilesForbidden, http.StatusForbidden, "Forbidden"},
+		{files.ErrFilesServer, http.StatusInternalServerError, "RemoteServerError"},
+		{errors.New("s3 unavailable"), http.StatusInternalServerError, "InternalError"},
+	}
+
+	for _, test := range tests {
+		s.rec = httptest.NewRecorder()
+		getOwner := func(ctx context.Context, path string) (*string, *string, error) {
+			return nil, nil, test.err
+		}
+
+		attributes := s.fileAttributes(getOwner, fileRequest())
+
+		s.Nil(attributes)
+		s.Equal(test.status, s.rec.Code, test.code)
+		s.Contains(s.rec.Body.String(), test.code)
+	}
+}
+
+func (s *AuthorisationTestSuite) TestFileAttributesWithoutTheFileOwner() {
+	_, err := api.FileAttributes(fileRequest())
+
+	s.ErrorIs(err, api.ErrFileOwnerUnknown)
+}
+
+func (s *AuthorisationTestSuite) TestUploadToTheAuthorisedCollection() {
+	b, formWriter := generateFormWriter("valid")
+	part, _ := formWriter.CreateFormFile("file", "file.csv")
+	part.Write([]byte("CONTENT"))
+	formWriter.Close()
+	req := generateRequest(b, formWriter)
+	req.Header.Set("Collection-Id", "1234567890")
+
+	api.CreateV1UploadHandler(stubStoreFunction).ServeHTTP(s.rec, req)
+
+	s.Equal(http.StatusOK, s.rec.Code)
+}
+
+func (s *AuthorisationTestSuite) TestUploadToAnotherCollectionIsForbidden() {
+	b, formWriter := generateFormWriter("valid")
+	part, _ := formWriter.CreateFormFile("file", "file.csv")
+	part.Write([]byte("CONTENT"))
+	formWriter.Close()
+	req := generateRequest(b, formWriter)
+	req.Header.Set("Collection-Id", "another-collection")
+
+	storeCalled := false
+	api.CreateV1UploadHandler(func(ctx context.Context, uf files.FileMetadataWithContentItem, r files.Resumable, c io.Reader) (bool, error) {
+		storeCalled = true
+		return false, nil
+	}).ServeHTTP(s.rec, req)
+
+	s.Equal(http.StatusForbidden, s.rec.Code)
+	s.Contains(s.rec.Body.String(), "CollectionMismatch")
+	s.False(storeCalled)
+}
+
+func (s *AuthorisationTestSuite) TestUploadToABundleIsForbiddenWhenAuthorisedForACollection() {
+	b, formWriter := generateFormWriterWithBundleId("valid")
+	part, _ := formWriter.CreateFormFile("file", "file.csv")
+	part.Write([]byte("CONTENT"))
+	formWriter.Close()
+	req := generateRequest(b, formWriter)
+	req.Header.Set("Collection-Id", "1234567890")
+
+	api.CreateV1UploadHandler(stubStoreFunction).ServeHTTP(s.rec, req)
+
+	s.Equal(http.StatusForbidden, s.rec.Code)
+	s.Contains(s.rec.Body.String(), "CollectionMismatch")
+}
+
+func (s *AuthorisationTestSuite) TestCheckChunkInAnotherCollectionIsForbidden() {
+	query := sessionForm()
+	query.Set("resumableChunkNumber", "1")
+	req := httptest.NewRequest(http.MethodGet, UploadURI+"?"+query.Encode(), nil)
+	req.Header.Set("Collection-Id", "another-collection")
+
+	api.CreateV1CheckChunkHandler(func(ctx context.Context, uf files.FileMetadataWithContentItem, r files.Resumable) (bool, error) {
+		s.Fail("chunk should not be checked")
+		return false, nil
+	}).ServeHTTP(s.rec, req)
+
+	s.Equal(http.StatusForbidden, s.rec.Code)
+	s.Contains(s.rec.Body.String(), "CollectionMismatch")
+}
+
+func (s *AuthorisationTestSuite) TestUploadSessionInAnotherCollectionIsForbidden() {
+	req := sessionRequest("/upload-new/sessions", sessionForm())
+	req.Header.Set("Collection-Id", "another-collection")
+
+	api.CreateUploadSessionHandler(func(ctx context.Context, uf files.FileMetadataWithContentItem, r files.Resumable) (*files.UploadSession, error) {
+		s.Fail("session should not be created")
+		return nil, nil
+	}).ServeHTTP(s.rec, req)
+
+	s.Equal(http.StatusForbidden, s.rec.Code)
+	s.Contains(s.rec.Body.String(), "CollectionMismatch")
+}
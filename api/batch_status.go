@@ -0,0 +1,78 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/ONSdigital/dp-net/v3/request"
+	"github.com/ONSdigital/dp-upload-service/config"
+	"github.com/ONSdigital/dp-upload-service/files"
+	"github.com/ONSdigital/log.go/v2/log"
+	"github.com/gorilla/mux"
+)
+
+const (
+	defaultStatusLimit = 100
+	maxStatusLimit     = 1000
+)
+
+var (
+	ErrInvalidOffset = errors.New("offset must be a number greater than or equal to 0")
+	ErrInvalidLimit  = errors.New("limit must be a number between 1 and 1000")
+)
+
+type BatchStatus func(ctx context.Context, id string, offset, limit int) (*files.BatchStatus, error)
+
+// BatchStatusHandler returns a page of the statuses of the files in the collection or bundle identified by the id
+// path variable, using the offset and limit query parameters
+func BatchStatusHandler(batchStatus BatchStatus) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		authHeaderValue := req.Header.Get(request.AuthHeaderKey)
+		augmentedContext := context.WithValue(req.Context(), config.AuthContextKey, authHeaderValue)
+
+		offset, err := queryInt(req, "offset", 0)
+		if err != nil || offset < 0 {
+			writeError(w, buildFieldErrors(ErrInvalidOffset, "offset", "min", req.URL.Query().Get("offset")), http.StatusBadRequest)
+			return
+		}
+		limit, err := queryInt(req, "limit", defaultStatusLimit)
+		if err != nil || limit < 1 || limit > maxStatusLimit {
+			writeError(w, buildFieldErrors(ErrInvalidLimit, "limit", "range", req.URL.Query().Get("limit")), http.StatusBadRequest)
+			return
+		}
+
+		id := mux.Vars(req)["id"]
+		batch, err := batchStatus(augmentedContext, id, offset, limit)
+		if err != nil {
+			log.Error(augmentedContext, "error getting batch status", err, log.Data{"id": id})
+			switch err {
+			case files.ErrFilesServer:
+				writeError(w, buildErrors(err, "RemoteServerError"), http.StatusInternalServerError)
+			case files.ErrFilesUnauthorised:
+				writeError(w, buildErrors(err, "Unauthorised"), http.StatusUnauthorized)
+			case files.ErrFilesForbidden:
+				writeError(w, buildErrors(err, "Forbidden"), http.StatusForbidden)
+			default:
+				writeError(w, buildErrors(err, "InternalError"), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(batch); err != nil {
+			log.Error(augmentedContext, "error encoding batch status response", err)
+		}
+	}
+}
+
+// queryInt returns the integer value of the query parameter, or the default if it was not provided
+func queryInt(req *http.Request, name string, defaultValue int) (int, error) {
+	value := req.URL.Query().Get(name)
+	if value == "" {
+		return defaultValue, nil
+	}
+	return strconv.Atoi(value)
+}
@@ -0,0 +1,107 @@
+package api_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ONSdigital/dp-upload-service/api"
+	"github.com/ONSdigital/dp-upload-service/files"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/suite"
+)
+
+type BatchStatusTestSuite struct {
+	suite.Suite
+
+	rec *httptest.ResponseRecorder
+}
+
+func TestBatchStatusTestSuite(t *testing.T) {
+	suite.Run(t, new(BatchStatusTestSuite))
+}
+
+func (s *BatchStatusTestSuite) SetupTest() {
+	s.rec = httptest.NewRecorder()
+}
+
+func (s *BatchStatusTestSuite) serve(target string, batchStatus api.BatchStatus) {
+	r := mux.NewRouter()
+	r.Path("/upload-new/collections/{id}/status").Methods(http.MethodGet).HandlerFunc(api.BatchStatusHandler(batchStatus))
+
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	r.ServeHTTP(s.rec, req)
+}
+
+func (s *BatchStatusTestSuite) TestBatchStatusReturns200() {
+	var capturedID string
+	var capturedOffset, capturedLimit int
+	s.serve("/upload-new/collections/collection-1/status?offset=20&limit=10", func(ctx context.Context, id string, offset, limit int) (*files.BatchStatus, error) {
+		capturedID, capturedOffset, capturedLimit = id, offset, limit
+		return &files.BatchStatus{
+			Count:      0,
+			Limit:      limit,
+			Offset:     offset,
+			TotalCount: 1,
+			States:     map[string]int{"UPLOADED": 1},
+			Items:      []files.Status{},
+		}, nil
+	})
+
+	s.Equal(http.StatusOK, s.rec.Code)
+	s.Equal("collection-1", capturedID)
+	s.Equal(20, capturedOffset)
+	s.Equal(10, capturedLimit)
+	s.Equal("application/json", s.rec.Header().Get("Content-Type"))
+	s.JSONEq(`{"count":0,"limit":10,"offset":20,"total_count":1,"states":{"UPLOADED":1},"items":[]}`, s.rec.Body.String())
+}
+
+func (s *BatchStatusTestSuite) TestDefaultPaging() {
+	var capturedOffset, capturedLimit int
+	s.serve("/upload-new/collections/collection-1/status", func(ctx context.Context, id string, offset, limit int) (*files.BatchStatus, error) {
+		capturedOffset, capturedLimit = offset, limit
+		return &files.BatchStatus{}, nil
+	})
+
+	s.Equal(http.StatusOK, s.rec.Code)
+	s.Equal(0, capturedOffset)
+	s.Equal(100, capturedLimit)
+}
+
+func (s *BatchStatusTestSuite) TestInvalidPagingReturns400() {
+	for _, query := range []string{"offset=-1", "offset=abc", "limit=0", "limit=1001", "limit=abc"} {
+		s.rec = httptest.NewRecorder()
+		called := false
+		s.serve("/upload-new/collections/collection-1/status?"+query, func(ctx context.Context, id string, offset, limit int) (*files.BatchStatus, error) {
+			called = true
+			return &files.BatchStatus{}, nil
+		})
+
+		s.Equal(http.StatusBadRequest, s.rec.Code, query)
+		s.Contains(s.rec.Body.String(), "ValidationError", query)
+		s.Contains(s.rec.Body.String(), `"field":"`+strings.SplitN(query, "=", 2)[0]+`"`, query)
+		s.False(called, query)
+	}
+}
+
+func (s *BatchStatusTestSuite) TestFilesAPIForbiddenReturns403() {
+	s.serve("/upload-new/collections/collection-1/status", func(ctx context.Context, id string, offset, limit int) (*files.BatchStatus, error) {
+		return nil, files.ErrFilesForbidden
+	})
+
+	s.Equal(http.StatusForbidden, s.rec.Code)
+}
+
+func (s *BatchStatusTestSuite) TestUnexpectedErrorReturns500() {
+	s.serve("/upload-new/collections/collection-1/status", func(ctx context.Context, id string, offset, limit int) (*files.BatchStatus, error) {
+		return nil, errors.New("broken")
+	})
+
+	s.Equal(http.StatusInternalServerError, s.rec.Code)
+	response, _ := io.ReadAll(s.rec.Body)
+	s.Contains(string(response), "InternalError")
+}
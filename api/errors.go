@@ -2,15 +2,39 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
 
 	"github.com/go-playground/validator"
+	"github.com/gorilla/schema"
 )
 
+// ValidationDocsURL documents the rules that request fields are validated against
+const ValidationDocsURL = "https://github.com/ONSdigital/dp-upload-service/blob/main/README.md#validation-errors"
+
+const (
+	// maxRejectedValueLength is the longest rejected value that is echoed back in full, longer values are shortened
+	maxRejectedValueLength = 64
+	// redactedValue replaces rejected values that cannot safely be echoed back, such as those with control characters
+	redactedValue = "[REDACTED]"
+)
+
+// JsonError describes one reason that a request failed. Errors caused by an invalid field also give the name of the
+// form field, the rule it broke and the value it was rejected with, along with a link to the documentation of the
+// rules. The description is a human-readable message.
 type JsonError struct {
 	Code        string `json:"code"`
 	Description string `json:"description"`
+	Field       string `json:"field,omitempty"`
+	Rule        string `json:"rule,omitempty"`
+	Value       string `json:"value,omitempty"`
+	DocsURL     string `json:"docs_url,omitempty"`
 }
 
 type JsonErrors struct {
@@ -28,12 +52,127 @@ func buildErrors(err error, code string) JsonErrors {
 	return JsonErrors{Error: []JsonError{{Description: err.Error(), Code: code}}}
 }
 
-func buildValidationErrors(validationErrs validator.ValidationErrors) JsonErrors {
+// buildFieldErrors describes a single field that broke the rule with the value
+func buildFieldErrors(err error, field, rule, value string) JsonErrors {
+	return JsonErrors{Error: []JsonError{newFieldError("ValidationError", err.Error(), field, rule, value)}}
+}
+
+// buildValidationErrors describes each field that failed validation, using the form to find the values they were
+// rejected with. The validator must report the form field names, as set up by newValidator.
+func buildValidationErrors(validationErrs validator.ValidationErrors, form url.Values) JsonErrors {
 	jsonErrs := JsonErrors{Error: []JsonError{}}
 
 	for _, validationErr := range validationErrs {
-		desc := fmt.Sprintf("%s %s", validationErr.Field(), validationErr.Tag())
-		jsonErrs.Error = append(jsonErrs.Error, JsonError{Code: "ValidationError", Description: desc})
+		field := validationErr.Field()
+		jsonErrs.Error = append(jsonErrs.Error, newFieldError(
+			"ValidationError",
+			validationMessage(field, validationErr.Tag()),
+			field,
+			validationErr.Tag(),
+			form.Get(field),
+		))
 	}
 	return jsonErrs
 }
+
+// buildDecodingErrors describes each field that could not be decoded from the form into the type it is read as. Any
+// other decoding error is reported with the code alone.
+func buildDecodingErrors(err error, code string, form url.Values) JsonErrors {
+	var multiErr schema.MultiError
+	if !errors.As(err, &multiErr) {
+		return buildErrors(err, code)
+	}
+
+	fields := make([]string, 0, len(multiErr))
+	for field := range multiErr {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	jsonErrs := JsonErrors{Error: []JsonError{}}
+	for _, field := range fields {
+		var conversionErr schema.ConversionError
+		if !errors.As(multiErr[field], &conversionErr) {
+			jsonErrs.Error = append(jsonErrs.Error, JsonError{Code: code, Description: multiErr[field].Error(), Field: field})
+			continue
+		}
+		jsonErrs.Error = append(jsonErrs.Error, newFieldError(
+			code,
+			fmt.Sprintf("%s must be %s", field, typeDescription(conversionErr.Type)),
+			field,
+			"type",
+			form.Get(field),
+		))
+	}
+	return jsonErrs
+}
+
+// newValidator returns a validator for the upload forms that reports fields by their form field names
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterValidation("aws-upload-key", awsUploadKeyValidator)  // nolint // Only fails due to coding error
+	v.RegisterValidation("unreserved-key", unreservedKeyValidator) // nolint // Only fails due to coding error
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("schema"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	return v
+}
+
+func newFieldError(code, description, field, rule, value string) JsonError {
+	return JsonError{
+		Code:        code,
+		Description: description,
+		Field:       field,
+		Rule:        rule,
+		Value:       redact(value),
+		DocsURL:     ValidationDocsURL,
+	}
+}
+
+// validationMessage describes the validation rule that the field broke
+func validationMessage(field, rule string) string {
+	switch rule {
+	case "required":
+		return fmt.Sprintf("%s is required", field)
+	case "aws-upload-key":
+		return fmt.Sprintf("%s can only contain letters, numbers and the characters /!*_'().-", field)
+	case "unreserved-key":
+		return fmt.Sprintf("%s cannot be under a prefix reserved by the service", field)
+	default:
+		return fmt.Sprintf("%s failed the %s rule", field, rule)
+	}
+}
+
+// typeDescription describes the values of the type that a field is decoded into
+func typeDescription(t reflect.Type) string {
+	if t == nil {
+		return "a valid value"
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "a whole number"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Bool:
+		return "true or false"
+	default:
+		return "a valid " + t.String()
+	}
+}
+
+// redact makes a rejected value safe to echo back in an error response. Values containing control characters are
+// replaced entirely, and long values are shortened.
+func redact(value string) string {
+	if strings.IndexFunc(value, unicode.IsControl) >= 0 {
+		return redactedValue
+	}
+	if runes := []rune(value); len(runes) > maxRejectedValueLength {
+		return string(runes[:maxRejectedValueLength]) + "..."
+	}
+	return value
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ONSdigital/dp-upload-service/files"
+	"github.com/ONSdigital/log.go/v2/log"
+	"github.com/gorilla/mux"
+)
+
+var ErrStreamingUnsupported = errors.New("streaming is not supported by the response writer")
+
+type SubscribeToUploadEvents func(path string) (<-chan files.UploadEvent, func())
+
+// UploadEventsHandler streams the events published for the upload of the file path as Server-Sent Events until the
+// file has been uploaded or quarantined, the client disconnects or the events are closed as the service shuts down. A
+// comment is sent at every heartbeat interval to keep the connection open while there is nothing to report; an interval
+// of zero sends no heartbeats.
+func UploadEventsHandler(subscribe SubscribeToUploadEvents, heartbeatInterval time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		ctx := req.Context()
+		path := mux.Vars(req)["path"]
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			log.Error(ctx, "unable to stream upload events", ErrStreamingUnsupported, log.Data{"path": path})
+			writeError(w, buildErrors(ErrStreamingUnsupported, "InternalError"), http.StatusInternalServerError)
+			return
+		}
+
+		// The server write timeout would otherwise end the stream, so remove it where the response writer allows
+		if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+			log.Info(ctx, "unable to remove write deadline for upload events stream", log.Data{"path": path, "error": err.Error()})
+		}
+
+		events, unsubscribe := subscribe(path)
+		defer unsubscribe()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, ": connected\n\n")
+		flusher.Flush()
+
+		var heartbeat <-chan time.Time
+		if heartbeatInterval > 0 {
+			ticker := time.NewTicker(heartbeatInterval)
+			defer ticker.Stop()
+			heartbeat = ticker.C
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-heartbeat:
+				if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+					return
+				}
+				flusher.Flush()
+			case event, ok := <-events:
+				if !ok {
+					return
+				}
+				data, err := json.Marshal(event)
+				if err != nil {
+					log.Error(ctx, "error encoding upload event", err, log.Data{"path": path})
+					continue
+				}
+				if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data); err != nil {
+					return
+				}
+				flusher.Flush()
+
+				if event.Type == files.EventUploaded || event.Type == files.EventQuarantined {
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package api_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-upload-service/api"
+	"github.com/ONSdigital/dp-upload-service/files"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/suite"
+)
+
+type EventsTestSuite struct {
+	suite.Suite
+
+	rec *httptest.ResponseRecorder
+	hub *files.Hub
+}
+
+func TestEventsTestSuite(t *testing.T) {
+	suite.Run(t, new(EventsTestSuite))
+}
+
+func (s *EventsTestSuite) SetupTest() {
+	s.rec = httptest.NewRecorder()
+	s.hub = files.NewHub()
+}
+
+// serve streams the events for data/file.csv in the background, returning a channel that is closed once the stream ends
+func (s *EventsTestSuite) serve(ctx context.Context, subscribe api.SubscribeToUploadEvents, heartbeatInterval time.Duration) <-chan struct{} {
+	r := mux.NewRouter()
+	r.Path("/upload-new/files/{path:.*?}/events").Methods(http.MethodGet).HandlerFunc(api.UploadEventsHandler(subscribe, heartbeatInterval))
+
+	req := httptest.NewRequest(http.MethodGet, "/upload-new/files/data/file.csv/events", nil).WithContext(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.ServeHTTP(s.rec, req)
+	}()
+	return done
+}
+
+func (s *EventsTestSuite) TestEventsAreStreamedUntilUploaded() {
+	subscribed := make(chan struct{})
+	done := s.serve(context.Background(), func(path string) (<-chan files.UploadEvent, func()) {
+		s.Equal("data/file.csv", path)
+		events, unsubscribe := s.hub.Subscribe(path)
+		close(subscribed)
+		return events, unsubscribe
+	}, time.Minute)
+
+	<-subscribed
+	s.hub.Publish(files.UploadEvent{Type: files.EventChunkReceived, Path: "data/file.csv", ChunkNumber: 1, TotalChunks: 1})
+	s.hub.Publish(files.UploadEvent{Type: files.EventUploaded, Path: "data/file.csv"})
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		s.FailNow("stream did not end once the file was uploaded")
+	}
+
+	s.Equal(http.StatusOK, s.rec.Code)
+	s.Equal("text/event-stream", s.rec.Header().Get("Content-Type"))
+	s.Equal("no-cache", s.rec.Header().Get("Cache-Control"))
+	body := s.rec.Body.String()
+	s.Contains(body, "event: chunk_received\ndata: {\"type\":\"chunk_received\",\"path\":\"data/file.csv\",\"chunk_number\":1,\"total_chunks\":1,")
+	s.Contains(body, "event: uploaded\n")
+}
+
+func (s *EventsTestSuite) TestStreamEndsWhenQuarantined() {
+	subscribed := make(chan struct{})
+	done := s.serve(context.Background(), func(path string) (<-chan files.UploadEvent, func()) {
+		events, unsubscribe := s.hub.Subscribe(path)
+		close(subscribed)
+		return events, unsubscribe
+	}, time.Minute)
+
+	<-subscribed
+	s.hub.Publish(files.UploadEvent{Type: files.EventQuarantined, Path: "data/file.csv", Error: "Eicar-Test-Signature"})
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		s.FailNow("stream did not end once the file was quarantined")
+	}
+
+	s.Contains(s.rec.Body.String(), "event: quarantined\n")
+}
+
+func (s *EventsTestSuite) TestStreamEndsWhenClientDisconnects() {
+	unsubscribed := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := s.serve(ctx, func(path string) (<-chan files.UploadEvent, func()) {
+		return make(chan files.UploadEvent), func() { close(unsubscribed) }
+	}, time.Minute)
+
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		s.FailNow("stream did not end when the client disconnected")
+	}
+	<-unsubscribed
+}
+
+func (s *EventsTestSuite) TestHeartbeatsAreSent() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := s.serve(ctx, func(path string) (<-chan files.UploadEvent, func()) {
+		return make(chan files.UploadEvent), func() {}
+	}, time.Millisecond)
+
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	<-done
+
+	s.Contains(s.rec.Body.String(), ": heartbeat\n\n")
+}
+
+func (s *EventsTestSuite) TestStreamEndsWhenEventsAreClosed() {
+	subscribed := make(chan struct{})
+	done := s.serve(context.Background(), func(path string) (<-chan files.UploadEvent, func()) {
+		events, unsubscribe := s.hub.Subscribe(path)
+		close(subscribed)
+		return events, unsubscribe
+	}, time.Minute)
+
+	<-subscribed
+	s.hub.Close()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		s.FailNow("stream did not end when the events were closed")
+	}
+}
+
+func (s *EventsTestSuite) TestNoHeartbeatsAreSentWithoutAnInterval() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := s.serve(ctx, func(path string) (<-chan files.UploadEvent, func()) {
+		return make(chan files.UploadEvent), func() {}
+	}, 0)
+
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	<-done
+
+	s.Equal(http.StatusOK, s.rec.Code)
+	s.NotContains(s.rec.Body.String(), ": heartbeat\n\n")
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/ONSdigital/log.go/v2/log"
+	"golang.org/x/sync/semaphore"
+)
+
+// InFlightLimiter caps the memory used by the request bodies being handled at any one time across all requests. A chunk
+// of a declared size is streamed straight through to S3, and any other chunk is buffered in memory up to the size of a
+// chunk sent by the SDK with anything larger spilled to disk, so no request counts for more than that.
+type InFlightLimiter struct {
+	sem      *semaphore.Weighted
+	maxBytes int64
+}
+
+// NewInFlightLimiter creates an InFlightLimiter allowing up to maxBytes of request bodies to be in flight at once
+func NewInFlightLimiter(maxBytes int64) *InFlightLimiter {
+	return &InFlightLimiter{
+		sem:      semaphore.NewWeighted(maxBytes),
+		maxBytes: maxBytes,
+	}
+}
+
+// Limit wraps the handler so that it only runs once there is enough capacity for the request body.
+// Requests wait for capacity to become available until their context is done.
+func (l *InFlightLimiter) Limit(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		weight := l.weight(req)
+		if err := l.sem.Acquire(req.Context(), weight); err != nil {
+			log.Error(req.Context(), "error waiting for upload capacity", err, log.Data{"content_length": req.ContentLength})
+			writeError(w, buildErrors(err, "UploadCapacityExceeded"), http.StatusServiceUnavailable)
+			return
+		}
+		defer l.sem.Release(weight)
+
+		next(w, req)
+	}
+}
+
+// weight returns the number of bytes of memory the request counts for: its length, up to the largest chunk that is
+// buffered in memory, and never more than the limit so that a request can still be handled on its own if the limit
+// is set lower than a chunk
+func (l *InFlightLimiter) weight(req *http.Request) int64 {
+	weight := req.ContentLength
+	if weight <= 0 || weight > maxMultipartMemory {
+		weight = maxMultipartMemory
+	}
+	if weight > l.maxBytes {
+		weight = l.maxBytes
+	}
+	return weight
+}
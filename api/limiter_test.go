@@ -0,0 +1,110 @@
+package api_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/ONSdigital/dp-upload-service/api"
+)
+
+type LimiterTestSuite struct {
+	suite.Suite
+}
+
+func TestLimiterTestSuite(t *testing.T) {
+	suite.Run(t, new(LimiterTestSuite))
+}
+
+func (s *LimiterTestSuite) TestRequestWithinLimitIsHandled() {
+	called := false
+	h := api.NewInFlightLimiter(100).Limit(func(w http.ResponseWriter, req *http.Request) {
+		called = true
+		w.WriteHeader(http.StatusOK)
+	})
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, UploadURI, strings.NewReader("0123456789")))
+
+	s.True(called)
+	s.Equal(http.StatusOK, rec.Code)
+}
+
+func (s *LimiterTestSuite) TestRequestLargerThanLimitIsHandledOnItsOwn() {
+	called := false
+	h := api.NewInFlightLimiter(5).Limit(func(w http.ResponseWriter, req *http.Request) {
+		called = true
+		w.WriteHeader(http.StatusOK)
+	})
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, UploadURI, strings.NewReader("0123456789")))
+
+	s.True(called)
+	s.Equal(http.StatusOK, rec.Code)
+}
+
+func (s *LimiterTestSuite) TestRequestWaitsForCapacity() {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	limiter := api.NewInFlightLimiter(10)
+
+	blocking := limiter.Limit(func(w http.ResponseWriter, req *http.Request) {
+		close(started)
+		<-release
+	})
+	go blocking.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, UploadURI, strings.NewReader("0123456789")))
+	<-started
+
+	called := false
+	h := limiter.Limit(func(w http.ResponseWriter, req *http.Request) {
+		called = true
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, UploadURI, strings.NewReader("0123456789")).WithContext(ctx)
+	h.ServeHTTP(rec, req)
+	close(release)
+
+	s.False(called)
+	s.Equal(http.StatusServiceUnavailable, rec.Code)
+	response := rec.Body.String()
+	s.Contains(response, "UploadCapacityExceeded")
+}
+
+func (s *LimiterTestSuite) TestRequestLargerThanAChunkCountsForOneChunk() {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	limiter := api.NewInFlightLimiter(2 * 6 * 1024 * 1024)
+	largeRequest := func() *http.Request {
+		req := httptest.NewRequest(http.MethodPost, UploadURI, strings.NewReader("0123456789"))
+		req.ContentLength = 100 * 1024 * 1024
+		return req
+	}
+
+	blocking := limiter.Limit(func(w http.ResponseWriter, req *http.Request) {
+		close(started)
+		<-release
+	})
+	go blocking.ServeHTTP(httptest.NewRecorder(), largeRequest())
+	<-started
+
+	called := false
+	h := limiter.Limit(func(w http.ResponseWriter, req *http.Request) {
+		called = true
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	h.ServeHTTP(httptest.NewRecorder(), largeRequest().WithContext(ctx))
+	close(release)
+
+	s.True(called)
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/ONSdigital/dp-net/v3/request"
+	"github.com/ONSdigital/dp-upload-service/config"
+	"github.com/ONSdigital/dp-upload-service/files"
+	"github.com/ONSdigital/log.go/v2/log"
+	"github.com/gorilla/mux"
+)
+
+type ListUploadedParts func(ctx context.Context, path string) (*files.UploadedParts, error)
+
+// UploadedPartsHandler lists the chunks received so far for the in progress upload of the file path
+func UploadedPartsHandler(listUploadedParts ListUploadedParts) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		authHeaderValue := req.Header.Get(request.AuthHeaderKey)
+		augmentedContext := context.WithValue(req.Context(), config.AuthContextKey, authHeaderValue)
+
+		path := mux.Vars(req)["path"]
+		parts, err := listUploadedParts(augmentedContext, path)
+		if err != nil {
+			log.Error(augmentedContext, "error listing uploaded parts", err, log.Data{"path": path})
+			switch err {
+			case files.ErrUploadNotFound:
+				writeError(w, buildErrors(err, "NotFound"), http.StatusNotFound)
+			case files.ErrUploadComplete:
+				writeError(w, buildErrors(err, "UploadComplete"), http.StatusConflict)
+			case files.ErrFilesServer:
+				writeError(w, buildErrors(err, "RemoteServerError"), http.StatusInternalServerError)
+			case files.ErrFilesUnauthorised:
+				writeError(w, buildErrors(err, "Unauthorised"), http.StatusUnauthorized)
+			case files.ErrFilesForbidden:
+				writeError(w, buildErrors(err, "Forbidden"), http.StatusForbidden)
+			default:
+				writeError(w, buildErrors(err, "InternalError"), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(parts); err != nil {
+			log.Error(augmentedContext, "error encoding uploaded parts response", err)
+		}
+	}
+}
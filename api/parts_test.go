@@ -0,0 +1,83 @@
+package api_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ONSdigital/dp-upload-service/api"
+	"github.com/ONSdigital/dp-upload-service/files"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/suite"
+)
+
+type PartsTestSuite struct {
+	suite.Suite
+
+	rec *httptest.ResponseRecorder
+}
+
+func TestPartsTestSuite(t *testing.T) {
+	suite.Run(t, new(PartsTestSuite))
+}
+
+func (s *PartsTestSuite) SetupTest() {
+	s.rec = httptest.NewRecorder()
+}
+
+func (s *PartsTestSuite) serve(listUploadedParts api.ListUploadedParts) {
+	r := mux.NewRouter()
+	r.Path("/upload-new/files/{path:.*?}/parts").Methods(http.MethodGet).HandlerFunc(api.UploadedPartsHandler(listUploadedParts))
+
+	req := httptest.NewRequest(http.MethodGet, "/upload-new/files/data/file.csv/parts", nil)
+	r.ServeHTTP(s.rec, req)
+}
+
+func (s *PartsTestSuite) TestUploadedPartsReturns200() {
+	var capturedPath string
+	s.serve(func(ctx context.Context, path string) (*files.UploadedParts, error) {
+		capturedPath = path
+		return &files.UploadedParts{
+			Path:  path,
+			Parts: []files.UploadedPart{{ChunkNumber: 2, SizeInBytes: 10, ETag: "etag-2"}},
+		}, nil
+	})
+
+	s.Equal(http.StatusOK, s.rec.Code)
+	s.Equal("data/file.csv", capturedPath)
+	s.Equal("application/json", s.rec.Header().Get("Content-Type"))
+	s.JSONEq(`{"path":"data/file.csv","parts":[{"chunk_number":2,"size_in_bytes":10,"etag":"etag-2"}]}`, s.rec.Body.String())
+}
+
+func (s *PartsTestSuite) TestUploadNotFoundReturns404() {
+	s.serve(func(ctx context.Context, path string) (*files.UploadedParts, error) {
+		return nil, files.ErrUploadNotFound
+	})
+
+	s.Equal(http.StatusNotFound, s.rec.Code)
+	response, _ := io.ReadAll(s.rec.Body)
+	s.Contains(string(response), "NotFound")
+}
+
+func (s *PartsTestSuite) TestCompletedUploadReturns409() {
+	s.serve(func(ctx context.Context, path string) (*files.UploadedParts, error) {
+		return nil, files.ErrUploadComplete
+	})
+
+	s.Equal(http.StatusConflict, s.rec.Code)
+	response, _ := io.ReadAll(s.rec.Body)
+	s.Contains(string(response), "UploadComplete")
+}
+
+func (s *PartsTestSuite) TestUnexpectedErrorReturns500() {
+	s.serve(func(ctx context.Context, path string) (*files.UploadedParts, error) {
+		return nil, errors.New("broken")
+	})
+
+	s.Equal(http.StatusInternalServerError, s.rec.Code)
+	response, _ := io.ReadAll(s.rec.Body)
+	s.Contains(string(response), "InternalError")
+}
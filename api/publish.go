@@ -0,0 +1,48 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/ONSdigital/dp-net/v3/request"
+	"github.com/ONSdigital/dp-upload-service/config"
+	"github.com/ONSdigital/dp-upload-service/files"
+	"github.com/ONSdigital/log.go/v2/log"
+	"github.com/gorilla/mux"
+)
+
+type PublishFile func(ctx context.Context, path string) error
+
+// PublishHandler marks the uploaded file at the path as published, responding with 204 once it has been published
+func PublishHandler(publishFile PublishFile) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		authHeaderValue := req.Header.Get(request.AuthHeaderKey)
+		augmentedContext := context.WithValue(req.Context(), config.AuthContextKey, authHeaderValue)
+
+		path := mux.Vars(req)["path"]
+		if err := publishFile(augmentedContext, path); err != nil {
+			log.Error(augmentedContext, "error publishing file", err, log.Data{"path": path})
+			switch err {
+			case files.ErrFilesAPINotFound:
+				writeError(w, buildErrors(err, "NotFound"), http.StatusNotFound)
+			case files.ErrUploadNotComplete:
+				writeError(w, buildErrors(err, "UploadNotComplete"), http.StatusConflict)
+			case files.ErrFileAlreadyPublished:
+				writeError(w, buildErrors(err, "AlreadyPublished"), http.StatusConflict)
+			case files.ErrFileNotPublishable:
+				writeError(w, buildErrors(err, "NotPublishable"), http.StatusConflict)
+			case files.ErrFilesServer:
+				writeError(w, buildErrors(err, "RemoteServerError"), http.StatusInternalServerError)
+			case files.ErrFilesUnauthorised:
+				writeError(w, buildErrors(err, "Unauthorised"), http.StatusUnauthorized)
+			case files.ErrFilesForbidden:
+				writeError(w, buildErrors(err, "Forbidden"), http.StatusForbidden)
+			default:
+				writeError(w, buildErrors(err, "InternalError"), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,76 @@
+package api_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ONSdigital/dp-upload-service/api"
+	"github.com/ONSdigital/dp-upload-service/files"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/suite"
+)
+
+type PublishTestSuite struct {
+	suite.Suite
+
+	rec *httptest.ResponseRecorder
+}
+
+func TestPublishTestSuite(t *testing.T) {
+	suite.Run(t, new(PublishTestSuite))
+}
+
+func (s *PublishTestSuite) SetupTest() {
+	s.rec = httptest.NewRecorder()
+}
+
+func (s *PublishTestSuite) serve(publishFile api.PublishFile) {
+	r := mux.NewRouter()
+	r.Path("/upload-new/files/{path:.*?}/publish").Methods(http.MethodPost).HandlerFunc(api.PublishHandler(publishFile))
+
+	req := httptest.NewRequest(http.MethodPost, "/upload-new/files/data/file.csv/publish", nil)
+	r.ServeHTTP(s.rec, req)
+}
+
+func (s *PublishTestSuite) TestPublishedFileReturns204() {
+	var capturedPath string
+	s.serve(func(ctx context.Context, path string) error {
+		capturedPath = path
+		return nil
+	})
+
+	s.Equal(http.StatusNoContent, s.rec.Code)
+	s.Equal("data/file.csv", capturedPath)
+}
+
+func (s *PublishTestSuite) TestErrorsAreMappedToResponses() {
+	tests := []struct {
+		err          error
+		expectedCode int
+		expectedBody string
+	}{
+		{files.ErrFilesAPINotFound, http.StatusNotFound, "NotFound"},
+		{files.ErrUploadNotComplete, http.StatusConflict, "UploadNotComplete"},
+		{files.ErrFileAlreadyPublished, http.StatusConflict, "AlreadyPublished"},
+		{files.ErrFileNotPublishable, http.StatusConflict, "NotPublishable"},
+		{files.ErrFilesUnauthorised, http.StatusUnauthorized, "Unauthorised"},
+		{files.ErrFilesForbidden, http.StatusForbidden, "Forbidden"},
+		{files.ErrFilesServer, http.StatusInternalServerError, "RemoteServerError"},
+		{errors.New("broken"), http.StatusInternalServerError, "InternalError"},
+	}
+
+	for _, test := range tests {
+		s.rec = httptest.NewRecorder()
+		s.serve(func(ctx context.Context, path string) error {
+			return test.err
+		})
+
+		s.Equal(test.expectedCode, s.rec.Code, test.err.Error())
+		response, _ := io.ReadAll(s.rec.Body)
+		s.Contains(string(response), test.expectedBody, test.err.Error())
+	}
+}
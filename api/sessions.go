@@ -0,0 +1,152 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+
+	filesAPI "github.com/ONSdigital/dp-api-clients-go/v2/files"
+	"github.com/ONSdigital/dp-net/v3/request"
+	"github.com/ONSdigital/dp-upload-service/config"
+	"github.com/ONSdigital/dp-upload-service/files"
+	"github.com/ONSdigital/log.go/v2/log"
+	"github.com/gorilla/mux"
+)
+
+type CreateUploadSession func(ctx context.Context, uf files.FileMetadataWithContentItem, r files.Resumable) (*files.UploadSession, error)
+
+type CompleteUploadSession func(ctx context.Context, uploadID string, uf files.FileMetadataWithContentItem, r files.Resumable) error
+
+// CreateUploadSessionHandler starts an upload whose chunks are sent directly to the static files bucket. It takes the
+// same fields as CreateV1UploadHandler, without the file, and responds with a presigned URL for each of the
+// resumableTotalChunks chunks.
+func CreateUploadSessionHandler(createUploadSession CreateUploadSession) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		form, ok := readSessionForm(w, req)
+		if !ok {
+			return
+		}
+
+		authHeaderValue := req.Header.Get(request.AuthHeaderKey)
+		augmentedContext := context.WithValue(req.Context(), config.AuthContextKey, authHeaderValue)
+
+		metadata, resumable, ok := decodeUploadForm(augmentedContext, w, form)
+		if !ok {
+			return
+		}
+
+		if !checkAuthorisedCollection(w, req, metadata) {
+			return
+		}
+
+		session, err := createUploadSession(augmentedContext, getStoreMetadata(metadata, resumable), resumable)
+		if err != nil {
+			log.Error(augmentedContext, "error creating upload session", err, log.Data{"path": metadata.Path})
+			writeSessionError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		if err := json.NewEncoder(w).Encode(session); err != nil {
+			log.Error(augmentedContext, "error encoding upload session response", err)
+		}
+	}
+}
+
+// CompleteUploadSessionHandler completes the upload session with the ID once every chunk has been uploaded to its
+// presigned URL, registering the file and marking it as uploaded. It takes the same fields that the session was
+// created with, and an optional fileChecksum.
+func CompleteUploadSessionHandler(completeUploadSession CompleteUploadSession) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		form, ok := readSessionForm(w, req)
+		if !ok {
+			return
+		}
+
+		authHeaderValue := req.Header.Get(request.AuthHeaderKey)
+		augmentedContext := context.WithValue(req.Context(), config.AuthContextKey, authHeaderValue)
+
+		metadata, resumable, ok := decodeUploadForm(augmentedContext, w, form)
+		if !ok {
+			return
+		}
+
+		if !checkAuthorisedCollection(w, req, metadata) {
+			return
+		}
+
+		uploadID := mux.Vars(req)["id"]
+		if err := completeUploadSession(augmentedContext, uploadID, getStoreMetadata(metadata, resumable), resumable); err != nil {
+			log.Error(augmentedContext, "error completing upload session", err, log.Data{"upload_id": uploadID})
+			writeSessionError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+	}
+}
+
+// readSessionForm reads the fields sent to the upload session endpoints, as either a multipart or a URL encoded form,
+// along with any in the query string. If the form cannot be read the error response is written and false is returned.
+func readSessionForm(w http.ResponseWriter, req *http.Request) (url.Values, bool) {
+	req.Body = http.MaxBytesReader(w, req.Body, maxFormFieldsSize)
+	err := req.ParseForm()
+	if err == nil {
+		err = req.ParseMultipartForm(maxFormFieldsSize)
+	}
+	if err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		log.Error(req.Context(), "error parsing form", err)
+		writeError(w, buildErrors(err, "ParsingForm"), http.StatusBadRequest)
+		return nil, false
+	}
+
+	return req.Form, true
+}
+
+func writeSessionError(w http.ResponseWriter, err error) {
+	switch err {
+	case files.ErrInvalidTotalChunks:
+		writeError(w, buildFieldErrors(err, "resumableTotalChunks", "range", ""), http.StatusBadRequest)
+	case filesAPI.ErrFileAlreadyRegistered:
+		writeError(w, buildErrors(err, "DuplicateFile"), http.StatusConflict)
+	case files.ErrUploadInProgress:
+		writeError(w, buildErrors(err, "UploadInProgress"), http.StatusConflict)
+	case files.ErrUploadNotFound:
+		writeError(w, buildErrors(err, "NotFound"), http.StatusNotFound)
+	case files.ErrPartsMissing:
+		writeError(w, buildErrors(err, "PartsMissing"), http.StatusConflict)
+	case files.ErrDirectUploadUnsupported:
+		writeError(w, buildErrors(err, "DirectUploadUnsupported"), http.StatusNotImplemented)
+	case files.ErrFileAPICreateInvalidData:
+		writeError(w, buildErrors(err, "RemoteValidationError"), http.StatusInternalServerError)
+	case files.ErrChunkTooSmall:
+		writeError(w, buildErrors(err, "ChunkTooSmall"), http.StatusBadRequest)
+	case files.ErrFileChecksumMismatch:
+		writeError(w, buildErrors(err, "FileChecksumMismatch"), http.StatusBadRequest)
+	case files.ErrInvalidFileChecksum:
+		writeError(w, buildErrors(err, "InvalidFileChecksum"), http.StatusBadRequest)
+	case files.ErrUnsupportedMediaType:
+		writeError(w, buildErrors(err, "UnsupportedMediaType"), http.StatusUnsupportedMediaType)
+	case files.ErrFileTooLarge:
+		writeError(w, buildErrors(err, "FileTooLarge"), http.StatusRequestEntityTooLarge)
+	case files.ErrSizeMismatch:
+		writeError(w, buildErrors(err, "SizeMismatch"), http.StatusBadRequest)
+	case files.ErrReservedPath:
+		writeError(w, buildErrors(err, "ReservedPath"), http.StatusBadRequest)
+	case files.ErrFileQuarantined:
+		writeError(w, buildErrors(err, "FileQuarantined"), http.StatusUnprocessableEntity)
+	case files.ErrScanFailed:
+		writeError(w, buildErrors(err, "ScanFailed"), http.StatusInternalServerError)
+	case files.ErrFilesServer:
+		writeError(w, buildErrors(err, "RemoteServerError"), http.StatusInternalServerError)
+	case files.ErrFilesUnauthorised:
+		writeError(w, buildErrors(err, "Unauthorised"), http.StatusUnauthorized)
+	case files.ErrFilesForbidden:
+		writeError(w, buildErrors(err, "Forbidden"), http.StatusForbidden)
+	default:
+		writeError(w, buildErrors(err, "InternalError"), http.StatusInternalServerError)
+	}
+}
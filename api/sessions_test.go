@@ -0,0 +1,240 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	filesAPI "github.com/ONSdigital/dp-api-clients-go/v2/files"
+	"github.com/ONSdigital/dp-upload-service/api"
+	"github.com/ONSdigital/dp-upload-service/files"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/suite"
+)
+
+type SessionsTestSuite struct {
+	suite.Suite
+
+	rec *httptest.ResponseRecorder
+}
+
+func TestSessionsTestSuite(t *testing.T) {
+	suite.Run(t, new(SessionsTestSuite))
+}
+
+func (s *SessionsTestSuite) SetupTest() {
+	s.rec = httptest.NewRecorder()
+}
+
+func (s *SessionsTestSuite) serveCreate(createUploadSession api.CreateUploadSession, req *http.Request) {
+	r := mux.NewRouter()
+	r.Path("/upload-new/sessions").Methods(http.MethodPost).HandlerFunc(api.CreateUploadSessionHandler(createUploadSession))
+	r.ServeHTTP(s.rec, req)
+}
+
+func (s *SessionsTestSuite) serveComplete(completeUploadSession api.CompleteUploadSession, req *http.Request) {
+	r := mux.NewRouter()
+	r.Path("/upload-new/sessions/{id}/complete").Methods(http.MethodPost).HandlerFunc(api.CompleteUploadSessionHandler(completeUploadSession))
+	r.ServeHTTP(s.rec, req)
+}
+
+func sessionForm() url.Values {
+	form := url.Values{}
+	form.Set("resumableFilename", "file.csv")
+	form.Set("path", "valid")
+	form.Set("isPublishable", "false")
+	form.Set("collectionId", "1234567890")
+	form.Set("title", "A New File")
+	form.Set("resumableTotalSize", "1478")
+	form.Set("resumableType", "text/csv")
+	form.Set("licence", "OGL v3")
+	form.Set("licenceUrl", "http://www.nationalarchives.gov.uk/doc/open-government-licence/version/3/")
+	form.Set("resumableTotalChunks", "2")
+	return form
+}
+
+func sessionRequest(uri string, form url.Values) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, uri, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req
+}
+
+func (s *SessionsTestSuite) TestCreateUploadSessionReturns201() {
+	var capturedMetadata files.FileMetadataWithContentItem
+	var capturedResumable files.Resumable
+	s.serveCreate(func(ctx context.Context, uf files.FileMetadataWithContentItem, r files.Resumable) (*files.UploadSession, error) {
+		capturedMetadata, capturedResumable = uf, r
+		return &files.UploadSession{
+			UploadID:  "upload-id",
+			Path:      uf.Path,
+			Parts:     []files.PresignedPart{{ChunkNumber: 1, URL: "https://bucket/valid/file.csv?partNumber=1"}},
+			ExpiresAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		}, nil
+	}, sessionRequest("/upload-new/sessions", sessionForm()))
+
+	s.Equal(http.StatusCreated, s.rec.Code)
+	s.Equal("valid/file.csv", capturedMetadata.Path)
+	s.Equal(2, capturedResumable.TotalChunks)
+	s.Equal("application/json", s.rec.Header().Get("Content-Type"))
+	s.JSONEq(`{
+		"upload_id": "upload-id",
+		"path": "valid/file.csv",
+		"parts": [{"chunk_number": 1, "url": "https://bucket/valid/file.csv?partNumber=1"}],
+		"expires_at": "2024-01-02T03:04:05Z"
+	}`, s.rec.Body.String())
+}
+
+func (s *SessionsTestSuite) TestCreateUploadSessionAcceptsMultipartForm() {
+	b, formWriter := generateFormWriter("valid")
+	formWriter.WriteField("resumableTotalChunks", "2")
+	formWriter.Close()
+	req := httptest.NewRequest(http.MethodPost, "/upload-new/sessions", b)
+	req.Header.Set("Content-Type", formWriter.FormDataContentType())
+
+	funcCalled := false
+	s.serveCreate(func(ctx context.Context, uf files.FileMetadataWithContentItem, r files.Resumable) (*files.UploadSession, error) {
+		funcCalled = true
+		return &files.UploadSession{}, nil
+	}, req)
+
+	s.Equal(http.StatusCreated, s.rec.Code)
+	s.True(funcCalled)
+}
+
+func (s *SessionsTestSuite) TestCreateUploadSessionRequiredFields() {
+	s.serveCreate(func(ctx context.Context, uf files.FileMetadataWithContentItem, r files.Resumable) (*files.UploadSession, error) {
+		s.Fail("session should not be created")
+		return nil, nil
+	}, sessionRequest("/upload-new/sessions", url.Values{}))
+
+	s.Equal(http.StatusBadRequest, s.rec.Code)
+	s.Contains(s.rec.Body.String(), "path is required")
+}
+
+func (s *SessionsTestSuite) TestCreateUploadSessionRejectsReservedPath() {
+	form := sessionForm()
+	form.Set("path", ".owners")
+
+	s.serveCreate(func(ctx context.Context, uf files.FileMetadataWithContentItem, r files.Resumable) (*files.UploadSession, error) {
+		s.Fail("session should not be created")
+		return nil, nil
+	}, sessionRequest("/upload-new/sessions", form))
+
+	s.Equal(http.StatusBadRequest, s.rec.Code)
+	s.Contains(s.rec.Body.String(), "unreserved-key")
+}
+
+func (s *SessionsTestSuite) TestCreateUploadSessionFormTooLarge() {
+	form := sessionForm()
+	form.Set("title", strings.Repeat("a", 2*1024*1024))
+
+	s.serveCreate(func(ctx context.Context, uf files.FileMetadataWithContentItem, r files.Resumable) (*files.UploadSession, error) {
+		return &files.UploadSession{}, nil
+	}, sessionRequest("/upload-new/sessions", form))
+
+	s.Equal(http.StatusBadRequest, s.rec.Code)
+	s.Contains(s.rec.Body.String(), "ParsingForm")
+}
+
+func (s *SessionsTestSuite) TestCreateUploadSessionErrors() {
+	tests := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{files.ErrInvalidTotalChunks, http.StatusBadRequest, "ValidationError"},
+		{files.ErrReservedPath, http.StatusBadRequest, "ReservedPath"},
+		{filesAPI.ErrFileAlreadyRegistered, http.StatusConflict, "DuplicateFile"},
+		{files.ErrUploadInProgress, http.StatusConflict, "UploadInProgress"},
+		{files.ErrDirectUploadUnsupported, http.StatusNotImplemented, "DirectUploadUnsupported"},
+		{files.ErrFilesForbidden, http.StatusForbidden, "Forbidden"},
+		{files.ErrS3Presign, http.StatusInternalServerError, "InternalError"},
+	}
+
+	for _, test := range tests {
+		s.rec = httptest.NewRecorder()
+		s.serveCreate(func(ctx context.Context, uf files.FileMetadataWithContentItem, r files.Resumable) (*files.UploadSession, error) {
+			return nil, test.err
+		}, sessionRequest("/upload-new/sessions", sessionForm()))
+
+		s.Equal(test.status, s.rec.Code, test.code)
+		s.Contains(s.rec.Body.String(), test.code)
+	}
+}
+
+func (s *SessionsTestSuite) TestCompleteUploadSessionReturns201() {
+	var capturedUploadID string
+	var capturedResumable files.Resumable
+	form := sessionForm()
+	form.Set("fileChecksum", "checksum")
+
+	s.serveComplete(func(ctx context.Context, uploadID string, uf files.FileMetadataWithContentItem, r files.Resumable) error {
+		capturedUploadID, capturedResumable = uploadID, r
+		return nil
+	}, sessionRequest("/upload-new/sessions/upload-id/complete", form))
+
+	s.Equal(http.StatusCreated, s.rec.Code)
+	s.Equal("upload-id", capturedUploadID)
+	s.Equal("checksum", capturedResumable.FileChecksum)
+}
+
+func (s *SessionsTestSuite) TestCompleteUploadSessionRequiredFields() {
+	s.serveComplete(func(ctx context.Context, uploadID string, uf files.FileMetadataWithContentItem, r files.Resumable) error {
+		s.Fail("session should not be completed")
+		return nil
+	}, sessionRequest("/upload-new/sessions/upload-id/complete", url.Values{}))
+
+	s.Equal(http.StatusBadRequest, s.rec.Code)
+}
+
+func (s *SessionsTestSuite) TestCompleteUploadSessionRejectsInvalidForm() {
+	req := httptest.NewRequest(http.MethodPost, "/upload-new/sessions/upload-id/complete", bytes.NewBufferString("--"))
+	req.Header.Set("Content-Type", "multipart/form-data")
+
+	s.serveComplete(func(ctx context.Context, uploadID string, uf files.FileMetadataWithContentItem, r files.Resumable) error {
+		return nil
+	}, req)
+
+	s.Equal(http.StatusBadRequest, s.rec.Code)
+	s.Contains(s.rec.Body.String(), "ParsingForm")
+}
+
+func (s *SessionsTestSuite) TestCompleteUploadSessionErrors() {
+	tests := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{files.ErrUploadNotFound, http.StatusNotFound, "NotFound"},
+		{files.ErrPartsMissing, http.StatusConflict, "PartsMissing"},
+		{files.ErrChunkTooSmall, http.StatusBadRequest, "ChunkTooSmall"},
+		{files.ErrFileChecksumMismatch, http.StatusBadRequest, "FileChecksumMismatch"},
+		{files.ErrInvalidFileChecksum, http.StatusBadRequest, "InvalidFileChecksum"},
+		{files.ErrUnsupportedMediaType, http.StatusUnsupportedMediaType, "UnsupportedMediaType"},
+		{files.ErrFileTooLarge, http.StatusRequestEntityTooLarge, "FileTooLarge"},
+		{files.ErrSizeMismatch, http.StatusBadRequest, "SizeMismatch"},
+		{files.ErrFileQuarantined, http.StatusUnprocessableEntity, "FileQuarantined"},
+		{files.ErrScanFailed, http.StatusInternalServerError, "ScanFailed"},
+		{files.ErrQuarantine, http.StatusInternalServerError, "InternalError"},
+		{filesAPI.ErrFileAlreadyRegistered, http.StatusConflict, "DuplicateFile"},
+		{files.ErrFileAPICreateInvalidData, http.StatusInternalServerError, "RemoteValidationError"},
+		{files.ErrFilesServer, http.StatusInternalServerError, "RemoteServerError"},
+		{files.ErrFilesUnauthorised, http.StatusUnauthorized, "Unauthorised"},
+		{files.ErrS3Upload, http.StatusInternalServerError, "InternalError"},
+	}
+
+	for _, test := range tests {
+		s.rec = httptest.NewRecorder()
+		s.serveComplete(func(ctx context.Context, uploadID string, uf files.FileMetadataWithContentItem, r files.Resumable) error {
+			return test.err
+		}, sessionRequest("/upload-new/sessions/upload-id/complete", sessionForm()))
+
+		s.Equal(test.status, s.rec.Code, test.code)
+		s.Contains(s.rec.Body.String(), test.code)
+	}
+}
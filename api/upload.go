@@ -2,15 +2,19 @@ package api
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
+	"net/url"
 	"regexp"
 
 	filesAPI "github.com/ONSdigital/dp-api-clients-go/v2/files"
 	"github.com/ONSdigital/dp-net/v3/request"
 	"github.com/ONSdigital/dp-upload-service/config"
 	"github.com/ONSdigital/dp-upload-service/files"
+	"github.com/ONSdigital/dp-upload-service/storage"
 	"github.com/ONSdigital/log.go/v2/log"
 	"github.com/go-playground/validator"
 	"github.com/gorilla/schema"
@@ -19,10 +23,16 @@ import (
 const (
 	maxChunkSize       = 5 * 1024 * 1024
 	maxMultipartMemory = maxChunkSize + 1024
+	maxFormFieldsSize  = 1024 * 1024
+)
+
+var (
+	ErrFormFieldsTooLarge  = errors.New("multipart form fields too large")
+	ErrChunkNumberRequired = errors.New("resumableChunkNumber required")
 )
 
 type Metadata struct {
-	Path          string  `schema:"path" validate:"required,aws-upload-key"`
+	Path          string  `schema:"path" validate:"required,aws-upload-key,unreserved-key"`
 	IsPublishable *bool   `schema:"isPublishable,omitempty" validate:"required"`
 	CollectionId  *string `schema:"collectionId,omitempty"`
 	BundleId      *string `schema:"bundleId,omitempty"`
@@ -36,64 +46,53 @@ type Metadata struct {
 	Version       string  `schema:"version"`
 }
 
-type StoreFile func(ctx context.Context, uf files.FileMetadataWithContentItem, r files.Resumable, content []byte) (bool, error)
+type StoreFile func(ctx context.Context, uf files.FileMetadataWithContentItem, r files.Resumable, content io.Reader) (bool, error)
 
+// CreateV1UploadHandler handles a chunk of a file being uploaded. The file part of the multipart form is streamed
+// straight through to storeFile rather than being read into memory, so all metadata fields must appear in the form
+// before the file part. Fields provided in the query string are also accepted.
 func CreateV1UploadHandler(storeFile StoreFile) http.HandlerFunc {
 	return func(w http.ResponseWriter, req *http.Request) {
-		if err := req.ParseMultipartForm(maxMultipartMemory); err != nil {
+		reader, err := req.MultipartReader()
+		if err != nil {
 			log.Error(req.Context(), "error parsing form", err)
 			writeError(w, buildErrors(err, "ParsingForm"), http.StatusBadRequest)
 			return
 		}
-		authHeaderValue := req.Header.Get(request.AuthHeaderKey)
-		augmentedContext := context.WithValue(req.Context(), config.AuthContextKey, authHeaderValue)
 
-		d := schema.NewDecoder()
-		d.IgnoreUnknownKeys(true)
-
-		metadata := Metadata{}
-		if err := d.Decode(&metadata, req.Form); err != nil {
-			log.Error(augmentedContext, "error decoding metadata form", err)
-			writeError(w, buildErrors(err, "DecodingMetadata"), http.StatusBadRequest)
+		form, content, err := readFormUntilFile(reader, req.URL.Query())
+		if err != nil {
+			log.Error(req.Context(), "error parsing form", err)
+			writeError(w, buildErrors(err, "ParsingForm"), http.StatusBadRequest)
 			return
 		}
-
-		resumable := files.Resumable{}
-		if err := d.Decode(&resumable, req.Form); err != nil {
-			log.Error(augmentedContext, "error decoding resumable form", err)
-			writeError(w, buildErrors(err, "DecodingResumable"), http.StatusBadRequest)
-			return
+		if content != nil {
+			defer func() {
+				if err := content.Close(); err != nil {
+					log.Error(req.Context(), "error closing file", err)
+				}
+			}()
 		}
 
-		v := validator.New()
-		v.RegisterValidation("aws-upload-key", awsUploadKeyValidator) // nolint // Only fails due to coding error
-		if err := v.Struct(metadata); err != nil {
-			if validationErrs, ok := err.(validator.ValidationErrors); ok {
-				writeError(w, buildValidationErrors(validationErrs), http.StatusBadRequest)
-				return
-			}
+		authHeaderValue := req.Header.Get(request.AuthHeaderKey)
+		augmentedContext := context.WithValue(req.Context(), config.AuthContextKey, authHeaderValue)
+
+		metadata, resumable, ok := decodeUploadForm(augmentedContext, w, form)
+		if !ok {
+			return
 		}
 
-		content, _, err := req.FormFile("file")
-		if err != nil {
-			log.Error(augmentedContext, "error getting file from form", err)
-			writeError(w, buildErrors(err, "FileForm"), http.StatusBadRequest)
+		if !checkAuthorisedCollection(w, req, metadata) {
 			return
 		}
-		defer func() {
-			if err := content.Close(); err != nil {
-				log.Error(augmentedContext, "error closing file", err)
-			}
-		}()
 
-		payload, err := io.ReadAll(content)
-		if err != nil {
-			log.Error(augmentedContext, "error getting file from form", err)
-			w.WriteHeader(http.StatusInternalServerError)
+		if content == nil {
+			log.Error(augmentedContext, "error getting file from form", http.ErrMissingFile)
+			writeError(w, buildErrors(http.ErrMissingFile, "FileForm"), http.StatusBadRequest)
 			return
 		}
 
-		allPartsUploaded, err := storeFile(augmentedContext, getStoreMetadata(metadata, resumable), resumable, payload)
+		allPartsUploaded, err := storeFile(augmentedContext, getStoreMetadata(metadata, resumable), resumable, content)
 		if err != nil {
 			switch err {
 			case filesAPI.ErrFileAlreadyRegistered:
@@ -102,6 +101,28 @@ func CreateV1UploadHandler(storeFile StoreFile) http.HandlerFunc {
 				writeError(w, buildErrors(err, "RemoteValidationError"), http.StatusInternalServerError)
 			case files.ErrChunkTooSmall:
 				writeError(w, buildErrors(err, "ChunkTooSmall"), http.StatusBadRequest)
+			case files.ErrUploadComplete:
+				writeError(w, buildErrors(err, "UploadComplete"), http.StatusConflict)
+			case files.ErrChecksumMismatch:
+				writeError(w, buildErrors(err, "ChecksumMismatch"), http.StatusBadRequest)
+			case files.ErrInvalidChecksum:
+				writeError(w, buildErrors(err, "InvalidChecksum"), http.StatusBadRequest)
+			case files.ErrFileChecksumMismatch:
+				writeError(w, buildErrors(err, "FileChecksumMismatch"), http.StatusBadRequest)
+			case files.ErrInvalidFileChecksum:
+				writeError(w, buildErrors(err, "InvalidFileChecksum"), http.StatusBadRequest)
+			case files.ErrUnsupportedMediaType:
+				writeError(w, buildErrors(err, "UnsupportedMediaType"), http.StatusUnsupportedMediaType)
+			case files.ErrFileTooLarge:
+				writeError(w, buildErrors(err, "FileTooLarge"), http.StatusRequestEntityTooLarge)
+			case files.ErrSizeMismatch:
+				writeError(w, buildErrors(err, "SizeMismatch"), http.StatusBadRequest)
+			case files.ErrReservedPath:
+				writeError(w, buildErrors(err, "ReservedPath"), http.StatusBadRequest)
+			case files.ErrFileQuarantined:
+				writeError(w, buildErrors(err, "FileQuarantined"), http.StatusUnprocessableEntity)
+			case files.ErrScanFailed:
+				writeError(w, buildErrors(err, "ScanFailed"), http.StatusInternalServerError)
 			case files.ErrFilesServer:
 				writeError(w, buildErrors(err, "RemoteServerError"), http.StatusInternalServerError)
 			case files.ErrFilesUnauthorised:
@@ -118,6 +139,112 @@ func CreateV1UploadHandler(storeFile StoreFile) http.HandlerFunc {
 	}
 }
 
+// CheckChunk reports whether the chunk described by the resumable fields has already been stored
+type CheckChunk func(ctx context.Context, uf files.FileMetadataWithContentItem, r files.Resumable) (bool, error)
+
+// CreateV1CheckChunkHandler lets resumable.js test whether a chunk has already been uploaded so that it can be skipped
+// when an upload is resumed. It takes the same fields as CreateV1UploadHandler in the query string and responds with
+// 200 if the chunk is already stored or 404 if it still needs to be sent.
+func CreateV1CheckChunkHandler(checkChunk CheckChunk) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		authHeaderValue := req.Header.Get(request.AuthHeaderKey)
+		augmentedContext := context.WithValue(req.Context(), config.AuthContextKey, authHeaderValue)
+
+		metadata, resumable, ok := decodeUploadForm(augmentedContext, w, req.URL.Query())
+		if !ok {
+			return
+		}
+
+		if !checkAuthorisedCollection(w, req, metadata) {
+			return
+		}
+
+		if resumable.CurrentChunk < 1 {
+			writeError(w, buildFieldErrors(ErrChunkNumberRequired, "resumableChunkNumber", "required", req.URL.Query().Get("resumableChunkNumber")), http.StatusBadRequest)
+			return
+		}
+
+		uploaded, err := checkChunk(augmentedContext, getStoreMetadata(metadata, resumable), resumable)
+		if err != nil {
+			log.Error(augmentedContext, "error checking chunk uploaded", err, log.Data{"chunk_number": resumable.CurrentChunk})
+			writeError(w, buildErrors(err, "InternalError"), http.StatusInternalServerError)
+			return
+		}
+
+		if !uploaded {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// decodeUploadForm decodes and validates the metadata and resumable fields used by the /upload-new endpoints. If the
+// fields are invalid the error response is written and false is returned.
+func decodeUploadForm(ctx context.Context, w http.ResponseWriter, form url.Values) (Metadata, files.Resumable, bool) {
+	d := schema.NewDecoder()
+	d.IgnoreUnknownKeys(true)
+
+	metadata := Metadata{}
+	if err := d.Decode(&metadata, form); err != nil {
+		log.Error(ctx, "error decoding metadata form", err)
+		writeError(w, buildDecodingErrors(err, "DecodingMetadata", form), http.StatusBadRequest)
+		return Metadata{}, files.Resumable{}, false
+	}
+
+	resumable := files.Resumable{}
+	if err := d.Decode(&resumable, form); err != nil {
+		log.Error(ctx, "error decoding resumable form", err)
+		writeError(w, buildDecodingErrors(err, "DecodingResumable", form), http.StatusBadRequest)
+		return Metadata{}, files.Resumable{}, false
+	}
+
+	if err := newValidator().Struct(metadata); err != nil {
+		if validationErrs, ok := err.(validator.ValidationErrors); ok {
+			writeError(w, buildValidationErrors(validationErrs, form), http.StatusBadRequest)
+			return Metadata{}, files.Resumable{}, false
+		}
+		// any other error means the metadata could not be validated at all, which is a coding error
+		log.Error(ctx, "error validating metadata", err)
+		writeError(w, buildErrors(err, "InternalError"), http.StatusInternalServerError)
+		return Metadata{}, files.Resumable{}, false
+	}
+
+	return metadata, resumable, true
+}
+
+// readFormUntilFile reads the multipart form fields into the provided values until the file part is reached, which is
+// returned unread. If the form has no file part, the returned part is nil.
+func readFormUntilFile(reader *multipart.Reader, values url.Values) (url.Values, *multipart.Part, error) {
+	remaining := int64(maxFormFieldsSize)
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return values, nil, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if part.FormName() == "file" {
+			return values, part, nil
+		}
+
+		value, err := io.ReadAll(io.LimitReader(part, remaining+1))
+		if err != nil {
+			return nil, nil, err
+		}
+		remaining -= int64(len(value))
+		if remaining < 0 {
+			return nil, nil, ErrFormFieldsTooLarge
+		}
+
+		values.Add(part.FormName(), string(value))
+	}
+}
+
 func awsUploadKeyValidator(fl validator.FieldLevel) bool {
 	path := fl.Field().String()
 	matched, _ := regexp.MatchString("^[a-z0-9A-Z\\/\\!\\*\\_\\'\\(\\)\\.\\-]*$", path)
@@ -125,6 +252,12 @@ func awsUploadKeyValidator(fl validator.FieldLevel) bool {
 	return matched
 }
 
+// unreservedKeyValidator checks that files are not uploaded under the prefixes of the records the service keeps in the
+// bucket. The prefix that infected files are quarantined under is configurable, so it is checked by the store.
+func unreservedKeyValidator(fl validator.FieldLevel) bool {
+	return !storage.IsReserved(fl.Field().String() + "/")
+}
+
 func getResponseStatus(allPartsUploaded bool) int {
 	if allPartsUploaded {
 		return http.StatusCreated
@@ -3,11 +3,14 @@ package api_test
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"io"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/suite"
@@ -18,7 +21,7 @@ import (
 	"github.com/ONSdigital/dp-upload-service/api"
 )
 
-var stubStoreFunction = func(ctx context.Context, uf files.FileMetadataWithContentItem, r files.Resumable, c []byte) (bool, error) {
+var stubStoreFunction = func(ctx context.Context, uf files.FileMetadataWithContentItem, r files.Resumable, c io.Reader) (bool, error) {
 	return false, nil
 }
 
@@ -71,13 +74,12 @@ func (s *UploadTestSuite) TestRequiredFields() {
 	s.Equal(http.StatusBadRequest, rec.Code)
 	response, _ := io.ReadAll(rec.Body)
 
-	s.Contains(string(response), "Path required")
-	s.Contains(string(response), "IsPublishable required")
-	s.Contains(string(response), "SizeInBytes required")
-	s.Contains(string(response), "SizeInBytes required")
-	s.Contains(string(response), "Type required")
-	s.Contains(string(response), "Licence required")
-	s.Contains(string(response), "LicenceUrl required")
+	s.Contains(string(response), "path is required")
+	s.Contains(string(response), "isPublishable is required")
+	s.Contains(string(response), "resumableTotalSize is required")
+	s.Contains(string(response), "resumableType is required")
+	s.Contains(string(response), "licence is required")
+	s.Contains(string(response), "licenceUrl is required")
 }
 
 func (s *UploadTestSuite) TestPathValid() {
@@ -90,7 +92,100 @@ func (s *UploadTestSuite) TestPathValid() {
 
 	s.Equal(http.StatusBadRequest, rec.Code)
 	response, _ := io.ReadAll(rec.Body)
-	s.Contains(string(response), "Path aws-upload-key")
+	s.Contains(string(response), "path can only contain letters, numbers and the characters /!*_'().-")
+}
+
+func (s *UploadTestSuite) TestPathUnderReservedPrefixRejected() {
+	b, formWriter := generateFormWriter(".verifications/data")
+	formWriter.Close()
+
+	h := api.CreateV1UploadHandler(func(ctx context.Context, uf files.FileMetadataWithContentItem, r files.Resumable, c io.Reader) (bool, error) {
+		s.Fail("file should not be uploaded")
+		return false, nil
+	})
+	h.ServeHTTP(rec, generateRequest(b, formWriter))
+
+	s.Equal(http.StatusBadRequest, rec.Code)
+	errs := decodeErrors(s.T(), rec.Body)
+	s.Require().Len(errs.Error, 1)
+	s.Equal("path", errs.Error[0].Field)
+	s.Equal("unreserved-key", errs.Error[0].Rule)
+}
+
+func (s *UploadTestSuite) TestPathOfOwnerRecordRejected() {
+	b, formWriter := generateFormWriter(".owners")
+	formWriter.Close()
+
+	h := api.CreateV1UploadHandler(stubStoreFunction)
+	h.ServeHTTP(rec, generateRequest(b, formWriter))
+
+	s.Equal(http.StatusBadRequest, rec.Code)
+	errs := decodeErrors(s.T(), rec.Body)
+	s.Require().Len(errs.Error, 1)
+	s.Equal("unreserved-key", errs.Error[0].Rule)
+}
+
+func (s *UploadTestSuite) TestValidationErrorsDescribeTheField() {
+	b, formWriter := generateFormWriter("\\x")
+	formWriter.Close()
+
+	h := api.CreateV1UploadHandler(stubStoreFunction)
+	h.ServeHTTP(rec, generateRequest(b, formWriter))
+
+	s.Equal(http.StatusBadRequest, rec.Code)
+	errs := decodeErrors(s.T(), rec.Body)
+	s.Require().Len(errs.Error, 1)
+	s.Equal("ValidationError", errs.Error[0].Code)
+	s.Equal("path", errs.Error[0].Field)
+	s.Equal("aws-upload-key", errs.Error[0].Rule)
+	s.Equal("\\x", errs.Error[0].Value)
+	s.Equal(api.ValidationDocsURL, errs.Error[0].DocsURL)
+}
+
+func (s *UploadTestSuite) TestLongRejectedValuesAreTruncated() {
+	b, formWriter := generateFormWriter(strings.Repeat("\\", 100))
+	formWriter.Close()
+
+	h := api.CreateV1UploadHandler(stubStoreFunction)
+	h.ServeHTTP(rec, generateRequest(b, formWriter))
+
+	s.Equal(http.StatusBadRequest, rec.Code)
+	errs := decodeErrors(s.T(), rec.Body)
+	s.Require().Len(errs.Error, 1)
+	s.Equal(strings.Repeat("\\", 64)+"...", errs.Error[0].Value)
+}
+
+func (s *UploadTestSuite) TestRejectedValuesWithControlCharactersAreRedacted() {
+	b, formWriter := generateFormWriter("data/\x1b[31mfile.csv")
+	formWriter.Close()
+
+	h := api.CreateV1UploadHandler(stubStoreFunction)
+	h.ServeHTTP(rec, generateRequest(b, formWriter))
+
+	s.Equal(http.StatusBadRequest, rec.Code)
+	errs := decodeErrors(s.T(), rec.Body)
+	s.Require().Len(errs.Error, 1)
+	s.Equal("path", errs.Error[0].Field)
+	s.Equal("[REDACTED]", errs.Error[0].Value)
+}
+
+func (s *UploadTestSuite) TestFieldsThatCannotBeDecodedAreDescribed() {
+	b := &bytes.Buffer{}
+	formWriter := multipart.NewWriter(b)
+	formWriter.WriteField("resumableTotalSize", "abc")
+	formWriter.Close()
+
+	h := api.CreateV1UploadHandler(stubStoreFunction)
+	h.ServeHTTP(rec, generateRequest(b, formWriter))
+
+	s.Equal(http.StatusBadRequest, rec.Code)
+	errs := decodeErrors(s.T(), rec.Body)
+	s.Require().Len(errs.Error, 1)
+	s.Equal("DecodingMetadata", errs.Error[0].Code)
+	s.Equal("resumableTotalSize", errs.Error[0].Field)
+	s.Equal("type", errs.Error[0].Rule)
+	s.Equal("abc", errs.Error[0].Value)
+	s.Equal("resumableTotalSize must be a whole number", errs.Error[0].Description)
 }
 
 func (s *UploadTestSuite) TestIsPublishableSetToFalseInNotARequireFailure() {
@@ -102,7 +197,7 @@ func (s *UploadTestSuite) TestIsPublishableSetToFalseInNotARequireFailure() {
 
 	s.Equal(http.StatusBadRequest, rec.Code)
 	response, _ := io.ReadAll(rec.Body)
-	s.NotContains(string(response), "IsPublishable required")
+	s.NotContains(string(response), "isPublishable is required")
 }
 
 func (s *UploadTestSuite) TestFileWasSupplied() {
@@ -134,9 +229,10 @@ func (s *UploadTestSuite) TestFileWasSuppliedWithBundleId() {
 func (s *UploadTestSuite) TestSuccessfulStorageOfCompleteFileReturns201() {
 	payload := "TEST DATA"
 	funcCalled := false
-	st := func(ctx context.Context, uf files.FileMetadataWithContentItem, r files.Resumable, fileContent []byte) (bool, error) {
+	st := func(ctx context.Context, uf files.FileMetadataWithContentItem, r files.Resumable, fileContent io.Reader) (bool, error) {
 		funcCalled = true
-		s.Equal(payload, string(fileContent))
+		stored, _ := io.ReadAll(fileContent)
+		s.Equal(payload, string(stored))
 		return true, nil
 	}
 
@@ -155,7 +251,7 @@ func (s *UploadTestSuite) TestSuccessfulStorageOfCompleteFileReturns201() {
 
 func (s *UploadTestSuite) TestChunkTooSmallReturns400() {
 	payload := "TEST DATA"
-	st := func(ctx context.Context, uf files.FileMetadataWithContentItem, r files.Resumable, fileContent []byte) (bool, error) {
+	st := func(ctx context.Context, uf files.FileMetadataWithContentItem, r files.Resumable, fileContent io.Reader) (bool, error) {
 		return true, files.ErrChunkTooSmall
 	}
 
@@ -174,8 +270,118 @@ func (s *UploadTestSuite) TestChunkTooSmallReturns400() {
 	s.Equal(http.StatusBadRequest, rec.Code)
 }
 
+func (s *UploadTestSuite) TestChecksumMismatchReturns400() {
+	var capturedResumable files.Resumable
+	st := func(ctx context.Context, uf files.FileMetadataWithContentItem, r files.Resumable, fileContent io.Reader) (bool, error) {
+		capturedResumable = r
+		return false, files.ErrChecksumMismatch
+	}
+
+	b, formWriter := generateFormWriter("valid")
+	formWriter.WriteField("resumableChunkChecksum", "Y2hlY2tzdW0=")
+	part, _ := formWriter.CreateFormFile("file", "testing.csv")
+	part.Write([]byte("TEST DATA"))
+	formWriter.Close()
+
+	h := api.CreateV1UploadHandler(st)
+	h.ServeHTTP(rec, generateRequest(b, formWriter))
+
+	s.Equal(http.StatusBadRequest, rec.Code)
+	s.Equal("Y2hlY2tzdW0=", capturedResumable.ChunkChecksum)
+	response, _ := io.ReadAll(rec.Body)
+	s.Contains(string(response), "ChecksumMismatch")
+}
+
+func (s *UploadTestSuite) TestUnsupportedMediaTypeReturns415() {
+	st := func(ctx context.Context, uf files.FileMetadataWithContentItem, r files.Resumable, fileContent io.Reader) (bool, error) {
+		return false, files.ErrUnsupportedMediaType
+	}
+
+	b, formWriter := generateFormWriter("valid")
+	part, _ := formWriter.CreateFormFile("file", "testing.csv")
+	part.Write([]byte("%PDF-1.7"))
+	formWriter.Close()
+
+	h := api.CreateV1UploadHandler(st)
+	h.ServeHTTP(rec, generateRequest(b, formWriter))
+
+	s.Equal(http.StatusUnsupportedMediaType, rec.Code)
+	response, _ := io.ReadAll(rec.Body)
+	s.Contains(string(response), "UnsupportedMediaType")
+}
+
+func (s *UploadTestSuite) TestSizeErrorsAreReturned() {
+	tests := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{files.ErrFileTooLarge, http.StatusRequestEntityTooLarge, "FileTooLarge"},
+		{files.ErrSizeMismatch, http.StatusBadRequest, "SizeMismatch"},
+	}
+
+	for _, test := range tests {
+		st := func(ctx context.Context, uf files.FileMetadataWithContentItem, r files.Resumable, fileContent io.Reader) (bool, error) {
+			return false, test.err
+		}
+
+		b, formWriter := generateFormWriter("valid")
+		part, _ := formWriter.CreateFormFile("file", "testing.csv")
+		part.Write([]byte("content"))
+		formWriter.Close()
+
+		rec := httptest.NewRecorder()
+		h := api.CreateV1UploadHandler(st)
+		h.ServeHTTP(rec, generateRequest(b, formWriter))
+
+		s.Equal(test.status, rec.Code, test.code)
+		response, _ := io.ReadAll(rec.Body)
+		s.Contains(string(response), test.code)
+	}
+}
+
+func (s *UploadTestSuite) TestQuarantinedFileReturns422() {
+	st := func(ctx context.Context, uf files.FileMetadataWithContentItem, r files.Resumable, fileContent io.Reader) (bool, error) {
+		return false, files.ErrFileQuarantined
+	}
+
+	b, formWriter := generateFormWriter("valid")
+	part, _ := formWriter.CreateFormFile("file", "testing.csv")
+	part.Write([]byte("infected"))
+	formWriter.Close()
+
+	h := api.CreateV1UploadHandler(st)
+	h.ServeHTTP(rec, generateRequest(b, formWriter))
+
+	s.Equal(http.StatusUnprocessableEntity, rec.Code)
+	response, _ := io.ReadAll(rec.Body)
+	s.Contains(string(response), "FileQuarantined")
+}
+
+func (s *UploadTestSuite) TestFileChecksumMismatchReturns400() {
+	var capturedResumable files.Resumable
+	st := func(ctx context.Context, uf files.FileMetadataWithContentItem, r files.Resumable, fileContent io.Reader) (bool, error) {
+		capturedResumable = r
+		return false, files.ErrFileChecksumMismatch
+	}
+
+	b, formWriter := generateFormWriter("valid")
+	formWriter.WriteField("fileChecksum", "Y2hlY2tzdW0=")
+	part, _ := formWriter.CreateFormFile("file", "testing.csv")
+	part.Write([]byte("TEST DATA"))
+	formWriter.Close()
+
+	h := api.CreateV1UploadHandler(st)
+	h.ServeHTTP(rec, generateRequest(b, formWriter))
+
+	s.Equal(http.StatusBadRequest, rec.Code)
+	s.Equal("Y2hlY2tzdW0=", capturedResumable.FileChecksum)
+	response, _ := io.ReadAll(rec.Body)
+	s.Contains(string(response), "FileChecksumMismatch")
+}
+
 func (s *UploadTestSuite) TestFilePathExistsInFilesAPIReturns409() {
-	st := func(ctx context.Context, uf files.FileMetadataWithContentItem, r files.Resumable, fileContent []byte) (bool, error) {
+	st := func(ctx context.Context, uf files.FileMetadataWithContentItem, r files.Resumable, fileContent io.Reader) (bool, error) {
 		return false, filesAPI.ErrFileAlreadyRegistered
 	}
 
@@ -193,7 +399,7 @@ func (s *UploadTestSuite) TestFilePathExistsInFilesAPIReturns409() {
 }
 
 func (s *UploadTestSuite) TestInvalidContentReturns500() {
-	st := func(ctx context.Context, uf files.FileMetadataWithContentItem, r files.Resumable, fileContent []byte) (bool, error) {
+	st := func(ctx context.Context, uf files.FileMetadataWithContentItem, r files.Resumable, fileContent io.Reader) (bool, error) {
 		return false, files.ErrFileAPICreateInvalidData
 	}
 
@@ -211,7 +417,7 @@ func (s *UploadTestSuite) TestInvalidContentReturns500() {
 }
 
 func (s *UploadTestSuite) TestServerErrorReturns500() {
-	st := func(ctx context.Context, uf files.FileMetadataWithContentItem, r files.Resumable, fileContent []byte) (bool, error) {
+	st := func(ctx context.Context, uf files.FileMetadataWithContentItem, r files.Resumable, fileContent io.Reader) (bool, error) {
 		return false, files.ErrFilesServer
 	}
 
@@ -229,7 +435,7 @@ func (s *UploadTestSuite) TestServerErrorReturns500() {
 }
 
 func (s *UploadTestSuite) TestFileUnathorisedErrorReturnsForbidden() {
-	st := func(ctx context.Context, uf files.FileMetadataWithContentItem, r files.Resumable, fileContent []byte) (bool, error) {
+	st := func(ctx context.Context, uf files.FileMetadataWithContentItem, r files.Resumable, fileContent io.Reader) (bool, error) {
 		return false, files.ErrFilesUnauthorised
 	}
 
@@ -248,7 +454,7 @@ func (s *UploadTestSuite) TestFileUnathorisedErrorReturnsForbidden() {
 
 func (s *UploadTestSuite) TestDatasetFieldsMappedToContentItem() {
 	var capturedMetadata files.FileMetadataWithContentItem
-	st := func(ctx context.Context, uf files.FileMetadataWithContentItem, r files.Resumable, fileContent []byte) (bool, error) {
+	st := func(ctx context.Context, uf files.FileMetadataWithContentItem, r files.Resumable, fileContent io.Reader) (bool, error) {
 		capturedMetadata = uf
 		return true, nil
 	}
@@ -284,7 +490,7 @@ func (s *UploadTestSuite) TestDatasetFieldsMappedToContentItem() {
 
 func (s *UploadTestSuite) TestContentItemNilWhenDatasetFieldsNotProvided() {
 	var capturedMetadata files.FileMetadataWithContentItem
-	st := func(ctx context.Context, uf files.FileMetadataWithContentItem, r files.Resumable, fileContent []byte) (bool, error) {
+	st := func(ctx context.Context, uf files.FileMetadataWithContentItem, r files.Resumable, fileContent io.Reader) (bool, error) {
 		capturedMetadata = uf
 		return true, nil
 	}
@@ -301,6 +507,147 @@ func (s *UploadTestSuite) TestContentItemNilWhenDatasetFieldsNotProvided() {
 	s.Nil(capturedMetadata.ContentItem)
 }
 
+func (s *UploadTestSuite) TestResumableFieldsAcceptedInQueryString() {
+	var capturedResumable files.Resumable
+	st := func(ctx context.Context, uf files.FileMetadataWithContentItem, r files.Resumable, fileContent io.Reader) (bool, error) {
+		capturedResumable = r
+		return false, nil
+	}
+
+	b, formWriter := generateFormWriter("valid")
+	part, _ := formWriter.CreateFormFile("file", "testing.csv")
+	part.Write([]byte("TEST DATA"))
+	formWriter.Close()
+
+	req := generateRequest(b, formWriter)
+	req.URL.RawQuery = "resumableChunkNumber=2&resumableTotalChunks=3"
+
+	h := api.CreateV1UploadHandler(st)
+	h.ServeHTTP(rec, req)
+
+	s.Equal(http.StatusOK, rec.Code)
+	s.Equal(int32(2), capturedResumable.CurrentChunk)
+	s.Equal(3, capturedResumable.TotalChunks)
+}
+
+func (s *UploadTestSuite) TestFieldsAfterFileAreIgnored() {
+	b := &bytes.Buffer{}
+	formWriter := multipart.NewWriter(b)
+	part, _ := formWriter.CreateFormFile("file", "testing.csv")
+	part.Write([]byte("TEST DATA"))
+	formWriter.WriteField("path", "valid")
+	formWriter.Close()
+
+	h := api.CreateV1UploadHandler(stubStoreFunction)
+	h.ServeHTTP(rec, generateRequest(b, formWriter))
+
+	s.Equal(http.StatusBadRequest, rec.Code)
+	response, _ := io.ReadAll(rec.Body)
+	s.Contains(string(response), "path is required")
+}
+
+func (s *UploadTestSuite) TestFormFieldsTooLarge() {
+	b, formWriter := generateFormWriter("valid")
+	formWriter.WriteField("title", strings.Repeat("a", 1024*1024))
+	formWriter.Close()
+
+	h := api.CreateV1UploadHandler(stubStoreFunction)
+	h.ServeHTTP(rec, generateRequest(b, formWriter))
+
+	s.Equal(http.StatusBadRequest, rec.Code)
+	response, _ := io.ReadAll(rec.Body)
+	s.Contains(string(response), "ParsingForm")
+}
+
+func (s *UploadTestSuite) TestCheckChunkFoundReturns200() {
+	var capturedMetadata files.FileMetadataWithContentItem
+	var capturedResumable files.Resumable
+	check := func(ctx context.Context, uf files.FileMetadataWithContentItem, r files.Resumable) (bool, error) {
+		capturedMetadata = uf
+		capturedResumable = r
+		return true, nil
+	}
+
+	h := api.CreateV1CheckChunkHandler(check)
+	h.ServeHTTP(rec, generateCheckChunkRequest(http.MethodGet, "2"))
+
+	s.Equal(http.StatusOK, rec.Code)
+	s.Equal("valid/file.csv", capturedMetadata.Path)
+	s.Equal(int32(2), capturedResumable.CurrentChunk)
+	s.Equal(3, capturedResumable.TotalChunks)
+}
+
+func (s *UploadTestSuite) TestCheckChunkNotFoundReturns404() {
+	check := func(ctx context.Context, uf files.FileMetadataWithContentItem, r files.Resumable) (bool, error) {
+		return false, nil
+	}
+
+	h := api.CreateV1CheckChunkHandler(check)
+	h.ServeHTTP(rec, generateCheckChunkRequest(http.MethodHead, "2"))
+
+	s.Equal(http.StatusNotFound, rec.Code)
+}
+
+func (s *UploadTestSuite) TestCheckChunkErrorReturns500() {
+	check := func(ctx context.Context, uf files.FileMetadataWithContentItem, r files.Resumable) (bool, error) {
+		return false, files.ErrS3CheckPart
+	}
+
+	h := api.CreateV1CheckChunkHandler(check)
+	h.ServeHTTP(rec, generateCheckChunkRequest(http.MethodGet, "2"))
+
+	s.Equal(http.StatusInternalServerError, rec.Code)
+}
+
+func (s *UploadTestSuite) TestCheckChunkWithoutChunkNumberReturns400() {
+	check := func(ctx context.Context, uf files.FileMetadataWithContentItem, r files.Resumable) (bool, error) {
+		s.Fail("check chunk should not be called")
+		return false, nil
+	}
+
+	h := api.CreateV1CheckChunkHandler(check)
+	h.ServeHTTP(rec, generateCheckChunkRequest(http.MethodGet, ""))
+
+	s.Equal(http.StatusBadRequest, rec.Code)
+	response, _ := io.ReadAll(rec.Body)
+	s.Contains(string(response), "resumableChunkNumber required")
+}
+
+func (s *UploadTestSuite) TestCheckChunkRequiredFields() {
+	check := func(ctx context.Context, uf files.FileMetadataWithContentItem, r files.Resumable) (bool, error) {
+		s.Fail("check chunk should not be called")
+		return false, nil
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, UploadURI+"?resumableChunkNumber=1", nil)
+	h := api.CreateV1CheckChunkHandler(check)
+	h.ServeHTTP(rec, req)
+
+	s.Equal(http.StatusBadRequest, rec.Code)
+	response, _ := io.ReadAll(rec.Body)
+	s.Contains(string(response), "path is required")
+}
+
+func generateCheckChunkRequest(method, chunkNumber string) *http.Request {
+	query := url.Values{}
+	query.Set("resumableFilename", "file.csv")
+	query.Set("path", "valid")
+	query.Set("isPublishable", "false")
+	query.Set("collectionId", "1234567890")
+	query.Set("title", "A New File")
+	query.Set("resumableTotalSize", "1478")
+	query.Set("resumableType", "text/csv")
+	query.Set("licence", "OGL v3")
+	query.Set("licenceUrl", "http://www.nationalarchives.gov.uk/doc/open-government-licence/version/3/")
+	query.Set("resumableTotalChunks", "3")
+	if chunkNumber != "" {
+		query.Set("resumableChunkNumber", chunkNumber)
+	}
+
+	req, _ := http.NewRequest(method, UploadURI+"?"+query.Encode(), nil)
+	return req
+}
+
 func generateFormWriter(path string) (*bytes.Buffer, *multipart.Writer) {
 	b := &bytes.Buffer{}
 	formWriter := multipart.NewWriter(b)
@@ -331,6 +678,14 @@ func generateFormWriterWithBundleId(path string) (*bytes.Buffer, *multipart.Writ
 	return b, formWriter
 }
 
+func decodeErrors(t *testing.T, body io.Reader) api.JsonErrors {
+	var errs api.JsonErrors
+	if err := json.NewDecoder(body).Decode(&errs); err != nil {
+		t.Fatalf("failed to decode error response: %v", err)
+	}
+	return errs
+}
+
 func generateRequest(b *bytes.Buffer, formWriter *multipart.Writer) *http.Request {
 	req, _ := http.NewRequest(http.MethodPost, UploadURI, b)
 	req.Header.Set("Content-Type", formWriter.FormDataContentType())
@@ -0,0 +1,584 @@
+package aws
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5" //nolint:gosec // MD5 is required by S3 for the Content-MD5 header
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	s3client "github.com/ONSdigital/dp-s3/v3"
+	"github.com/ONSdigital/dp-upload-service/storage"
+	"github.com/ONSdigital/log.go/v2/log"
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+// sdkClient is the subset of the AWS S3 SDK client used directly by Client
+type sdkClient interface {
+	ListMultipartUploads(ctx context.Context, in *s3.ListMultipartUploadsInput, optFns ...func(*s3.Options)) (*s3.ListMultipartUploadsOutput, error)
+	ListParts(ctx context.Context, in *s3.ListPartsInput, optFns ...func(*s3.Options)) (*s3.ListPartsOutput, error)
+	CreateMultipartUpload(ctx context.Context, in *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error)
+	UploadPart(ctx context.Context, in *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error)
+	CompleteMultipartUpload(ctx context.Context, in *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error)
+	AbortMultipartUpload(ctx context.Context, in *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error)
+	HeadObject(ctx context.Context, in *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
+	CopyObject(ctx context.Context, in *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error)
+	UploadPartCopy(ctx context.Context, in *s3.UploadPartCopyInput, optFns ...func(*s3.Options)) (*s3.UploadPartCopyOutput, error)
+	GetObject(ctx context.Context, in *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	PutObject(ctx context.Context, in *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	DeleteObject(ctx context.Context, in *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+	ListObjectsV2(ctx context.Context, in *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
+}
+
+// maxBufferedPartSize is the largest payload that is copied into memory to make it seekable when it cannot be streamed.
+// Larger payloads, from clients that send bigger chunks than the SDK does, are spilled to a temporary file instead, so
+// that the memory used by each request stays bounded however large its chunk is.
+const maxBufferedPartSize = storage.MinPartSize
+
+// bufferPool holds the buffers used to make non-seekable payloads seekable before they are signed and sent to S3
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// Client is the S3 storage.Driver. It streams multipart upload parts to S3 rather than requiring them to be held in
+// memory. Any functionality not related to uploading parts is provided by the embedded dp-s3 client.
+//
+// Parts whose size is known are streamed straight through to S3 over HTTPS, with an unsigned payload and a SHA256
+// checksum sent as a trailer once the part has been read. S3 only accepts a trailer over TLS, so parts sent to a plain
+// HTTP endpoint, such as a local S3 stub, are buffered and signed instead.
+//
+// The multipart upload for each key is recorded in the bucket, so that every instance of the service uploads the parts
+// of a file to the same multipart upload and only one of them completes it.
+type Client struct {
+	*s3client.Client
+	sdk        sdkClient
+	presign    *s3.PresignClient
+	region     string
+	bucketName string
+	streaming  bool
+}
+
+// NewClient creates a new Client for the given bucket name, using the provided AWS config and S3 options
+func NewClient(bucketName string, cfg awssdk.Config, optFns ...func(*s3.Options)) *Client {
+	sdk := s3.NewFromConfig(cfg, optFns...)
+	endpoint := sdk.Options().BaseEndpoint
+	return &Client{
+		Client:     s3client.NewClientWithConfig(bucketName, cfg, optFns...),
+		sdk:        sdk,
+		presign:    s3.NewPresignClient(sdk),
+		region:     cfg.Region,
+		bucketName: bucketName,
+		streaming:  endpoint == nil || strings.HasPrefix(*endpoint, "https://"),
+	}
+}
+
+// UploadPart streams the payload to S3 as a part of the multipart upload for the requested key, creating the
+// multipart upload if needed. Once all parts have been received the multipart upload is completed.
+//
+// A part whose size is known is streamed with that Content-Length when the client can stream, so only the bytes in
+// transit are held in memory. Otherwise request signing needs to read the payload twice, so a payload that is not an
+// io.ReadSeeker is copied into a pooled buffer first, or into a temporary file if it is larger than
+// maxBufferedPartSize. Either way an error from reading the payload, such as a checksum mismatch, is returned rather
+// than the part being stored.
+func (cli *Client) UploadPart(ctx context.Context, req *storage.PartRequest, payload io.Reader) (storage.PartResponse, error) {
+	logData := log.Data{
+		"chunk_number": req.PartNumber,
+		"max_chunks":   req.TotalParts,
+		"file_name":    req.FileName,
+		"bucket_name":  cli.bucketName,
+	}
+
+	input := &s3.UploadPartInput{
+		Bucket:     &cli.bucketName,
+		Key:        &req.Key,
+		PartNumber: &req.PartNumber,
+	}
+	// the stream cannot be rewound, so a streamed part is not retried
+	var optFns []func(*s3.Options)
+
+	// every part carries a SHA256 checksum, as the multipart upload is created with one
+	input.ChecksumAlgorithm = types.ChecksumAlgorithmSha256
+
+	body := &readRecorder{r: payload}
+	if cli.streaming && req.Size > 0 {
+		input.Body = body
+		input.ContentLength = &req.Size
+		optFns = append(optFns, func(o *s3.Options) { o.RetryMaxAttempts = 1 })
+	} else {
+		buffered, contentMD5, release, err := prepareBody(payload)
+		if err != nil {
+			return storage.PartResponse{}, s3client.NewError(fmt.Errorf("error reading part: %w", err), logData)
+		}
+		defer release()
+		input.Body = buffered
+		input.ContentMD5 = &contentMD5
+	}
+
+	uploadID, err := cli.getOrCreateMultipartUpload(ctx, req)
+	if err != nil {
+		return storage.PartResponse{}, s3client.NewError(err, logData)
+	}
+	input.UploadId = &uploadID
+
+	uploadPartOutput, err := cli.sdk.UploadPart(ctx, input, optFns...)
+	if err != nil {
+		if body.err != nil {
+			return storage.PartResponse{}, s3client.NewError(fmt.Errorf("error reading part: %w", body.err), logData)
+		}
+		return storage.PartResponse{}, s3client.NewError(fmt.Errorf("error uploading part: %w", err), logData)
+	}
+
+	log.Info(ctx, "chunk accepted", logData)
+
+	allPartsUploaded, err := cli.completeIfAllPartsUploaded(ctx, uploadID, req)
+	if err != nil {
+		return storage.PartResponse{}, s3client.NewError(err, logData)
+	}
+
+	return storage.PartResponse{
+		ETag:             strings.Trim(awssdk.ToString(uploadPartOutput.ETag), "\""),
+		AllPartsUploaded: allPartsUploaded,
+	}, nil
+}
+
+// completeIfAllPartsUploaded completes the multipart upload once every part has been uploaded, returning true only to
+// the request that completed it. Parts can arrive in any order and at the same time, at any instance of the service,
+// so the request that completes the upload first claims it in the upload record, and an upload that has been claimed or
+// no longer exists is taken to have been completed by another request.
+func (cli *Client) completeIfAllPartsUploaded(ctx context.Context, uploadID string, req *storage.PartRequest) (bool, error) {
+	parts, err := cli.listParts(ctx, req.Key, uploadID)
+	if err != nil {
+		if isNoSuchUpload(err) {
+			log.Info(ctx, "multipart upload already completed", log.Data{"key": req.Key, "chunk_number": req.PartNumber})
+			return false, nil
+		}
+		return false, err
+	}
+
+	if len(parts) != req.TotalParts {
+		return false, nil
+	}
+
+	claimed, err := cli.claimCompletion(ctx, req.Key, uploadID)
+	if err != nil || !claimed {
+		if err == nil {
+			log.Info(ctx, "multipart upload already being completed", log.Data{"key": req.Key, "chunk_number": req.PartNumber})
+		}
+		return false, err
+	}
+
+	if err := cli.completeUpload(ctx, uploadID, req.Key, parts); err != nil {
+		if isNoSuchUpload(err) {
+			log.Info(ctx, "multipart upload already completed", log.Data{"key": req.Key, "chunk_number": req.PartNumber})
+			return false, nil
+		}
+		cli.releaseCompletion(ctx, req.Key, uploadID)
+		return false, err
+	}
+	cli.removeUploadRecord(ctx, req.Key, uploadID)
+
+	return true, nil
+}
+
+// listParts returns every part uploaded so far to the multipart upload, following the pages S3 splits them into
+func (cli *Client) listParts(ctx context.Context, key, uploadID string) ([]types.Part, error) {
+	var parts []types.Part
+	input := &s3.ListPartsInput{
+		Key:      &key,
+		Bucket:   &cli.bucketName,
+		UploadId: &uploadID,
+	}
+
+	for {
+		output, err := cli.sdk.ListParts(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("error listing parts: %w", err)
+		}
+
+		parts = append(parts, output.Parts...)
+
+		if !awssdk.ToBool(output.IsTruncated) {
+			return parts, nil
+		}
+		input.PartNumberMarker = output.NextPartNumberMarker
+	}
+}
+
+// isNoSuchUpload reports whether S3 rejected a request because the multipart upload has been completed or aborted
+func isNoSuchUpload(err error) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && apiErr.ErrorCode() == "NoSuchUpload"
+}
+
+// PartExists reports whether the requested part has been uploaded to the in progress multipart upload for the key.
+// Unlike CheckPartUploaded it never completes the multipart upload, so it is safe to call from a read-only request.
+func (cli *Client) PartExists(ctx context.Context, req *storage.PartRequest) (bool, error) {
+	logData := log.Data{
+		"chunk_number": req.PartNumber,
+		"file_name":    req.FileName,
+		"bucket_name":  cli.bucketName,
+	}
+
+	uploadID, found, err := cli.findMultipartUpload(ctx, req.Key)
+	if err != nil {
+		return false, s3client.NewError(err, logData)
+	}
+	if !found {
+		return false, nil
+	}
+
+	// list from the part before the requested one so that only a single part needs to be returned
+	marker := strconv.Itoa(int(req.PartNumber) - 1)
+	maxParts := int32(1)
+	output, err := cli.sdk.ListParts(ctx, &s3.ListPartsInput{
+		Key:              &req.Key,
+		Bucket:           &cli.bucketName,
+		UploadId:         &uploadID,
+		PartNumberMarker: &marker,
+		MaxParts:         &maxParts,
+	})
+	if err != nil {
+		return false, s3client.NewError(fmt.Errorf("error listing parts: %w", err), logData)
+	}
+
+	return len(output.Parts) > 0 && *output.Parts[0].PartNumber == req.PartNumber, nil
+}
+
+// ListUploadedParts returns the ID of the in progress multipart upload for the key and the parts uploaded to it so far,
+// in part number order. It returns false if there is no multipart upload in progress.
+func (cli *Client) ListUploadedParts(ctx context.Context, key string) (storage.MultipartUploadParts, bool, error) {
+	logData := log.Data{
+		"key":         key,
+		"bucket_name": cli.bucketName,
+	}
+
+	uploadID, found, err := cli.findMultipartUpload(ctx, key)
+	if err != nil {
+		return storage.MultipartUploadParts{}, false, s3client.NewError(err, logData)
+	}
+	if !found {
+		return storage.MultipartUploadParts{}, false, nil
+	}
+
+	parts, err := cli.listParts(ctx, key, uploadID)
+	if err != nil {
+		if isNoSuchUpload(err) {
+			return storage.MultipartUploadParts{}, false, nil
+		}
+		return storage.MultipartUploadParts{}, false, s3client.NewError(err, logData)
+	}
+
+	uploaded := storage.MultipartUploadParts{UploadID: uploadID, Parts: make([]storage.UploadedPart, 0, len(parts))}
+	for _, part := range parts {
+		uploaded.Parts = append(uploaded.Parts, storage.UploadedPart{
+			PartNumber:   awssdk.ToInt32(part.PartNumber),
+			SizeInBytes:  awssdk.ToInt64(part.Size),
+			ETag:         strings.Trim(awssdk.ToString(part.ETag), "\""),
+			LastModified: awssdk.ToTime(part.LastModified),
+		})
+	}
+
+	return uploaded, true, nil
+}
+
+// AbortMultipartUpload aborts the in progress multipart upload for the key, discarding any parts uploaded so far.
+// It returns false if there was no multipart upload in progress.
+func (cli *Client) AbortMultipartUpload(ctx context.Context, key string) (bool, error) {
+	logData := log.Data{
+		"key":         key,
+		"bucket_name": cli.bucketName,
+	}
+
+	uploadID, found, err := cli.findMultipartUpload(ctx, key)
+	if err != nil {
+		return false, s3client.NewError(err, logData)
+	}
+	if !found {
+		return false, nil
+	}
+
+	if err := cli.AbortMultipartUploadByID(ctx, key, uploadID); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// AbortMultipartUploadByID aborts the multipart upload with the given ID, discarding any parts uploaded so far
+func (cli *Client) AbortMultipartUploadByID(ctx context.Context, key, uploadID string) error {
+	logData := log.Data{
+		"key":         key,
+		"upload_id":   uploadID,
+		"bucket_name": cli.bucketName,
+	}
+
+	_, err := cli.sdk.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   &cli.bucketName,
+		Key:      &key,
+		UploadId: &uploadID,
+	})
+	if err != nil {
+		return s3client.NewError(fmt.Errorf("error aborting multipart upload: %w", err), logData)
+	}
+	cli.removeUploadRecord(ctx, key, uploadID)
+
+	log.Info(ctx, "multipart upload aborted", logData)
+
+	return nil
+}
+
+// ListMultipartUploads returns every multipart upload in the bucket that has been started but not completed or aborted
+func (cli *Client) ListMultipartUploads(ctx context.Context) ([]storage.MultipartUpload, error) {
+	var uploads []storage.MultipartUpload
+	input := &s3.ListMultipartUploadsInput{Bucket: &cli.bucketName}
+
+	for {
+		output, err := cli.sdk.ListMultipartUploads(ctx, input)
+		if err != nil {
+			return nil, s3client.NewError(fmt.Errorf("error fetching multipart list: %w", err), log.Data{"bucket_name": cli.bucketName})
+		}
+
+		for _, upload := range output.Uploads {
+			uploads = append(uploads, storage.MultipartUpload{
+				Key:       awssdk.ToString(upload.Key),
+				UploadID:  awssdk.ToString(upload.UploadId),
+				Initiated: awssdk.ToTime(upload.Initiated),
+			})
+		}
+
+		if !awssdk.ToBool(output.IsTruncated) {
+			return uploads, nil
+		}
+		input.KeyMarker = output.NextKeyMarker
+		input.UploadIdMarker = output.NextUploadIdMarker
+	}
+}
+
+// findMultipartUpload returns the ID of the in progress multipart upload for the key, if there is one
+func (cli *Client) findMultipartUpload(ctx context.Context, key string) (string, bool, error) {
+	listMultiOutput, err := cli.sdk.ListMultipartUploads(ctx, &s3.ListMultipartUploadsInput{
+		Bucket: &cli.bucketName,
+		Prefix: &key,
+	})
+	if err != nil {
+		return "", false, fmt.Errorf("error fetching multipart list: %w", err)
+	}
+
+	for _, upload := range listMultiOutput.Uploads {
+		if *upload.Key == key {
+			return *upload.UploadId, true, nil
+		}
+	}
+
+	return "", false, nil
+}
+
+// getOrCreateMultipartUpload returns the ID of the multipart upload recorded for the requested key, creating and
+// recording one if there is none, or storage.ErrAlreadyUploaded if the object has already been stored. If another
+// request records a multipart upload for the key first, the one created here is aborted and the recorded one is used
+// instead.
+func (cli *Client) getOrCreateMultipartUpload(ctx context.Context, req *storage.PartRequest) (string, error) {
+	record, _, found, err := cli.readUploadRecord(ctx, req.Key)
+	if err != nil {
+		return "", err
+	}
+	if found {
+		return record.UploadID, nil
+	}
+
+	// the object is only stored once its multipart upload completes, so this is a part sent again after that
+	if _, err := cli.Head(ctx, req.Key); err == nil {
+		return "", storage.ErrAlreadyUploaded
+	} else if !errors.Is(err, storage.ErrNotFound) {
+		return "", err
+	}
+
+	uploadID, err := cli.startUpload(ctx, req.Key, req.ContentType)
+	if !errors.Is(err, errPreconditionFailed) {
+		return uploadID, err
+	}
+
+	record, _, found, err = cli.readUploadRecord(ctx, req.Key)
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		return "", errors.New("multipart upload created by another request no longer exists")
+	}
+	return record.UploadID, nil
+}
+
+// createUpload starts a new multipart upload for the key, returning its upload ID. S3 keeps the SHA256 checksum of each
+// part, and of the completed object, so that a copy of the object can be verified without reading it.
+func (cli *Client) createUpload(ctx context.Context, key, contentType string) (string, error) {
+	output, err := cli.sdk.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:            &cli.bucketName,
+		Key:               &key,
+		ContentType:       &contentType,
+		ChecksumAlgorithm: types.ChecksumAlgorithmSha256,
+	})
+	if err != nil {
+		return "", fmt.Errorf("error creating multipart upload: %w", err)
+	}
+
+	return *output.UploadId, nil
+}
+
+// startUpload starts a new multipart upload for the key and records it, returning errPreconditionFailed if another
+// multipart upload has already been recorded for the key
+func (cli *Client) startUpload(ctx context.Context, key, contentType string) (string, error) {
+	uploadID, err := cli.createUpload(ctx, key, contentType)
+	if err != nil {
+		return "", err
+	}
+
+	if err := cli.writeUploadRecord(ctx, key, uploadRecord{UploadID: uploadID}, ""); err != nil {
+		cli.abortUnrecordedUpload(ctx, key, uploadID)
+		return "", err
+	}
+
+	return uploadID, nil
+}
+
+// abortUnrecordedUpload aborts a multipart upload that could not be recorded, logging rather than returning any error
+// so that the original error is kept. Any upload left behind is aborted by the reaper.
+func (cli *Client) abortUnrecordedUpload(ctx context.Context, key, uploadID string) {
+	if _, err := cli.sdk.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   &cli.bucketName,
+		Key:      &key,
+		UploadId: &uploadID,
+	}); err != nil {
+		log.Error(ctx, "failed to abort unrecorded multipart upload", err, log.Data{"key": key, "upload_id": uploadID})
+	}
+}
+
+// completeUpload completes the multipart upload from the provided list of uploaded parts
+func (cli *Client) completeUpload(ctx context.Context, uploadID, key string, parts []types.Part) error {
+	completedParts := make([]types.CompletedPart, 0, len(parts))
+	for _, part := range parts {
+		completedParts = append(completedParts, types.CompletedPart{
+			PartNumber:     part.PartNumber,
+			ETag:           part.ETag,
+			ChecksumSHA256: part.ChecksumSHA256,
+		})
+	}
+
+	_, err := cli.sdk.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Key:      &key,
+		UploadId: &uploadID,
+		MultipartUpload: &types.CompletedMultipartUpload{
+			Parts: completedParts,
+		},
+		Bucket: &cli.bucketName,
+	})
+	if err != nil {
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) && apiErr.ErrorCode() == "EntityTooSmall" {
+			return fmt.Errorf("error completing multipart upload: %w: %w", storage.ErrPartTooSmall, err)
+		}
+		return fmt.Errorf("error completing multipart upload: %w", err)
+	}
+
+	return nil
+}
+
+// prepareBody returns the payload as an io.ReadSeeker along with its base64 encoded MD5 digest, which S3 uses to reject
+// any part corrupted in transit. Payloads that can't seek are copied into a pooled buffer, or spilled to a temporary
+// file once they are larger than maxBufferedPartSize. The returned release function must be called once the payload is
+// no longer needed.
+func prepareBody(payload io.Reader) (io.ReadSeeker, string, func(), error) {
+	hash := md5.New()
+
+	if rs, ok := payload.(io.ReadSeeker); ok {
+		if _, err := io.Copy(hash, rs); err != nil {
+			return nil, "", nil, err
+		}
+		if _, err := rs.Seek(0, io.SeekStart); err != nil {
+			return nil, "", nil, err
+		}
+		return rs, base64.StdEncoding.EncodeToString(hash.Sum(nil)), func() {}, nil
+	}
+
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	content := io.TeeReader(payload, hash)
+
+	_, err := io.CopyN(buf, content, maxBufferedPartSize+1)
+	if err == io.EOF {
+		return bytes.NewReader(buf.Bytes()), base64.StdEncoding.EncodeToString(hash.Sum(nil)), func() { putBuffer(buf) }, nil
+	}
+	if err != nil {
+		putBuffer(buf)
+		return nil, "", nil, err
+	}
+
+	file, err := spillToFile(buf, content)
+	putBuffer(buf)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	release := func() {
+		if err := file.Close(); err != nil {
+			log.Error(context.Background(), "error closing spilled part", err, log.Data{"path": file.Name()})
+		}
+		if err := os.Remove(file.Name()); err != nil {
+			log.Error(context.Background(), "error removing spilled part", err, log.Data{"path": file.Name()})
+		}
+	}
+
+	return file, base64.StdEncoding.EncodeToString(hash.Sum(nil)), release, nil
+}
+
+// readRecorder keeps the first error other than io.EOF from reading the payload, as the S3 client reports a payload
+// that failed part way through being streamed as a transport error
+type readRecorder struct {
+	r   io.Reader
+	err error
+}
+
+func (r *readRecorder) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if err != nil && err != io.EOF && r.err == nil {
+		r.err = err
+	}
+	return n, err
+}
+
+// spillToFile writes the start of a payload that has already been buffered, followed by the rest of the payload, to a
+// new temporary file, returning the file ready to be read from the start
+func spillToFile(start *bytes.Buffer, rest io.Reader) (*os.File, error) {
+	file, err := os.CreateTemp("", "dp-upload-service-part-*")
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = io.Copy(file, io.MultiReader(start, rest))
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+		return nil, err
+	}
+
+	return file, nil
+}
+
+// putBuffer returns a buffer to the pool, unless it has grown larger than a part that is buffered in memory
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() <= 2*maxBufferedPartSize {
+		bufferPool.Put(buf)
+	}
+}
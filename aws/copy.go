@@ -0,0 +1,121 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	s3client "github.com/ONSdigital/dp-s3/v3"
+	"github.com/ONSdigital/log.go/v2/log"
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// CopyFrom makes a server-side copy of the object with the source key in the source bucket to the key in this bucket,
+// returning the ETag of the copy. The object never passes through the service. An object that was uploaded in parts is
+// copied part by part with the same part boundaries, and any other object in a single request. S3 calculates the
+// SHA256 checksum of each part as it is copied, so an intact copy of an object that was uploaded with checksums has the
+// same checksum as the source, whichever key either bucket is encrypted with. If any part fails to copy, the multipart
+// upload is aborted so that no partial copy is left behind.
+func (cli *Client) CopyFrom(ctx context.Context, sourceBucket, sourceKey, key string) (string, error) {
+	logData := log.Data{
+		"key":           key,
+		"source_bucket": sourceBucket,
+		"source_key":    sourceKey,
+		"bucket_name":   cli.bucketName,
+	}
+	copySource := (&url.URL{Path: sourceBucket + "/" + sourceKey}).EscapedPath()
+
+	// the first part describes how many parts the source was uploaded in, if it was uploaded in parts
+	head, err := cli.sdk.HeadObject(ctx, &s3.HeadObjectInput{Bucket: &sourceBucket, Key: &sourceKey, PartNumber: awssdk.Int32(1)})
+	if err != nil {
+		return "", s3client.NewError(fmt.Errorf("error getting source object metadata: %w", err), logData)
+	}
+	partsCount := awssdk.ToInt32(head.PartsCount)
+
+	if partsCount == 0 {
+		output, err := cli.sdk.CopyObject(ctx, &s3.CopyObjectInput{
+			Bucket:            &cli.bucketName,
+			Key:               &key,
+			CopySource:        &copySource,
+			ChecksumAlgorithm: types.ChecksumAlgorithmSha256,
+		})
+		if err != nil {
+			return "", s3client.NewError(fmt.Errorf("error copying object: %w", err), logData)
+		}
+		return strings.Trim(awssdk.ToString(output.CopyObjectResult.ETag), "\""), nil
+	}
+
+	created, err := cli.sdk.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:            &cli.bucketName,
+		Key:               &key,
+		ContentType:       head.ContentType,
+		ChecksumAlgorithm: types.ChecksumAlgorithmSha256,
+	})
+	if err != nil {
+		return "", s3client.NewError(fmt.Errorf("error creating multipart upload: %w", err), logData)
+	}
+	uploadID := awssdk.ToString(created.UploadId)
+	logData["upload_id"] = uploadID
+
+	var parts []types.CompletedPart
+	var start int64
+	for partNumber := int32(1); partNumber <= partsCount; partNumber++ {
+		logData["part_number"] = partNumber
+
+		if partNumber > 1 {
+			head, err = cli.sdk.HeadObject(ctx, &s3.HeadObjectInput{Bucket: &sourceBucket, Key: &sourceKey, PartNumber: &partNumber})
+			if err != nil {
+				cli.abortCopy(ctx, key, uploadID)
+				return "", s3client.NewError(fmt.Errorf("error getting source part metadata: %w", err), logData)
+			}
+		}
+		end := start + awssdk.ToInt64(head.ContentLength)
+		copyRange := fmt.Sprintf("bytes=%d-%d", start, end-1)
+		start = end
+
+		output, err := cli.sdk.UploadPartCopy(ctx, &s3.UploadPartCopyInput{
+			Bucket:          &cli.bucketName,
+			Key:             &key,
+			UploadId:        &uploadID,
+			PartNumber:      awssdk.Int32(partNumber),
+			CopySource:      &copySource,
+			CopySourceRange: &copyRange,
+		})
+		if err != nil {
+			cli.abortCopy(ctx, key, uploadID)
+			return "", s3client.NewError(fmt.Errorf("error copying part: %w", err), logData)
+		}
+
+		parts = append(parts, types.CompletedPart{
+			PartNumber:     awssdk.Int32(partNumber),
+			ETag:           output.CopyPartResult.ETag,
+			ChecksumSHA256: output.CopyPartResult.ChecksumSHA256,
+		})
+	}
+	delete(logData, "part_number")
+
+	completed, err := cli.sdk.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          &cli.bucketName,
+		Key:             &key,
+		UploadId:        &uploadID,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+	})
+	if err != nil {
+		cli.abortCopy(ctx, key, uploadID)
+		return "", s3client.NewError(fmt.Errorf("error completing multipart copy: %w", err), logData)
+	}
+
+	log.Info(ctx, "object copied", logData)
+
+	return strings.Trim(awssdk.ToString(completed.ETag), "\""), nil
+}
+
+// abortCopy aborts a failed multipart copy, logging rather than returning any error so that the original error is kept
+func (cli *Client) abortCopy(ctx context.Context, key, uploadID string) {
+	if err := cli.AbortMultipartUploadByID(ctx, key, uploadID); err != nil {
+		log.Error(ctx, "failed to abort multipart copy", err, log.Data{"key": key, "upload_id": uploadID})
+	}
+}
@@ -0,0 +1,191 @@
+package aws
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ONSdigital/dp-upload-service/storage"
+	"github.com/ONSdigital/log.go/v2/log"
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+const (
+	// uploadRecordPrefix is the prefix of the keys of the upload records, which are kept out of the way of the objects
+	// uploaded to the bucket
+	uploadRecordPrefix = storage.MultipartUploadPrefix
+
+	// completionClaimTimeout is how long an instance has to complete a multipart upload once it has claimed it, after
+	// which another instance can take over in case the first has stopped
+	completionClaimTimeout = 15 * time.Minute
+)
+
+// uploadRecord is stored in the bucket alongside each in progress multipart upload started by UploadPart or
+// CreateMultipartUpload. The instances of the service sharing the bucket use S3 conditional writes on the record to
+// agree on a single multipart upload for each key, and on the one request that completes it.
+type uploadRecord struct {
+	UploadID        string     `json:"upload_id"`
+	CompletionClaim *time.Time `json:"completion_claim,omitempty"`
+}
+
+// claimed reports whether another request is completing the multipart upload
+func (r uploadRecord) claimed() bool {
+	return r.CompletionClaim != nil && time.Since(*r.CompletionClaim) < completionClaimTimeout
+}
+
+func uploadRecordKey(key string) string {
+	return uploadRecordPrefix + key
+}
+
+// readUploadRecord returns the record of the multipart upload in progress for the key along with its ETag, or false if
+// there is none. A record left behind by a multipart upload that no longer exists, such as one aborted by a lifecycle
+// rule, is removed and treated as if there were none.
+func (cli *Client) readUploadRecord(ctx context.Context, key string) (uploadRecord, string, bool, error) {
+	record, etag, found, err := cli.getUploadRecord(ctx, key)
+	if err != nil || !found {
+		return uploadRecord{}, "", false, err
+	}
+
+	if _, err := cli.sdk.ListParts(ctx, &s3.ListPartsInput{
+		Bucket:   &cli.bucketName,
+		Key:      &key,
+		UploadId: &record.UploadID,
+		MaxParts: awssdk.Int32(1),
+	}); err != nil {
+		if !isNoSuchUpload(err) {
+			return uploadRecord{}, "", false, fmt.Errorf("error listing parts: %w", err)
+		}
+		cli.removeUploadRecord(ctx, key, record.UploadID)
+		return uploadRecord{}, "", false, nil
+	}
+
+	return record, etag, true, nil
+}
+
+// getUploadRecord returns the record stored for the key along with its ETag, or false if there is none, without
+// checking that its multipart upload still exists
+func (cli *Client) getUploadRecord(ctx context.Context, key string) (uploadRecord, string, bool, error) {
+	recordKey := uploadRecordKey(key)
+	output, err := cli.sdk.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: &cli.bucketName,
+		Key:    &recordKey,
+	})
+	if err != nil {
+		var noSuchKey *types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return uploadRecord{}, "", false, nil
+		}
+		return uploadRecord{}, "", false, fmt.Errorf("error reading upload record: %w", err)
+	}
+	defer output.Body.Close()
+
+	var record uploadRecord
+	if err := json.NewDecoder(output.Body).Decode(&record); err != nil {
+		return uploadRecord{}, "", false, fmt.Errorf("error reading upload record: %w", err)
+	}
+
+	return record, awssdk.ToString(output.ETag), true, nil
+}
+
+// writeUploadRecord stores the record of the multipart upload for the key. The record is only written if it has not
+// changed since it was read with the ETag, or if there is no record when the ETag is empty, otherwise
+// errPreconditionFailed is returned.
+func (cli *Client) writeUploadRecord(ctx context.Context, key string, record uploadRecord, etag string) error {
+	content, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("error writing upload record: %w", err)
+	}
+
+	recordKey := uploadRecordKey(key)
+	input := &s3.PutObjectInput{
+		Bucket:      &cli.bucketName,
+		Key:         &recordKey,
+		Body:        bytes.NewReader(content),
+		ContentType: awssdk.String("application/json"),
+	}
+	if etag == "" {
+		input.IfNoneMatch = awssdk.String("*")
+	} else {
+		input.IfMatch = &etag
+	}
+
+	if _, err := cli.sdk.PutObject(ctx, input); err != nil {
+		if isPreconditionFailed(err) {
+			return errPreconditionFailed
+		}
+		return fmt.Errorf("error writing upload record: %w", err)
+	}
+	return nil
+}
+
+// removeUploadRecord removes the record of the multipart upload with the ID, if it is still the one recorded for the
+// key, logging rather than returning any error as a record that is left behind is removed when it is next read
+func (cli *Client) removeUploadRecord(ctx context.Context, key, uploadID string) {
+	logData := log.Data{"key": key, "upload_id": uploadID, "bucket_name": cli.bucketName}
+
+	record, etag, found, err := cli.getUploadRecord(ctx, key)
+	if err != nil {
+		log.Error(ctx, "failed to remove upload record", err, logData)
+		return
+	}
+	if !found || record.UploadID != uploadID {
+		return
+	}
+
+	recordKey := uploadRecordKey(key)
+	if _, err := cli.sdk.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket:  &cli.bucketName,
+		Key:     &recordKey,
+		IfMatch: &etag,
+	}); err != nil && !isPreconditionFailed(err) {
+		log.Error(ctx, "failed to remove upload record", err, logData)
+	}
+}
+
+// claimCompletion records that the multipart upload with the ID is being completed, returning false if it is no longer
+// the upload recorded for the key or another request has already claimed it
+func (cli *Client) claimCompletion(ctx context.Context, key, uploadID string) (bool, error) {
+	record, etag, found, err := cli.readUploadRecord(ctx, key)
+	if err != nil || !found || record.UploadID != uploadID || record.claimed() {
+		return false, err
+	}
+
+	now := time.Now().UTC()
+	record.CompletionClaim = &now
+	if err := cli.writeUploadRecord(ctx, key, record, etag); err != nil {
+		if errors.Is(err, errPreconditionFailed) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// releaseCompletion gives up the claim to complete the multipart upload with the ID after failing to complete it, so
+// that it can be completed when a part is retried. Any error is logged, as the claim also expires.
+func (cli *Client) releaseCompletion(ctx context.Context, key, uploadID string) {
+	record, etag, found, err := cli.readUploadRecord(ctx, key)
+	if err == nil && found && record.UploadID == uploadID {
+		record.CompletionClaim = nil
+		err = cli.writeUploadRecord(ctx, key, record, etag)
+	}
+	if err != nil {
+		log.Error(ctx, "failed to release claim to complete multipart upload", err, log.Data{"key": key, "upload_id": uploadID})
+	}
+}
+
+// errPreconditionFailed is returned when an upload record has been changed by another request since it was read
+var errPreconditionFailed = errors.New("upload record has changed")
+
+// isPreconditionFailed reports whether S3 rejected a conditional write because the object had been changed, or was
+// being changed at the same time by another request
+func isPreconditionFailed(err error) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && (apiErr.ErrorCode() == "PreconditionFailed" || apiErr.ErrorCode() == "ConditionalRequestConflict")
+}
@@ -1,44 +1,185 @@
 package aws
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
 
-	"github.com/ONSdigital/dp-healthcheck/healthcheck"
 	s3client "github.com/ONSdigital/dp-s3/v3"
+	"github.com/ONSdigital/dp-upload-service/storage"
+	"github.com/ONSdigital/log.go/v2/log"
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
 )
 
-//go:generate moq -out mock/s3.go -pkg mock_aws . S3Clienter
-
 const (
 	PathStyle = s3client.PathStyle
 )
 
-type S3Clienter interface {
-	UploadPart(ctx context.Context, req *s3client.UploadPartRequest, payload []byte) (s3client.MultipartUploadResponse, error)
-	CheckPartUploaded(ctx context.Context, req *s3client.UploadPartRequest) (bool, error)
-	Checker(ctx context.Context, state *healthcheck.CheckState) error
-	Head(ctx context.Context, key string) (*s3.HeadObjectOutput, error)
+var _ storage.Driver = (*Client)(nil)
+
+// CheckPartUploaded reports whether the requested part has been uploaded, completing the multipart upload if every
+// part has been received
+func (cli *Client) CheckPartUploaded(ctx context.Context, req *storage.PartRequest) (bool, error) {
+	logData := log.Data{
+		"chunk_number": req.PartNumber,
+		"max_chunks":   req.TotalParts,
+		"file_name":    req.FileName,
+		"bucket_name":  cli.bucketName,
+		"identifier":   req.Key,
+	}
+
+	record, _, found, err := cli.readUploadRecord(ctx, req.Key)
+	if err != nil {
+		return false, s3client.NewError(err, logData)
+	}
+	if !found {
+		return false, s3client.NewError(storage.ErrNotUploaded, logData)
+	}
+
+	parts, err := cli.listParts(ctx, req.Key, record.UploadID)
+	if err != nil {
+		if isNoSuchUpload(err) {
+			return false, s3client.NewError(fmt.Errorf("%w: %w", storage.ErrNotUploaded, err), logData)
+		}
+		return false, s3client.NewError(err, logData)
+	}
+
+	if len(parts) == req.TotalParts {
+		completed, err := cli.completeIfAllPartsUploaded(ctx, record.UploadID, req)
+		if err != nil {
+			return false, s3client.NewError(err, logData)
+		}
+		return completed, nil
+	}
+
+	for _, part := range parts {
+		if awssdk.ToInt32(part.PartNumber) == req.PartNumber {
+			log.Info(ctx, "chunk already uploaded", logData)
+			return true, nil
+		}
+	}
+
+	return false, s3client.NewError(storage.ErrPartNotFound, logData)
+}
+
+// Head describes the object with the key, including the checksum S3 keeps for it, returning storage.ErrNotFound if it
+// does not exist
+func (cli *Client) Head(ctx context.Context, key string) (storage.ObjectInfo, error) {
+	head, err := cli.sdk.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket:       &cli.bucketName,
+		Key:          &key,
+		ChecksumMode: types.ChecksumModeEnabled,
+	})
+	if err != nil {
+		return storage.ObjectInfo{}, notFound(s3client.NewError(
+			fmt.Errorf("error getting object metadata: %w", err),
+			log.Data{"key": key, "bucket_name": cli.bucketName},
+		))
+	}
+
+	return storage.ObjectInfo{
+		SizeInBytes:  awssdk.ToInt64(head.ContentLength),
+		ETag:         strings.Trim(awssdk.ToString(head.ETag), "\""),
+		Checksum:     awssdk.ToString(head.ChecksumSHA256),
+		ContentType:  awssdk.ToString(head.ContentType),
+		LastModified: awssdk.ToTime(head.LastModified),
+	}, nil
+}
+
+// Get returns the content of the object with the key and its size, returning storage.ErrNotFound if it does not exist
+func (cli *Client) Get(ctx context.Context, key string) (io.ReadCloser, *int64, error) {
+	body, size, err := cli.Client.Get(ctx, key)
+	if err != nil {
+		return nil, nil, notFound(err)
+	}
+	return body, size, nil
 }
 
-type Bucket struct {
-	region, name string
-	S3Clienter
+// Put stores the content as the object with the key in a single request, replacing any existing object
+func (cli *Client) Put(ctx context.Context, key, contentType string, content io.Reader) error {
+	logData := log.Data{"key": key, "bucket_name": cli.bucketName}
+
+	// the content is read into memory so that it can be signed, which is fine for the small objects that are put
+	body, err := io.ReadAll(content)
+	if err != nil {
+		return s3client.NewError(fmt.Errorf("error reading object content: %w", err), logData)
+	}
+
+	if _, err := cli.sdk.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      &cli.bucketName,
+		Key:         &key,
+		Body:        bytes.NewReader(body),
+		ContentType: &contentType,
+	}); err != nil {
+		return s3client.NewError(fmt.Errorf("error putting object: %w", err), logData)
+	}
+	return nil
 }
 
-func NewBucket(region, name string, client S3Clienter) *Bucket {
-	return &Bucket{
-		region:     region,
-		name:       name,
-		S3Clienter: client,
+// List returns every object in the bucket whose key starts with the prefix
+func (cli *Client) List(ctx context.Context, prefix string) ([]storage.ObjectSummary, error) {
+	var objects []storage.ObjectSummary
+	input := &s3.ListObjectsV2Input{Bucket: &cli.bucketName, Prefix: &prefix}
+
+	for {
+		output, err := cli.sdk.ListObjectsV2(ctx, input)
+		if err != nil {
+			return nil, s3client.NewError(fmt.Errorf("error listing objects: %w", err), log.Data{"prefix": prefix, "bucket_name": cli.bucketName})
+		}
+
+		for _, object := range output.Contents {
+			objects = append(objects, storage.ObjectSummary{
+				Key:          awssdk.ToString(object.Key),
+				LastModified: awssdk.ToTime(object.LastModified),
+			})
+		}
+
+		if !awssdk.ToBool(output.IsTruncated) {
+			return objects, nil
+		}
+		input.ContinuationToken = output.NextContinuationToken
 	}
 }
 
-func (b *Bucket) GetS3URL(path string) (string, error) {
-	// Generate URL from region, bucket and S3 key defined by query
-	s3Url, err := s3client.NewURL(b.region, b.name, path)
+// URL returns the path style S3 URL of the object with the key
+func (cli *Client) URL(key string) (string, error) {
+	s3Url, err := s3client.NewURL(cli.region, cli.bucketName, key)
 	if err != nil {
 		return "", err
 	}
 	return s3Url.String(PathStyle)
 }
+
+// PresignGet returns a URL that can be used to download the object with the key until it expires. If
+// contentDisposition is set, S3 sends it as the Content-Disposition header of the download.
+func (cli *Client) PresignGet(ctx context.Context, key string, expires time.Duration, contentDisposition string) (string, error) {
+	input := &s3.GetObjectInput{
+		Bucket: &cli.bucketName,
+		Key:    &key,
+	}
+	if contentDisposition != "" {
+		input.ResponseContentDisposition = &contentDisposition
+	}
+
+	req, err := cli.presign.PresignGetObject(ctx, input, s3.WithPresignExpires(expires))
+	if err != nil {
+		return "", s3client.NewError(fmt.Errorf("error presigning object: %w", err), map[string]interface{}{"key": key, "bucket_name": cli.bucketName})
+	}
+	return req.URL, nil
+}
+
+// notFound wraps the error with storage.ErrNotFound if S3 rejected the request because the object does not exist
+func notFound(err error) error {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && (apiErr.ErrorCode() == "NotFound" || apiErr.ErrorCode() == "NoSuchKey") {
+		return fmt.Errorf("%w: %w", storage.ErrNotFound, err)
+	}
+	return err
+}
@@ -0,0 +1,80 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	s3client "github.com/ONSdigital/dp-s3/v3"
+	"github.com/ONSdigital/dp-upload-service/storage"
+	"github.com/ONSdigital/log.go/v2/log"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// CreateMultipartUpload starts a multipart upload for the key whose parts are uploaded directly to S3, returning its
+// upload ID, or storage.ErrUploadInProgress if another multipart upload has already been recorded for the key
+func (cli *Client) CreateMultipartUpload(ctx context.Context, key, contentType string) (string, error) {
+	logData := log.Data{"key": key, "bucket_name": cli.bucketName}
+
+	uploadID, err := cli.startUpload(ctx, key, contentType)
+	if errors.Is(err, errPreconditionFailed) {
+		// the record may have been left behind by an upload that no longer exists, in which case reading it removes it
+		_, _, found, readErr := cli.readUploadRecord(ctx, key)
+		if readErr == nil && !found {
+			uploadID, err = cli.startUpload(ctx, key, contentType)
+		}
+	}
+	if err != nil {
+		if errors.Is(err, errPreconditionFailed) {
+			err = storage.ErrUploadInProgress
+		}
+		return "", s3client.NewError(err, logData)
+	}
+
+	return uploadID, nil
+}
+
+// PresignUploadPart returns a URL that the part with the number can be uploaded to with a PUT request until it
+// expires, without any AWS credentials
+func (cli *Client) PresignUploadPart(ctx context.Context, key, uploadID string, partNumber int32, expires time.Duration) (string, error) {
+	req, err := cli.presign.PresignUploadPart(ctx, &s3.UploadPartInput{
+		Bucket:     &cli.bucketName,
+		Key:        &key,
+		UploadId:   &uploadID,
+		PartNumber: &partNumber,
+	}, s3.WithPresignExpires(expires))
+	if err != nil {
+		return "", s3client.NewError(fmt.Errorf("error presigning part: %w", err), log.Data{"key": key, "bucket_name": cli.bucketName, "chunk_number": partNumber})
+	}
+	return req.URL, nil
+}
+
+// CompleteMultipartUpload completes the multipart upload with the ID from every part uploaded to it, returning
+// storage.ErrNotUploaded if it has already been completed or aborted, or is being completed by another request
+func (cli *Client) CompleteMultipartUpload(ctx context.Context, key, uploadID string) error {
+	logData := log.Data{"key": key, "bucket_name": cli.bucketName, "upload_id": uploadID}
+
+	claimed, err := cli.claimCompletion(ctx, key, uploadID)
+	if err != nil {
+		return s3client.NewError(err, logData)
+	}
+	if !claimed {
+		return s3client.NewError(storage.ErrNotUploaded, logData)
+	}
+
+	parts, err := cli.listParts(ctx, key, uploadID)
+	if err == nil {
+		err = cli.completeUpload(ctx, uploadID, key, parts)
+	}
+	if err != nil {
+		if isNoSuchUpload(err) {
+			return s3client.NewError(fmt.Errorf("%w: %w", storage.ErrNotUploaded, err), logData)
+		}
+		cli.releaseCompletion(ctx, key, uploadID)
+		return s3client.NewError(err, logData)
+	}
+	cli.removeUploadRecord(ctx, key, uploadID)
+
+	return nil
+}
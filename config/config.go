@@ -3,6 +3,7 @@ package config
 import (
 	"time"
 
+	"github.com/ONSdigital/dp-authorisation/v2/authorisation"
 	"github.com/ONSdigital/dp-net/v3/request"
 
 	"github.com/kelseyhightower/envconfig"
@@ -14,18 +15,51 @@ const (
 
 type ContextKey string
 
+// AuthConfig is the configuration of the authorisation middleware that checks the permissions of callers
+type AuthConfig = authorisation.Config
+
+// Storage backends selected by STORAGE_BACKEND
+const (
+	StorageBackendS3         = "s3"
+	StorageBackendFilesystem = "filesystem"
+)
+
 // Config represents service configuration for dp-upload-service
 type Config struct {
 	BindAddr                       string        `envconfig:"BIND_ADDR"`
 	AwsRegion                      string        `envconfig:"AWS_REGION"`
 	LocalstackHost                 string        `envconfig:"LOCALSTACK_HOST"`
+	StorageBackend                 string        `envconfig:"STORAGE_BACKEND"`
+	FilesystemStoragePath          string        `envconfig:"FILESYSTEM_STORAGE_PATH"`
 	UploadBucketName               string        `envconfig:"UPLOAD_BUCKET_NAME"`
 	StaticFilesEncryptedBucketName string        `envconfig:"STATIC_FILES_ENCRYPTED_BUCKET_NAME"`
+	PublicBucketName               string        `envconfig:"PUBLIC_BUCKET_NAME"`
 	GracefulShutdownTimeout        time.Duration `envconfig:"GRACEFUL_SHUTDOWN_TIMEOUT"`
 	HealthCheckInterval            time.Duration `envconfig:"HEALTHCHECK_INTERVAL"`
 	HealthCheckCriticalTimeout     time.Duration `envconfig:"HEALTHCHECK_CRITICAL_TIMEOUT"`
 	FilesAPIURL                    string        `envconfig:"FILES_API_URL"`
 	ServiceAuthToken               string        `envconfig:"SERVICE_AUTH_TOKEN"         json:"-"`
+	MaxInFlightUploadBytes         int64         `envconfig:"MAX_IN_FLIGHT_UPLOAD_BYTES"`
+	MultipartUploadReaperInterval  time.Duration `envconfig:"MULTIPART_UPLOAD_REAPER_INTERVAL"`
+	MultipartUploadMaxAge          time.Duration `envconfig:"MULTIPART_UPLOAD_MAX_AGE"`
+	StatusCheckConcurrency         int           `envconfig:"STATUS_CHECK_CONCURRENCY"`
+	EventsHeartbeatInterval        time.Duration `envconfig:"EVENTS_HEARTBEAT_INTERVAL"`
+	UploadSessionExpiry            time.Duration `envconfig:"UPLOAD_SESSION_EXPIRY"`
+	PresignedURLExpiry             time.Duration `envconfig:"PRESIGNED_URL_EXPIRY"`
+	UploadAllowedTypes             []string      `envconfig:"UPLOAD_ALLOWED_TYPES"`
+	DatasetUploadAllowedTypes      []string      `envconfig:"DATASET_UPLOAD_ALLOWED_TYPES"`
+	VerificationWorkers            int           `envconfig:"VERIFICATION_WORKERS"`
+	VerificationRetryInterval      time.Duration `envconfig:"VERIFICATION_RETRY_INTERVAL"`
+	PublicCopyWorkers              int           `envconfig:"PUBLIC_COPY_WORKERS"`
+	PublicCopyRetryInterval        time.Duration `envconfig:"PUBLIC_COPY_RETRY_INTERVAL"`
+	ClamAVAddr                     string        `envconfig:"CLAMAV_ADDR"`
+	ClamAVTimeout                  time.Duration `envconfig:"CLAMAV_TIMEOUT"`
+	ClamAVMaxStreamSize            int64         `envconfig:"CLAMAV_MAX_STREAM_SIZE"`
+	QuarantinePrefix               string        `envconfig:"QUARANTINE_PREFIX"`
+	MaxUploadFileSize              int64         `envconfig:"MAX_UPLOAD_FILE_SIZE"`
+	MaxSessionFileSize             int64         `envconfig:"MAX_SESSION_FILE_SIZE"`
+	DatasetMaxUploadFileSize       int64         `envconfig:"DATASET_MAX_UPLOAD_FILE_SIZE"`
+	AuthConfig
 }
 
 // Get returns the default config with any modifications through environment
@@ -34,6 +68,8 @@ func Get() (*Config, error) {
 	cfg := &Config{
 		BindAddr:                       ":25100",
 		AwsRegion:                      "eu-west-2",
+		StorageBackend:                 StorageBackendS3,
+		FilesystemStoragePath:          "/tmp/dp-upload-service",
 		UploadBucketName:               "deprecated",
 		StaticFilesEncryptedBucketName: "testing",
 		GracefulShutdownTimeout:        5 * time.Second,
@@ -41,6 +77,22 @@ func Get() (*Config, error) {
 		HealthCheckCriticalTimeout:     90 * time.Second,
 		FilesAPIURL:                    "http://localhost:26900", //401 via api-router [http://localhost:23200/v1]
 		ServiceAuthToken:               "c60198e9-1864-4b68-ad0b-1e858e5b46a4",
+		MaxInFlightUploadBytes:         256 * 1024 * 1024,
+		MultipartUploadReaperInterval:  time.Hour,
+		MultipartUploadMaxAge:          7 * 24 * time.Hour,
+		StatusCheckConcurrency:         10,
+		EventsHeartbeatInterval:        15 * time.Second,
+		UploadSessionExpiry:            time.Hour,
+		PresignedURLExpiry:             15 * time.Minute,
+		VerificationWorkers:            4,
+		VerificationRetryInterval:      5 * time.Minute,
+		PublicCopyWorkers:              2,
+		PublicCopyRetryInterval:        5 * time.Minute,
+		ClamAVTimeout:                  10 * time.Minute,
+		ClamAVMaxStreamSize:            25 * 1024 * 1024,
+		QuarantinePrefix:               "quarantine/",
+		MaxSessionFileSize:             10000 * 5 * 1024 * 1024,
+		AuthConfig:                     *authorisation.NewDefaultConfig(),
 	}
 
 	return cfg, envconfig.Process("", cfg)
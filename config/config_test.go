@@ -24,12 +24,37 @@ func TestConfig(t *testing.T) {
 			Convey("Then the values should be set to the expected defaults", func() {
 				So(testCfg.BindAddr, ShouldEqual, ":25100")
 				So(testCfg.AwsRegion, ShouldEqual, "eu-west-2")
+				So(testCfg.StorageBackend, ShouldEqual, "s3")
+				So(testCfg.FilesystemStoragePath, ShouldEqual, "/tmp/dp-upload-service")
 				So(testCfg.UploadBucketName, ShouldEqual, "deprecated")
 				So(testCfg.StaticFilesEncryptedBucketName, ShouldEqual, "testing")
+				So(testCfg.PublicBucketName, ShouldBeEmpty)
 				So(testCfg.GracefulShutdownTimeout, ShouldEqual, 5*time.Second)
 				So(testCfg.HealthCheckInterval, ShouldEqual, 30*time.Second)
 				So(testCfg.HealthCheckCriticalTimeout, ShouldEqual, 90*time.Second)
 				So(testCfg.ServiceAuthToken, ShouldEqual, "c60198e9-1864-4b68-ad0b-1e858e5b46a4")
+				So(testCfg.MaxInFlightUploadBytes, ShouldEqual, 256*1024*1024)
+				So(testCfg.MultipartUploadReaperInterval, ShouldEqual, time.Hour)
+				So(testCfg.MultipartUploadMaxAge, ShouldEqual, 7*24*time.Hour)
+				So(testCfg.StatusCheckConcurrency, ShouldEqual, 10)
+				So(testCfg.EventsHeartbeatInterval, ShouldEqual, 15*time.Second)
+				So(testCfg.UploadSessionExpiry, ShouldEqual, time.Hour)
+				So(testCfg.PresignedURLExpiry, ShouldEqual, 15*time.Minute)
+				So(testCfg.UploadAllowedTypes, ShouldBeEmpty)
+				So(testCfg.DatasetUploadAllowedTypes, ShouldBeEmpty)
+				So(testCfg.VerificationWorkers, ShouldEqual, 4)
+				So(testCfg.VerificationRetryInterval, ShouldEqual, 5*time.Minute)
+				So(testCfg.PublicCopyWorkers, ShouldEqual, 2)
+				So(testCfg.PublicCopyRetryInterval, ShouldEqual, 5*time.Minute)
+				So(testCfg.ClamAVAddr, ShouldEqual, "")
+				So(testCfg.ClamAVTimeout, ShouldEqual, 10*time.Minute)
+				So(testCfg.ClamAVMaxStreamSize, ShouldEqual, 25*1024*1024)
+				So(testCfg.QuarantinePrefix, ShouldEqual, "quarantine/")
+				So(testCfg.MaxUploadFileSize, ShouldEqual, 0)
+				So(testCfg.MaxSessionFileSize, ShouldEqual, 10000*5*1024*1024)
+				So(testCfg.DatasetMaxUploadFileSize, ShouldEqual, 0)
+				So(testCfg.AuthConfig.Enabled, ShouldBeFalse)
+				So(testCfg.AuthConfig.PermissionsAPIURL, ShouldEqual, "http://localhost:25400")
 			})
 
 			Convey("Then a second call to config should return the same config", func() {
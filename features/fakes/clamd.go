@@ -0,0 +1,86 @@
+package fakes
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+)
+
+// EICARSignature is the signature that the Clamd fake reports for content containing the EICAR anti-virus test string
+const EICARSignature = "Eicar-Test-Signature"
+
+var eicar = []byte("EICAR-STANDARD-ANTIVIRUS-TEST-FILE")
+
+// Clamd is a clamd daemon that answers PING and INSTREAM commands over TCP, so that the service can use its real
+// ClamAV scanner. It reports content as infected if it contains the EICAR anti-virus test string, and clean otherwise.
+type Clamd struct {
+	listener net.Listener
+}
+
+// NewClamd starts a Clamd fake. Close must be called once it is no longer needed.
+func NewClamd() *Clamd {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(err)
+	}
+
+	c := &Clamd{listener: listener}
+	go c.serve()
+	return c
+}
+
+// Addr returns the address of the fake, to be used as CLAMAV_ADDR
+func (c *Clamd) Addr() string {
+	return c.listener.Addr().String()
+}
+
+// Close stops the fake
+func (c *Clamd) Close() {
+	c.listener.Close()
+}
+
+func (c *Clamd) serve() {
+	for {
+		conn, err := c.listener.Accept()
+		if err != nil {
+			return
+		}
+		go handleClamdConn(conn)
+	}
+}
+
+func handleClamdConn(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	command, err := r.ReadString(0)
+	if err != nil {
+		return
+	}
+
+	switch command {
+	case "zPING\x00":
+		conn.Write([]byte("PONG\x00"))
+	case "zINSTREAM\x00":
+		var content bytes.Buffer
+		for {
+			var size uint32
+			if err := binary.Read(r, binary.BigEndian, &size); err != nil || size == 0 {
+				break
+			}
+			if _, err := io.CopyN(&content, r, int64(size)); err != nil {
+				return
+			}
+		}
+
+		if bytes.Contains(content.Bytes(), eicar) {
+			conn.Write([]byte("stream: " + EICARSignature + " FOUND\x00"))
+			return
+		}
+		conn.Write([]byte("stream: OK\x00"))
+	default:
+		conn.Write([]byte("UNKNOWN COMMAND\x00"))
+	}
+}
@@ -0,0 +1,289 @@
+package fakes
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+
+	filesAPIModels "github.com/ONSdigital/dp-files-api/api"
+	filesAPITypes "github.com/ONSdigital/dp-files-api/files"
+	filesSDK "github.com/ONSdigital/dp-files-api/sdk"
+	filesAPIStore "github.com/ONSdigital/dp-files-api/store"
+	"github.com/ONSdigital/dp-upload-service/files"
+)
+
+const filesURI = "/files"
+
+// FilesAPIRequest records a request made to the FilesAPI fake
+type FilesAPIRequest struct {
+	Method        string
+	Path          string
+	Query         string
+	Body          string
+	Authorization string
+}
+
+// FileChecksum is the checksum of a file sent to the FilesAPI fake when the file is marked as uploaded
+type FileChecksum struct {
+	Checksum          string `json:"checksum"`
+	ChecksumAlgorithm string `json:"checksum_algorithm"`
+}
+
+// FilesAPI is an in-memory dp-files-api served over HTTP, so that the service can use its real Files API client. It
+// keeps the metadata of registered files, records every request made to it, and can be made to fail requests with
+// Fail so that error handling can be tested without a real Files API.
+type FilesAPI struct {
+	server *httptest.Server
+
+	mu        sync.Mutex
+	files     map[string]filesAPITypes.StoredRegisteredMetaData
+	checksums map[string]FileChecksum
+	requests  []FilesAPIRequest
+	faults    map[string]int
+}
+
+// NewFilesAPI starts a FilesAPI fake with no registered files. Close must be called once it is no longer needed.
+func NewFilesAPI() *FilesAPI {
+	f := &FilesAPI{
+		files:     make(map[string]filesAPITypes.StoredRegisteredMetaData),
+		checksums: make(map[string]FileChecksum),
+		faults:    make(map[string]int),
+	}
+	f.server = httptest.NewServer(http.HandlerFunc(f.serveHTTP))
+	return f
+}
+
+// URL returns the base URL of the fake, to be used as FILES_API_URL
+func (f *FilesAPI) URL() string {
+	return f.server.URL
+}
+
+// Close stops the fake
+func (f *FilesAPI) Close() {
+	f.server.Close()
+}
+
+// AddFile registers the file as if it had been registered through the Files API
+func (f *FilesAPI) AddFile(metadata filesAPITypes.StoredRegisteredMetaData) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.files[metadata.Path] = metadata
+}
+
+// File returns the metadata of the registered file with the path, or false if there is none
+func (f *FilesAPI) File(path string) (filesAPITypes.StoredRegisteredMetaData, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	metadata, ok := f.files[path]
+	return metadata, ok
+}
+
+// Checksum returns the checksum the file with the path was marked as uploaded with, or false if there is none
+func (f *FilesAPI) Checksum(path string) (FileChecksum, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	checksum, ok := f.checksums[path]
+	return checksum, ok
+}
+
+// Fail makes every following request with the method respond with the status code and no change to the registered
+// files, until Fail is called again for the method with a status code of 0
+func (f *FilesAPI) Fail(method string, statusCode int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if statusCode == 0 {
+		delete(f.faults, method)
+		return
+	}
+	f.faults[method] = statusCode
+}
+
+// Requests returns the requests made with the method to the path, or every request if either is empty
+func (f *FilesAPI) Requests(method, path string) []FilesAPIRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var requests []FilesAPIRequest
+	for _, r := range f.requests {
+		if (method == "" || r.Method == method) && (path == "" || r.Path == path) {
+			requests = append(requests, r)
+		}
+	}
+	return requests
+}
+
+// ClearRequests forgets every request made so far, without changing the registered files
+func (f *FilesAPI) ClearRequests() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.requests = nil
+}
+
+func (f *FilesAPI) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.requests = append(f.requests, FilesAPIRequest{
+		Method:        req.Method,
+		Path:          req.URL.Path,
+		Query:         req.URL.RawQuery,
+		Body:          string(body),
+		Authorization: req.Header.Get("Authorization"),
+	})
+
+	if req.URL.Path == "/health" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if statusCode, ok := f.faults[req.Method]; ok {
+		writeFilesAPIError(w, statusCode, "InjectedFault", "fault injected by the Files API fake")
+		return
+	}
+
+	path, isFile := strings.CutPrefix(req.URL.Path, filesURI+"/")
+	switch {
+	case req.URL.Path == filesURI && req.Method == http.MethodPost:
+		f.registerFile(w, body)
+	case req.URL.Path == filesURI && req.Method == http.MethodGet:
+		f.listFiles(w, req)
+	case isFile && req.Method == http.MethodGet:
+		f.getFile(w, path)
+	case isFile && req.Method == http.MethodPatch:
+		f.patchFile(w, path, body)
+	case isFile && req.Method == http.MethodDelete:
+		f.deleteFile(w, path)
+	default:
+		writeFilesAPIError(w, http.StatusNotFound, "NotFound", "no such route")
+	}
+}
+
+func (f *FilesAPI) registerFile(w http.ResponseWriter, body []byte) {
+	var metadata filesAPITypes.StoredRegisteredMetaData
+	if err := json.Unmarshal(body, &metadata); err != nil {
+		writeFilesAPIError(w, http.StatusBadRequest, "BadJsonEncoding", err.Error())
+		return
+	}
+	if _, ok := f.files[metadata.Path]; ok {
+		writeFilesAPIError(w, http.StatusConflict, "DuplicateFileError", "file already registered")
+		return
+	}
+
+	metadata.State = filesAPIStore.StateCreated
+	f.files[metadata.Path] = metadata
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (f *FilesAPI) listFiles(w http.ResponseWriter, req *http.Request) {
+	collectionID := req.URL.Query().Get("collection_id")
+	bundleID := req.URL.Query().Get("bundle_id")
+
+	items := []filesAPITypes.StoredRegisteredMetaData{}
+	for _, metadata := range f.files {
+		if collectionID != "" && (metadata.CollectionID == nil || *metadata.CollectionID != collectionID) {
+			continue
+		}
+		if bundleID != "" && (metadata.BundleID == nil || *metadata.BundleID != bundleID) {
+			continue
+		}
+		items = append(items, metadata)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Path < items[j].Path })
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"count": len(items), "total_count": len(items), "items": items})
+}
+
+func (f *FilesAPI) getFile(w http.ResponseWriter, path string) {
+	metadata, ok := f.files[path]
+	if !ok {
+		writeFilesAPIError(w, http.StatusNotFound, "FileNotRegistered", "file not registered")
+		return
+	}
+	writeJSON(w, http.StatusOK, metadata)
+}
+
+func (f *FilesAPI) patchFile(w http.ResponseWriter, path string, body []byte) {
+	metadata, ok := f.files[path]
+	if !ok {
+		writeFilesAPIError(w, http.StatusNotFound, "FileNotRegistered", "file not registered")
+		return
+	}
+
+	var patch struct {
+		filesSDK.FilePatchRequest
+		FileChecksum
+	}
+	if err := json.Unmarshal(body, &patch); err != nil {
+		writeFilesAPIError(w, http.StatusBadRequest, "BadJsonEncoding", err.Error())
+		return
+	}
+	if !validStateChange(patch.StateMetadata) {
+		writeFilesAPIError(w, http.StatusBadRequest, "InvalidStateChange", "invalid state change")
+		return
+	}
+	applyStateMetadata(&metadata, patch.StateMetadata)
+	if patch.ETag != "" {
+		metadata.Etag = patch.ETag
+	}
+	if patch.Checksum != "" {
+		f.checksums[path] = patch.FileChecksum
+	}
+
+	f.files[path] = metadata
+	w.WriteHeader(http.StatusOK)
+}
+
+func (f *FilesAPI) deleteFile(w http.ResponseWriter, path string) {
+	if _, ok := f.files[path]; !ok {
+		writeFilesAPIError(w, http.StatusNotFound, "FileNotRegistered", "file not registered")
+		return
+	}
+	delete(f.files, path)
+	delete(f.checksums, path)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// validStateChange reports whether Files API accepts the PATCH, which only changes the state of a file to UPLOADED,
+// PUBLISHED, MOVED or QUARANTINED, or otherwise sets its collection or bundle
+func validStateChange(state filesAPIModels.StateMetadata) bool {
+	if state.State == nil {
+		return true
+	}
+	switch *state.State {
+	case filesAPIStore.StateUploaded, filesAPIStore.StatePublished, filesAPIStore.StateMoved, files.StateQuarantined:
+		return true
+	}
+	return false
+}
+
+func applyStateMetadata(metadata *filesAPITypes.StoredRegisteredMetaData, state filesAPIModels.StateMetadata) {
+	if state.State != nil {
+		metadata.State = *state.State
+	}
+	if state.CollectionID != nil {
+		metadata.CollectionID = state.CollectionID
+	}
+	if state.BundleID != nil {
+		metadata.BundleID = state.BundleID
+	}
+}
+
+func writeFilesAPIError(w http.ResponseWriter, statusCode int, code, description string) {
+	writeJSON(w, statusCode, filesAPIModels.JSONErrors{Error: []filesAPIModels.JSONError{{Code: code, Description: description}}})
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,594 @@
+package fakes
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5" //nolint:gosec // MD5 is used to calculate ETags the same way as S3
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ONSdigital/dp-healthcheck/healthcheck"
+	s3client "github.com/ONSdigital/dp-s3/v3"
+	"github.com/ONSdigital/dp-upload-service/aws"
+	"github.com/ONSdigital/dp-upload-service/storage"
+)
+
+// region is the AWS region used in the URLs of S3 fakes
+const region = "eu-west-2"
+
+// S3Call records a call made to an S3 fake. Key is empty for calls that are not about a single object.
+type S3Call struct {
+	Method string
+	Key    string
+}
+
+// S3Object is an object stored by an S3 fake
+type S3Object struct {
+	Content      []byte
+	ContentType  string
+	ETag         string
+	LastModified time.Time
+}
+
+type s3Part struct {
+	content      []byte
+	etag         string
+	lastModified time.Time
+}
+
+type s3Upload struct {
+	id          string
+	contentType string
+	initiated   time.Time
+	parts       map[int32]s3Part
+}
+
+// S3Storage holds the buckets of the S3 fakes, so that objects can be copied between them
+type S3Storage struct {
+	mu      sync.Mutex
+	buckets map[string]*S3
+}
+
+// NewS3Storage creates an S3Storage with no buckets
+func NewS3Storage() *S3Storage {
+	return &S3Storage{buckets: make(map[string]*S3)}
+}
+
+// Bucket returns the S3 fake for the bucket name, creating an empty bucket the first time it is requested
+func (st *S3Storage) Bucket(name string) *S3 {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if st.buckets[name] == nil {
+		st.buckets[name] = &S3{
+			storage:    st,
+			bucketName: name,
+			objects:    make(map[string]S3Object),
+			uploads:    make(map[string]*s3Upload),
+			faults:     make(map[string]error),
+		}
+	}
+	return st.buckets[name]
+}
+
+// S3 is an in-memory S3 storage.Driver for a single bucket. It records every call made to it, and any method can be made
+// to fail with Fail so that error handling can be tested without a real S3.
+type S3 struct {
+	storage    *S3Storage
+	bucketName string
+
+	mu       sync.Mutex
+	objects  map[string]S3Object
+	uploads  map[string]*s3Upload
+	calls    []S3Call
+	faults   map[string]error
+	uploadID int
+}
+
+var _ storage.Driver = (*S3)(nil)
+
+// Fail makes every following call to the named storage.Driver method return the error, until Fail is called again for
+// the method with a nil error
+func (f *S3) Fail(method string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err == nil {
+		delete(f.faults, method)
+		return
+	}
+	f.faults[method] = err
+}
+
+// Calls returns the calls made to the named storage.Driver method, or every call if the method is empty
+func (f *S3) Calls(method string) []S3Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var calls []S3Call
+	for _, call := range f.calls {
+		if method == "" || call.Method == method {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// PutObject stores the content as a single part object, replacing any existing object with the key
+func (f *S3) PutObject(key string, content []byte, contentType string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.objects[key] = S3Object{
+		Content:      content,
+		ContentType:  contentType,
+		ETag:         md5Hex(content),
+		LastModified: time.Now().UTC(),
+	}
+}
+
+// Object returns the object stored with the key, or false if there is none
+func (f *S3) Object(key string) (S3Object, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	obj, ok := f.objects[key]
+	return obj, ok
+}
+
+// begin records the call and returns the error injected for the method, if any. The caller must hold the mutex.
+func (f *S3) begin(method, key string) error {
+	f.calls = append(f.calls, S3Call{Method: method, Key: key})
+	return f.faults[method]
+}
+
+func (f *S3) UploadPart(ctx context.Context, req *storage.PartRequest, payload io.Reader) (storage.PartResponse, error) {
+	content, readErr := io.ReadAll(payload)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.begin("UploadPart", req.Key); err != nil {
+		return storage.PartResponse{}, err
+	}
+	if readErr != nil {
+		return storage.PartResponse{}, s3client.NewError(fmt.Errorf("error reading part: %w", readErr), nil)
+	}
+
+	upload := f.uploads[req.Key]
+	if upload == nil {
+		if _, ok := f.objects[req.Key]; ok {
+			return storage.PartResponse{}, s3client.NewError(storage.ErrAlreadyUploaded, nil)
+		}
+		upload = f.createUpload(req.Key, req.ContentType)
+	}
+
+	etag := md5Hex(content)
+	upload.parts[req.PartNumber] = s3Part{content: content, etag: etag, lastModified: time.Now().UTC()}
+
+	allPartsUploaded, err := f.completeIfAllPartsUploaded(req)
+	if err != nil {
+		return storage.PartResponse{}, err
+	}
+
+	return storage.PartResponse{ETag: etag, AllPartsUploaded: allPartsUploaded}, nil
+}
+
+func (f *S3) CheckPartUploaded(ctx context.Context, req *storage.PartRequest) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.begin("CheckPartUploaded", req.Key); err != nil {
+		return false, err
+	}
+
+	upload := f.uploads[req.Key]
+	if upload == nil {
+		return false, s3client.NewError(storage.ErrNotUploaded, nil)
+	}
+	if len(upload.parts) == req.TotalParts {
+		return f.completeIfAllPartsUploaded(req)
+	}
+	if _, ok := upload.parts[req.PartNumber]; ok {
+		return true, nil
+	}
+	return false, s3client.NewError(storage.ErrPartNotFound, nil)
+}
+
+func (f *S3) PartExists(ctx context.Context, req *storage.PartRequest) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.begin("PartExists", req.Key); err != nil {
+		return false, err
+	}
+
+	upload := f.uploads[req.Key]
+	if upload == nil {
+		return false, nil
+	}
+	_, ok := upload.parts[req.PartNumber]
+	return ok, nil
+}
+
+func (f *S3) ListUploadedParts(ctx context.Context, key string) (storage.MultipartUploadParts, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.begin("ListUploadedParts", key); err != nil {
+		return storage.MultipartUploadParts{}, false, err
+	}
+
+	upload := f.uploads[key]
+	if upload == nil {
+		return storage.MultipartUploadParts{}, false, nil
+	}
+
+	parts := make([]storage.UploadedPart, 0, len(upload.parts))
+	for _, number := range sortedPartNumbers(upload) {
+		part := upload.parts[number]
+		parts = append(parts, storage.UploadedPart{
+			PartNumber:   number,
+			SizeInBytes:  int64(len(part.content)),
+			ETag:         part.etag,
+			LastModified: part.lastModified,
+		})
+	}
+	return storage.MultipartUploadParts{UploadID: upload.id, Parts: parts}, true, nil
+}
+
+func (f *S3) AbortMultipartUpload(ctx context.Context, key string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.begin("AbortMultipartUpload", key); err != nil {
+		return false, err
+	}
+
+	if f.uploads[key] == nil {
+		return false, nil
+	}
+	delete(f.uploads, key)
+	return true, nil
+}
+
+func (f *S3) AbortMultipartUploadByID(ctx context.Context, key, uploadID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.begin("AbortMultipartUploadByID", key); err != nil {
+		return err
+	}
+
+	if upload := f.uploads[key]; upload == nil || upload.id != uploadID {
+		return s3client.NewError(fmt.Errorf("multipart upload %q does not exist", uploadID), nil)
+	}
+	delete(f.uploads, key)
+	return nil
+}
+
+func (f *S3) ListMultipartUploads(ctx context.Context) ([]storage.MultipartUpload, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.begin("ListMultipartUploads", ""); err != nil {
+		return nil, err
+	}
+
+	var uploads []storage.MultipartUpload
+	for key, upload := range f.uploads {
+		uploads = append(uploads, storage.MultipartUpload{Key: key, UploadID: upload.id, Initiated: upload.initiated})
+	}
+	sort.Slice(uploads, func(i, j int) bool { return uploads[i].Key < uploads[j].Key })
+	return uploads, nil
+}
+
+// Checker reports the bucket as healthy, unless the Checker method has been made to fail
+func (f *S3) Checker(ctx context.Context, state *healthcheck.CheckState) error {
+	f.mu.Lock()
+	err := f.begin("Checker", "")
+	f.mu.Unlock()
+
+	if err != nil {
+		return state.Update(healthcheck.StatusCritical, err.Error(), 0)
+	}
+	return state.Update(healthcheck.StatusOK, "S3 fake is healthy", 0)
+}
+
+func (f *S3) Head(ctx context.Context, key string) (storage.ObjectInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.begin("Head", key); err != nil {
+		return storage.ObjectInfo{}, err
+	}
+
+	obj, ok := f.objects[key]
+	if !ok {
+		return storage.ObjectInfo{}, s3client.NewError(storage.ErrNotFound, nil)
+	}
+
+	return storage.ObjectInfo{
+		SizeInBytes:  int64(len(obj.Content)),
+		ETag:         obj.ETag,
+		ContentType:  obj.ContentType,
+		LastModified: obj.LastModified,
+	}, nil
+}
+
+func (f *S3) Get(ctx context.Context, key string) (io.ReadCloser, *int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.begin("Get", key); err != nil {
+		return nil, nil, err
+	}
+
+	obj, ok := f.objects[key]
+	if !ok {
+		return nil, nil, s3client.NewError(storage.ErrNotFound, nil)
+	}
+
+	size := int64(len(obj.Content))
+	return io.NopCloser(bytes.NewReader(obj.Content)), &size, nil
+}
+
+func (f *S3) Put(ctx context.Context, key, contentType string, content io.Reader) error {
+	body, err := io.ReadAll(content)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.begin("Put", key); err != nil {
+		return err
+	}
+
+	f.objects[key] = S3Object{
+		Content:      body,
+		ContentType:  contentType,
+		ETag:         md5Hex(body),
+		LastModified: time.Now().UTC(),
+	}
+	return nil
+}
+
+func (f *S3) List(ctx context.Context, prefix string) ([]storage.ObjectSummary, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.begin("List", ""); err != nil {
+		return nil, err
+	}
+
+	var objects []storage.ObjectSummary
+	for key, obj := range f.objects {
+		if strings.HasPrefix(key, prefix) {
+			objects = append(objects, storage.ObjectSummary{Key: key, LastModified: obj.LastModified})
+		}
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+
+	return objects, nil
+}
+
+func (f *S3) Delete(ctx context.Context, key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.begin("Delete", key); err != nil {
+		return err
+	}
+
+	delete(f.objects, key)
+	return nil
+}
+
+// CopyFrom copies the object with the source key from the bucket of the same S3Storage with the source bucket name to
+// the key in this bucket
+func (f *S3) CopyFrom(ctx context.Context, sourceBucket, sourceKey, key string) (string, error) {
+	source, ok := f.storage.Bucket(sourceBucket).Object(sourceKey)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.begin("CopyFrom", key); err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", s3client.NewError(storage.ErrNotFound, nil)
+	}
+
+	source.LastModified = time.Now().UTC()
+	f.objects[key] = source
+	return source.ETag, nil
+}
+
+// URL returns the path style S3 URL the object with the key would have in a real bucket
+func (f *S3) URL(key string) (string, error) {
+	s3Url, err := s3client.NewURL(region, f.bucketName, key)
+	if err != nil {
+		return "", err
+	}
+	return s3Url.String(aws.PathStyle)
+}
+
+// PresignGet returns the URL of the object with the key with its expiry and any content disposition as query
+// parameters, as the fake has no credentials to sign it with
+func (f *S3) PresignGet(ctx context.Context, key string, expires time.Duration, contentDisposition string) (string, error) {
+	f.mu.Lock()
+	err := f.begin("PresignGet", key)
+	f.mu.Unlock()
+	if err != nil {
+		return "", err
+	}
+
+	s3Url, err := f.URL(key)
+	if err != nil {
+		return "", err
+	}
+	query := url.Values{"X-Amz-Expires": {strconv.Itoa(int(expires.Seconds()))}}
+	if contentDisposition != "" {
+		query.Set("response-content-disposition", contentDisposition)
+	}
+	return s3Url + "?" + query.Encode(), nil
+}
+
+func (f *S3) CreateMultipartUpload(ctx context.Context, key, contentType string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.begin("CreateMultipartUpload", key); err != nil {
+		return "", err
+	}
+	if f.uploads[key] != nil {
+		return "", s3client.NewError(storage.ErrUploadInProgress, nil)
+	}
+
+	return f.createUpload(key, contentType).id, nil
+}
+
+// PresignUploadPart returns the URL of the object with the key with the upload ID, part number and expiry as query
+// parameters, which can be passed to UploadPresignedPart to upload the part as if it had been sent to S3
+func (f *S3) PresignUploadPart(ctx context.Context, key, uploadID string, partNumber int32, expires time.Duration) (string, error) {
+	f.mu.Lock()
+	err := f.begin("PresignUploadPart", key)
+	f.mu.Unlock()
+	if err != nil {
+		return "", err
+	}
+
+	s3Url, err := f.URL(key)
+	if err != nil {
+		return "", err
+	}
+	query := url.Values{
+		"partNumber":    {strconv.Itoa(int(partNumber))},
+		"uploadId":      {uploadID},
+		"X-Amz-Expires": {strconv.Itoa(int(expires.Seconds()))},
+	}
+	return s3Url + "?" + query.Encode(), nil
+}
+
+// UploadPresignedPart uploads the content to a URL returned by PresignUploadPart, as a client would upload a part
+// directly to S3
+func (f *S3) UploadPresignedPart(presignedURL string, content []byte) error {
+	u, err := url.Parse(presignedURL)
+	if err != nil {
+		return err
+	}
+	key, ok := strings.CutPrefix(u.Path, "/"+f.bucketName+"/")
+	if !ok {
+		return fmt.Errorf("presigned URL %q is not for bucket %q", presignedURL, f.bucketName)
+	}
+	partNumber, err := strconv.Atoi(u.Query().Get("partNumber"))
+	if err != nil {
+		return fmt.Errorf("presigned URL %q has no part number: %w", presignedURL, err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.begin("UploadPresignedPart", key); err != nil {
+		return err
+	}
+
+	upload := f.uploads[key]
+	if upload == nil || upload.id != u.Query().Get("uploadId") {
+		return s3client.NewError(storage.ErrNotUploaded, nil)
+	}
+	upload.parts[int32(partNumber)] = s3Part{content: content, etag: md5Hex(content), lastModified: time.Now().UTC()}
+	return nil
+}
+
+func (f *S3) CompleteMultipartUpload(ctx context.Context, key, uploadID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.begin("CompleteMultipartUpload", key); err != nil {
+		return err
+	}
+
+	upload := f.uploads[key]
+	if upload == nil || upload.id != uploadID {
+		return s3client.NewError(storage.ErrNotUploaded, nil)
+	}
+	return f.assemble(key, upload)
+}
+
+// createUpload starts a new multipart upload for the key, replacing any in progress multipart upload for it. The
+// caller must hold the mutex.
+func (f *S3) createUpload(key, contentType string) *s3Upload {
+	f.uploadID++
+	upload := &s3Upload{
+		id:          strconv.Itoa(f.uploadID),
+		contentType: contentType,
+		initiated:   time.Now().UTC(),
+		parts:       make(map[int32]s3Part),
+	}
+	f.uploads[key] = upload
+	return upload
+}
+
+// completeIfAllPartsUploaded assembles the parts into the object once every part has been uploaded. The caller must
+// hold the mutex.
+func (f *S3) completeIfAllPartsUploaded(req *storage.PartRequest) (bool, error) {
+	upload := f.uploads[req.Key]
+	if upload == nil || len(upload.parts) != req.TotalParts {
+		return false, nil
+	}
+
+	if err := f.assemble(req.Key, upload); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// assemble writes the parts of the multipart upload into the object, with the same minimum part size and ETag as S3.
+// The caller must hold the mutex.
+func (f *S3) assemble(key string, upload *s3Upload) error {
+	numbers := sortedPartNumbers(upload)
+	var content []byte
+	digests := md5.New() //nolint:gosec
+	for i, number := range numbers {
+		part := upload.parts[number]
+		if i < len(numbers)-1 && len(part.content) < storage.MinPartSize {
+			return s3client.NewError(storage.ErrPartTooSmall, nil)
+		}
+		digest, _ := hex.DecodeString(part.etag)
+		digests.Write(digest)
+		content = append(content, part.content...)
+	}
+
+	f.objects[key] = S3Object{
+		Content:      content,
+		ContentType:  upload.contentType,
+		ETag:         fmt.Sprintf("%s-%d", hex.EncodeToString(digests.Sum(nil)), len(numbers)),
+		LastModified: time.Now().UTC(),
+	}
+	delete(f.uploads, key)
+	return nil
+}
+
+func sortedPartNumbers(upload *s3Upload) []int32 {
+	numbers := make([]int32, 0, len(upload.parts))
+	for number := range upload.parts {
+		numbers = append(numbers, number)
+	}
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+	return numbers
+}
+
+func md5Hex(content []byte) string {
+	sum := md5.Sum(content) //nolint:gosec
+	return hex.EncodeToString(sum[:])
+}
@@ -4,7 +4,6 @@ Feature: Collection ID Optional
     And the file meta-data is:
       | isPublishable      | true                                                                      |
       | title              | The number of people                                                      |
-      | resumableTotalSize | 14794                                                                     |
       | licence            | OGL v3                                                                    |
       | licenceUrl         | http://www.nationalarchives.gov.uk/doc/open-government-licence/version/3/ |
     And the data file "populations.csv" with content:
@@ -32,8 +31,10 @@ Feature: Collection ID Optional
           "path": "data/populations.csv",
           "is_publishable": true,
           "title": "The number of people",
-          "size_in_bytes": 14794,
+          "size_in_bytes": 19,
           "type": "text/csv",
+          "state": "",
+          "etag": "",
           "licence": "OGL v3",
           "licence_url": "http://www.nationalarchives.gov.uk/doc/open-government-licence/version/3/"
         }
@@ -42,6 +43,9 @@ Feature: Collection ID Optional
         """
         {
           "state": "UPLOADED",
-          "etag": "32204b5b34cd2e635d6d69a443916584-1"
+          "etag": "104996db18d74d7f0ba1347e43cc8878-1",
+          "checksum": "whTztO2qSyxiGpGQZjsK12tkEyEO6Qeotkk4TV97/cw=",
+          "checksum_algorithm": "SHA256"
         }
         """
+    And the SHA256 checksum "whTztO2qSyxiGpGQZjsK12tkEyEO6Qeotkk4TV97/cw=" should be stored for the file "data/populations.csv"
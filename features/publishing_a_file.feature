@@ -0,0 +1,80 @@
+Feature: Publishing a file
+
+  Scenario: An uploaded file is marked as published
+    Given dp-files-api has a file with path "testing" and filename "valid" registered with meta-data:
+            """
+            {
+                "path": "testing/valid",
+                "is_publishable": true,
+                "title": "",
+                "size_in_bytes": 7,
+                "type": "text/plain",
+                "licence": "na",
+                "licence_url": "na",
+                "state": "UPLOADED"
+            }
+            """
+    When I POST "/upload-new/files/testing/valid/publish"
+            """
+            """
+    Then the HTTP status code should be "204"
+    And the file "testing/valid" should be marked as published
+
+  Scenario: A file that has not finished uploading cannot be published
+    Given dp-files-api has a file with path "testing" and filename "valid" registered with meta-data:
+            """
+            {
+                "path": "testing/valid",
+                "is_publishable": true,
+                "title": "",
+                "size_in_bytes": 7,
+                "type": "text/plain",
+                "licence": "na",
+                "licence_url": "na",
+                "state": "CREATED"
+            }
+            """
+    When I POST "/upload-new/files/testing/valid/publish"
+            """
+            """
+    Then the HTTP status code should be "409"
+    And the file "testing/valid" should not be marked as published
+
+  Scenario: A file that is not registered cannot be published
+    Given dp-files-api has a file with path "testing" and filename "valid" registered with meta-data:
+            """
+            {
+                "path": "testing/valid",
+                "is_publishable": true,
+                "title": "",
+                "size_in_bytes": 7,
+                "type": "text/plain",
+                "licence": "na",
+                "licence_url": "na",
+                "state": "UPLOADED"
+            }
+            """
+    When I POST "/upload-new/files/testing/invalid/publish"
+            """
+            """
+    Then the HTTP status code should be "404"
+
+  Scenario: A file cannot be published when dp-files-api fails to mark it as published
+    Given dp-files-api has a file with path "testing" and filename "valid" registered with meta-data:
+            """
+            {
+                "path": "testing/valid",
+                "is_publishable": true,
+                "title": "",
+                "size_in_bytes": 7,
+                "type": "text/plain",
+                "licence": "na",
+                "licence_url": "na",
+                "state": "UPLOADED"
+            }
+            """
+    And dp-files-api responds to "PATCH" requests with status "500"
+    When I POST "/upload-new/files/testing/valid/publish"
+            """
+            """
+    Then the HTTP status code should be "500"
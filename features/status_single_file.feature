@@ -34,6 +34,9 @@ Feature: Status for a single file
                   },
                   "file_content": {
                       "valid": true
+                  },
+                  "scan": {
+                      "verdict": "CLEAN"
                   }
               }
             """
@@ -5,21 +5,19 @@ import (
 	"net/http"
 	"time"
 
-	dpaws "github.com/ONSdigital/dp-upload-service/aws"
-
+	"github.com/ONSdigital/dp-authorisation/v2/authorisation"
 	"github.com/ONSdigital/dp-healthcheck/healthcheck"
 	dphttp "github.com/ONSdigital/dp-net/v3/http"
-	s3client "github.com/ONSdigital/dp-s3/v3"
 	"github.com/ONSdigital/dp-upload-service/config"
+	"github.com/ONSdigital/dp-upload-service/features/fakes"
+	"github.com/ONSdigital/dp-upload-service/filesystem"
 	"github.com/ONSdigital/dp-upload-service/service"
-	"github.com/aws/aws-sdk-go-v2/aws"
-	awsConfig "github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/credentials"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/ONSdigital/dp-upload-service/storage"
 )
 
 type external struct {
 	Server *dphttp.Server
+	S3     *fakes.S3Storage
 }
 
 func (e external) DoGetHTTPServer(bindAddr string, router http.Handler) service.HTTPServer {
@@ -35,34 +33,30 @@ func (e external) DoGetHealthCheck(cfg *config.Config, buildTime, gitCommit, ver
 	return &check, nil
 }
 
-func (e external) DoGetS3Uploaded(ctx context.Context, cfg *config.Config) (dpaws.S3Clienter, error) {
-	return generateS3Client(ctx, cfg, cfg.UploadBucketName)
+func (e external) DoGetS3Uploaded(ctx context.Context, cfg *config.Config) (storage.Driver, error) {
+	return e.storageDrivers().New(ctx, cfg, cfg.UploadBucketName)
 }
 
-func (e external) DoGetStaticFileS3Uploader(ctx context.Context, cfg *config.Config) (dpaws.S3Clienter, error) {
-	return generateS3Client(ctx, cfg, cfg.StaticFilesEncryptedBucketName)
+func (e external) DoGetStaticFileS3Uploader(ctx context.Context, cfg *config.Config) (storage.Driver, error) {
+	return e.storageDrivers().New(ctx, cfg, cfg.StaticFilesEncryptedBucketName)
 }
 
-func generateS3Client(ctx context.Context, cfg *config.Config, bucketName string) (dpaws.S3Clienter, error) {
-
-	var AWSConfig aws.Config
-	var err error
-	var client *s3client.Client
-
-	AWSConfig, err = awsConfig.LoadDefaultConfig(
-		ctx,
-		awsConfig.WithRegion(cfg.AwsRegion),
-		awsConfig.WithCredentialsProvider(credentials.NewStaticCredentialsProvider("test", "test", "")),
-	)
+func (e external) DoGetS3Public(ctx context.Context, cfg *config.Config) (storage.Driver, error) {
+	return e.storageDrivers().New(ctx, cfg, cfg.PublicBucketName)
+}
 
-	if err != nil {
-		return nil, err
-	}
+func (e external) DoGetAuthorisationMiddleware(ctx context.Context, authorisationConfig *authorisation.Config) (authorisation.Middleware, error) {
+	return authorisation.NewNoopMiddleware(), nil
+}
 
-	client = s3client.NewClientWithConfig(bucketName, AWSConfig, func(options *s3.Options) {
-		options.BaseEndpoint = aws.String(localStackHost)
-		options.UsePathStyle = true
+// storageDrivers registers the in-memory fake of S3 in place of the S3 driver, alongside the filesystem driver
+func (e external) storageDrivers() *storage.Registry {
+	drivers := storage.NewRegistry()
+	drivers.Register(config.StorageBackendS3, func(ctx context.Context, cfg *config.Config, bucketName string) (storage.Driver, error) {
+		return e.S3.Bucket(bucketName), nil
 	})
-
-	return client, nil
+	drivers.Register(config.StorageBackendFilesystem, func(ctx context.Context, cfg *config.Config, bucketName string) (storage.Driver, error) {
+		return filesystem.NewClient(cfg.FilesystemStoragePath, bucketName), nil
+	})
+	return drivers
 }
@@ -2,29 +2,27 @@ package steps
 
 import (
 	"bytes"
-	"context"
+	"encoding/json"
 	"fmt"
-	"io"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
-	s3client "github.com/ONSdigital/dp-s3/v3"
-
-	"github.com/ONSdigital/dp-net/v3/request"
-
-	"github.com/ONSdigital/dp-upload-service/config"
+	filesAPITypes "github.com/ONSdigital/dp-files-api/files"
+	"github.com/ONSdigital/dp-upload-service/features/fakes"
+	"github.com/ONSdigital/dp-upload-service/files"
+	"github.com/ONSdigital/dp-upload-service/storage"
 	"github.com/cucumber/godog"
 	"github.com/pkg/errors"
 	"github.com/rdumont/assistdog"
 	"github.com/stretchr/testify/assert"
 )
 
-var requests map[string]string
-
 const filesURI = "/files"
 
 func (c *UploadComponent) RegisterSteps(ctx *godog.ScenarioContext) {
@@ -32,21 +30,35 @@ func (c *UploadComponent) RegisterSteps(ctx *godog.ScenarioContext) {
 	ctx.Step(`^dp-files-api does not have a file "([^"]*)" registered$`, c.dpfilesapiDoesNotHaveAFileRegistered)
 	ctx.Step(`^dp-files-api has a file with path "([^"]*)" and filename "([^"]*)" registered with meta-data:$`, c.dpfilesapiHasAFileWithPathAndFilenameRegisteredWithMetadata)
 	ctx.Step(`^the data file "([^"]*)" with content:$`, c.theDataFile)
+	ctx.Step(`^the data file "([^"]*)" with (\d+) generated rows$`, c.theDataFileWithGeneratedRows)
 	ctx.Step(`^the file meta-data is:$`, c.theFileMetadataIs)
 	ctx.Step(`^the 1st part of the file "([^"]*)" has been uploaded with resumable parameters:$`, c.the1StPartOfTheFileHasBeenUploaded)
+	ctx.Step(`^the S3 bucket fails to "([^"]*)"$`, c.theS3BucketFailsTo)
+	ctx.Step(`^the maximum upload file size is (\d+) bytes$`, c.theMaximumUploadFileSizeIs)
+	ctx.Step(`^the media types allowed to be uploaded are "([^"]*)"$`, c.theMediaTypesAllowedToBeUploadedAre)
+	ctx.Step(`^dp-files-api responds to "([^"]*)" requests with status "(\d+)"$`, c.dpfilesapiRespondsToRequestsWithStatus)
 
 	// Whens
 	ctx.Step(`^I upload the file "([^"]*)" with the following form resumable parameters:$`, c.iUploadTheFileWithTheFollowingFormResumableParameters)
 	ctx.Step(`^I upload the file "([^"]*)" with the following form resumable parameters and auth header "([^"]*)"$`, c.iUploadTheFileWithTheFollowingFormResumableParametersAndAuthHeader)
+	ctx.Step(`^I upload the file "([^"]*)" directly to S3 with the following form resumable parameters:$`, c.iUploadTheFileDirectlyToS3WithTheFollowingFormResumableParameters)
 
 	// Thens
-	ctx.Step(`^the file upload should be marked as started using payload:$`, c.theFileUploadOfShouldBeMarkedAsStartedUsingPayload)
+	ctx.Step(`^the file upload should be marked as (?:started|created) using payload:$`, c.theFileUploadOfShouldBeMarkedAsStartedUsingPayload)
 	ctx.Step(`^the file "([^"]*)" should be marked as uploaded using payload:$`, c.theFileUploadOfShouldBeMarkedAsUploadedUsingPayload)
-	ctx.Step(`^the files api POST request should contain a default authorization header$`, c.theFilesApiPOSTRequestShouldContainADefaultAuthorizationHeader)
-	ctx.Step(`^the files api PATCH request with path \("([^"]*)"\) should contain a default authorization header$`, c.theFilesApiPATCHRequestWithPathShouldContainADefaultAuthorizationHeader)
+	ctx.Step(`^the SHA256 checksum "([^"]*)" should be stored for the file "([^"]*)"$`, c.theChecksumShouldBeStoredForTheFile)
+	ctx.Step(`^the files api POST request should contain the authorization header "([^"]*)"$`, c.theFilesApiPOSTRequestShouldContainTheAuthorizationHeader)
+	ctx.Step(`^the files api PATCH request with path \("([^"]*)"\) should contain the authorization header "([^"]*)"$`, c.theFilesApiPATCHRequestWithPathShouldContainTheAuthorizationHeader)
+	ctx.Step(`^the files api requests should not contain an authorization header$`, c.theFilesApiRequestsShouldNotContainAnAuthorizationHeader)
+	ctx.Step(`^the file "([^"]*)" should be marked as published$`, c.theFileShouldBeMarkedAsPublished)
+	ctx.Step(`^the file "([^"]*)" should be marked as quarantined$`, c.theFileShouldBeMarkedAsQuarantined)
+	ctx.Step(`^the path "([^"]*)" should be available in the S3 bucket:$`, c.thePathShouldBeAvailableInTheS3Bucket)
+	ctx.Step(`^the stored file "([^"]*)" should match the sent file "([^"]*)"$`, c.theStoredFileShouldMatchTheSentFile)
+	ctx.Step(`^the file "([^"]*)" should be quarantined at "([^"]*)"$`, c.theFileShouldBeQuarantinedAt)
 	// Buts
 	ctx.Step(`^the file should not be marked as uploaded$`, c.theFileShouldNotBeMarkedAsUploaded)
-	ctx.Step(`^the file upload should not have been registered again$`, c.theFileUploadShouldNotHaveBeenRegisteredAgain)
+	ctx.Step(`^the file upload should not have been registered(?: again)?$`, c.theFileUploadShouldNotHaveBeenRegisteredAgain)
+	ctx.Step(`^the file "([^"]*)" should not be marked as published$`, c.theFileShouldNotBeMarkedAsPublished)
 
 }
 
@@ -74,80 +86,55 @@ func (c *UploadComponent) theDataFile(filename string, fileContent *godog.DocStr
 	return nil
 }
 
+// theDataFileWithGeneratedRows writes a CSV file with the number of rows, so that files large enough to be uploaded in
+// more than one chunk don't need to be kept in the repository
+func (c *UploadComponent) theDataFileWithGeneratedRows(filename string, rows int) error {
+	var content bytes.Buffer
+	for i := 1; i <= rows; i++ {
+		fmt.Fprintf(&content, "%d,country %d\n", i, i)
+	}
+
+	return os.WriteFile(fmt.Sprintf("%s/%s", testFilePath, filename), content.Bytes(), 0o644)
+}
+
 func (c *UploadComponent) dpfilesapiDoesNotHaveAFileRegistered(filename string) error {
-	requests = make(map[string]string)
-	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		body, _ := io.ReadAll(r.Body)
-		requests[fmt.Sprintf("%s|%s", r.URL.Path, r.Method)] = string(body)
-		requests[fmt.Sprintf("%s|%s|auth", r.URL.Path, r.Method)] = r.Header.Get(request.AuthHeaderKey)
-
-		if r.Method == http.MethodPost {
-			w.WriteHeader(http.StatusCreated)
-		} else {
-			w.WriteHeader(http.StatusOK)
-		}
-	}))
+	// every scenario starts with a Files API fake that has no files registered
+	if _, ok := c.filesAPI.File(strings.TrimPrefix(filename, "/")); ok {
+		return fmt.Errorf("file %s is registered", filename)
+	}
+	return nil
+}
 
-	if err := os.Setenv("FILES_API_URL", s.URL); err != nil {
+func (c *UploadComponent) dpfilesapiHasAFileWithPathAndFilenameRegisteredWithMetadata(path, filename string, jsonResponse *godog.DocString) error {
+	var metadata filesAPITypes.StoredRegisteredMetaData
+	if err := json.Unmarshal([]byte(jsonResponse.Content), &metadata); err != nil {
 		return err
 	}
 
+	pathAndFilename := path + "/" + filename
+	metadata.Path = pathAndFilename
+	c.filesAPI.AddFile(metadata)
+	c.staticFilesBucket().PutObject(pathAndFilename, []byte("content"), "type")
+
 	return nil
 }
 
-func (c *UploadComponent) dpfilesapiHasAFileWithPathAndFilenameRegisteredWithMetadata(path, filename string, jsonResponse *godog.DocString) error {
-	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodPost {
-			body, _ := io.ReadAll(r.Body)
-			expectedPath := fmt.Sprintf("%s/%s", path, filename)
-			if strings.Contains(string(body), expectedPath) {
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusConflict)
-				_, _ = w.Write([]byte(`{"errors":[{"errorCode":"DuplicateFileError","description":"file already registered"}]}`))
-
-				return
-			}
-			w.WriteHeader(http.StatusCreated)
-			return
-		}
-
-		if r.Method == http.MethodGet {
-			expectedPath := fmt.Sprintf("%s/%s", path, filename)
-			if strings.HasSuffix(r.URL.Path, expectedPath) {
-				w.WriteHeader(http.StatusOK)
-				w.Header().Set("Content-Type", "application/json")
-				_, _ = w.Write([]byte(jsonResponse.Content))
-				return
-			}
-		}
+func (c *UploadComponent) theS3BucketFailsTo(method string) error {
+	c.staticFilesBucket().Fail(method, errors.New("S3 fake failure"))
+	return nil
+}
 
-		if strings.HasSuffix(r.URL.Path, "/valid") {
-			w.WriteHeader(http.StatusOK)
-			w.Header().Set("Content-Type", "application/json")
-			_, _ = w.Write([]byte(jsonResponse.Content))
-		} else {
-			w.WriteHeader(http.StatusNotFound)
-		}
-	}))
+func (c *UploadComponent) theMaximumUploadFileSizeIs(size string) error {
+	return os.Setenv("MAX_UPLOAD_FILE_SIZE", size)
+}
 
-	pathAndFilename := path + "/" + filename
-	cfg, _ := config.Get()
-
-	//setup s3
-	s3Client, _ := c.svcList.GetS3StaticFileUploader(context.Background(), cfg)
-	_, err := s3Client.UploadPart(context.Background(), &s3client.UploadPartRequest{
-		UploadKey:   pathAndFilename,
-		Type:        "type",
-		ChunkNumber: 1,
-		TotalChunks: 1,
-		FileName:    filename,
-	}, []byte("content"))
-
-	if err := os.Setenv("FILES_API_URL", s.URL); err != nil {
-		return err
-	}
+func (c *UploadComponent) theMediaTypesAllowedToBeUploadedAre(mediaTypes string) error {
+	return os.Setenv("UPLOAD_ALLOWED_TYPES", mediaTypes)
+}
 
-	return err
+func (c *UploadComponent) dpfilesapiRespondsToRequestsWithStatus(method string, statusCode int) error {
+	c.filesAPI.Fail(method, statusCode)
+	return nil
 }
 
 func (c *UploadComponent) theFileMetadataIs(table *godog.Table) error {
@@ -179,6 +166,7 @@ func (c *UploadComponent) iUploadTheFileWithTheFollowingFormResumableParameters(
 
 	assist := assistdog.NewDefault()
 	queryParams, _ := assist.ParseMap(table)
+	c.declareSize(queryParams, testPayload)
 
 	total, _ := strconv.ParseInt(queryParams["resumableTotalChunks"], 10, 32)
 	current, _ := strconv.ParseInt(queryParams["resumableChunkNumber"], 10, 32)
@@ -225,6 +213,73 @@ func (c *UploadComponent) iUploadTheFileWithTheFollowingFormResumableParameters(
 	return nil
 }
 
+// iUploadTheFileDirectlyToS3WithTheFollowingFormResumableParameters creates an upload session, uploads each chunk of
+// the file to its presigned URL and then completes the session, leaving the response to completing it to be checked
+func (c *UploadComponent) iUploadTheFileDirectlyToS3WithTheFollowingFormResumableParameters(filename string, table *godog.Table) error {
+	testPayload, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	assist := assistdog.NewDefault()
+	params, _ := assist.ParseMap(table)
+	c.declareSize(params, testPayload)
+
+	form := url.Values{}
+	for key, value := range c.fileMetadata {
+		form.Set(key, value)
+	}
+	for key, value := range params {
+		form.Set(key, value)
+	}
+
+	handler, err := c.ApiFeature.Initialiser()
+	if err != nil {
+		return err
+	}
+
+	w := postForm(handler, "http://foo/upload-new/sessions", form)
+	if w.Code != http.StatusCreated {
+		c.ApiFeature.HTTPResponse = w.Result()
+		return nil
+	}
+
+	var session files.UploadSession
+	if err := json.NewDecoder(w.Body).Decode(&session); err != nil {
+		return err
+	}
+	for _, part := range session.Parts {
+		start := int(part.ChunkNumber-1) * storage.MinPartSize
+		end := min(start+storage.MinPartSize, len(testPayload))
+		if err := c.staticFilesBucket().UploadPresignedPart(part.URL, testPayload[start:end]); err != nil {
+			return err
+		}
+	}
+
+	w = postForm(handler, fmt.Sprintf("http://foo/upload-new/sessions/%s/complete", session.UploadID), form)
+	c.ApiFeature.HTTPResponse = w.Result()
+	return nil
+}
+
+// declareSize sets resumableTotalSize to the size of the file being uploaded, unless the scenario declares a size
+func (c *UploadComponent) declareSize(params map[string]string, payload []byte) {
+	if _, ok := c.fileMetadata["resumableTotalSize"]; ok {
+		return
+	}
+	if _, ok := params["resumableTotalSize"]; !ok {
+		params["resumableTotalSize"] = strconv.Itoa(len(payload))
+	}
+}
+
+func postForm(handler http.Handler, target string, form url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
 func (c *UploadComponent) iUploadTheFileWithTheFollowingFormResumableParametersAndAuthHeader(filename, authHeader string, table *godog.Table) error {
 	b := &bytes.Buffer{}
 	formWriter := multipart.NewWriter(b)
@@ -244,6 +299,7 @@ func (c *UploadComponent) iUploadTheFileWithTheFollowingFormResumableParametersA
 
 	assist := assistdog.NewDefault()
 	queryParams, _ := assist.ParseMap(table)
+	c.declareSize(queryParams, testPayload)
 
 	total, _ := strconv.ParseInt(queryParams["resumableTotalChunks"], 10, 32)
 	current, _ := strconv.ParseInt(queryParams["resumableChunkNumber"], 10, 32)
@@ -297,41 +353,140 @@ func (c *UploadComponent) iUploadTheFileWithTheFollowingFormResumableParametersA
 // -----
 
 func (c *UploadComponent) theFileUploadOfShouldBeMarkedAsStartedUsingPayload(expectedFilesPayload *godog.DocString) error {
-	assert.JSONEq(c.ApiFeature, expectedFilesPayload.Content, requests[fmt.Sprintf("%s|%s", filesURI, http.MethodPost)])
-
+	posts := c.filesAPI.Requests(http.MethodPost, filesURI)
+	if assert.Len(c.ApiFeature, posts, 1) {
+		assert.JSONEq(c.ApiFeature, expectedFilesPayload.Content, posts[0].Body)
+	}
 	return c.ApiFeature.StepError()
 }
 
+// patchesOf waits for the file to be marked as uploaded, which happens in the background once the file is completed,
+// and returns the PATCH requests that have been made for it
+func (c *UploadComponent) patchesOf(filepath string) []fakes.FilesAPIRequest {
+	path := fmt.Sprintf("%s/%s", filesURI, filepath)
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if patches := c.filesAPI.Requests(http.MethodPatch, path); len(patches) > 0 {
+			return patches
+		}
+	}
+	return nil
+}
+
 func (c *UploadComponent) theFileUploadOfShouldBeMarkedAsUploadedUsingPayload(filepath string, expectedFilesPayload *godog.DocString) error {
-	assert.JSONEq(c.ApiFeature, expectedFilesPayload.Content, requests[fmt.Sprintf("%s/%s|%s", filesURI, filepath, http.MethodPatch)])
+	patches := c.patchesOf(filepath)
+	if assert.Len(c.ApiFeature, patches, 1) {
+		assert.JSONEq(c.ApiFeature, expectedFilesPayload.Content, patches[0].Body)
+	}
+	return c.ApiFeature.StepError()
+}
+
+func (c *UploadComponent) theChecksumShouldBeStoredForTheFile(checksum, filepath string) error {
+	c.patchesOf(filepath)
+	stored, ok := c.filesAPI.Checksum(filepath)
+	if assert.True(c.ApiFeature, ok, "no checksum stored for %s", filepath) {
+		assert.Equal(c.ApiFeature, checksum, stored.Checksum)
+		assert.Equal(c.ApiFeature, files.ChecksumAlgorithmSHA256, stored.ChecksumAlgorithm)
+	}
 	return c.ApiFeature.StepError()
 }
 
 func (c *UploadComponent) theFileShouldNotBeMarkedAsUploaded() error {
-	assert.NotContains(c.ApiFeature, requests, fmt.Sprintf("%s|%s", filesURI, http.MethodPatch))
+	assert.Empty(c.ApiFeature, c.filesAPI.Requests(http.MethodPatch, ""))
 	return c.ApiFeature.StepError()
 }
+
 func (c *UploadComponent) the1StPartOfTheFileHasBeenUploaded(filename string, table *godog.Table) error {
 	err := c.iUploadTheFileWithTheFollowingFormResumableParameters(filename, table)
 
-	requests = make(map[string]string)
+	c.filesAPI.ClearRequests()
 
 	return err
 }
 
 func (c *UploadComponent) theFileUploadShouldNotHaveBeenRegisteredAgain() error {
-	assert.NotContains(c.ApiFeature, requests, "/files")
+	assert.Empty(c.ApiFeature, c.filesAPI.Requests(http.MethodPost, filesURI))
 	return c.ApiFeature.StepError()
 }
 
-func (c *UploadComponent) theFilesApiPOSTRequestShouldContainADefaultAuthorizationHeader() error {
-	cfg, _ := config.Get()
-	assert.Equal(c.ApiFeature, "Bearer "+cfg.ServiceAuthToken, requests[fmt.Sprintf("%s|%s|auth", filesURI, http.MethodPost)])
+func (c *UploadComponent) theFileShouldBeMarkedAsPublished(filepath string) error {
+	patches := c.filesAPI.Requests(http.MethodPatch, fmt.Sprintf("%s/%s", filesURI, filepath))
+	if assert.Len(c.ApiFeature, patches, 1) {
+		assert.JSONEq(c.ApiFeature, `{"state": "PUBLISHED"}`, patches[0].Body)
+	}
+	return c.ApiFeature.StepError()
+}
+
+func (c *UploadComponent) theFileShouldBeMarkedAsQuarantined(filepath string) error {
+	// files are scanned in the background once they have been uploaded
+	var patches []fakes.FilesAPIRequest
+	for deadline := time.Now().Add(5 * time.Second); len(patches) == 0 && time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		patches = c.filesAPI.Requests(http.MethodPatch, fmt.Sprintf("%s/%s", filesURI, filepath))
+	}
+	if assert.Len(c.ApiFeature, patches, 1) {
+		assert.JSONEq(c.ApiFeature, `{"state": "QUARANTINED"}`, patches[0].Body)
+	}
 	return c.ApiFeature.StepError()
 }
 
-func (c *UploadComponent) theFilesApiPATCHRequestWithPathShouldContainADefaultAuthorizationHeader(filepath string) error {
-	cfg, _ := config.Get()
-	assert.Equal(c.ApiFeature, "Bearer "+cfg.ServiceAuthToken, requests[fmt.Sprintf("%s/%s|%s|auth", filesURI, filepath, http.MethodPatch)])
+func (c *UploadComponent) theFileShouldNotBeMarkedAsPublished(filepath string) error {
+	assert.Empty(c.ApiFeature, c.filesAPI.Requests(http.MethodPatch, fmt.Sprintf("%s/%s", filesURI, filepath)))
+	return c.ApiFeature.StepError()
+}
+
+func (c *UploadComponent) thePathShouldBeAvailableInTheS3Bucket(path string, expectedContent *godog.DocString) error {
+	object, ok := c.staticFilesBucket().Object(strings.TrimPrefix(path, "/"))
+	if assert.True(c.ApiFeature, ok, "no object stored at %s", path) {
+		assert.Equal(c.ApiFeature, expectedContent.Content, string(object.Content))
+	}
+	return c.ApiFeature.StepError()
+}
+
+func (c *UploadComponent) theStoredFileShouldMatchTheSentFile(path, filename string) error {
+	sent, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	object, ok := c.staticFilesBucket().Object(path)
+	if assert.True(c.ApiFeature, ok, "no object stored at %s", path) {
+		assert.Equal(c.ApiFeature, sent, object.Content)
+	}
+	return c.ApiFeature.StepError()
+}
+
+func (c *UploadComponent) theFileShouldBeQuarantinedAt(path, quarantinePath string) error {
+	// files are scanned in the background once they have been uploaded
+	var ok bool
+	for deadline := time.Now().Add(5 * time.Second); !ok && time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		_, ok = c.staticFilesBucket().Object(quarantinePath)
+	}
+	assert.True(c.ApiFeature, ok, "no object stored at %s", quarantinePath)
+	_, ok = c.staticFilesBucket().Object(path)
+	assert.False(c.ApiFeature, ok, "infected file still stored at %s", path)
+	return c.ApiFeature.StepError()
+}
+
+func (c *UploadComponent) theFilesApiPOSTRequestShouldContainTheAuthorizationHeader(authHeader string) error {
+	posts := c.filesAPI.Requests(http.MethodPost, filesURI)
+	if assert.NotEmpty(c.ApiFeature, posts) {
+		assert.Equal(c.ApiFeature, authHeader, posts[0].Authorization)
+	}
+	return c.ApiFeature.StepError()
+}
+
+func (c *UploadComponent) theFilesApiPATCHRequestWithPathShouldContainTheAuthorizationHeader(filepath, authHeader string) error {
+	patches := c.patchesOf(filepath)
+	if assert.NotEmpty(c.ApiFeature, patches) {
+		assert.Equal(c.ApiFeature, authHeader, patches[0].Authorization)
+	}
+	return c.ApiFeature.StepError()
+}
+
+func (c *UploadComponent) theFilesApiRequestsShouldNotContainAnAuthorizationHeader() error {
+	requests := c.filesAPI.Requests("", "")
+	if assert.NotEmpty(c.ApiFeature, requests) {
+		for _, r := range requests {
+			assert.Empty(c.ApiFeature, r.Authorization, "%s %s", r.Method, r.Path)
+		}
+	}
 	return c.ApiFeature.StepError()
 }
@@ -7,21 +7,14 @@ import (
 	"os"
 	"time"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	awsConfig "github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/credentials"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
-
 	componenttest "github.com/ONSdigital/dp-component-test"
 	dphttp "github.com/ONSdigital/dp-net/v3/http"
 	"github.com/ONSdigital/dp-upload-service/config"
+	"github.com/ONSdigital/dp-upload-service/features/fakes"
 	"github.com/ONSdigital/dp-upload-service/service"
 )
 
-const (
-	localStackHost = "http://localstack:4566"
-	testFilePath   = "test-data"
-)
+const testFilePath = "test-data"
 
 type UploadComponent struct {
 	server       *dphttp.Server
@@ -29,6 +22,9 @@ type UploadComponent struct {
 	svcList      *service.ExternalServiceList
 	ApiFeature   *componenttest.APIFeature
 	fileMetadata map[string]string
+	s3           *fakes.S3Storage
+	filesAPI     *fakes.FilesAPI
+	clamd        *fakes.Clamd
 
 	errChan chan error
 }
@@ -36,45 +32,58 @@ type UploadComponent struct {
 func NewUploadComponent() *UploadComponent {
 	s := dphttp.NewServer("", http.NewServeMux())
 	s.HandleOSSignals = false
+	storage := fakes.NewS3Storage()
 
 	return &UploadComponent{
 		server:  s,
 		errChan: make(chan error),
-		svcList: service.NewServiceList(external{Server: s}),
+		s3:      storage,
+		svcList: service.NewServiceList(external{Server: s, S3: storage}),
 	}
 }
 
 func (c *UploadComponent) Initialiser() (http.Handler, error) {
 	var err error
-	c.svcList = service.NewServiceList(external{Server: c.server})
+	c.svcList = service.NewServiceList(external{Server: c.server, S3: c.s3})
 	c.svc, err = service.Run(context.Background(), c.svcList, "1", "1", "1", c.errChan)
 	time.Sleep(5 * time.Second) // Wait for healthchecks to run before executing tests. TODO consider moving to a Given step for healthchecks
 	return c.server.Handler, err
 }
 
+// Reset replaces S3, dp-files-api and clamd with empty in-memory fakes, so that each scenario starts from a clean state
 func (c *UploadComponent) Reset() {
-	// clear out test bucket
 	cfg, _ := config.Get()
-	var err error
 
-	AWSConfig, err := awsConfig.LoadDefaultConfig(
-		context.Background(),
-		awsConfig.WithRegion(cfg.AwsRegion),
-		awsConfig.WithCredentialsProvider(credentials.NewStaticCredentialsProvider("test", "test", "")),
-	)
+	if cfg.StorageBackend == config.StorageBackendFilesystem {
+		if err := os.RemoveAll(cfg.FilesystemStoragePath); err != nil {
+			panic(fmt.Sprintf("Failed to remove filesystem storage: %s", err.Error()))
+		}
+	}
 
-	if err != nil {
-		panic(fmt.Sprintf("Failed to load default config s3: %s", err.Error()))
+	c.s3 = fakes.NewS3Storage()
+	if c.filesAPI != nil {
+		c.filesAPI.Close()
+	}
+	c.filesAPI = fakes.NewFilesAPI()
+	if err := os.Setenv("FILES_API_URL", c.filesAPI.URL()); err != nil {
+		panic(fmt.Sprintf("Failed to set FILES_API_URL: %s", err.Error()))
+	}
+	if c.clamd != nil {
+		c.clamd.Close()
+	}
+	c.clamd = fakes.NewClamd()
+	if err := os.Setenv("CLAMAV_ADDR", c.clamd.Addr()); err != nil {
+		panic(fmt.Sprintf("Failed to set CLAMAV_ADDR: %s", err.Error()))
 	}
 
-	s3client := s3.NewFromConfig(AWSConfig, func(options *s3.Options) {
-		options.BaseEndpoint = aws.String(localStackHost)
-		options.UsePathStyle = true
-	})
-	deleteObjectsInBucket(cfg.UploadBucketName, s3client)
+	for _, env := range []string{"MAX_UPLOAD_FILE_SIZE", "UPLOAD_ALLOWED_TYPES"} {
+		if err := os.Unsetenv(env); err != nil {
+			panic(fmt.Sprintf("Failed to unset %s: %s", env, err.Error()))
+		}
+	}
 
 	// removet
-	err = os.RemoveAll(testFilePath)
+	err := os.RemoveAll(testFilePath)
 	if err != nil {
 		panic("Failed to remove test-data")
 	}
@@ -83,31 +92,22 @@ func (c *UploadComponent) Reset() {
 	}
 }
 
+// staticFilesBucket returns the fake of the bucket that static files are uploaded to
+func (c *UploadComponent) staticFilesBucket() *fakes.S3 {
+	cfg, _ := config.Get()
+	return c.s3.Bucket(cfg.StaticFilesEncryptedBucketName)
+}
+
 func (c *UploadComponent) Close() error {
 	ctx, _ := context.WithTimeout(context.Background(), 10*time.Second) //nolint
+	if c.filesAPI != nil {
+		c.filesAPI.Close()
+	}
+	if c.clamd != nil {
+		c.clamd.Close()
+	}
 	if c.svc != nil {
 		return c.svc.Close(ctx)
 	}
 	return nil
 }
-
-func deleteObjectsInBucket(bucketName string, client *s3.Client) {
-	if client != nil {
-		listObjectInput := &s3.ListObjectsInput{
-			Bucket: aws.String(bucketName),
-		}
-		listObjectOutput, err := client.ListObjects(context.Background(), listObjectInput)
-
-		if err != nil {
-			fmt.Println("Error is :", err.Error())
-			return
-		}
-		for _, object := range listObjectOutput.Contents {
-			deleteObjectInput := &s3.DeleteObjectInput{
-				Bucket: aws.String(bucketName),
-				Key:    object.Key,
-			}
-			_, _ = client.DeleteObject(context.Background(), deleteObjectInput)
-		}
-	}
-}
@@ -6,7 +6,6 @@ Feature: Uploading a file
       | isPublishable      | true                                                                      |
       | collectionId       | 1234-asdfg-54321-qwerty                                                   |
       | title              | The number of people                                                      |
-      | resumableTotalSize | 14794                                                                     |
       | licence            | OGL v3                                                                    |
       | licenceUrl         | http://www.nationalarchives.gov.uk/doc/open-government-licence/version/3/ |
 
@@ -37,8 +36,10 @@ Feature: Uploading a file
           "is_publishable": true,
           "collection_id": "1234-asdfg-54321-qwerty",
           "title": "The number of people",
-          "size_in_bytes": 14794,
+          "size_in_bytes": 19,
           "type": "text/csv",
+          "state": "",
+          "etag": "",
           "licence": "OGL v3",
           "licence_url": "http://www.nationalarchives.gov.uk/doc/open-government-licence/version/3/"
         }
@@ -47,56 +48,66 @@ Feature: Uploading a file
         """
         {
           "state": "UPLOADED",
-          "etag": "32204b5b34cd2e635d6d69a443916584-1"
+          "etag": "104996db18d74d7f0ba1347e43cc8878-1",
+          "checksum": "whTztO2qSyxiGpGQZjsK12tkEyEO6Qeotkk4TV97/cw=",
+          "checksum_algorithm": "SHA256"
         }
         """
+    And the SHA256 checksum "whTztO2qSyxiGpGQZjsK12tkEyEO6Qeotkk4TV97/cw=" should be stored for the file "data/populations.csv"
 
-  Scenario: File upload is marked as started when first chunk is uploaded
-    When I upload the file "features/countries.csv" with the following form resumable parameters:
+  Scenario: File upload is not registered until its last chunk is uploaded
+    Given the data file "countries.csv" with 300000 generated rows
+    When I upload the file "test-data/countries.csv" with the following form resumable parameters:
       | resumableFilename    | countries.csv      |
       | resumableType        | text/csv           |
       | resumableTotalChunks | 2                  |
       | resumableChunkNumber | 1                  |
       | path                 | data               |
     Then the HTTP status code should be "200"
-    And the file upload should be marked as created using payload:
-        """
-        {
-        "path": "data/countries.csv",
-        "is_publishable": true,
-        "collection_id": "1234-asdfg-54321-qwerty",
-        "title": "The number of people",
-        "size_in_bytes": 14794,
-        "type": "text/csv",
-        "licence": "OGL v3",
-        "licence_url": "http://www.nationalarchives.gov.uk/doc/open-government-licence/version/3/"
-        }
-        """
+    And the file upload should not have been registered
     But the file should not be marked as uploaded
 
   Scenario: File ends up in bucket as result of uploading second chunk
-    Given the 1st part of the file "features/countries.csv" has been uploaded with resumable parameters:
+    Given the data file "countries.csv" with 300000 generated rows
+    And the 1st part of the file "test-data/countries.csv" has been uploaded with resumable parameters:
       | resumableFilename    | countries.csv      |
       | resumableType        | text/csv           |
       | resumableTotalChunks | 2                  |
       | resumableChunkNumber | 1                  |
       | path                 | data               |
-    When I upload the file "features/countries.csv" with the following form resumable parameters:
+    When I upload the file "test-data/countries.csv" with the following form resumable parameters:
       | resumableFilename    | countries.csv      |
       | resumableType        | text/csv           |
       | resumableTotalChunks | 2                  |
       | resumableChunkNumber | 2                  |
       | path                 | data               |
     Then the HTTP status code should be "201"
+    And the file upload should be marked as started using payload:
+        """
+        {
+        "path": "data/countries.csv",
+        "is_publishable": true,
+        "collection_id": "1234-asdfg-54321-qwerty",
+        "title": "The number of people",
+        "size_in_bytes": 6377790,
+        "type": "text/csv",
+        "state": "",
+        "etag": "",
+        "licence": "OGL v3",
+        "licence_url": "http://www.nationalarchives.gov.uk/doc/open-government-licence/version/3/"
+        }
+        """
     And the file "data/countries.csv" should be marked as uploaded using payload:
         """
         {
           "state": "UPLOADED",
-          "etag": "5d363622e69e68f5baadc6cef11cbd9f-2"
+          "etag": "2a7866e4f2c28218a0dcfae04aff4818-2",
+          "checksum": "AF+nKC9jqgOoLyHMW4ke8GLO9w/RkZ1tuyvbmr/U9gs=",
+          "checksum_algorithm": "SHA256"
         }
         """
-    And the stored file "data/countries.csv" should match the sent file "features/countries.csv"
-    But the file upload should not have been registered again
+    And the SHA256 checksum "AF+nKC9jqgOoLyHMW4ke8GLO9w/RkZ1tuyvbmr/U9gs=" should be stored for the file "data/countries.csv"
+    And the stored file "data/countries.csv" should match the sent file "test-data/countries.csv"
 
     Scenario: The one where a single chunk file is uploaded using an authorisation header
       Given the data file "authorized.csv" with content:
@@ -104,16 +115,30 @@ Feature: Uploading a file
         brian,1
         russ,2
         """
-      When I upload the file "test-data/authorized.csv" with the following form resumable parameters:
+      When I upload the file "test-data/authorized.csv" with the following form resumable parameters and auth header "Bearer user-token"
         | resumableFilename    | authorized.csv       |
         | resumableType        | text/csv             |
         | resumableTotalChunks | 1                    |
         | resumableChunkNumber | 1                    |
         | path                 | data                 |
-      Then the files api POST request should contain a default authorization header
-      And the files api PATCH request with path ("data/authorized.csv") should contain a default authorization header
+      Then the files api POST request should contain the authorization header "Bearer user-token"
+      And the files api PATCH request with path ("data/authorized.csv") should contain the authorization header "Bearer user-token"
       And the HTTP status code should be "201"
 
+    Scenario: The one where a single chunk file is uploaded without an authorisation header
+      Given the data file "unauthorized.csv" with content:
+        """
+        brian,1
+        russ,2
+        """
+      When I upload the file "test-data/unauthorized.csv" with the following form resumable parameters:
+        | resumableFilename    | unauthorized.csv     |
+        | resumableType        | text/csv             |
+        | resumableTotalChunks | 1                    |
+        | resumableChunkNumber | 1                    |
+        | path                 | data                 |
+      Then the files api requests should not contain an authorization header
+
     Scenario: Uploading a file that already exists returns 409 Conflict
       Given dp-files-api has a file with path "data" and filename "populations.csv" registered with meta-data:
         """
@@ -129,7 +154,6 @@ Feature: Uploading a file
         | isPublishable      | true                                                                      |
         | collectionId       | 1234-asdfg-54321-qwerty                                                   |
         | title              | The number of people                                                      |
-        | resumableTotalSize | 14794                                                                     |
         | licence            | OGL v3                                                                    |
         | licenceUrl         | http://www.nationalarchives.gov.uk/doc/open-government-licence/version/3/ |
       When I upload the file "test-data/populations.csv" with the following form resumable parameters:
@@ -150,7 +174,6 @@ Feature: Uploading a file
         | isPublishable      | true                                                                      |
         | collectionId       | 1234-asdfg-54321-qwerty                                                   |
         | title              | CPIH Dataset                                                              |
-        | resumableTotalSize | 14794                                                                     |
         | licence            | OGL v3                                                                    |
         | licenceUrl         | http://www.nationalarchives.gov.uk/doc/open-government-licence/version/3/ |
         | datasetId          | cpih01                                                                    |
@@ -182,8 +205,10 @@ Feature: Uploading a file
             "is_publishable": true,
             "collection_id": "1234-asdfg-54321-qwerty",
             "title": "CPIH Dataset",
-            "size_in_bytes": 14794,
+            "size_in_bytes": 38,
             "type": "text/csv",
+            "state": "",
+            "etag": "",
             "licence": "OGL v3",
             "licence_url": "http://www.nationalarchives.gov.uk/doc/open-government-licence/version/3/",
             "content_item": {
@@ -197,8 +222,105 @@ Feature: Uploading a file
           """
           {
             "state": "UPLOADED",
-            "etag": "32204b5b34cd2e635d6d69a443916584-1"
+            "etag": "6bf848979a1391b89191b0d1bf65033e-1",
+            "checksum": "zWKkuSAfdcUN9ysAoMmaaP8I2tiq2uyaUrv+C+eLIhE=",
+            "checksum_algorithm": "SHA256"
           }
           """
+      And the SHA256 checksum "zWKkuSAfdcUN9ysAoMmaaP8I2tiq2uyaUrv+C+eLIhE=" should be stored for the file "data/cpih-data.csv"
+
 
 
+    Scenario: Uploading a file fails when S3 does not accept the part
+      Given the data file "populations.csv" with content:
+        """
+        mark,1
+        jon,2
+        russ,3
+        """
+      And the S3 bucket fails to "UploadPart"
+      When I upload the file "test-data/populations.csv" with the following form resumable parameters:
+        | resumableFilename    | populations.csv |
+        | resumableType        | text/csv        |
+        | resumableTotalChunks | 1               |
+        | resumableChunkNumber | 1               |
+        | path                 | data            |
+      Then the HTTP status code should be "500"
+      But the file should not be marked as uploaded
+
+    Scenario: Uploading a file whose content does not match its type returns 415 Unsupported Media Type
+      Given the media types allowed to be uploaded are "text/csv,application/pdf"
+      And the data file "report.csv" with content:
+        """
+        %PDF-1.7
+        """
+      When I upload the file "test-data/report.csv" with the following form resumable parameters:
+        | resumableFilename    | report.csv |
+        | resumableType        | text/csv   |
+        | resumableTotalChunks | 1          |
+        | resumableChunkNumber | 1          |
+        | path                 | data       |
+      Then the HTTP status code should be "415"
+      And I should receive the following JSON response:
+        """
+        {"errors":[{"code":"UnsupportedMediaType","description":"file type is not allowed or does not match the content of the file"}]}
+        """
+      But the file should not be marked as uploaded
+
+    Scenario: Uploading a file that contains malware quarantines it once it has been uploaded
+      Given the data file "infected.csv" with content:
+        """
+        X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*
+        """
+      When I upload the file "test-data/infected.csv" with the following form resumable parameters:
+        | resumableFilename    | infected.csv |
+        | resumableType        | text/csv     |
+        | resumableTotalChunks | 1            |
+        | resumableChunkNumber | 1            |
+        | path                 | data         |
+      Then the HTTP status code should be "201"
+      And the file "data/infected.csv" should be quarantined at "quarantine/data/infected.csv"
+      And the file "data/infected.csv" should be marked as quarantined
+
+    Scenario: Uploading a file that does not match its declared size returns 400 Bad Request
+      Given the data file "populations.csv" with content:
+        """
+        mark,1
+        jon,2
+        russ,3
+        """
+      When I upload the file "test-data/populations.csv" with the following form resumable parameters:
+        | resumableFilename    | populations.csv |
+        | resumableType        | text/csv        |
+        | resumableTotalSize   | 100             |
+        | resumableTotalChunks | 1               |
+        | resumableChunkNumber | 1               |
+        | path                 | data            |
+      Then the HTTP status code should be "400"
+      And I should receive the following JSON response:
+        """
+        {"errors":[{"code":"SizeMismatch","description":"size of file does not match its declared size"}]}
+        """
+      But the file should not be marked as uploaded
+
+    Scenario: Uploading a file larger than the maximum file size returns 413 Request Entity Too Large
+      Given the maximum upload file size is 1024 bytes
+      And the data file "populations.csv" with content:
+        """
+        mark,1
+        jon,2
+        russ,3
+        """
+      When I upload the file "test-data/populations.csv" with the following form resumable parameters:
+        | resumableFilename    | populations.csv |
+        | resumableType        | text/csv        |
+        | resumableTotalSize   | 2048            |
+        | resumableTotalChunks | 1               |
+        | resumableChunkNumber | 1               |
+        | path                 | data            |
+      Then the HTTP status code should be "413"
+      And I should receive the following JSON response:
+        """
+        {"errors":[{"code":"FileTooLarge","description":"file is larger than the maximum file size"}]}
+        """
+      But the file should not be marked as uploaded
@@ -0,0 +1,50 @@
+Feature: Uploading a file directly to S3
+
+  Background:
+    Given dp-files-api does not have a file "/data/populations.csv" registered
+    And the file meta-data is:
+      | isPublishable      | true                                                                      |
+      | collectionId       | 1234-asdfg-54321-qwerty                                                   |
+      | title              | The number of people                                                      |
+      | licence            | OGL v3                                                                    |
+      | licenceUrl         | http://www.nationalarchives.gov.uk/doc/open-government-licence/version/3/ |
+
+  Scenario: File uploaded to presigned URLs is registered and marked as uploaded once the session is completed
+    Given the data file "populations.csv" with content:
+        """
+        mark,1
+        jon,2
+        russ,3
+        """
+    When I upload the file "test-data/populations.csv" directly to S3 with the following form resumable parameters:
+      | resumableFilename    | populations.csv      |
+      | resumableType        | text/csv             |
+      | resumableTotalChunks | 1                    |
+      | path                 | data                 |
+    Then the HTTP status code should be "201"
+    And the stored file "data/populations.csv" should match the sent file "test-data/populations.csv"
+    And the file upload should be marked as started using payload:
+        """
+        {
+          "path": "data/populations.csv",
+          "is_publishable": true,
+          "collection_id": "1234-asdfg-54321-qwerty",
+          "title": "The number of people",
+          "size_in_bytes": 19,
+          "type": "text/csv",
+          "state": "",
+          "etag": "",
+          "licence": "OGL v3",
+          "licence_url": "http://www.nationalarchives.gov.uk/doc/open-government-licence/version/3/"
+        }
+        """
+    And the file "data/populations.csv" should be marked as uploaded using payload:
+        """
+        {
+          "state": "UPLOADED",
+          "etag": "104996db18d74d7f0ba1347e43cc8878-1",
+          "checksum": "whTztO2qSyxiGpGQZjsK12tkEyEO6Qeotkk4TV97/cw=",
+          "checksum_algorithm": "SHA256"
+        }
+        """
+    And the SHA256 checksum "whTztO2qSyxiGpGQZjsK12tkEyEO6Qeotkk4TV97/cw=" should be stored for the file "data/populations.csv"
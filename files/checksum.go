@@ -0,0 +1,157 @@
+package files
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5" //nolint:gosec // MD5 is supported for compatibility with Content-MD5
+	"crypto/sha256"
+	"encoding"
+	"encoding/base64"
+	"hash"
+	"io"
+	"strings"
+
+	"github.com/ONSdigital/dp-upload-service/storage"
+	"github.com/ONSdigital/log.go/v2/log"
+)
+
+const (
+	ChecksumAlgorithmSHA256 = "SHA256"
+	ChecksumAlgorithmMD5    = "MD5"
+)
+
+// runningChecksumPrefix is the prefix of the running checksums of the uploads in progress
+const runningChecksumPrefix = storage.RunningChecksumPrefix
+
+// runningChecksum is the state of the SHA256 digest of the chunks of an upload that have been received so far. It is
+// extended by each chunk that arrives after the chunk before it, so that the checksum of a file whose chunks arrive in
+// order is known as soon as its last chunk has been streamed to S3, without reading the file back.
+type runningChecksum struct {
+	Chunks int32  `json:"chunks"`
+	State  []byte `json:"state"`
+}
+
+// checksumReader calculates a digest of everything read through it and, once the underlying reader is exhausted,
+// returns ErrChecksumMismatch instead of io.EOF if the digest doesn't match the expected one
+type checksumReader struct {
+	r        io.Reader
+	hash     hash.Hash
+	expected []byte
+}
+
+// newChecksumReader wraps the content so that it is verified against the base64 encoded checksum as it is read.
+// An empty algorithm defaults to SHA256.
+func newChecksumReader(content io.Reader, algorithm, checksum string) (io.Reader, error) {
+	expected, err := base64.StdEncoding.DecodeString(checksum)
+	if err != nil {
+		return nil, ErrInvalidChecksum
+	}
+
+	var h hash.Hash
+	switch strings.ToUpper(algorithm) {
+	case "", ChecksumAlgorithmSHA256:
+		h = sha256.New()
+	case ChecksumAlgorithmMD5:
+		h = md5.New() //nolint:gosec
+	default:
+		return nil, ErrInvalidChecksum
+	}
+
+	if len(expected) != h.Size() {
+		return nil, ErrInvalidChecksum
+	}
+
+	return &checksumReader{r: content, hash: h, expected: expected}, nil
+}
+
+// validateFileChecksum checks that the client supplied whole file checksum is a base64 encoded SHA256 digest
+func validateFileChecksum(checksum string) error {
+	decoded, err := base64.StdEncoding.DecodeString(checksum)
+	if err != nil || len(decoded) != sha256.Size {
+		return ErrInvalidFileChecksum
+	}
+	return nil
+}
+
+// sha256Checksum reads the content through to the end and returns its base64 encoded SHA256 digest
+func sha256Checksum(content io.Reader) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, content); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(h.Sum(nil)), nil
+}
+
+func (c *checksumReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.hash.Write(p[:n]) // nolint // hash.Write never returns an error
+
+	if err == io.EOF && !bytes.Equal(c.hash.Sum(nil), c.expected) {
+		return n, ErrChecksumMismatch
+	}
+
+	return n, err
+}
+
+// resumeChecksum returns the SHA256 digest of the chunks before the chunk, ready to be extended with its content, or nil
+// if they have not all been digested, such as when chunks arrive out of order, or the total number of chunks is not
+// known so the digest could never be known to cover the whole file. A chunk that is sent again after later chunks have
+// been digested discards the running checksum, as its content could have changed.
+func (s Store) resumeChecksum(ctx context.Context, path string, resumable Resumable) hash.Hash {
+	chunk := resumable.CurrentChunk
+	if resumable.TotalChunks < 1 {
+		return nil
+	}
+	h := sha256.New()
+	if chunk == 1 {
+		return h
+	}
+
+	var running runningChecksum
+	found, err := getJSON(ctx, s.bucket, runningChecksumPrefix+path, &running)
+	if err != nil {
+		log.Error(ctx, "failed to read running checksum of upload", err, log.Data{"path": path})
+		return nil
+	}
+	if !found {
+		return nil
+	}
+	if running.Chunks >= chunk {
+		s.removeRunningChecksum(ctx, path)
+		return nil
+	}
+	if running.Chunks != chunk-1 {
+		return nil
+	}
+
+	if err := h.(encoding.BinaryUnmarshaler).UnmarshalBinary(running.State); err != nil {
+		log.Error(ctx, "failed to restore running checksum of upload", err, log.Data{"path": path})
+		return nil
+	}
+	return h
+}
+
+// recordRunningChecksum records the digest of the chunks up to and including the chunk, so that the next chunk can
+// extend it. An error is only logged, as the checksum is then calculated from the completed file instead.
+func (s Store) recordRunningChecksum(ctx context.Context, path string, chunk int32, h hash.Hash) {
+	state, err := h.(encoding.BinaryMarshaler).MarshalBinary()
+	if err == nil {
+		err = putJSON(ctx, s.bucket, runningChecksumPrefix+path, runningChecksum{Chunks: chunk, State: state})
+	}
+	if err != nil {
+		log.Error(ctx, "failed to record running checksum of upload", err, log.Data{"path": path, "chunk": chunk})
+	}
+}
+
+// RemoveUploadRecords removes what is kept for an upload in progress, once the upload has been abandoned and aborted
+func (s Store) RemoveUploadRecords(ctx context.Context, path string) {
+	s.removeRunningChecksum(ctx, path)
+	s.removeUploadOwner(ctx, path)
+}
+
+// removeRunningChecksum removes the running checksum of an upload that has been completed or abandoned
+func (s Store) removeRunningChecksum(ctx context.Context, path string) {
+	if err := s.bucket.Delete(ctx, runningChecksumPrefix+path); err != nil {
+		log.Error(ctx, "failed to remove running checksum of upload", err, log.Data{"path": path})
+	}
+}
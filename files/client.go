@@ -0,0 +1,162 @@
+package files
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	filesAPIModels "github.com/ONSdigital/dp-files-api/api"
+	filesAPITypes "github.com/ONSdigital/dp-files-api/files"
+	filesSDK "github.com/ONSdigital/dp-files-api/sdk"
+	filesAPIStore "github.com/ONSdigital/dp-files-api/store"
+	"github.com/ONSdigital/log.go/v2/log"
+)
+
+// Client extends the dp-files-api SDK client with the requests it does not yet provide
+type Client struct {
+	*filesSDK.Client
+}
+
+// NewClient creates a new Client for the Files API at the given URL
+func NewClient(filesAPIURL string) *Client {
+	return &Client{filesSDK.New(filesAPIURL)}
+}
+
+// filesCollection is the response from the Files API when listing the files in a collection or bundle
+type filesCollection struct {
+	Items []filesAPITypes.StoredRegisteredMetaData `json:"items"`
+}
+
+// GetFilesMetadata returns the metadata of every file in the collection or bundle. Only one of collectionID and
+// bundleID should be provided.
+func (c *Client) GetFilesMetadata(ctx context.Context, collectionID, bundleID string, headers filesSDK.Headers) ([]filesAPITypes.StoredRegisteredMetaData, error) {
+	u, err := url.Parse(c.URL() + "/files")
+	if err != nil {
+		return nil, err
+	}
+
+	query := url.Values{}
+	if collectionID != "" {
+		query.Set("collection_id", collectionID)
+	}
+	if bundleID != "" {
+		query.Set("bundle_id", bundleID)
+	}
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequest(http.MethodGet, u.String(), http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	headers.Add(req)
+
+	resp, err := c.Health().Client.Do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Error(ctx, "error closing http response body", err)
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &filesSDK.APIError{
+			StatusCode: resp.StatusCode,
+			Errors:     unmarshalJSONErrors(ctx, resp.Body),
+		}
+	}
+
+	var collection filesCollection
+	if err := json.NewDecoder(resp.Body).Decode(&collection); err != nil {
+		return nil, err
+	}
+
+	return collection.Items, nil
+}
+
+// uploadedPatch is the PATCH request that marks a file as uploaded, along with the whole file checksum of its content
+type uploadedPatch struct {
+	filesSDK.FilePatchRequest
+	Checksum          string `json:"checksum,omitempty"`
+	ChecksumAlgorithm string `json:"checksum_algorithm,omitempty"`
+}
+
+// MarkFileUploadedWithChecksum marks the file as uploaded with the ETag of its content, as MarkFileUploaded does, and
+// stores the base64 encoded SHA256 checksum of its content with it so that downloads of the file can be checked
+func (c *Client) MarkFileUploadedWithChecksum(ctx context.Context, path, etag, checksum string, headers filesSDK.Headers) error {
+	state := filesAPIStore.StateUploaded
+	patch := uploadedPatch{
+		FilePatchRequest: filesSDK.FilePatchRequest{
+			StateMetadata: filesAPIModels.StateMetadata{State: &state},
+			ETag:          etag,
+		},
+	}
+	if checksum != "" {
+		patch.Checksum = checksum
+		patch.ChecksumAlgorithm = ChecksumAlgorithmSHA256
+	}
+	return c.patchFile(ctx, path, patch, headers)
+}
+
+// MarkFileQuarantined moves the file to the QUARANTINED state, from which it can never be marked as uploaded or
+// published, once it has been found to be infected
+func (c *Client) MarkFileQuarantined(ctx context.Context, path string, headers filesSDK.Headers) error {
+	state := StateQuarantined
+	return c.patchFile(ctx, path, filesSDK.FilePatchRequest{
+		StateMetadata: filesAPIModels.StateMetadata{State: &state},
+	}, headers)
+}
+
+// patchFile sends the PATCH request for the file at the path, returning an APIError if it is not accepted
+func (c *Client) patchFile(ctx context.Context, path string, patch interface{}, headers filesSDK.Headers) error {
+	u, err := url.Parse(c.URL() + "/files")
+	if err != nil {
+		return err
+	}
+	u = u.JoinPath(strings.TrimPrefix(path, "/"))
+
+	payload, err := json.Marshal(patch)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPatch, u.String(), bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	headers.Add(req)
+
+	resp, err := c.Health().Client.Do(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Error(ctx, "error closing http response body", err)
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return &filesSDK.APIError{
+			StatusCode: resp.StatusCode,
+			Errors:     unmarshalJSONErrors(ctx, resp.Body),
+		}
+	}
+
+	return nil
+}
+
+// unmarshalJSONErrors returns the errors from the response body, or nil if the body does not contain any
+func unmarshalJSONErrors(ctx context.Context, body io.Reader) *filesAPIModels.JSONErrors {
+	var jsonErrors filesAPIModels.JSONErrors
+	if err := json.NewDecoder(body).Decode(&jsonErrors); err != nil {
+		log.Error(ctx, "body did not match expected structure for JSON errors", err)
+		return nil
+	}
+	return &jsonErrors
+}
@@ -0,0 +1,137 @@
+package files_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	filesSDK "github.com/ONSdigital/dp-files-api/sdk"
+	"github.com/ONSdigital/dp-upload-service/files"
+	"github.com/stretchr/testify/suite"
+)
+
+type ClientSuite struct {
+	suite.Suite
+
+	requests []*http.Request
+	bodies   []string
+	status   int
+	response string
+	server   *httptest.Server
+}
+
+func TestClient(t *testing.T) {
+	suite.Run(t, new(ClientSuite))
+}
+
+func (s *ClientSuite) SetupTest() {
+	s.requests = nil
+	s.bodies = nil
+	s.status = http.StatusOK
+	s.response = ""
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		s.requests = append(s.requests, r)
+		s.bodies = append(s.bodies, string(body))
+		w.WriteHeader(s.status)
+		w.Write([]byte(s.response))
+	}))
+}
+
+func (s *ClientSuite) TearDownTest() {
+	s.server.Close()
+}
+
+func (s *ClientSuite) TestGetFilesMetadataForCollection() {
+	s.response = `{"count":2,"limit":2,"offset":0,"total_count":2,"items":[{"path":"data/a.csv","state":"UPLOADED"},{"path":"data/b.csv","state":"CREATED"}]}`
+	client := files.NewClient(s.server.URL)
+
+	metadata, err := client.GetFilesMetadata(context.Background(), "collection-1", "", filesSDK.Headers{Authorization: "token"})
+
+	s.NoError(err)
+	s.Require().Len(metadata, 2)
+	s.Equal("data/a.csv", metadata[0].Path)
+	s.Equal("CREATED", metadata[1].State)
+	s.Require().Len(s.requests, 1)
+	s.Equal(http.MethodGet, s.requests[0].Method)
+	s.Equal("/files", s.requests[0].URL.Path)
+	s.Equal("collection_id=collection-1", s.requests[0].URL.RawQuery)
+	s.Equal("Bearer token", s.requests[0].Header.Get("Authorization"))
+}
+
+func (s *ClientSuite) TestGetFilesMetadataForBundle() {
+	s.response = `{"items":[]}`
+	client := files.NewClient(s.server.URL)
+
+	metadata, err := client.GetFilesMetadata(context.Background(), "", "bundle-1", filesSDK.Headers{})
+
+	s.NoError(err)
+	s.Empty(metadata)
+	s.Equal("bundle_id=bundle-1", s.requests[0].URL.RawQuery)
+}
+
+func (s *ClientSuite) TestGetFilesMetadataReturnsAPIError() {
+	s.status = http.StatusForbidden
+	s.response = `{"errors":[{"errorCode":"Forbidden","description":"forbidden"}]}`
+	client := files.NewClient(s.server.URL)
+
+	_, err := client.GetFilesMetadata(context.Background(), "collection-1", "", filesSDK.Headers{})
+
+	var apiErr *filesSDK.APIError
+	s.Require().ErrorAs(err, &apiErr)
+	s.Equal(http.StatusForbidden, apiErr.StatusCode)
+}
+
+func (s *ClientSuite) TestGetFilesMetadataToleratesNonJSONErrors() {
+	s.status = http.StatusForbidden
+	s.response = "forbidden"
+	client := files.NewClient(s.server.URL)
+
+	_, err := client.GetFilesMetadata(context.Background(), "collection-1", "", filesSDK.Headers{})
+
+	var apiErr *filesSDK.APIError
+	s.Require().ErrorAs(err, &apiErr)
+	s.Equal(http.StatusForbidden, apiErr.StatusCode)
+	s.Nil(apiErr.Errors)
+}
+
+func (s *ClientSuite) TestMarkFileUploadedWithChecksum() {
+	client := files.NewClient(s.server.URL)
+
+	err := client.MarkFileUploadedWithChecksum(context.Background(), "data/a.csv", "etag-1", "Y2hlY2tzdW0=", filesSDK.Headers{Authorization: "token"})
+
+	s.NoError(err)
+	s.Require().Len(s.requests, 1)
+	s.Equal(http.MethodPatch, s.requests[0].Method)
+	s.Equal("/files/data/a.csv", s.requests[0].URL.Path)
+	s.Equal("Bearer token", s.requests[0].Header.Get("Authorization"))
+	s.JSONEq(`{"state":"UPLOADED","etag":"etag-1","checksum":"Y2hlY2tzdW0=","checksum_algorithm":"SHA256"}`, s.bodies[0])
+}
+
+func (s *ClientSuite) TestMarkFileQuarantined() {
+	client := files.NewClient(s.server.URL)
+
+	err := client.MarkFileQuarantined(context.Background(), "data/a.csv", filesSDK.Headers{Authorization: "token"})
+
+	s.NoError(err)
+	s.Require().Len(s.requests, 1)
+	s.Equal(http.MethodPatch, s.requests[0].Method)
+	s.Equal("/files/data/a.csv", s.requests[0].URL.Path)
+	s.Equal("Bearer token", s.requests[0].Header.Get("Authorization"))
+	s.JSONEq(`{"state":"QUARANTINED"}`, s.bodies[0])
+}
+
+func (s *ClientSuite) TestMarkFileUploadedWithChecksumReturnsAPIError() {
+	s.status = http.StatusBadRequest
+	s.response = `{"errors":[{"errorCode":"InvalidStateChange","description":"invalid state change"}]}`
+	client := files.NewClient(s.server.URL)
+
+	err := client.MarkFileUploadedWithChecksum(context.Background(), "data/a.csv", "etag-1", "", filesSDK.Headers{})
+
+	var apiErr *filesSDK.APIError
+	s.Require().ErrorAs(err, &apiErr)
+	s.Equal(http.StatusBadRequest, apiErr.StatusCode)
+	s.JSONEq(`{"state":"UPLOADED","etag":"etag-1"}`, s.bodies[0])
+}
@@ -0,0 +1,109 @@
+package files
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	EventChunkReceived = "chunk_received"
+	EventRegistered    = "registered"
+	EventUploaded      = "uploaded"
+	EventQuarantined   = "quarantined"
+	EventFailed        = "failed"
+)
+
+const subscriberBufferSize = 32
+
+// UploadEvent describes a step in the upload of a file, published as each chunk is accepted, the file is registered
+// with Files API and marked as uploaded or quarantined, or when any of those steps fails. The Error of a quarantined
+// event is the name of the malware that was found.
+type UploadEvent struct {
+	Type        string    `json:"type"`
+	Path        string    `json:"path"`
+	ChunkNumber int32     `json:"chunk_number,omitempty"`
+	TotalChunks int       `json:"total_chunks,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	Time        time.Time `json:"time"`
+}
+
+// Hub is an in-process pub/sub hub that passes upload events to the subscribers watching the path of the file. Any
+// part of the service can publish to it, but only the subscribers of the instance that handled a step are told about it.
+type Hub struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan UploadEvent]struct{}
+	closed      bool
+}
+
+// NewHub creates an empty Hub
+func NewHub() *Hub {
+	return &Hub{subscribers: make(map[string]map[chan UploadEvent]struct{})}
+}
+
+// Subscribe returns a channel of the events published for the path, and a function that must be called to
+// unsubscribe once the events are no longer needed. The channel is closed when the hub is closed.
+func (h *Hub) Subscribe(path string) (<-chan UploadEvent, func()) {
+	events := make(chan UploadEvent, subscriberBufferSize)
+
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		close(events)
+		return events, func() {}
+	}
+	if h.subscribers[path] == nil {
+		h.subscribers[path] = make(map[chan UploadEvent]struct{})
+	}
+	h.subscribers[path][events] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	return events, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			delete(h.subscribers[path], events)
+			if len(h.subscribers[path]) == 0 {
+				delete(h.subscribers, path)
+			}
+		})
+	}
+}
+
+// Publish passes the event to every subscriber of its path without blocking. Events are dropped for subscribers that
+// have fallen too far behind, so that a slow client cannot hold up an upload.
+func (h *Hub) Publish(event UploadEvent) {
+	if h == nil {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for events := range h.subscribers[event.Path] {
+		select {
+		case events <- event:
+		default:
+		}
+	}
+}
+
+// Close closes the channel of every subscriber, so that their streams end and the server can shut down. Nothing is
+// passed to subscribers once the hub is closed.
+func (h *Hub) Close() {
+	if h == nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for path, subscribers := range h.subscribers {
+		for events := range subscribers {
+			close(events)
+		}
+		delete(h.subscribers, path)
+	}
+}
@@ -0,0 +1,90 @@
+package files_test
+
+import (
+	"testing"
+
+	"github.com/ONSdigital/dp-upload-service/files"
+	"github.com/stretchr/testify/suite"
+)
+
+type HubSuite struct {
+	suite.Suite
+
+	hub *files.Hub
+}
+
+func TestHub(t *testing.T) {
+	suite.Run(t, new(HubSuite))
+}
+
+func (s *HubSuite) SetupTest() {
+	s.hub = files.NewHub()
+}
+
+func (s *HubSuite) TestEventsArePublishedToSubscribersOfThePath() {
+	first, unsubscribeFirst := s.hub.Subscribe("data/file.csv")
+	defer unsubscribeFirst()
+	second, unsubscribeSecond := s.hub.Subscribe("data/file.csv")
+	defer unsubscribeSecond()
+	other, unsubscribeOther := s.hub.Subscribe("data/other.csv")
+	defer unsubscribeOther()
+
+	s.hub.Publish(files.UploadEvent{Type: files.EventChunkReceived, Path: "data/file.csv", ChunkNumber: 1})
+
+	s.Equal([]files.UploadEvent{<-first}, []files.UploadEvent{<-second})
+	s.Empty(receivedEvents(other))
+}
+
+func (s *HubSuite) TestPublishSetsTheTimeOfTheEvent() {
+	events, unsubscribe := s.hub.Subscribe("data/file.csv")
+	defer unsubscribe()
+
+	s.hub.Publish(files.UploadEvent{Type: files.EventRegistered, Path: "data/file.csv"})
+
+	s.False((<-events).Time.IsZero())
+}
+
+func (s *HubSuite) TestUnsubscribedChannelReceivesNoEvents() {
+	events, unsubscribe := s.hub.Subscribe("data/file.csv")
+	unsubscribe()
+	unsubscribe()
+
+	s.hub.Publish(files.UploadEvent{Type: files.EventUploaded, Path: "data/file.csv"})
+
+	s.Empty(receivedEvents(events))
+}
+
+func (s *HubSuite) TestPublishDoesNotBlockOnSlowSubscribers() {
+	events, unsubscribe := s.hub.Subscribe("data/file.csv")
+	defer unsubscribe()
+
+	for i := 0; i < 100; i++ {
+		s.hub.Publish(files.UploadEvent{Type: files.EventChunkReceived, Path: "data/file.csv", ChunkNumber: int32(i + 1)})
+	}
+
+	received := receivedEvents(events)
+	s.NotEmpty(received)
+	s.Less(len(received), 100)
+	s.Equal(int32(1), received[0].ChunkNumber)
+}
+
+func (s *HubSuite) TestPublishingToNilHubIsIgnored() {
+	var hub *files.Hub
+	s.NotPanics(func() {
+		hub.Publish(files.UploadEvent{Type: files.EventUploaded, Path: "data/file.csv"})
+	})
+}
+
+func (s *HubSuite) TestClosingTheHubClosesSubscriptions() {
+	events, unsubscribe := s.hub.Subscribe("data/file.csv")
+	defer unsubscribe()
+
+	s.hub.Close()
+
+	_, ok := <-events
+	s.False(ok)
+	closed, unsubscribeClosed := s.hub.Subscribe("data/file.csv")
+	defer unsubscribeClosed()
+	_, ok = <-closed
+	s.False(ok)
+}
@@ -21,14 +21,23 @@ var _ files.FilesClienter = &FilesClienterMock{}
 //
 //		// make and configure a mocked files.FilesClienter
 //		mockedFilesClienter := &FilesClienterMock{
+//			DeleteFileFunc: func(ctx context.Context, path string, headers filesSDK.Headers) error {
+//				panic("mock out the DeleteFile method")
+//			},
 //			GetFileFunc: func(ctx context.Context, path string, headers filesSDK.Headers) (*filesAPITypes.StoredRegisteredMetaData, error) {
 //				panic("mock out the GetFile method")
 //			},
+//			GetFilesMetadataFunc: func(ctx context.Context, collectionID string, bundleID string, headers filesSDK.Headers) ([]filesAPITypes.StoredRegisteredMetaData, error) {
+//				panic("mock out the GetFilesMetadata method")
+//			},
 //			MarkFilePublishedFunc: func(ctx context.Context, path string, headers filesSDK.Headers) error {
 //				panic("mock out the MarkFilePublished method")
 //			},
-//			MarkFileUploadedFunc: func(ctx context.Context, path string, etag string, headers filesSDK.Headers) error {
-//				panic("mock out the MarkFileUploaded method")
+//			MarkFileQuarantinedFunc: func(ctx context.Context, path string, headers filesSDK.Headers) error {
+//				panic("mock out the MarkFileQuarantined method")
+//			},
+//			MarkFileUploadedWithChecksumFunc: func(ctx context.Context, path string, etag string, checksum string, headers filesSDK.Headers) error {
+//				panic("mock out the MarkFileUploadedWithChecksum method")
 //			},
 //			RegisterFileFunc: func(ctx context.Context, metadata filesAPITypes.StoredRegisteredMetaData, headers filesSDK.Headers) error {
 //				panic("mock out the RegisterFile method")
@@ -40,20 +49,38 @@ var _ files.FilesClienter = &FilesClienterMock{}
 //
 //	}
 type FilesClienterMock struct {
+	// DeleteFileFunc mocks the DeleteFile method.
+	DeleteFileFunc func(ctx context.Context, path string, headers filesSDK.Headers) error
+
 	// GetFileFunc mocks the GetFile method.
 	GetFileFunc func(ctx context.Context, path string, headers filesSDK.Headers) (*filesAPITypes.StoredRegisteredMetaData, error)
 
+	// GetFilesMetadataFunc mocks the GetFilesMetadata method.
+	GetFilesMetadataFunc func(ctx context.Context, collectionID string, bundleID string, headers filesSDK.Headers) ([]filesAPITypes.StoredRegisteredMetaData, error)
+
 	// MarkFilePublishedFunc mocks the MarkFilePublished method.
 	MarkFilePublishedFunc func(ctx context.Context, path string, headers filesSDK.Headers) error
 
-	// MarkFileUploadedFunc mocks the MarkFileUploaded method.
-	MarkFileUploadedFunc func(ctx context.Context, path string, etag string, headers filesSDK.Headers) error
+	// MarkFileQuarantinedFunc mocks the MarkFileQuarantined method.
+	MarkFileQuarantinedFunc func(ctx context.Context, path string, headers filesSDK.Headers) error
+
+	// MarkFileUploadedWithChecksumFunc mocks the MarkFileUploadedWithChecksum method.
+	MarkFileUploadedWithChecksumFunc func(ctx context.Context, path string, etag string, checksum string, headers filesSDK.Headers) error
 
 	// RegisterFileFunc mocks the RegisterFile method.
 	RegisterFileFunc func(ctx context.Context, metadata filesAPITypes.StoredRegisteredMetaData, headers filesSDK.Headers) error
 
 	// calls tracks calls to the methods.
 	calls struct {
+		// DeleteFile holds details about calls to the DeleteFile method.
+		DeleteFile []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Path is the path argument value.
+			Path string
+			// Headers is the headers argument value.
+			Headers filesSDK.Headers
+		}
 		// GetFile holds details about calls to the GetFile method.
 		GetFile []struct {
 			// Ctx is the ctx argument value.
@@ -63,6 +90,17 @@ type FilesClienterMock struct {
 			// Headers is the headers argument value.
 			Headers filesSDK.Headers
 		}
+		// GetFilesMetadata holds details about calls to the GetFilesMetadata method.
+		GetFilesMetadata []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// CollectionID is the collectionID argument value.
+			CollectionID string
+			// BundleID is the bundleID argument value.
+			BundleID string
+			// Headers is the headers argument value.
+			Headers filesSDK.Headers
+		}
 		// MarkFilePublished holds details about calls to the MarkFilePublished method.
 		MarkFilePublished []struct {
 			// Ctx is the ctx argument value.
@@ -72,14 +110,25 @@ type FilesClienterMock struct {
 			// Headers is the headers argument value.
 			Headers filesSDK.Headers
 		}
-		// MarkFileUploaded holds details about calls to the MarkFileUploaded method.
-		MarkFileUploaded []struct {
+		// MarkFileQuarantined holds details about calls to the MarkFileQuarantined method.
+		MarkFileQuarantined []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Path is the path argument value.
+			Path string
+			// Headers is the headers argument value.
+			Headers filesSDK.Headers
+		}
+		// MarkFileUploadedWithChecksum holds details about calls to the MarkFileUploadedWithChecksum method.
+		MarkFileUploadedWithChecksum []struct {
 			// Ctx is the ctx argument value.
 			Ctx context.Context
 			// Path is the path argument value.
 			Path string
 			// Etag is the etag argument value.
 			Etag string
+			// Checksum is the checksum argument value.
+			Checksum string
 			// Headers is the headers argument value.
 			Headers filesSDK.Headers
 		}
@@ -93,10 +142,53 @@ type FilesClienterMock struct {
 			Headers filesSDK.Headers
 		}
 	}
-	lockGetFile           sync.RWMutex
-	lockMarkFilePublished sync.RWMutex
-	lockMarkFileUploaded  sync.RWMutex
-	lockRegisterFile      sync.RWMutex
+	lockDeleteFile                   sync.RWMutex
+	lockGetFile                      sync.RWMutex
+	lockGetFilesMetadata             sync.RWMutex
+	lockMarkFilePublished            sync.RWMutex
+	lockMarkFileQuarantined          sync.RWMutex
+	lockMarkFileUploadedWithChecksum sync.RWMutex
+	lockRegisterFile                 sync.RWMutex
+}
+
+// DeleteFile calls DeleteFileFunc.
+func (mock *FilesClienterMock) DeleteFile(ctx context.Context, path string, headers filesSDK.Headers) error {
+	if mock.DeleteFileFunc == nil {
+		panic("FilesClienterMock.DeleteFileFunc: method is nil but FilesClienter.DeleteFile was just called")
+	}
+	callInfo := struct {
+		Ctx     context.Context
+		Path    string
+		Headers filesSDK.Headers
+	}{
+		Ctx:     ctx,
+		Path:    path,
+		Headers: headers,
+	}
+	mock.lockDeleteFile.Lock()
+	mock.calls.DeleteFile = append(mock.calls.DeleteFile, callInfo)
+	mock.lockDeleteFile.Unlock()
+	return mock.DeleteFileFunc(ctx, path, headers)
+}
+
+// DeleteFileCalls gets all the calls that were made to DeleteFile.
+// Check the length with:
+//
+//	len(mockedFilesClienter.DeleteFileCalls())
+func (mock *FilesClienterMock) DeleteFileCalls() []struct {
+	Ctx     context.Context
+	Path    string
+	Headers filesSDK.Headers
+} {
+	var calls []struct {
+		Ctx     context.Context
+		Path    string
+		Headers filesSDK.Headers
+	}
+	mock.lockDeleteFile.RLock()
+	calls = mock.calls.DeleteFile
+	mock.lockDeleteFile.RUnlock()
+	return calls
 }
 
 // GetFile calls GetFileFunc.
@@ -139,6 +231,50 @@ func (mock *FilesClienterMock) GetFileCalls() []struct {
 	return calls
 }
 
+// GetFilesMetadata calls GetFilesMetadataFunc.
+func (mock *FilesClienterMock) GetFilesMetadata(ctx context.Context, collectionID string, bundleID string, headers filesSDK.Headers) ([]filesAPITypes.StoredRegisteredMetaData, error) {
+	if mock.GetFilesMetadataFunc == nil {
+		panic("FilesClienterMock.GetFilesMetadataFunc: method is nil but FilesClienter.GetFilesMetadata was just called")
+	}
+	callInfo := struct {
+		Ctx          context.Context
+		CollectionID string
+		BundleID     string
+		Headers      filesSDK.Headers
+	}{
+		Ctx:          ctx,
+		CollectionID: collectionID,
+		BundleID:     bundleID,
+		Headers:      headers,
+	}
+	mock.lockGetFilesMetadata.Lock()
+	mock.calls.GetFilesMetadata = append(mock.calls.GetFilesMetadata, callInfo)
+	mock.lockGetFilesMetadata.Unlock()
+	return mock.GetFilesMetadataFunc(ctx, collectionID, bundleID, headers)
+}
+
+// GetFilesMetadataCalls gets all the calls that were made to GetFilesMetadata.
+// Check the length with:
+//
+//	len(mockedFilesClienter.GetFilesMetadataCalls())
+func (mock *FilesClienterMock) GetFilesMetadataCalls() []struct {
+	Ctx          context.Context
+	CollectionID string
+	BundleID     string
+	Headers      filesSDK.Headers
+} {
+	var calls []struct {
+		Ctx          context.Context
+		CollectionID string
+		BundleID     string
+		Headers      filesSDK.Headers
+	}
+	mock.lockGetFilesMetadata.RLock()
+	calls = mock.calls.GetFilesMetadata
+	mock.lockGetFilesMetadata.RUnlock()
+	return calls
+}
+
 // MarkFilePublished calls MarkFilePublishedFunc.
 func (mock *FilesClienterMock) MarkFilePublished(ctx context.Context, path string, headers filesSDK.Headers) error {
 	if mock.MarkFilePublishedFunc == nil {
@@ -179,47 +315,91 @@ func (mock *FilesClienterMock) MarkFilePublishedCalls() []struct {
 	return calls
 }
 
-// MarkFileUploaded calls MarkFileUploadedFunc.
-func (mock *FilesClienterMock) MarkFileUploaded(ctx context.Context, path string, etag string, headers filesSDK.Headers) error {
-	if mock.MarkFileUploadedFunc == nil {
-		panic("FilesClienterMock.MarkFileUploadedFunc: method is nil but FilesClienter.MarkFileUploaded was just called")
+// MarkFileQuarantined calls MarkFileQuarantinedFunc.
+func (mock *FilesClienterMock) MarkFileQuarantined(ctx context.Context, path string, headers filesSDK.Headers) error {
+	if mock.MarkFileQuarantinedFunc == nil {
+		panic("FilesClienterMock.MarkFileQuarantinedFunc: method is nil but FilesClienter.MarkFileQuarantined was just called")
 	}
 	callInfo := struct {
 		Ctx     context.Context
 		Path    string
-		Etag    string
 		Headers filesSDK.Headers
 	}{
 		Ctx:     ctx,
 		Path:    path,
-		Etag:    etag,
 		Headers: headers,
 	}
-	mock.lockMarkFileUploaded.Lock()
-	mock.calls.MarkFileUploaded = append(mock.calls.MarkFileUploaded, callInfo)
-	mock.lockMarkFileUploaded.Unlock()
-	return mock.MarkFileUploadedFunc(ctx, path, etag, headers)
+	mock.lockMarkFileQuarantined.Lock()
+	mock.calls.MarkFileQuarantined = append(mock.calls.MarkFileQuarantined, callInfo)
+	mock.lockMarkFileQuarantined.Unlock()
+	return mock.MarkFileQuarantinedFunc(ctx, path, headers)
 }
 
-// MarkFileUploadedCalls gets all the calls that were made to MarkFileUploaded.
+// MarkFileQuarantinedCalls gets all the calls that were made to MarkFileQuarantined.
 // Check the length with:
 //
-//	len(mockedFilesClienter.MarkFileUploadedCalls())
-func (mock *FilesClienterMock) MarkFileUploadedCalls() []struct {
+//	len(mockedFilesClienter.MarkFileQuarantinedCalls())
+func (mock *FilesClienterMock) MarkFileQuarantinedCalls() []struct {
 	Ctx     context.Context
 	Path    string
-	Etag    string
 	Headers filesSDK.Headers
 } {
 	var calls []struct {
 		Ctx     context.Context
 		Path    string
-		Etag    string
 		Headers filesSDK.Headers
 	}
-	mock.lockMarkFileUploaded.RLock()
-	calls = mock.calls.MarkFileUploaded
-	mock.lockMarkFileUploaded.RUnlock()
+	mock.lockMarkFileQuarantined.RLock()
+	calls = mock.calls.MarkFileQuarantined
+	mock.lockMarkFileQuarantined.RUnlock()
+	return calls
+}
+
+// MarkFileUploadedWithChecksum calls MarkFileUploadedWithChecksumFunc.
+func (mock *FilesClienterMock) MarkFileUploadedWithChecksum(ctx context.Context, path string, etag string, checksum string, headers filesSDK.Headers) error {
+	if mock.MarkFileUploadedWithChecksumFunc == nil {
+		panic("FilesClienterMock.MarkFileUploadedWithChecksumFunc: method is nil but FilesClienter.MarkFileUploadedWithChecksum was just called")
+	}
+	callInfo := struct {
+		Ctx      context.Context
+		Path     string
+		Etag     string
+		Checksum string
+		Headers  filesSDK.Headers
+	}{
+		Ctx:      ctx,
+		Path:     path,
+		Etag:     etag,
+		Checksum: checksum,
+		Headers:  headers,
+	}
+	mock.lockMarkFileUploadedWithChecksum.Lock()
+	mock.calls.MarkFileUploadedWithChecksum = append(mock.calls.MarkFileUploadedWithChecksum, callInfo)
+	mock.lockMarkFileUploadedWithChecksum.Unlock()
+	return mock.MarkFileUploadedWithChecksumFunc(ctx, path, etag, checksum, headers)
+}
+
+// MarkFileUploadedWithChecksumCalls gets all the calls that were made to MarkFileUploadedWithChecksum.
+// Check the length with:
+//
+//	len(mockedFilesClienter.MarkFileUploadedWithChecksumCalls())
+func (mock *FilesClienterMock) MarkFileUploadedWithChecksumCalls() []struct {
+	Ctx      context.Context
+	Path     string
+	Etag     string
+	Checksum string
+	Headers  filesSDK.Headers
+} {
+	var calls []struct {
+		Ctx      context.Context
+		Path     string
+		Etag     string
+		Checksum string
+		Headers  filesSDK.Headers
+	}
+	mock.lockMarkFileUploadedWithChecksum.RLock()
+	calls = mock.calls.MarkFileUploadedWithChecksum
+	mock.lockMarkFileUploadedWithChecksum.RUnlock()
 	return calls
 }
 
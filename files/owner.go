@@ -0,0 +1,58 @@
+package files
+
+import (
+	"context"
+	"net/http"
+
+	filesAPI "github.com/ONSdigital/dp-api-clients-go/v2/files"
+	filesSDK "github.com/ONSdigital/dp-files-api/sdk"
+	"github.com/ONSdigital/dp-upload-service/storage"
+	"github.com/ONSdigital/log.go/v2/log"
+)
+
+// uploadOwnerPrefix is where the collection and bundle of each upload in progress are recorded, until the file has
+// been registered with Files API
+const uploadOwnerPrefix = storage.UploadOwnerPrefix
+
+// uploadOwner is the collection and bundle that an upload in progress was started for
+type uploadOwner struct {
+	CollectionID *string `json:"collection_id,omitempty"`
+	BundleID     *string `json:"bundle_id,omitempty"`
+}
+
+// FileOwner returns the collection and bundle that the file is registered in, which requests for the file are
+// authorised against. A file whose upload is still in progress is in the collection and bundle its upload was started
+// for, and a file that is not being uploaded either is in neither.
+func (s Store) FileOwner(ctx context.Context, path string) (collectionID, bundleID *string, err error) {
+	storedMetadata, err := s.files.GetFile(ctx, path, filesSDK.Headers{Authorization: getAuthTokenFromContext(ctx)})
+	if err == nil {
+		return storedMetadata.CollectionID, storedMetadata.BundleID, nil
+	}
+	if apiErr, ok := err.(*filesSDK.APIError); !ok || apiErr.StatusCode != http.StatusNotFound {
+		return nil, nil, mapFilesAPIError(err)
+	}
+
+	var owner uploadOwner
+	if _, err := getJSON(ctx, s.bucket, uploadOwnerPrefix+path, &owner); err != nil {
+		log.Error(ctx, "failed to read owner of upload", err, log.Data{"path": path})
+		return nil, nil, ErrS3Download
+	}
+	return owner.CollectionID, owner.BundleID, nil
+}
+
+// recordUploadOwner records the collection and bundle that the upload of the file was started for, so that requests
+// for the file can be authorised before it has been registered with Files API. An error is only logged, as the upload
+// can carry on without it, but only callers whose permissions are not scoped to a collection or bundle can follow it.
+func (s Store) recordUploadOwner(ctx context.Context, metadata filesAPI.FileMetaData) {
+	owner := uploadOwner{CollectionID: metadata.CollectionID, BundleID: metadata.BundleID}
+	if err := putJSON(ctx, s.bucket, uploadOwnerPrefix+metadata.Path, owner); err != nil {
+		log.Error(ctx, "failed to record owner of upload", err, log.Data{"path": metadata.Path})
+	}
+}
+
+// removeUploadOwner removes the owner of an upload that has been completed or abandoned
+func (s Store) removeUploadOwner(ctx context.Context, path string) {
+	if err := s.bucket.Delete(ctx, uploadOwnerPrefix+path); err != nil {
+		log.Error(ctx, "failed to remove owner of upload", err, log.Data{"path": path})
+	}
+}
@@ -0,0 +1,262 @@
+package files
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	filesAPITypes "github.com/ONSdigital/dp-files-api/files"
+	filesAPIStore "github.com/ONSdigital/dp-files-api/store"
+	"github.com/ONSdigital/dp-upload-service/storage"
+	"github.com/ONSdigital/log.go/v2/log"
+)
+
+const (
+	CopyStateNotCopied = "NOT_COPIED"
+	CopyStateCopying   = "COPYING"
+	CopyStateCopied    = "COPIED"
+	CopyStateUnknown   = "UNKNOWN"
+)
+
+// PublicCopyStatus reports whether a published file has been copied to the public bucket
+type PublicCopyStatus struct {
+	Bucket      string `json:"bucket"`
+	State       string `json:"state"`
+	ETag        string `json:"etag,omitempty"`
+	SizeInBytes int64  `json:"size_in_bytes,omitempty"`
+	Err         string `json:"error,omitempty"`
+}
+
+// publicCopyPrefix is the prefix of the records of the published files that have not yet been copied to the public
+// bucket
+const publicCopyPrefix = storage.PublicCopyPrefix
+
+// maxCachedCopies is the number of public copies that are cached, beyond which the copies of any other files are looked
+// up in the public bucket each time their status is fetched
+const maxCachedCopies = 10000
+
+// copyCache keeps the public copies that have been made, or found, by the instance. A published file is never copied
+// again once its copy has been verified, so the status of its copy does not need to be read from the public bucket on
+// every request.
+type copyCache struct {
+	mu     sync.Mutex
+	copies map[string]storage.ObjectInfo
+}
+
+func (c *copyCache) get(path string) (storage.ObjectInfo, bool) {
+	if c == nil {
+		return storage.ObjectInfo{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	info, ok := c.copies[path]
+	return info, ok
+}
+
+func (c *copyCache) add(path string, info storage.ObjectInfo) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.copies) < maxCachedCopies {
+		c.copies[path] = info
+	}
+}
+
+// WithPublicBucket returns a copy of the store that copies each file to the public bucket once it has been published
+func (s Store) WithPublicBucket(bucket *storage.Bucket) Store {
+	s.public = bucket
+	s.copies = &copyCache{copies: make(map[string]storage.ObjectInfo)}
+	return s
+}
+
+// WithPublicCopyQueue returns a copy of the store that copies published files to the public bucket in the background,
+// rather than before the request that published them returns. The queue must process each path with
+// CopyToPublicBucket.
+func (s Store) WithPublicCopyQueue(queue Queue) Store {
+	s.publicCopies = queue
+	return s
+}
+
+// copyPublishedFile records that the published file needs to be copied to the public bucket, so that a copy that
+// fails, or is interrupted, is retried later by any instance of the service, then copies it. The file has already been
+// published, so any error is logged rather than returned.
+func (s Store) copyPublishedFile(ctx context.Context, path string) {
+	if err := putJSON(ctx, s.bucket, publicCopyPrefix+path, struct{}{}); err != nil {
+		log.Error(ctx, "failed to record copy of published file to public bucket", err, log.Data{"path": path})
+		return
+	}
+
+	if s.publicCopies != nil {
+		s.publicCopies.Enqueue(ctx, path)
+		return
+	}
+	if err := s.CopyToPublicBucket(ctx, path); err != nil {
+		log.Error(ctx, "failed to copy published file to public bucket", err, log.Data{"path": path})
+	}
+}
+
+// CopyToPublicBucket copies the published file at the path to the public bucket if its copy has been recorded, then
+// removes the record, so that it can be called for the same file more than once
+func (s Store) CopyToPublicBucket(ctx context.Context, path string) error {
+	if _, err := s.bucket.Head(ctx, publicCopyPrefix+path); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil
+		}
+		log.Error(ctx, "failed to read record of public copy", err, log.Data{"path": path})
+		return ErrS3Head
+	}
+
+	if err := s.copyToPublicBucket(ctx, path); err != nil {
+		return err
+	}
+
+	if err := s.bucket.Delete(ctx, publicCopyPrefix+path); err != nil {
+		log.Error(ctx, "failed to remove record of public copy", err, log.Data{"path": path})
+	}
+	return nil
+}
+
+// PendingPublicCopies lists the paths of the published files whose copy was recorded more than
+// PUBLIC_COPY_RETRY_INTERVAL ago, which have either failed to be copied or are being copied by an instance that has
+// stopped
+func (s Store) PendingPublicCopies(ctx context.Context) ([]string, error) {
+	return s.pendingRecords(ctx, publicCopyPrefix, s.cfg.PublicCopyRetryInterval)
+}
+
+// copyToPublicBucket makes a server-side copy of the published file in the public bucket, then verifies the copy by
+// comparing its SHA256 checksum with the original. The ETags of the two cannot be compared, as the ETag of an object
+// encrypted with KMS is not a digest of its content. A copy that does not match is deleted so that it can be copied
+// again.
+func (s Store) copyToPublicBucket(ctx context.Context, path string) error {
+	logData := log.Data{"path": path, "bucket": s.public.Name()}
+
+	source, err := s.bucket.Head(ctx, path)
+	if err != nil {
+		log.Error(ctx, "failed to get published file info from s3", err, logData)
+		return ErrS3Head
+	}
+
+	if _, err := s.public.CopyFrom(ctx, s.bucket.Name(), path, path); err != nil {
+		log.Error(ctx, "failed to copy published file to public bucket", err, logData)
+		return ErrS3Upload
+	}
+
+	copied, err := s.public.Head(ctx, path)
+	if err != nil {
+		log.Error(ctx, "failed to get public copy info from s3", err, logData)
+		return ErrS3Head
+	}
+
+	matches, err := s.publicCopyMatches(ctx, path, source, copied)
+	if err != nil {
+		log.Error(ctx, "failed to compare public copy with published file", err, logData)
+		return err
+	}
+	if !matches {
+		log.Error(ctx, "public copy does not match published file", ErrFileChecksumMismatch, logData)
+		if err := s.public.Delete(ctx, path); err != nil {
+			log.Error(ctx, "failed to delete public copy that does not match published file", err, logData)
+		}
+		return ErrFileChecksumMismatch
+	}
+
+	s.copies.add(path, copied)
+	log.Info(ctx, "published file copied to public bucket", logData)
+	return nil
+}
+
+// publicCopyMatches reports whether the public copy has the same content as the published file. S3 keeps the SHA256
+// checksum of an object that was uploaded with one, and calculates the checksum of each copy as it is made, so the two
+// are compared without reading either. A file uploaded without a checksum, such as one uploaded in parts of an unknown
+// size, is read back from both buckets instead.
+func (s Store) publicCopyMatches(ctx context.Context, path string, source, copied storage.ObjectInfo) (bool, error) {
+	if source.Checksum != "" && copied.Checksum != "" {
+		return source.Checksum == copied.Checksum, nil
+	}
+
+	expected, err := s.objectChecksum(ctx, s.bucket, path)
+	if err != nil {
+		return false, err
+	}
+	actual, err := s.objectChecksum(ctx, s.public, path)
+	if err != nil {
+		return false, err
+	}
+	return expected == actual, nil
+}
+
+// objectChecksum reads the object with the path from the bucket and returns its SHA256 checksum
+func (s Store) objectChecksum(ctx context.Context, bucket *storage.Bucket, path string) (string, error) {
+	body, _, err := bucket.Get(ctx, path)
+	if err != nil {
+		log.Error(ctx, "failed to read file from s3", err, log.Data{"path": path, "bucket": bucket.Name()})
+		return "", ErrS3Download
+	}
+	defer func() {
+		if err := body.Close(); err != nil {
+			log.Error(ctx, "error closing s3 object body", err, log.Data{"path": path, "bucket": bucket.Name()})
+		}
+	}()
+
+	checksum, err := sha256Checksum(body)
+	if err != nil {
+		log.Error(ctx, "failed to read file from s3", err, log.Data{"path": path, "bucket": bucket.Name()})
+		return "", ErrS3Download
+	}
+	return checksum, nil
+}
+
+// publicCopyStatus reports whether the file has been copied to the public bucket, returning nil if there is no public
+// bucket or the file has not been published
+func (s Store) publicCopyStatus(ctx context.Context, storedMetadata *filesAPITypes.StoredRegisteredMetaData) *PublicCopyStatus {
+	if s.public == nil || storedMetadata.State != filesAPIStore.StatePublished {
+		return nil
+	}
+
+	status := &PublicCopyStatus{Bucket: s.public.Name()}
+	if head, ok := s.copies.get(storedMetadata.Path); ok {
+		status.State = CopyStateCopied
+		status.ETag = head.ETag
+		status.SizeInBytes = head.SizeInBytes
+		return status
+	}
+
+	head, err := s.public.Head(ctx, storedMetadata.Path)
+	if err == nil {
+		s.copies.add(storedMetadata.Path, head)
+		status.State = CopyStateCopied
+		status.ETag = head.ETag
+		status.SizeInBytes = head.SizeInBytes
+		return status
+	}
+	if !errors.Is(err, storage.ErrNotFound) {
+		log.Error(ctx, "failed to get public copy info from s3", err, log.Data{"path": storedMetadata.Path})
+		status.State = CopyStateUnknown
+		status.Err = err.Error()
+		return status
+	}
+
+	// a file is being copied from when its copy is recorded until the copy has been made
+	_, copying, err := s.public.ListUploadedParts(ctx, storedMetadata.Path)
+	if err == nil && !copying {
+		if _, headErr := s.bucket.Head(ctx, publicCopyPrefix+storedMetadata.Path); headErr == nil {
+			copying = true
+		} else if !errors.Is(headErr, storage.ErrNotFound) {
+			err = headErr
+		}
+	}
+	switch {
+	case err != nil:
+		log.Error(ctx, "failed to list copied parts in s3", err, log.Data{"path": storedMetadata.Path})
+		status.State = CopyStateUnknown
+		status.Err = err.Error()
+	case copying:
+		status.State = CopyStateCopying
+	default:
+		status.State = CopyStateNotCopied
+	}
+
+	return status
+}
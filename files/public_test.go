@@ -0,0 +1,346 @@
+package files_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	filesAPITypes "github.com/ONSdigital/dp-files-api/files"
+	filesSDK "github.com/ONSdigital/dp-files-api/sdk"
+	"github.com/ONSdigital/dp-upload-service/config"
+	"github.com/ONSdigital/dp-upload-service/files"
+	mock_files "github.com/ONSdigital/dp-upload-service/files/mock"
+	"github.com/ONSdigital/dp-upload-service/storage"
+	mock_storage "github.com/ONSdigital/dp-upload-service/storage/mock"
+)
+
+// publicBucket returns a public bucket that keeps the checksum of each copy made in it, which is the same as the
+// checksum of the published files
+func (s *StoreSuite) publicBucket() (*mock_storage.DriverMock, *storage.Bucket) {
+	var mu sync.Mutex
+	copies := map[string]string{}
+	mockPublic := &mock_storage.DriverMock{
+		CopyFromFunc: func(ctx context.Context, sourceBucket, sourceKey, key string) (string, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			copies[key] = "checksum"
+			return "public-etag", nil
+		},
+		HeadFunc: func(ctx context.Context, key string) (storage.ObjectInfo, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			checksum, ok := copies[key]
+			if !ok {
+				return storage.ObjectInfo{}, storage.ErrNotFound
+			}
+			return storage.ObjectInfo{SizeInBytes: 100, ETag: "public-etag", Checksum: checksum}, nil
+		},
+		ListUploadedPartsFunc: func(ctx context.Context, key string) (storage.MultipartUploadParts, bool, error) {
+			return storage.MultipartUploadParts{}, false, nil
+		},
+		DeleteFunc: func(ctx context.Context, key string) error {
+			mu.Lock()
+			defer mu.Unlock()
+			delete(copies, key)
+			return nil
+		},
+	}
+	return mockPublic, storage.NewBucket("public", mockPublic)
+}
+
+// givenPublicCopyRecords keeps the records of public copies that are stored and removed, so that a copy is only
+// recorded once it has been stored
+func (s *StoreSuite) givenPublicCopyRecords() map[string]bool {
+	var mu sync.Mutex
+	records := map[string]bool{}
+	s.mockS3.PutFunc = func(ctx context.Context, key, contentType string, content io.Reader) error {
+		mu.Lock()
+		defer mu.Unlock()
+		records[key] = true
+		return nil
+	}
+	s.mockS3.DeleteFunc = func(ctx context.Context, key string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		delete(records, key)
+		return nil
+	}
+	s.mockS3.HeadFunc = func(ctx context.Context, key string) (storage.ObjectInfo, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if strings.HasPrefix(key, ".public-copies/") && !records[key] {
+			return storage.ObjectInfo{}, storage.ErrNotFound
+		}
+		return storage.ObjectInfo{SizeInBytes: 100, ETag: "head-object-etag", Checksum: "checksum"}, nil
+	}
+	return records
+}
+
+func (s *StoreSuite) TestPublishCopiesFileToPublicBucket() {
+	s.uploadedFile()
+	records := s.givenPublicCopyRecords()
+	mockPublic, public := s.publicBucket()
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{}).WithPublicBucket(public)
+
+	err := store.PublishFile(context.Background(), "data/file.csv")
+
+	s.NoError(err)
+	s.Require().Len(mockPublic.CopyFromCalls(), 1)
+	s.Equal("name", mockPublic.CopyFromCalls()[0].SourceBucket)
+	s.Equal("data/file.csv", mockPublic.CopyFromCalls()[0].SourceKey)
+	s.Equal("data/file.csv", mockPublic.CopyFromCalls()[0].Key)
+	s.Len(mockPublic.DeleteCalls(), 0)
+	s.Len(mockPublic.GetCalls(), 0)
+	s.Len(s.mockS3.GetCalls(), 0)
+	s.Empty(records)
+}
+
+func (s *StoreSuite) TestPublishQueuesCopyToPublicBucket() {
+	s.uploadedFile()
+	records := s.givenPublicCopyRecords()
+	mockPublic, public := s.publicBucket()
+	queue := &mock_files.QueueMock{EnqueueFunc: func(ctx context.Context, path string) {}}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{}).WithPublicBucket(public).WithPublicCopyQueue(queue)
+
+	err := store.PublishFile(context.Background(), "data/file.csv")
+
+	s.NoError(err)
+	s.Require().Len(queue.EnqueueCalls(), 1)
+	s.Equal("data/file.csv", queue.EnqueueCalls()[0].Path)
+	s.True(records[".public-copies/data/file.csv"])
+	s.Len(mockPublic.CopyFromCalls(), 0)
+
+	err = store.CopyToPublicBucket(context.Background(), "data/file.csv")
+
+	s.NoError(err)
+	s.Len(mockPublic.CopyFromCalls(), 1)
+	s.Empty(records)
+}
+
+func (s *StoreSuite) TestCopyToPublicBucketWithoutRecordDoesNothing() {
+	s.givenPublicCopyRecords()
+	mockPublic, public := s.publicBucket()
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{}).WithPublicBucket(public)
+
+	err := store.CopyToPublicBucket(context.Background(), "data/file.csv")
+
+	s.NoError(err)
+	s.Len(mockPublic.CopyFromCalls(), 0)
+}
+
+func (s *StoreSuite) TestPublicCopyThatDoesNotMatchIsDeletedAndRetried() {
+	s.uploadedFile()
+	records := s.givenPublicCopyRecords()
+	mockPublic, public := s.publicBucket()
+	mockPublic.HeadFunc = func(ctx context.Context, key string) (storage.ObjectInfo, error) {
+		return storage.ObjectInfo{SizeInBytes: 100, ETag: "head-object-etag", Checksum: "corrupted-checksum"}, nil
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{}).WithPublicBucket(public)
+
+	records[".public-copies/data/file.csv"] = true
+
+	err := store.CopyToPublicBucket(context.Background(), "data/file.csv")
+
+	s.ErrorIs(err, files.ErrFileChecksumMismatch)
+	s.Require().Len(mockPublic.DeleteCalls(), 1)
+	s.Equal("data/file.csv", mockPublic.DeleteCalls()[0].Key)
+	s.True(records[".public-copies/data/file.csv"])
+}
+
+func (s *StoreSuite) TestPublicCopyIsNotVerifiedByItsETag() {
+	s.uploadedFile()
+	records := s.givenPublicCopyRecords()
+	mockPublic, public := s.publicBucket()
+	mockPublic.CopyFromFunc = func(ctx context.Context, sourceBucket, sourceKey, key string) (string, error) {
+		return "kms-etag", nil
+	}
+	mockPublic.HeadFunc = func(ctx context.Context, key string) (storage.ObjectInfo, error) {
+		return storage.ObjectInfo{SizeInBytes: 100, ETag: "kms-etag", Checksum: "checksum"}, nil
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{}).WithPublicBucket(public)
+
+	err := store.PublishFile(context.Background(), "data/file.csv")
+
+	s.NoError(err)
+	s.Len(mockPublic.DeleteCalls(), 0)
+	s.Empty(records)
+}
+
+func (s *StoreSuite) TestPublicCopyWithoutAChecksumIsVerifiedByReadingIt() {
+	s.uploadedFile()
+	records := s.givenPublicCopyRecords()
+	s.mockS3.HeadFunc = func(ctx context.Context, key string) (storage.ObjectInfo, error) {
+		if strings.HasPrefix(key, ".public-copies/") && !records[key] {
+			return storage.ObjectInfo{}, storage.ErrNotFound
+		}
+		return storage.ObjectInfo{SizeInBytes: 7, ETag: "head-object-etag"}, nil
+	}
+	s.mockS3.GetFunc = func(ctx context.Context, key string) (io.ReadCloser, *int64, error) {
+		return io.NopCloser(strings.NewReader("CONTENT")), nil, nil
+	}
+	mockPublic, public := s.publicBucket()
+	mockPublic.HeadFunc = func(ctx context.Context, key string) (storage.ObjectInfo, error) {
+		return storage.ObjectInfo{SizeInBytes: 7, ETag: "public-etag"}, nil
+	}
+	mockPublic.GetFunc = func(ctx context.Context, key string) (io.ReadCloser, *int64, error) {
+		return io.NopCloser(strings.NewReader("CORRUPT")), nil, nil
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{}).WithPublicBucket(public)
+
+	records[".public-copies/data/file.csv"] = true
+
+	err := store.CopyToPublicBucket(context.Background(), "data/file.csv")
+
+	s.ErrorIs(err, files.ErrFileChecksumMismatch)
+	s.Require().Len(s.mockS3.GetCalls(), 1)
+	s.Equal("data/file.csv", s.mockS3.GetCalls()[0].Key)
+	s.Require().Len(mockPublic.GetCalls(), 1)
+	s.Require().Len(mockPublic.DeleteCalls(), 1)
+	s.True(records[".public-copies/data/file.csv"])
+
+	mockPublic.GetFunc = s.mockS3.GetFunc
+
+	err = store.CopyToPublicBucket(context.Background(), "data/file.csv")
+
+	s.NoError(err)
+	s.Len(mockPublic.DeleteCalls(), 1)
+	s.Empty(records)
+}
+
+func (s *StoreSuite) TestStatusOfPublicCopyIsCached() {
+	s.uploadedFile()
+	s.givenPublicCopyRecords()
+	mockPublic, public := s.publicBucket()
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{}).WithPublicBucket(public)
+	s.Require().NoError(store.PublishFile(context.Background(), "data/file.csv"))
+	heads := len(mockPublic.HeadCalls())
+	s.mockFiles.GetFileFunc = func(ctx context.Context, path string, headers filesSDK.Headers) (*filesAPITypes.StoredRegisteredMetaData, error) {
+		return &filesAPITypes.StoredRegisteredMetaData{Path: path, State: "PUBLISHED"}, nil
+	}
+
+	for i := 0; i < 3; i++ {
+		status, err := store.Status(context.Background(), "data/file.csv")
+
+		s.NoError(err)
+		s.Require().NotNil(status.PublicCopy)
+		s.Equal(files.PublicCopyStatus{Bucket: "public", State: files.CopyStateCopied, ETag: "public-etag", SizeInBytes: 100}, *status.PublicCopy)
+	}
+	s.Len(mockPublic.HeadCalls(), heads)
+}
+
+func (s *StoreSuite) TestPendingPublicCopiesAreOlderThanRetryInterval() {
+	s.mockS3.ListFunc = func(ctx context.Context, prefix string) ([]storage.ObjectSummary, error) {
+		return []storage.ObjectSummary{
+			{Key: ".public-copies/data/old.csv", LastModified: time.Now().Add(-time.Hour)},
+			{Key: ".public-copies/data/new.csv", LastModified: time.Now()},
+		}, nil
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{PublicCopyRetryInterval: time.Minute})
+
+	paths, err := store.PendingPublicCopies(context.Background())
+
+	s.NoError(err)
+	s.Equal([]string{"data/old.csv"}, paths)
+	s.Equal(".public-copies/", s.mockS3.ListCalls()[0].Prefix)
+}
+
+func (s *StoreSuite) TestPublishingAgainCopiesMissingPublicCopy() {
+	s.mockFiles.GetFileFunc = func(ctx context.Context, path string, headers filesSDK.Headers) (*filesAPITypes.StoredRegisteredMetaData, error) {
+		return &filesAPITypes.StoredRegisteredMetaData{Path: path, State: "PUBLISHED", IsPublishable: true}, nil
+	}
+	s.givenPublicCopyRecords()
+	mockPublic, public := s.publicBucket()
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{}).WithPublicBucket(public)
+
+	err := store.PublishFile(context.Background(), "data/file.csv")
+
+	s.NoError(err)
+	s.Len(mockPublic.CopyFromCalls(), 1)
+	s.Len(s.mockFiles.MarkFilePublishedCalls(), 0)
+}
+
+func (s *StoreSuite) TestPublishingAgainWithPublicCopyIsRejected() {
+	s.mockFiles.GetFileFunc = func(ctx context.Context, path string, headers filesSDK.Headers) (*filesAPITypes.StoredRegisteredMetaData, error) {
+		return &filesAPITypes.StoredRegisteredMetaData{Path: path, State: "PUBLISHED", IsPublishable: true}, nil
+	}
+	mockPublic, public := s.publicBucket()
+	mockPublic.HeadFunc = func(ctx context.Context, key string) (storage.ObjectInfo, error) {
+		return storage.ObjectInfo{}, nil
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{}).WithPublicBucket(public)
+
+	err := store.PublishFile(context.Background(), "data/file.csv")
+
+	s.ErrorIs(err, files.ErrFileAlreadyPublished)
+	s.Len(mockPublic.CopyFromCalls(), 0)
+}
+
+func (s *StoreSuite) TestStatusReportsPublicCopy() {
+	s.mockFiles.GetFileFunc = func(ctx context.Context, path string, headers filesSDK.Headers) (*filesAPITypes.StoredRegisteredMetaData, error) {
+		return &filesAPITypes.StoredRegisteredMetaData{Path: path, State: "PUBLISHED"}, nil
+	}
+	mockPublic, public := s.publicBucket()
+
+	tests := []struct {
+		head     func(ctx context.Context, key string) (storage.ObjectInfo, error)
+		copying  bool
+		recorded bool
+		expected files.PublicCopyStatus
+	}{
+		{
+			head: func(ctx context.Context, key string) (storage.ObjectInfo, error) {
+				return storage.ObjectInfo{SizeInBytes: 100, ETag: "public-etag"}, nil
+			},
+			expected: files.PublicCopyStatus{Bucket: "public", State: files.CopyStateCopied, ETag: "public-etag", SizeInBytes: 100},
+		},
+		{
+			head:     mockPublic.HeadFunc,
+			copying:  true,
+			expected: files.PublicCopyStatus{Bucket: "public", State: files.CopyStateCopying},
+		},
+		{
+			head:     mockPublic.HeadFunc,
+			recorded: true,
+			expected: files.PublicCopyStatus{Bucket: "public", State: files.CopyStateCopying},
+		},
+		{
+			head:     mockPublic.HeadFunc,
+			expected: files.PublicCopyStatus{Bucket: "public", State: files.CopyStateNotCopied},
+		},
+		{
+			head: func(ctx context.Context, key string) (storage.ObjectInfo, error) {
+				return storage.ObjectInfo{}, errors.New("head error")
+			},
+			expected: files.PublicCopyStatus{Bucket: "public", State: files.CopyStateUnknown, Err: "head error"},
+		},
+	}
+
+	for _, test := range tests {
+		records := s.givenPublicCopyRecords()
+		records[".public-copies/data/file.csv"] = test.recorded
+		mockPublic.HeadFunc = test.head
+		mockPublic.ListUploadedPartsFunc = func(ctx context.Context, key string) (storage.MultipartUploadParts, bool, error) {
+			return storage.MultipartUploadParts{}, test.copying, nil
+		}
+		store := files.NewStore(s.mockFiles, s.bucket, &config.Config{}).WithPublicBucket(public)
+
+		status, err := store.Status(context.Background(), "data/file.csv")
+
+		s.NoError(err)
+		s.Require().NotNil(status.PublicCopy, test.expected.State)
+		s.Equal(test.expected, *status.PublicCopy)
+	}
+}
+
+func (s *StoreSuite) TestStatusOmitsPublicCopyForUnpublishedFile() {
+	_, public := s.publicBucket()
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{}).WithPublicBucket(public)
+
+	status, err := store.Status(context.Background(), "data/file.csv")
+
+	s.NoError(err)
+	s.Nil(status.PublicCopy)
+}
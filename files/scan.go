@@ -0,0 +1,171 @@
+package files
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+
+	filesAPITypes "github.com/ONSdigital/dp-files-api/files"
+	filesSDK "github.com/ONSdigital/dp-files-api/sdk"
+	filesAPIStore "github.com/ONSdigital/dp-files-api/store"
+	"github.com/ONSdigital/dp-upload-service/scan"
+	"github.com/ONSdigital/dp-upload-service/storage"
+	"github.com/ONSdigital/log.go/v2/log"
+)
+
+// Verdicts of scanning a file for malware, as reported in its status
+const (
+	ScanVerdictPending  = "PENDING"
+	ScanVerdictClean    = "CLEAN"
+	ScanVerdictInfected = "INFECTED"
+)
+
+// StateQuarantined is the Files API state of a file that has been found to be infected and moved under the quarantine
+// prefix. It is set instead of UPLOADED, so that the file can never be published.
+const StateQuarantined = "QUARANTINED"
+
+// scanOverlap is how much of the end of each segment of a file that is scanned in segments is scanned again at the
+// start of the next, so that a signature spanning the boundary between them is still found
+const scanOverlap = 1024 * 1024
+
+// ScanStatus reports whether a file has passed its malware scan. Files are scanned when they are verified, before they
+// are marked as uploaded, so the verdict is pending until then.
+type ScanStatus struct {
+	Verdict        string `json:"verdict"`
+	QuarantinePath string `json:"quarantine_path,omitempty"`
+}
+
+// WithScanner returns a copy of the store that scans each file for malware when it is verified. Clean files are
+// marked as uploaded, and infected files are moved under the quarantine prefix and marked as quarantined, so that they
+// can never be published.
+func (s Store) WithScanner(scanner scan.Scanner) Store {
+	s.scanner = scanner
+	return s
+}
+
+// scanSegments scans the content in segments of at most CLAMAV_MAX_STREAM_SIZE, as clamd rejects streams longer than
+// its StreamMaxLength, so that files of any size can be scanned. The end of each segment is scanned again at the start
+// of the next.
+func (s Store) scanSegments(ctx context.Context, content io.Reader) (scan.Result, error) {
+	segmentSize := s.cfg.ClamAVMaxStreamSize
+	if segmentSize <= 0 {
+		return s.scanner.Scan(ctx, content)
+	}
+	overlap := min(scanOverlap, segmentSize/2)
+
+	var previous []byte
+	for {
+		limit := segmentSize - int64(len(previous))
+		tail := &tailBuffer{size: int(overlap)}
+		segment := io.TeeReader(io.LimitReader(content, limit), tail)
+
+		result, err := s.scanner.Scan(ctx, io.MultiReader(bytes.NewReader(previous), segment))
+		if err != nil || result.Infected {
+			return result, err
+		}
+		// the scanner does not have to read the segment through to the end
+		if _, err := io.Copy(io.Discard, segment); err != nil {
+			return scan.Result{}, err
+		}
+		if tail.written < limit {
+			return result, nil
+		}
+		previous = tail.Bytes()
+	}
+}
+
+// tailBuffer keeps the last size bytes written to it, along with a count of every byte written
+type tailBuffer struct {
+	size    int
+	buf     []byte
+	written int64
+}
+
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.written += int64(len(p))
+	t.buf = append(t.buf, p...)
+	// the kept bytes are only moved once the buffer has grown to twice the size, so each byte is moved at most once
+	if len(t.buf) > 2*t.size {
+		t.buf = append(t.buf[:0], t.buf[len(t.buf)-t.size:]...)
+	}
+	return len(p), nil
+}
+
+// Bytes returns the last size bytes written
+func (t *tailBuffer) Bytes() []byte {
+	if len(t.buf) > t.size {
+		return t.buf[len(t.buf)-t.size:]
+	}
+	return t.buf
+}
+
+// quarantineFile moves an infected file under the quarantine prefix, where it can be inspected but never published,
+// and marks it as quarantined in Files API
+func (s Store) quarantineFile(ctx context.Context, path, signature string) error {
+	quarantinePath := s.quarantinePath(path)
+	logData := log.Data{"path": path, "quarantine_path": quarantinePath, "signature": signature}
+
+	log.Warn(ctx, "malware found in uploaded file", logData)
+	if _, err := s.bucket.CopyFrom(ctx, s.bucket.Name(), path, quarantinePath); err != nil {
+		log.Error(ctx, "failed to copy infected file to quarantine", err, logData)
+		return ErrQuarantine
+	}
+	if err := s.bucket.Delete(ctx, path); err != nil {
+		log.Error(ctx, "failed to delete infected file", err, logData)
+		return ErrQuarantine
+	}
+	if err := s.markFileQuarantined(ctx, path); err != nil {
+		return err
+	}
+	s.removeVerification(ctx, path)
+	s.events.Publish(UploadEvent{Type: EventQuarantined, Path: path, Error: signature})
+
+	log.Info(ctx, "infected file quarantined", logData)
+	return ErrFileQuarantined
+}
+
+// markFileQuarantined moves the quarantined file to the QUARANTINED state in Files API. The verification of the file is
+// kept if it fails, so that it is marked again when the verification is retried.
+func (s Store) markFileQuarantined(ctx context.Context, path string) error {
+	headers := filesSDK.Headers{Authorization: getAuthTokenFromContext(ctx)}
+	if err := s.files.MarkFileQuarantined(ctx, path, headers); err != nil {
+		log.Error(ctx, "failed to mark file as quarantined in dp-files-api", err, log.Data{"path": path})
+		return mapFilesAPIError(err)
+	}
+	return nil
+}
+
+// isQuarantined reports whether the file has been moved under the quarantine prefix
+func (s Store) isQuarantined(ctx context.Context, path string) (bool, error) {
+	_, err := s.bucket.Head(ctx, s.quarantinePath(path))
+	if errors.Is(err, storage.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// quarantinePath returns the key that an infected file with the path is moved to
+func (s Store) quarantinePath(path string) string {
+	return s.cfg.QuarantinePrefix + path
+}
+
+// scanStatus reports the verdict of scanning the registered file from its state in Files API, or nil if files are not
+// being scanned. Files that have been marked as uploaded are clean, and files that have been marked as quarantined are
+// infected.
+func (s Store) scanStatus(storedMetadata *filesAPITypes.StoredRegisteredMetaData) *ScanStatus {
+	if s.scanner == nil {
+		return nil
+	}
+
+	switch storedMetadata.State {
+	case filesAPIStore.StateUploaded, filesAPIStore.StatePublished, filesAPIStore.StateMoved:
+		return &ScanStatus{Verdict: ScanVerdictClean}
+	case StateQuarantined:
+		return &ScanStatus{Verdict: ScanVerdictInfected, QuarantinePath: s.quarantinePath(storedMetadata.Path)}
+	}
+	return &ScanStatus{Verdict: ScanVerdictPending}
+}
@@ -0,0 +1,276 @@
+package files_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	filesAPI "github.com/ONSdigital/dp-api-clients-go/v2/files"
+	filesAPITypes "github.com/ONSdigital/dp-files-api/files"
+	filesSDK "github.com/ONSdigital/dp-files-api/sdk"
+	filesAPIStore "github.com/ONSdigital/dp-files-api/store"
+	"github.com/ONSdigital/dp-upload-service/config"
+	"github.com/ONSdigital/dp-upload-service/files"
+	"github.com/ONSdigital/dp-upload-service/scan"
+	mock_scan "github.com/ONSdigital/dp-upload-service/scan/mock"
+	"github.com/ONSdigital/dp-upload-service/storage"
+)
+
+var scannedMetadata = files.FileMetadataWithContentItem{FileMetaData: filesAPI.FileMetaData{Path: "data/file.csv", Type: "text/csv"}}
+
+func (s *StoreSuite) scanner(result scan.Result, err error) *mock_scan.ScannerMock {
+	s.mockS3.CopyFromFunc = func(ctx context.Context, sourceBucket, sourceKey, key string) (string, error) {
+		return "quarantine-etag", nil
+	}
+	return &mock_scan.ScannerMock{
+		ScanFunc: func(ctx context.Context, content io.Reader) (scan.Result, error) {
+			_, _ = io.ReadAll(content)
+			return result, err
+		},
+	}
+}
+
+// givenQuarantined makes the bucket report whether there is an object under the quarantine prefix
+func (s *StoreSuite) givenQuarantined(quarantined bool) {
+	s.mockS3.HeadFunc = func(ctx context.Context, key string) (storage.ObjectInfo, error) {
+		if strings.HasPrefix(key, "quarantine/") && !quarantined {
+			return storage.ObjectInfo{}, storage.ErrNotFound
+		}
+		return storage.ObjectInfo{SizeInBytes: 100, ETag: "head-object-etag"}, nil
+	}
+}
+
+func (s *StoreSuite) TestCleanFileIsMarkedAsUploaded() {
+	scanner := s.scanner(scan.Result{}, nil)
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{QuarantinePrefix: "quarantine/"}).WithScanner(scanner)
+
+	uploaded, err := store.UploadFile(context.Background(), scannedMetadata, lastResumable, content)
+
+	s.NoError(err)
+	s.True(uploaded)
+	s.Len(scanner.ScanCalls(), 1)
+	s.Len(s.mockS3.GetCalls(), 1)
+	s.Len(s.mockFiles.MarkFileUploadedWithChecksumCalls(), 1)
+	s.Len(s.mockS3.CopyFromCalls(), 0)
+}
+
+func (s *StoreSuite) TestChecksumIsCalculatedFromTheContentThatIsScanned() {
+	// clamd stops reading a stream once it has found malware or the stream is too long
+	scanner := &mock_scan.ScannerMock{
+		ScanFunc: func(ctx context.Context, content io.Reader) (scan.Result, error) {
+			_, _ = io.ReadFull(content, make([]byte, 3))
+			return scan.Result{}, nil
+		},
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{}).WithScanner(scanner)
+
+	_, err := store.UploadFile(context.Background(), scannedMetadata, lastResumable, content)
+
+	s.NoError(err)
+	s.Len(s.mockS3.GetCalls(), 1)
+	digest := sha256.Sum256([]byte("CONTENT"))
+	s.Require().Len(s.mockFiles.MarkFileUploadedWithChecksumCalls(), 1)
+	s.Equal(base64.StdEncoding.EncodeToString(digest[:]), s.mockFiles.MarkFileUploadedWithChecksumCalls()[0].Checksum)
+}
+
+func (s *StoreSuite) TestInfectedFileIsQuarantined() {
+	scanner := s.scanner(scan.Result{Infected: true, Signature: "Eicar-Test-Signature"}, nil)
+	hub := files.NewHub()
+	events, unsubscribe := hub.Subscribe("data/file.csv")
+	defer unsubscribe()
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{QuarantinePrefix: "quarantine/"}).WithScanner(scanner).WithEvents(hub)
+
+	_, err := store.UploadFile(context.Background(), scannedMetadata, lastResumable, content)
+
+	s.ErrorIs(err, files.ErrFileQuarantined)
+	s.Require().Len(s.mockS3.CopyFromCalls(), 1)
+	s.Equal("name", s.mockS3.CopyFromCalls()[0].SourceBucket)
+	s.Equal("data/file.csv", s.mockS3.CopyFromCalls()[0].SourceKey)
+	s.Equal("quarantine/data/file.csv", s.mockS3.CopyFromCalls()[0].Key)
+	s.Equal([]string{"data/file.csv", ".verifications/data/file.csv"}, s.deletedKeys())
+	s.Len(s.mockFiles.MarkFileUploadedWithChecksumCalls(), 0)
+	s.Require().Len(s.mockFiles.MarkFileQuarantinedCalls(), 1)
+	s.Equal("data/file.csv", s.mockFiles.MarkFileQuarantinedCalls()[0].Path)
+	s.Len(s.mockFiles.DeleteFileCalls(), 0)
+
+	published := receivedEvents(events)
+	s.Require().Len(published, 4)
+	s.Equal(files.EventQuarantined, published[2].Type)
+	s.Equal("Eicar-Test-Signature", published[2].Error)
+	s.Equal(files.EventFailed, published[3].Type)
+}
+
+func (s *StoreSuite) TestInfectedFileVerificationIsKeptWhenMarkingItQuarantinedFails() {
+	scanner := s.scanner(scan.Result{Infected: true}, nil)
+	s.mockFiles.MarkFileQuarantinedFunc = func(ctx context.Context, path string, headers filesSDK.Headers) error {
+		return &filesSDK.APIError{StatusCode: http.StatusInternalServerError}
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{QuarantinePrefix: "quarantine/"}).WithScanner(scanner)
+
+	_, err := store.UploadFile(context.Background(), scannedMetadata, lastResumable, content)
+
+	s.ErrorIs(err, files.ErrFilesServer)
+	s.Len(s.mockS3.CopyFromCalls(), 1)
+	// the verification is left to be retried
+	s.Equal([]string{"data/file.csv"}, s.deletedKeys())
+}
+
+func (s *StoreSuite) TestFileIsNotMarkedAsUploadedWhenScanFails() {
+	scanner := s.scanner(scan.Result{}, errors.New("clamd unavailable"))
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{}).WithScanner(scanner)
+
+	_, err := store.UploadFile(context.Background(), scannedMetadata, lastResumable, content)
+
+	s.ErrorIs(err, files.ErrScanFailed)
+	s.Len(s.mockFiles.MarkFileUploadedWithChecksumCalls(), 0)
+	// the verification is left to be retried
+	s.Empty(s.deletedKeys())
+}
+
+func (s *StoreSuite) TestInfectedFileIsNotDeletedWhenTheCopyFails() {
+	scanner := s.scanner(scan.Result{Infected: true}, nil)
+	s.mockS3.CopyFromFunc = func(ctx context.Context, sourceBucket, sourceKey, key string) (string, error) {
+		return "", errors.New("copy failed")
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{}).WithScanner(scanner)
+
+	_, err := store.UploadFile(context.Background(), scannedMetadata, lastResumable, content)
+
+	s.ErrorIs(err, files.ErrQuarantine)
+	s.Empty(s.deletedKeys())
+	s.Len(s.mockFiles.MarkFileUploadedWithChecksumCalls(), 0)
+}
+
+func (s *StoreSuite) TestCompleteUploadSessionQuarantinesInfectedFile() {
+	s.givenSessionPartsUploaded(1)
+	scanner := s.scanner(scan.Result{Infected: true}, nil)
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{}).WithScanner(scanner)
+
+	err := store.CompleteUploadSession(context.Background(), "upload-id", sessionMetadata, files.Resumable{TotalChunks: 1})
+
+	s.ErrorIs(err, files.ErrFileQuarantined)
+	s.Len(s.mockS3.CopyFromCalls(), 1)
+	s.Len(s.mockFiles.MarkFileUploadedWithChecksumCalls(), 0)
+}
+
+func (s *StoreSuite) TestVerifyFileThatHasBeenQuarantinedRemovesRecord() {
+	s.givenVerificationRecorded(`{"etag":"recorded-etag"}`)
+	s.mockS3.GetFunc = func(ctx context.Context, key string) (io.ReadCloser, *int64, error) {
+		if key == ".verifications/data/file.csv" {
+			return io.NopCloser(strings.NewReader(`{"etag":"recorded-etag"}`)), nil, nil
+		}
+		return nil, nil, storage.ErrNotFound
+	}
+	s.givenQuarantined(true)
+	scanner := s.scanner(scan.Result{}, nil)
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{QuarantinePrefix: "quarantine/"}).WithScanner(scanner)
+
+	err := store.VerifyFile(context.Background(), "data/file.csv")
+
+	s.NoError(err)
+	s.Len(scanner.ScanCalls(), 0)
+	s.Len(s.mockFiles.MarkFileUploadedWithChecksumCalls(), 0)
+	s.Len(s.mockFiles.MarkFileQuarantinedCalls(), 1)
+	s.Require().Len(s.mockS3.DeleteCalls(), 1)
+	s.Equal(".verifications/data/file.csv", s.mockS3.DeleteCalls()[0].Key)
+}
+
+// segmentScanner records the content of each segment that it scans, and finds malware in any segment containing the
+// signature
+func segmentScanner(signature string, segments *[]string) *mock_scan.ScannerMock {
+	return &mock_scan.ScannerMock{
+		ScanFunc: func(ctx context.Context, content io.Reader) (scan.Result, error) {
+			segment, _ := io.ReadAll(content)
+			*segments = append(*segments, string(segment))
+			if signature != "" && strings.Contains(string(segment), signature) {
+				return scan.Result{Infected: true, Signature: "Test-Signature"}, nil
+			}
+			return scan.Result{}, nil
+		},
+	}
+}
+
+func (s *StoreSuite) TestFileLargerThanTheStreamSizeIsAccepted() {
+	s.mockS3.UploadPartFunc = func(ctx context.Context, req *storage.PartRequest, payload io.Reader) (storage.PartResponse, error) {
+		return storage.PartResponse{AllPartsUploaded: false}, nil
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{ClamAVMaxStreamSize: 50}).WithScanner(s.scanner(scan.Result{}, nil))
+
+	_, err := store.UploadFile(context.Background(), files.FileMetadataWithContentItem{
+		FileMetaData: filesAPI.FileMetaData{Path: "data/file.csv", Type: "text/csv", SizeInBytes: 51},
+	}, firstResumable, content)
+
+	s.NoError(err)
+	s.Len(s.mockS3.UploadPartCalls(), 1)
+}
+
+func (s *StoreSuite) TestFileLargerThanTheStreamSizeIsScannedInOverlappingSegments() {
+	var segments []string
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{ClamAVMaxStreamSize: 4}).WithScanner(segmentScanner("", &segments))
+
+	uploaded, err := store.UploadFile(context.Background(), scannedMetadata, lastResumable, content)
+
+	s.NoError(err)
+	s.True(uploaded)
+	s.Equal([]string{"CONT", "NTEN", "ENT"}, segments)
+	digest := sha256.Sum256([]byte("CONTENT"))
+	s.Require().Len(s.mockFiles.MarkFileUploadedWithChecksumCalls(), 1)
+	s.Equal(base64.StdEncoding.EncodeToString(digest[:]), s.mockFiles.MarkFileUploadedWithChecksumCalls()[0].Checksum)
+}
+
+func (s *StoreSuite) TestSignatureSpanningSegmentsIsFound() {
+	var segments []string
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{ClamAVMaxStreamSize: 4, QuarantinePrefix: "quarantine/"}).
+		WithScanner(segmentScanner("TE", &segments))
+	s.mockS3.CopyFromFunc = func(ctx context.Context, sourceBucket, sourceKey, key string) (string, error) {
+		return "quarantine-etag", nil
+	}
+
+	_, err := store.UploadFile(context.Background(), scannedMetadata, lastResumable, content)
+
+	s.ErrorIs(err, files.ErrFileQuarantined)
+	// the signature spans the end of the first segment, and no more is scanned once it has been found
+	s.Equal([]string{"CONT", "NTEN"}, segments)
+	s.Len(s.mockFiles.MarkFileUploadedWithChecksumCalls(), 0)
+	s.Len(s.mockFiles.MarkFileQuarantinedCalls(), 1)
+}
+
+func (s *StoreSuite) TestStatusReportsScanVerdict() {
+	tests := []struct {
+		state          string
+		verdict        string
+		quarantinePath string
+	}{
+		{filesAPIStore.StateCreated, files.ScanVerdictPending, ""},
+		{filesAPIStore.StateUploaded, files.ScanVerdictClean, ""},
+		{filesAPIStore.StatePublished, files.ScanVerdictClean, ""},
+		{files.StateQuarantined, files.ScanVerdictInfected, "quarantine/data/file.csv"},
+	}
+
+	for _, test := range tests {
+		s.mockFiles.GetFileFunc = func(ctx context.Context, path string, headers filesSDK.Headers) (*filesAPITypes.StoredRegisteredMetaData, error) {
+			return &filesAPITypes.StoredRegisteredMetaData{Path: path, State: test.state}, nil
+		}
+		store := files.NewStore(s.mockFiles, s.bucket, &config.Config{QuarantinePrefix: "quarantine/"}).WithScanner(s.scanner(scan.Result{}, nil))
+
+		status, err := store.Status(context.Background(), "data/file.csv")
+
+		s.Require().NoError(err)
+		s.Require().NotNil(status.Scan, test.state)
+		s.Equal(test.verdict, status.Scan.Verdict, test.state)
+		s.Equal(test.quarantinePath, status.Scan.QuarantinePath, test.state)
+	}
+}
+
+func (s *StoreSuite) TestStatusWithoutAScannerHasNoVerdict() {
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	status, err := store.Status(context.Background(), "data/file.csv")
+
+	s.NoError(err)
+	s.Nil(status.Scan)
+}
@@ -0,0 +1,215 @@
+package files
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	filesAPI "github.com/ONSdigital/dp-api-clients-go/v2/files"
+	filesSDK "github.com/ONSdigital/dp-files-api/sdk"
+	"github.com/ONSdigital/dp-upload-service/mediatype"
+	"github.com/ONSdigital/dp-upload-service/storage"
+	"github.com/ONSdigital/log.go/v2/log"
+)
+
+// maxSessionChunks is the most parts a multipart upload can have in S3
+const maxSessionChunks = 10000
+
+// UploadSession is a multipart upload whose chunks are uploaded by the client directly to the static files bucket,
+// using a presigned URL for each chunk, rather than through the service
+type UploadSession struct {
+	UploadID  string          `json:"upload_id"`
+	Path      string          `json:"path"`
+	Parts     []PresignedPart `json:"parts"`
+	ExpiresAt time.Time       `json:"expires_at"`
+}
+
+// PresignedPart is the URL that a chunk of an upload session can be uploaded to with a PUT request
+type PresignedPart struct {
+	ChunkNumber int32  `json:"chunk_number"`
+	URL         string `json:"url"`
+}
+
+// CreateUploadSession starts a multipart upload for the file, returning a presigned URL for each of its chunks. The
+// file is not registered with Files API until the session is completed with CompleteUploadSession.
+func (s Store) CreateUploadSession(ctx context.Context, metadata FileMetadataWithContentItem, resumable Resumable) (*UploadSession, error) {
+	path := metadata.Path
+	logData := log.Data{"path": path, "total_chunks": resumable.TotalChunks}
+
+	if err := s.checkPathNotReserved(ctx, path); err != nil {
+		return nil, err
+	}
+	if resumable.TotalChunks < 1 || resumable.TotalChunks > maxSessionChunks {
+		return nil, ErrInvalidTotalChunks
+	}
+
+	if !s.mediaTypes.Allows(metadata.Type) {
+		log.Warn(ctx, "file type is not allowed", log.Data{"path": path, "type": metadata.Type})
+		return nil, ErrUnsupportedMediaType
+	}
+
+	if err := checkDeclaredSize(ctx, path, metadata.SizeInBytes, s.cfg.MaxSessionFileSize); err != nil {
+		return nil, err
+	}
+
+	headers := filesSDK.Headers{Authorization: getAuthTokenFromContext(ctx)}
+	if _, err := s.files.GetFile(ctx, path, headers); err == nil {
+		return nil, filesAPI.ErrFileAlreadyRegistered
+	} else if apiErr, ok := err.(*filesSDK.APIError); !ok || apiErr.StatusCode != http.StatusNotFound {
+		log.Error(ctx, "failed to get file metadata", err, logData)
+		return nil, mapFilesAPIError(err)
+	}
+
+	_, found, err := s.bucket.ListUploadedParts(ctx, path)
+	if err != nil {
+		log.Error(ctx, "failed to list uploaded parts in s3", err, logData)
+		return nil, ErrS3ListParts
+	}
+	if found {
+		return nil, ErrUploadInProgress
+	}
+
+	uploadID, err := s.bucket.CreateMultipartUpload(ctx, path, resumable.Type)
+	if err != nil {
+		// another instance of the service can start an upload for the path after it was listed
+		if errors.Is(err, storage.ErrUploadInProgress) {
+			return nil, ErrUploadInProgress
+		}
+		log.Error(ctx, "failed to create multipart upload in s3", err, logData)
+		return nil, ErrS3Upload
+	}
+	logData["upload_id"] = uploadID
+
+	session := &UploadSession{
+		UploadID:  uploadID,
+		Path:      path,
+		Parts:     make([]PresignedPart, 0, resumable.TotalChunks),
+		ExpiresAt: time.Now().UTC().Add(s.cfg.UploadSessionExpiry),
+	}
+	for chunk := int32(1); chunk <= int32(resumable.TotalChunks); chunk++ {
+		url, err := s.bucket.PresignUploadPart(ctx, path, uploadID, chunk, s.cfg.UploadSessionExpiry)
+		if err != nil {
+			log.Error(ctx, "failed to presign chunk upload", err, logData)
+			if abortErr := s.bucket.AbortMultipartUploadByID(ctx, path, uploadID); abortErr != nil {
+				log.Error(ctx, "failed to abort multipart upload in s3", abortErr, logData)
+			}
+			if errors.Is(err, storage.ErrPresignNotSupported) {
+				return nil, ErrDirectUploadUnsupported
+			}
+			return nil, ErrS3Presign
+		}
+		session.Parts = append(session.Parts, PresignedPart{ChunkNumber: chunk, URL: url})
+	}
+
+	s.recordUploadOwner(ctx, metadata.FileMetaData)
+
+	log.Info(ctx, "upload session created", logData)
+	return session, nil
+}
+
+// CompleteUploadSession completes the multipart upload of an upload session once every chunk has been uploaded to
+// its presigned URL, then registers the file with Files API and marks it as uploaded, as for the last chunk of an
+// upload through the service
+func (s Store) CompleteUploadSession(ctx context.Context, uploadID string, metadata FileMetadataWithContentItem, resumable Resumable) error {
+	err := s.completeUploadSession(ctx, uploadID, metadata, resumable)
+	if err != nil {
+		s.events.Publish(UploadEvent{
+			Type:        EventFailed,
+			Path:        metadata.Path,
+			TotalChunks: resumable.TotalChunks,
+			Error:       err.Error(),
+		})
+	}
+
+	return err
+}
+
+func (s Store) completeUploadSession(ctx context.Context, uploadID string, metadata FileMetadataWithContentItem, resumable Resumable) error {
+	path := metadata.Path
+	logData := log.Data{"path": path, "upload_id": uploadID}
+
+	if err := s.checkPathNotReserved(ctx, path); err != nil {
+		return err
+	}
+	if !s.mediaTypes.Allows(metadata.Type) {
+		log.Warn(ctx, "file type is not allowed", log.Data{"path": path, "type": metadata.Type})
+		return ErrUnsupportedMediaType
+	}
+
+	if resumable.FileChecksum != "" {
+		if err := validateFileChecksum(resumable.FileChecksum); err != nil {
+			log.Error(ctx, "invalid file checksum", err, logData)
+			return err
+		}
+	}
+
+	upload, found, err := s.bucket.ListUploadedParts(ctx, path)
+	if err != nil {
+		log.Error(ctx, "failed to list uploaded parts in s3", err, logData)
+		return ErrS3ListParts
+	}
+	if !found || upload.UploadID != uploadID {
+		return ErrUploadNotFound
+	}
+	if len(upload.Parts) != resumable.TotalChunks {
+		log.Warn(ctx, "upload session completed before every chunk was uploaded", log.Data{
+			"path":            path,
+			"upload_id":       uploadID,
+			"total_chunks":    resumable.TotalChunks,
+			"chunks_uploaded": len(upload.Parts),
+		})
+		return ErrPartsMissing
+	}
+
+	if err := s.bucket.CompleteMultipartUpload(ctx, path, uploadID); err != nil {
+		log.Error(ctx, "failed to complete multipart upload in s3", err, logData)
+		if errors.Is(err, storage.ErrPartTooSmall) {
+			return ErrChunkTooSmall
+		}
+		if errors.Is(err, storage.ErrNotUploaded) {
+			return ErrUploadNotFound
+		}
+		return ErrS3Upload
+	}
+
+	if s.mediaTypes.SniffsContent(metadata.Type) {
+		if err := s.checkContentMatchesType(ctx, path, metadata.Type); err != nil {
+			return err
+		}
+	}
+
+	_, err = s.completeFile(ctx, metadata, resumable.FileChecksum, "")
+	s.removeUploadOwner(ctx, path)
+	return err
+}
+
+// checkContentMatchesType sniffs the type of a file completed from chunks uploaded directly to storage, which the
+// service never sees, deleting the file if its content does not match its declared type
+func (s Store) checkContentMatchesType(ctx context.Context, path, declaredType string) error {
+	logData := log.Data{"path": path, "type": declaredType}
+
+	body, _, err := s.bucket.Get(ctx, path)
+	if err != nil {
+		log.Error(ctx, "failed to get completed file from s3", err, logData)
+		return ErrS3Download
+	}
+	head, _, err := mediatype.Sniff(body)
+	if closeErr := body.Close(); closeErr != nil {
+		log.Error(ctx, "error closing s3 object body", closeErr, logData)
+	}
+	if err != nil {
+		log.Error(ctx, "failed to read start of completed file from s3", err, logData)
+		return ErrS3Download
+	}
+
+	if mediatype.Matches(declaredType, head) {
+		return nil
+	}
+
+	log.Warn(ctx, "file content does not match its type", logData)
+	if err := s.bucket.Delete(ctx, path); err != nil {
+		log.Error(ctx, "failed to delete completed file from s3", err, logData)
+	}
+	return ErrUnsupportedMediaType
+}
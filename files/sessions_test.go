@@ -0,0 +1,352 @@
+package files_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	filesAPI "github.com/ONSdigital/dp-api-clients-go/v2/files"
+	filesAPITypes "github.com/ONSdigital/dp-files-api/files"
+	filesSDK "github.com/ONSdigital/dp-files-api/sdk"
+	"github.com/ONSdigital/dp-upload-service/config"
+	"github.com/ONSdigital/dp-upload-service/files"
+	"github.com/ONSdigital/dp-upload-service/storage"
+)
+
+var sessionMetadata = files.FileMetadataWithContentItem{FileMetaData: filesAPI.FileMetaData{Path: "data/file.csv", Type: "text/csv"}}
+
+func (s *StoreSuite) givenNewSession() {
+	s.mockFiles.GetFileFunc = func(ctx context.Context, path string, headers filesSDK.Headers) (*filesAPITypes.StoredRegisteredMetaData, error) {
+		return nil, &filesSDK.APIError{StatusCode: http.StatusNotFound}
+	}
+	s.mockS3.CreateMultipartUploadFunc = func(ctx context.Context, key, contentType string) (string, error) {
+		return "upload-id", nil
+	}
+	s.mockS3.PresignUploadPartFunc = func(ctx context.Context, key, uploadID string, partNumber int32, expires time.Duration) (string, error) {
+		return fmt.Sprintf("https://bucket/%s?uploadId=%s&partNumber=%d", key, uploadID, partNumber), nil
+	}
+	s.mockS3.AbortMultipartUploadByIDFunc = func(ctx context.Context, key, uploadID string) error {
+		return nil
+	}
+}
+
+func (s *StoreSuite) givenSessionPartsUploaded(count int) {
+	parts := make([]storage.UploadedPart, 0, count)
+	for i := 1; i <= count; i++ {
+		parts = append(parts, storage.UploadedPart{PartNumber: int32(i)})
+	}
+	s.mockS3.ListUploadedPartsFunc = func(ctx context.Context, key string) (storage.MultipartUploadParts, bool, error) {
+		return storage.MultipartUploadParts{UploadID: "upload-id", Parts: parts}, true, nil
+	}
+	s.mockS3.CompleteMultipartUploadFunc = func(ctx context.Context, key, uploadID string) error {
+		return nil
+	}
+}
+
+func (s *StoreSuite) TestCreateUploadSession() {
+	s.givenNewSession()
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{UploadSessionExpiry: time.Hour})
+
+	session, err := store.CreateUploadSession(context.Background(), sessionMetadata, files.Resumable{Type: "text/csv", TotalChunks: 2})
+
+	s.NoError(err)
+	s.Equal("upload-id", session.UploadID)
+	s.Equal("data/file.csv", session.Path)
+	s.Require().Len(session.Parts, 2)
+	s.Equal(int32(1), session.Parts[0].ChunkNumber)
+	s.Equal("https://bucket/data/file.csv?uploadId=upload-id&partNumber=2", session.Parts[1].URL)
+	s.WithinDuration(time.Now().Add(time.Hour), session.ExpiresAt, time.Minute)
+	s.Require().Len(s.mockS3.CreateMultipartUploadCalls(), 1)
+	s.Equal("text/csv", s.mockS3.CreateMultipartUploadCalls()[0].ContentType)
+	s.Equal(time.Hour, s.mockS3.PresignUploadPartCalls()[0].Expires)
+	s.Len(s.mockFiles.RegisterFileCalls(), 0)
+	s.Require().Len(s.mockS3.PutCalls(), 1)
+	s.Equal(".upload-owners/data/file.csv", s.mockS3.PutCalls()[0].Key)
+}
+
+func (s *StoreSuite) TestCreateUploadSessionInvalidTotalChunks() {
+	s.givenNewSession()
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	for _, totalChunks := range []int{0, 10001} {
+		_, err := store.CreateUploadSession(context.Background(), sessionMetadata, files.Resumable{TotalChunks: totalChunks})
+
+		s.ErrorIs(err, files.ErrInvalidTotalChunks)
+	}
+	s.Len(s.mockS3.CreateMultipartUploadCalls(), 0)
+}
+
+func (s *StoreSuite) TestCreateUploadSessionUnderQuarantinePrefix() {
+	s.givenNewSession()
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{QuarantinePrefix: "quarantine/"})
+	metadata := files.FileMetadataWithContentItem{FileMetaData: filesAPI.FileMetaData{Path: "quarantine/data/file.csv", Type: "text/csv"}}
+
+	_, err := store.CreateUploadSession(context.Background(), metadata, files.Resumable{Type: "text/csv", TotalChunks: 1})
+
+	s.ErrorIs(err, files.ErrReservedPath)
+	s.Len(s.mockS3.CreateMultipartUploadCalls(), 0)
+}
+
+func (s *StoreSuite) TestCreateUploadSessionForRegisteredFile() {
+	s.givenNewSession()
+	s.mockFiles.GetFileFunc = func(ctx context.Context, path string, headers filesSDK.Headers) (*filesAPITypes.StoredRegisteredMetaData, error) {
+		return &filesAPITypes.StoredRegisteredMetaData{Path: path}, nil
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	_, err := store.CreateUploadSession(context.Background(), sessionMetadata, files.Resumable{TotalChunks: 1})
+
+	s.ErrorIs(err, filesAPI.ErrFileAlreadyRegistered)
+	s.Len(s.mockS3.CreateMultipartUploadCalls(), 0)
+}
+
+func (s *StoreSuite) TestCreateUploadSessionFilesAPIError() {
+	s.givenNewSession()
+	s.mockFiles.GetFileFunc = func(ctx context.Context, path string, headers filesSDK.Headers) (*filesAPITypes.StoredRegisteredMetaData, error) {
+		return nil, &filesSDK.APIError{StatusCode: http.StatusForbidden}
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	_, err := store.CreateUploadSession(context.Background(), sessionMetadata, files.Resumable{TotalChunks: 1})
+
+	s.ErrorIs(err, files.ErrFilesForbidden)
+}
+
+func (s *StoreSuite) TestCreateUploadSessionWithUploadInProgress() {
+	s.givenNewSession()
+	s.mockS3.ListUploadedPartsFunc = func(ctx context.Context, key string) (storage.MultipartUploadParts, bool, error) {
+		return storage.MultipartUploadParts{UploadID: "other-upload-id"}, true, nil
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	_, err := store.CreateUploadSession(context.Background(), sessionMetadata, files.Resumable{TotalChunks: 1})
+
+	s.ErrorIs(err, files.ErrUploadInProgress)
+	s.Len(s.mockS3.CreateMultipartUploadCalls(), 0)
+}
+
+func (s *StoreSuite) TestCreateUploadSessionStartedByAnotherInstance() {
+	s.givenNewSession()
+	s.mockS3.CreateMultipartUploadFunc = func(ctx context.Context, key, contentType string) (string, error) {
+		return "", fmt.Errorf("bucket/data/file.csv: %w", storage.ErrUploadInProgress)
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	_, err := store.CreateUploadSession(context.Background(), sessionMetadata, files.Resumable{TotalChunks: 1})
+
+	s.ErrorIs(err, files.ErrUploadInProgress)
+}
+
+func (s *StoreSuite) TestCreateUploadSessionS3Error() {
+	s.givenNewSession()
+	s.mockS3.CreateMultipartUploadFunc = func(ctx context.Context, key, contentType string) (string, error) {
+		return "", errors.New("s3 error")
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	_, err := store.CreateUploadSession(context.Background(), sessionMetadata, files.Resumable{TotalChunks: 1})
+
+	s.ErrorIs(err, files.ErrS3Upload)
+}
+
+func (s *StoreSuite) TestCreateUploadSessionPresignErrorAbortsUpload() {
+	s.givenNewSession()
+	s.mockS3.PresignUploadPartFunc = func(ctx context.Context, key, uploadID string, partNumber int32, expires time.Duration) (string, error) {
+		return "", errors.New("s3 error")
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	_, err := store.CreateUploadSession(context.Background(), sessionMetadata, files.Resumable{TotalChunks: 1})
+
+	s.ErrorIs(err, files.ErrS3Presign)
+	s.Require().Len(s.mockS3.AbortMultipartUploadByIDCalls(), 1)
+	s.Equal("upload-id", s.mockS3.AbortMultipartUploadByIDCalls()[0].UploadID)
+}
+
+func (s *StoreSuite) TestCreateUploadSessionPresignNotSupported() {
+	s.givenNewSession()
+	s.mockS3.PresignUploadPartFunc = func(ctx context.Context, key, uploadID string, partNumber int32, expires time.Duration) (string, error) {
+		return "", storage.ErrPresignNotSupported
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	_, err := store.CreateUploadSession(context.Background(), sessionMetadata, files.Resumable{TotalChunks: 1})
+
+	s.ErrorIs(err, files.ErrDirectUploadUnsupported)
+	s.Len(s.mockS3.AbortMultipartUploadByIDCalls(), 1)
+}
+
+func (s *StoreSuite) TestCreateUploadSessionOfTypeNotAllowed() {
+	s.givenNewSession()
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{UploadAllowedTypes: []string{"application/pdf"}})
+
+	_, err := store.CreateUploadSession(context.Background(), sessionMetadata, files.Resumable{Type: "text/csv", TotalChunks: 1})
+
+	s.ErrorIs(err, files.ErrUnsupportedMediaType)
+	s.Len(s.mockS3.CreateMultipartUploadCalls(), 0)
+}
+
+func (s *StoreSuite) TestCreateUploadSessionLargerThanMaxFileSize() {
+	s.givenNewSession()
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{MaxSessionFileSize: 100})
+	metadata := sessionMetadata
+	metadata.SizeInBytes = 101
+
+	_, err := store.CreateUploadSession(context.Background(), metadata, files.Resumable{Type: "text/csv", TotalChunks: 1})
+
+	s.ErrorIs(err, files.ErrFileTooLarge)
+	s.Len(s.mockS3.CreateMultipartUploadCalls(), 0)
+}
+
+func (s *StoreSuite) TestCompleteUploadSession() {
+	s.givenSessionPartsUploaded(2)
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	err := store.CompleteUploadSession(context.Background(), "upload-id", sessionMetadata, files.Resumable{TotalChunks: 2})
+
+	s.NoError(err)
+	s.Require().Len(s.mockS3.CompleteMultipartUploadCalls(), 1)
+	s.Equal("data/file.csv", s.mockS3.CompleteMultipartUploadCalls()[0].Key)
+	s.Equal("upload-id", s.mockS3.CompleteMultipartUploadCalls()[0].UploadID)
+	s.Require().Len(s.mockFiles.RegisterFileCalls(), 1)
+	s.Equal("data/file.csv", s.mockFiles.RegisterFileCalls()[0].Metadata.Path)
+	s.Require().Len(s.mockFiles.MarkFileUploadedWithChecksumCalls(), 1)
+	s.Equal("head-object-etag", s.mockFiles.MarkFileUploadedWithChecksumCalls()[0].Etag)
+}
+
+func (s *StoreSuite) TestCompleteUploadSessionPublishesEvents() {
+	s.givenSessionPartsUploaded(1)
+	hub := files.NewHub()
+	events, unsubscribe := hub.Subscribe("data/file.csv")
+	defer unsubscribe()
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{}).WithEvents(hub)
+
+	err := store.CompleteUploadSession(context.Background(), "upload-id", sessionMetadata, files.Resumable{TotalChunks: 1})
+
+	s.NoError(err)
+	received := receivedEvents(events)
+	s.Require().Len(received, 2)
+	s.Equal(files.EventRegistered, received[0].Type)
+	s.Equal(files.EventUploaded, received[1].Type)
+}
+
+func (s *StoreSuite) TestCompleteUnknownUploadSession() {
+	s.givenSessionPartsUploaded(1)
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	err := store.CompleteUploadSession(context.Background(), "other-upload-id", sessionMetadata, files.Resumable{TotalChunks: 1})
+
+	s.ErrorIs(err, files.ErrUploadNotFound)
+	s.Len(s.mockS3.CompleteMultipartUploadCalls(), 0)
+}
+
+func (s *StoreSuite) TestCompleteUploadSessionWithNoUploadInProgress() {
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	err := store.CompleteUploadSession(context.Background(), "upload-id", sessionMetadata, files.Resumable{TotalChunks: 1})
+
+	s.ErrorIs(err, files.ErrUploadNotFound)
+}
+
+func (s *StoreSuite) TestCompleteUploadSessionWithPartsMissing() {
+	s.givenSessionPartsUploaded(1)
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	err := store.CompleteUploadSession(context.Background(), "upload-id", sessionMetadata, files.Resumable{TotalChunks: 2})
+
+	s.ErrorIs(err, files.ErrPartsMissing)
+	s.Len(s.mockS3.CompleteMultipartUploadCalls(), 0)
+	s.Len(s.mockFiles.RegisterFileCalls(), 0)
+}
+
+func (s *StoreSuite) TestCompleteUploadSessionWithChunkTooSmall() {
+	s.givenSessionPartsUploaded(2)
+	s.mockS3.CompleteMultipartUploadFunc = func(ctx context.Context, key, uploadID string) error {
+		return fmt.Errorf("%w: too small", storage.ErrPartTooSmall)
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	err := store.CompleteUploadSession(context.Background(), "upload-id", sessionMetadata, files.Resumable{TotalChunks: 2})
+
+	s.ErrorIs(err, files.ErrChunkTooSmall)
+	s.Len(s.mockFiles.RegisterFileCalls(), 0)
+}
+
+func (s *StoreSuite) TestCompleteUploadSessionS3Error() {
+	s.givenSessionPartsUploaded(1)
+	s.mockS3.CompleteMultipartUploadFunc = func(ctx context.Context, key, uploadID string) error {
+		return errors.New("s3 error")
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	err := store.CompleteUploadSession(context.Background(), "upload-id", sessionMetadata, files.Resumable{TotalChunks: 1})
+
+	s.ErrorIs(err, files.ErrS3Upload)
+}
+
+func (s *StoreSuite) TestCompleteUploadSessionFileChecksumMismatch() {
+	s.givenSessionPartsUploaded(1)
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+	digest := sha256.Sum256([]byte("OTHER CONTENT"))
+	resumable := files.Resumable{TotalChunks: 1, FileChecksum: base64.StdEncoding.EncodeToString(digest[:])}
+
+	err := store.CompleteUploadSession(context.Background(), "upload-id", sessionMetadata, resumable)
+
+	s.ErrorIs(err, files.ErrFileChecksumMismatch)
+	s.Len(s.mockFiles.DeleteFileCalls(), 1)
+	s.Len(s.mockFiles.MarkFileUploadedWithChecksumCalls(), 0)
+}
+
+func (s *StoreSuite) TestCompleteUploadSessionSizeMismatch() {
+	s.givenSessionPartsUploaded(1)
+	s.mockS3.DeleteFunc = func(ctx context.Context, key string) error {
+		return nil
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+	metadata := sessionMetadata
+	metadata.SizeInBytes = 99
+
+	err := store.CompleteUploadSession(context.Background(), "upload-id", metadata, files.Resumable{TotalChunks: 1})
+
+	s.ErrorIs(err, files.ErrSizeMismatch)
+	s.Equal([]string{"data/file.csv"}, s.deletedKeys())
+	s.Len(s.mockFiles.RegisterFileCalls(), 0)
+	s.Len(s.mockFiles.MarkFileUploadedWithChecksumCalls(), 0)
+}
+
+func (s *StoreSuite) TestCompleteUploadSessionRegistrationFails() {
+	s.givenSessionPartsUploaded(1)
+	s.mockFiles.RegisterFileFunc = func(ctx context.Context, metadata filesAPITypes.StoredRegisteredMetaData, headers filesSDK.Headers) error {
+		return &filesSDK.APIError{StatusCode: http.StatusConflict}
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	err := store.CompleteUploadSession(context.Background(), "upload-id", sessionMetadata, files.Resumable{TotalChunks: 1})
+
+	s.ErrorIs(err, filesAPI.ErrFileAlreadyRegistered)
+	s.Len(s.mockFiles.MarkFileUploadedWithChecksumCalls(), 0)
+}
+
+func (s *StoreSuite) TestCompleteUploadSessionWhoseContentDoesNotMatchItsType() {
+	s.givenSessionPartsUploaded(1)
+	s.mockS3.GetFunc = func(ctx context.Context, key string) (io.ReadCloser, *int64, error) {
+		return io.NopCloser(strings.NewReader("%PDF-1.7\n")), nil, nil
+	}
+	s.mockS3.DeleteFunc = func(ctx context.Context, key string) error {
+		return nil
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{UploadAllowedTypes: []string{"text/csv"}})
+
+	err := store.CompleteUploadSession(context.Background(), "upload-id", sessionMetadata, files.Resumable{TotalChunks: 1})
+
+	s.ErrorIs(err, files.ErrUnsupportedMediaType)
+	s.Require().Len(s.mockS3.DeleteCalls(), 1)
+	s.Equal("data/file.csv", s.mockS3.DeleteCalls()[0].Key)
+	s.Len(s.mockFiles.RegisterFileCalls(), 0)
+}
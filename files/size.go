@@ -0,0 +1,80 @@
+package files
+
+import (
+	"context"
+	"io"
+
+	"github.com/ONSdigital/log.go/v2/log"
+)
+
+// checkDeclaredSize rejects a file whose declared size is larger than the maximum, before any of it is stored. A
+// maximum of 0 allows files of any size.
+func checkDeclaredSize(ctx context.Context, path string, declared uint64, max int64) error {
+	if max > 0 && declared > uint64(max) {
+		log.Warn(ctx, "file is larger than the maximum file size", log.Data{
+			"path":          path,
+			"size_in_bytes": declared,
+			"max_file_size": max,
+		})
+		return ErrFileTooLarge
+	}
+	return nil
+}
+
+// checkCompletedSize compares the size of the file that has just been completed in S3 with its declared size,
+// deleting the file if they differ so that it can be uploaded again. A declared size of 0 is not checked.
+func (s Store) checkCompletedSize(ctx context.Context, path string, declared uint64, actual int64) error {
+	if declared == 0 || uint64(actual) == declared {
+		return nil
+	}
+
+	log.Warn(ctx, "completed file does not match its declared size", log.Data{"path": path, "size_in_bytes": declared, "content_length": actual})
+	s.discardCompletedFile(ctx, path)
+	return ErrSizeMismatch
+}
+
+// sizeLimitReader counts the bytes read through it and returns ErrSizeMismatch as soon as more than the limit have
+// been read, so that a chunk cannot be larger than the file it is part of
+type sizeLimitReader struct {
+	r     io.Reader
+	limit uint64
+	read  uint64
+}
+
+// newSizeLimitReader wraps the content so that reading more than the limit fails. A limit of 0 is not enforced.
+func newSizeLimitReader(content io.Reader, limit uint64) io.Reader {
+	if limit == 0 {
+		return content
+	}
+	return &sizeLimitReader{r: content, limit: limit}
+}
+
+func (l *sizeLimitReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.read += uint64(n)
+	if l.read > l.limit {
+		return n, ErrSizeMismatch
+	}
+	return n, err
+}
+
+// chunkSizeReader returns ErrSizeMismatch if the content is longer or shorter than the declared size of the chunk
+type chunkSizeReader struct {
+	r        io.Reader
+	declared int64
+	read     int64
+}
+
+// newChunkSizeReader wraps the content so that reading it fails unless it is exactly the declared size
+func newChunkSizeReader(content io.Reader, declared int64) io.Reader {
+	return &chunkSizeReader{r: content, declared: declared}
+}
+
+func (c *chunkSizeReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.read += int64(n)
+	if c.read > c.declared || (err == io.EOF && c.read != c.declared) {
+		return n, ErrSizeMismatch
+	}
+	return n, err
+}
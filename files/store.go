@@ -2,17 +2,24 @@ package files
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
+	"io"
 	"net/http"
+	"sort"
 	"strings"
+	"time"
 
 	filesAPI "github.com/ONSdigital/dp-api-clients-go/v2/files"
 	filesAPITypes "github.com/ONSdigital/dp-files-api/files"
 	filesSDK "github.com/ONSdigital/dp-files-api/sdk"
-	s3client "github.com/ONSdigital/dp-s3/v3"
-	"github.com/ONSdigital/dp-upload-service/aws"
+	filesAPIStore "github.com/ONSdigital/dp-files-api/store"
 	"github.com/ONSdigital/dp-upload-service/config"
+	"github.com/ONSdigital/dp-upload-service/mediatype"
+	"github.com/ONSdigital/dp-upload-service/scan"
+	"github.com/ONSdigital/dp-upload-service/storage"
 	"github.com/ONSdigital/log.go/v2/log"
+	"golang.org/x/sync/errgroup"
 )
 
 //go:generate moq -out mock/files.go -pkg mock_files . FilesClienter
@@ -24,10 +31,34 @@ var (
 	ErrS3Upload                 = errors.New("uploading part failed")
 	ErrS3Download               = errors.New("downloading file failed")
 	ErrS3Head                   = errors.New("getting file info failed")
+	ErrS3CheckPart              = errors.New("checking uploaded part failed")
+	ErrS3Abort                  = errors.New("aborting upload failed")
+	ErrS3ListParts              = errors.New("listing uploaded parts failed")
+	ErrUploadNotFound           = errors.New("no upload in progress for this path")
+	ErrUploadComplete           = errors.New("upload has already completed")
 	ErrChunkTooSmall            = errors.New("chunk size below minimum 5MB")
 	ErrFilesServer              = errors.New("file api returning internal server errors")
 	ErrFilesUnauthorised        = errors.New("authentication required")
 	ErrFilesForbidden           = errors.New("access forbidden")
+	ErrChecksumMismatch         = errors.New("chunk checksum does not match the content received")
+	ErrInvalidChecksum          = errors.New("chunk checksum is not a valid base64 encoded SHA256 or MD5 digest")
+	ErrFileChecksumMismatch     = errors.New("file checksum does not match the uploaded file")
+	ErrInvalidFileChecksum      = errors.New("file checksum is not a valid base64 encoded SHA256 digest")
+	ErrUploadNotComplete        = errors.New("upload has not completed")
+	ErrFileAlreadyPublished     = errors.New("file has already been published")
+	ErrFileNotPublishable       = errors.New("file is not publishable")
+	ErrInvalidTotalChunks       = errors.New("total chunks must be between 1 and 10000")
+	ErrUploadInProgress         = errors.New("an upload is already in progress for this path")
+	ErrPartsMissing             = errors.New("not every chunk has been uploaded")
+	ErrDirectUploadUnsupported  = errors.New("storage does not support uploading chunks directly")
+	ErrS3Presign                = errors.New("presigning chunk upload failed")
+	ErrUnsupportedMediaType     = errors.New("file type is not allowed or does not match the content of the file")
+	ErrScanFailed               = errors.New("scanning file for malware failed")
+	ErrQuarantine               = errors.New("moving infected file to quarantine failed")
+	ErrFileQuarantined          = errors.New("file contains malware and has been quarantined")
+	ErrFileTooLarge             = errors.New("file is larger than the maximum file size")
+	ErrSizeMismatch             = errors.New("size of file does not match its declared size")
+	ErrReservedPath             = errors.New("path is under a prefix reserved by the service")
 )
 
 // FileMetadataWithContentItem extends the files API metadata with content_item
@@ -46,20 +77,34 @@ type FilesClienter interface {
 	GetFile(ctx context.Context, path string, headers filesSDK.Headers) (*filesAPITypes.StoredRegisteredMetaData, error)
 	RegisterFile(ctx context.Context, metadata filesAPITypes.StoredRegisteredMetaData, headers filesSDK.Headers) error
 	MarkFilePublished(ctx context.Context, path string, headers filesSDK.Headers) error
-	MarkFileUploaded(ctx context.Context, path string, etag string, headers filesSDK.Headers) error
+	MarkFileUploadedWithChecksum(ctx context.Context, path, etag, checksum string, headers filesSDK.Headers) error
+	MarkFileQuarantined(ctx context.Context, path string, headers filesSDK.Headers) error
+	DeleteFile(ctx context.Context, path string, headers filesSDK.Headers) error
+	GetFilesMetadata(ctx context.Context, collectionID, bundleID string, headers filesSDK.Headers) ([]filesAPITypes.StoredRegisteredMetaData, error)
 }
 
 type Store struct {
-	files  FilesClienter
-	bucket *aws.Bucket
-	cfg    *config.Config
+	files         FilesClienter
+	bucket        *storage.Bucket
+	cfg           *config.Config
+	events        *Hub
+	public        *storage.Bucket
+	copies        *copyCache
+	scanner       scan.Scanner
+	mediaTypes    mediatype.Policy
+	verifications Queue
+	publicCopies  Queue
 }
 
 type Resumable struct {
-	FileName     string `schema:"resumableFilename"`
-	Type         string `schema:"resumableType"`
-	CurrentChunk int32  `schema:"resumableChunkNumber"`
-	TotalChunks  int    `schema:"resumableTotalChunks"`
+	FileName               string `schema:"resumableFilename"`
+	Type                   string `schema:"resumableType"`
+	CurrentChunk           int32  `schema:"resumableChunkNumber"`
+	CurrentChunkSize       int64  `schema:"resumableCurrentChunkSize"`
+	TotalChunks            int    `schema:"resumableTotalChunks"`
+	ChunkChecksum          string `schema:"resumableChunkChecksum"`
+	ChunkChecksumAlgorithm string `schema:"resumableChunkChecksumAlgorithm"`
+	FileChecksum           string `schema:"fileChecksum"`
 }
 
 type StatusMessage struct {
@@ -70,22 +115,133 @@ type StatusMessage struct {
 type Status struct {
 	Metadata    filesAPITypes.FileMetaData `json:"metadata"`
 	FileContent StatusMessage              `json:"file_content"`
+	Progress    *UploadProgress            `json:"progress,omitempty"`
+	PublicCopy  *PublicCopyStatus          `json:"public_copy,omitempty"`
+	Scan        *ScanStatus                `json:"scan,omitempty"`
 }
 
-func NewStore(files FilesClienter, bucket *aws.Bucket, cfg *config.Config) Store {
-	return Store{files, bucket, cfg}
+// BatchStatus is a page of the statuses of the files in a collection or bundle, along with the number of files in
+// each state across every page
+type BatchStatus struct {
+	Count      int            `json:"count"`
+	Limit      int            `json:"limit"`
+	Offset     int            `json:"offset"`
+	TotalCount int            `json:"total_count"`
+	States     map[string]int `json:"states"`
+	Items      []Status       `json:"items"`
+}
+
+// UploadProgress describes how much of an in progress upload has been received. The expected total number of chunks
+// is only known once the file has been registered or its last, shorter, chunk has arrived.
+type UploadProgress struct {
+	UploadID            string     `json:"upload_id"`
+	PartsReceived       int        `json:"parts_received"`
+	BytesReceived       int64      `json:"bytes_received"`
+	ExpectedTotalChunks int        `json:"expected_total_chunks,omitempty"`
+	LastPartReceivedAt  *time.Time `json:"last_part_received_at,omitempty"`
+}
+
+// UploadedPart describes a chunk of an in progress upload that has been received
+type UploadedPart struct {
+	ChunkNumber int32  `json:"chunk_number"`
+	SizeInBytes int64  `json:"size_in_bytes"`
+	ETag        string `json:"etag"`
+}
+
+// UploadedParts lists the chunks of an in progress upload that have been received so far
+type UploadedParts struct {
+	Path  string         `json:"path"`
+	Parts []UploadedPart `json:"parts"`
+}
+
+func NewStore(files FilesClienter, bucket *storage.Bucket, cfg *config.Config) Store {
+	return Store{files: files, bucket: bucket, cfg: cfg, mediaTypes: mediatype.NewPolicy(cfg.UploadAllowedTypes)}
+}
+
+// WithEvents returns a copy of the store that publishes the progress of each upload to the hub
+func (s Store) WithEvents(hub *Hub) Store {
+	s.events = hub
+	return s
 }
 
 func (s Store) Status(ctx context.Context, path string) (*Status, error) {
-	authToken := getAuthTokenFromContext(ctx, s.cfg)
+	authToken := getAuthTokenFromContext(ctx)
 
 	//metadata
 	storedMetadata, err := s.files.GetFile(ctx, path, filesSDK.Headers{Authorization: authToken})
 	if err != nil {
+		// files are only registered once every chunk has been received, so until then only S3 knows about the upload
+		if apiErr, ok := err.(*filesSDK.APIError); ok && apiErr.StatusCode == http.StatusNotFound {
+			if progress := s.uploadProgress(ctx, path, 0); progress != nil {
+				return &Status{
+					Metadata:    filesAPITypes.FileMetaData{Path: path},
+					FileContent: newStatusMessage(false, nil),
+					Progress:    progress,
+				}, nil
+			}
+		}
 		log.Error(ctx, "failed to get file metadata", err, log.Data{"path": path})
 		return nil, ErrFilesAPINotFound
 	}
 
+	return s.fileStatus(ctx, storedMetadata), nil
+}
+
+// CollectionStatus returns a page of the statuses of the files in the collection, ordered by path
+func (s Store) CollectionStatus(ctx context.Context, collectionID string, offset, limit int) (*BatchStatus, error) {
+	return s.batchStatus(ctx, collectionID, "", offset, limit)
+}
+
+// BundleStatus returns a page of the statuses of the files in the bundle, ordered by path
+func (s Store) BundleStatus(ctx context.Context, bundleID string, offset, limit int) (*BatchStatus, error) {
+	return s.batchStatus(ctx, "", bundleID, offset, limit)
+}
+
+// batchStatus lists the files in the collection or bundle from Files API, counting them by state, and checks the
+// content of the files on the requested page in S3 using a bounded number of concurrent requests
+func (s Store) batchStatus(ctx context.Context, collectionID, bundleID string, offset, limit int) (*BatchStatus, error) {
+	logData := log.Data{"collection_id": collectionID, "bundle_id": bundleID}
+	headers := filesSDK.Headers{Authorization: getAuthTokenFromContext(ctx)}
+
+	storedMetadata, err := s.files.GetFilesMetadata(ctx, collectionID, bundleID, headers)
+	if err != nil {
+		log.Error(ctx, "failed to list files metadata", err, logData)
+		return nil, mapFilesAPIError(err)
+	}
+
+	sort.Slice(storedMetadata, func(i, j int) bool {
+		return storedMetadata[i].Path < storedMetadata[j].Path
+	})
+
+	batch := &BatchStatus{
+		Limit:      limit,
+		Offset:     offset,
+		TotalCount: len(storedMetadata),
+		States:     make(map[string]int),
+	}
+	for _, metadata := range storedMetadata {
+		batch.States[metadata.State]++
+	}
+
+	page := storedMetadata[min(offset, len(storedMetadata)):min(offset+limit, len(storedMetadata))]
+	batch.Count = len(page)
+	batch.Items = make([]Status, len(page))
+
+	var checks errgroup.Group
+	checks.SetLimit(max(s.cfg.StatusCheckConcurrency, 1))
+	for i := range page {
+		checks.Go(func() error {
+			batch.Items[i] = *s.fileStatus(ctx, &page[i])
+			return nil
+		})
+	}
+	_ = checks.Wait()
+
+	return batch, nil
+}
+
+// fileStatus builds the status of a registered file, checking its content in S3 and the progress of any upload
+func (s Store) fileStatus(ctx context.Context, storedMetadata *filesAPITypes.StoredRegisteredMetaData) *Status {
 	metadata := filesAPITypes.FileMetaData{
 		Path:          storedMetadata.Path,
 		IsPublishable: storedMetadata.IsPublishable,
@@ -100,13 +256,66 @@ func (s Store) Status(ctx context.Context, path string) (*Status, error) {
 	}
 
 	//file content
-	head, err := s.bucket.Head(ctx, path)
-	fileContent := newStatusMessage(head != nil && head.ContentLength != nil && *head.ContentLength > 0, err)
+	head, err := s.bucket.Head(ctx, storedMetadata.Path)
+	fileContent := newStatusMessage(head.SizeInBytes > 0, err)
 
-	return &Status{
+	status := &Status{
 		Metadata:    metadata,
 		FileContent: fileContent,
-	}, nil
+		Progress:    s.uploadProgress(ctx, storedMetadata.Path, storedMetadata.SizeInBytes),
+		PublicCopy:  s.publicCopyStatus(ctx, storedMetadata),
+		Scan:        s.scanStatus(storedMetadata),
+	}
+
+	return status
+}
+
+// uploadProgress summarises the parts received so far by the in progress multipart upload for the path, returning nil
+// if there is no upload in progress or the parts cannot be listed
+func (s Store) uploadProgress(ctx context.Context, path string, sizeInBytes uint64) *UploadProgress {
+	upload, found, err := s.bucket.ListUploadedParts(ctx, path)
+	if err != nil {
+		log.Error(ctx, "failed to list uploaded parts in s3", err, log.Data{"path": path})
+		return nil
+	}
+	if !found {
+		return nil
+	}
+
+	progress := &UploadProgress{
+		UploadID:            upload.UploadID,
+		PartsReceived:       len(upload.Parts),
+		ExpectedTotalChunks: expectedTotalChunks(upload.Parts, sizeInBytes),
+	}
+	for _, part := range upload.Parts {
+		progress.BytesReceived += part.SizeInBytes
+		if progress.LastPartReceivedAt == nil || part.LastModified.After(*progress.LastPartReceivedAt) {
+			lastModified := part.LastModified
+			progress.LastPartReceivedAt = &lastModified
+		}
+	}
+
+	return progress
+}
+
+// expectedTotalChunks works out how many chunks the file is split into from its size, or from the last chunk if it
+// has arrived, as every chunk but the last is the same size as the first. It returns 0 if this is not yet known.
+func expectedTotalChunks(parts []storage.UploadedPart, sizeInBytes uint64) int {
+	if len(parts) == 0 || parts[0].PartNumber != 1 || parts[0].SizeInBytes <= 0 {
+		return 0
+	}
+
+	chunkSize := parts[0].SizeInBytes
+	if sizeInBytes > 0 {
+		return int((int64(sizeInBytes) + chunkSize - 1) / chunkSize)
+	}
+
+	for _, part := range parts[1:] {
+		if part.SizeInBytes < chunkSize {
+			return int(part.PartNumber)
+		}
+	}
+	return 0
 }
 
 // registerFileWithContentItem uses the dp-files-api SDK to register file with content_item
@@ -133,72 +342,426 @@ func (s Store) registerFileWithContentItem(ctx context.Context, metadata FileMet
 		ContentItem:   contentItem,
 	}
 
-	authToken := getAuthTokenFromContext(ctx, s.cfg)
+	authToken := getAuthTokenFromContext(ctx)
 	err := s.files.RegisterFile(ctx, storedMetadata, filesSDK.Headers{Authorization: authToken})
 
-	if err != nil {
-		if apiErr, ok := err.(*filesSDK.APIError); ok {
-			switch apiErr.StatusCode {
-			case http.StatusConflict:
-				return filesAPI.ErrFileAlreadyRegistered
-			case http.StatusBadRequest:
-				return ErrFileAPICreateInvalidData
-			case http.StatusUnauthorized:
-				return ErrFilesUnauthorised
-			case http.StatusForbidden:
-				return ErrFilesForbidden
-			case http.StatusInternalServerError:
-				return ErrFilesServer
-			}
+	if apiErr, ok := err.(*filesSDK.APIError); ok {
+		switch apiErr.StatusCode {
+		case http.StatusConflict:
+			return filesAPI.ErrFileAlreadyRegistered
+		case http.StatusBadRequest:
+			return ErrFileAPICreateInvalidData
+		}
+	}
+
+	return mapFilesAPIError(err)
+}
+
+// mapFilesAPIError converts the errors common to all Files API requests into the matching Store error
+func mapFilesAPIError(err error) error {
+	if apiErr, ok := err.(*filesSDK.APIError); ok {
+		switch apiErr.StatusCode {
+		case http.StatusUnauthorized:
+			return ErrFilesUnauthorised
+		case http.StatusForbidden:
+			return ErrFilesForbidden
+		case http.StatusInternalServerError:
+			return ErrFilesServer
 		}
 	}
 
 	return err
 }
 
-func (s Store) UploadFile(ctx context.Context, metadata FileMetadataWithContentItem, resumable Resumable, content []byte) (bool, error) {
+func (s Store) UploadFile(ctx context.Context, metadata FileMetadataWithContentItem, resumable Resumable, content io.Reader) (bool, error) {
+	uploaded, err := s.uploadFile(ctx, metadata, resumable, content)
+	if err != nil {
+		s.events.Publish(UploadEvent{
+			Type:        EventFailed,
+			Path:        metadata.Path,
+			ChunkNumber: resumable.CurrentChunk,
+			TotalChunks: resumable.TotalChunks,
+			Error:       err.Error(),
+		})
+	}
+
+	return uploaded, err
+}
+
+func (s Store) uploadFile(ctx context.Context, metadata FileMetadataWithContentItem, resumable Resumable, content io.Reader) (bool, error) {
 	baseMetadata := metadata.FileMetaData
-	authToken := getAuthTokenFromContext(ctx, s.cfg)
+
+	if err := s.checkPathNotReserved(ctx, baseMetadata.Path); err != nil {
+		return false, err
+	}
+	if !s.mediaTypes.Allows(baseMetadata.Type) {
+		log.Warn(ctx, "file type is not allowed", log.Data{"path": baseMetadata.Path, "type": baseMetadata.Type})
+		return false, ErrUnsupportedMediaType
+	}
+
+	if err := checkDeclaredSize(ctx, baseMetadata.Path, baseMetadata.SizeInBytes, s.cfg.MaxUploadFileSize); err != nil {
+		return false, err
+	}
+
+	if resumable.FileChecksum != "" {
+		if err := validateFileChecksum(resumable.FileChecksum); err != nil {
+			log.Error(ctx, "invalid file checksum", err, log.Data{"path": baseMetadata.Path})
+			return false, err
+		}
+	}
+
+	// the type of the file is sniffed from the start of its first chunk, before the chunk is stored
+	if resumable.CurrentChunk == 1 && s.mediaTypes.SniffsContent(baseMetadata.Type) {
+		head, sniffed, err := mediatype.Sniff(content)
+		if err != nil {
+			log.Error(ctx, "failed to read start of chunk", err, log.Data{"path": baseMetadata.Path})
+			return false, err
+		}
+		if !mediatype.Matches(baseMetadata.Type, head) {
+			log.Warn(ctx, "file content does not match its type", log.Data{"path": baseMetadata.Path, "type": baseMetadata.Type})
+			return false, ErrUnsupportedMediaType
+		}
+		content = sniffed
+	}
+
+	// no chunk can be larger than the whole file, so reading past its declared size fails as soon as it happens, and a
+	// chunk whose size is declared is streamed to storage with that length, so it must be exactly that long
+	content = newSizeLimitReader(content, baseMetadata.SizeInBytes)
+	if resumable.CurrentChunkSize > 0 {
+		content = newChunkSizeReader(content, resumable.CurrentChunkSize)
+	}
+
+	if resumable.ChunkChecksum != "" {
+		verified, err := newChecksumReader(content, resumable.ChunkChecksumAlgorithm, resumable.ChunkChecksum)
+		if err != nil {
+			log.Error(ctx, "invalid chunk checksum", err, log.Data{"algorithm": resumable.ChunkChecksumAlgorithm})
+			return false, err
+		}
+		content = verified
+	}
+
+	// the checksum of the whole file is built up as the chunks are streamed to S3, whenever they arrive in order
+	running := s.resumeChecksum(ctx, baseMetadata.Path, resumable)
+	if running != nil {
+		content = io.TeeReader(content, running)
+	}
 
 	part := generateUploadPart(baseMetadata, resumable)
 	response, err := s.bucket.UploadPart(ctx, part, content)
 	if err != nil {
 		log.Error(ctx, "failed to write chunk to s3", err, log.Data{"s3-upload-part": part})
-		if _, ok := err.(*s3client.ErrChunkTooSmall); ok {
+		if errors.Is(err, storage.ErrPartTooSmall) {
 			return false, ErrChunkTooSmall
 		}
+		if errors.Is(err, storage.ErrAlreadyUploaded) {
+			// a first chunk starts a new upload of a file that is already stored, rather than continuing one
+			if resumable.CurrentChunk == 1 {
+				return false, filesAPI.ErrFileAlreadyRegistered
+			}
+			return false, ErrUploadComplete
+		}
+		if errors.Is(err, ErrChecksumMismatch) {
+			return false, ErrChecksumMismatch
+		}
+		if errors.Is(err, ErrSizeMismatch) {
+			return false, ErrSizeMismatch
+		}
 		return false, ErrS3Upload
 	}
+	s.events.Publish(UploadEvent{
+		Type:        EventChunkReceived,
+		Path:        baseMetadata.Path,
+		ChunkNumber: resumable.CurrentChunk,
+		TotalChunks: resumable.TotalChunks,
+	})
 
-	if response.AllPartsUploaded {
-		if err = s.registerFileWithContentItem(ctx, metadata); err != nil {
-			log.Error(ctx, "failed to register file metadata with dp-files-api", err, log.Data{"metadata": metadata})
-			return false, err
+	if !response.AllPartsUploaded {
+		if resumable.CurrentChunk == 1 {
+			s.recordUploadOwner(ctx, baseMetadata)
+		}
+		if running != nil {
+			s.recordRunningChecksum(ctx, baseMetadata.Path, resumable.CurrentChunk, running)
 		}
+		return false, nil
+	}
 
-		head, err := s.bucket.Head(ctx, baseMetadata.Path)
-		if err != nil {
-			log.Error(ctx, "failed to get completed file info from s3", err, log.Data{"key": baseMetadata.Path})
-			return false, ErrS3Head
+	// the running checksum only covers the whole file if the chunk that completed it was the last one
+	var checksum string
+	if running != nil && int(resumable.CurrentChunk) == resumable.TotalChunks {
+		checksum = base64.StdEncoding.EncodeToString(running.Sum(nil))
+	}
+	if resumable.CurrentChunk > 1 && resumable.TotalChunks > 0 {
+		s.removeRunningChecksum(ctx, baseMetadata.Path)
+	}
+	// the owner of the upload was recorded with its first chunk, unless that completed it
+	if resumable.CurrentChunk > 1 {
+		defer s.removeUploadOwner(ctx, baseMetadata.Path)
+	}
+	return s.completeFile(ctx, metadata, resumable.FileChecksum, checksum)
+}
+
+// completeFile checks the file that has just been completed in S3 against its declared size, records that it needs to
+// be verified against the checksum sent for the whole file, if there was one, then registers it with Files API. Once
+// it has been verified, in the background if there is a verification queue, it is marked as uploaded. The checksum is
+// the one calculated as the chunks were received, or empty if it has to be calculated from the completed file. It
+// reports whether the file was registered and found in S3, even if verifying it failed.
+func (s Store) completeFile(ctx context.Context, metadata FileMetadataWithContentItem, fileChecksum, checksum string) (bool, error) {
+	baseMetadata := metadata.FileMetaData
+
+	head, err := s.bucket.Head(ctx, baseMetadata.Path)
+	if err != nil {
+		log.Error(ctx, "failed to get completed file info from s3", err, log.Data{"key": baseMetadata.Path})
+		return false, ErrS3Head
+	}
+	if head.ETag == "" {
+		log.Error(ctx, "failed to get completed file etag from s3", err, log.Data{"key": baseMetadata.Path})
+		return false, ErrS3Head
+	}
+	if err := s.checkCompletedSize(ctx, baseMetadata.Path, baseMetadata.SizeInBytes, head.SizeInBytes); err != nil {
+		return false, err
+	}
+
+	// the verification is recorded before the file is registered, so that a file is never left registered without it
+	record := verification{ETag: head.ETag, FileChecksum: fileChecksum, Checksum: checksum}
+	if err := s.recordVerification(ctx, baseMetadata.Path, record); err != nil {
+		s.discardCompletedFile(ctx, baseMetadata.Path)
+		return false, err
+	}
+
+	if err = s.registerFileWithContentItem(ctx, metadata); err != nil {
+		log.Error(ctx, "failed to register file metadata with dp-files-api", err, log.Data{"metadata": metadata})
+		if !errors.Is(err, filesAPI.ErrFileAlreadyRegistered) {
+			s.discardCompletedFile(ctx, baseMetadata.Path)
 		}
-		if head.ETag == nil {
-			log.Error(ctx, "failed to get completed file etag from s3", err, log.Data{"key": baseMetadata.Path})
-			return false, ErrS3Head
+		s.removeVerification(ctx, baseMetadata.Path)
+		return false, err
+	}
+	s.events.Publish(UploadEvent{Type: EventRegistered, Path: baseMetadata.Path})
+
+	if err := s.verify(ctx, baseMetadata.Path, record); err != nil {
+		return true, err
+	}
+
+	return true, nil
+}
+
+// discardCompletedFile deletes a file that has been completed in S3 but can't be registered, so that it can be
+// uploaded again rather than having its chunks rejected as belonging to an upload that has already completed
+func (s Store) discardCompletedFile(ctx context.Context, path string) {
+	if err := s.bucket.Delete(ctx, path); err != nil {
+		log.Error(ctx, "failed to delete completed file from s3", err, log.Data{"path": path})
+	}
+}
+
+// ChunkUploaded reports whether the chunk described by the resumable fields has already been uploaded, so that an
+// interrupted upload can be resumed without sending it again
+func (s Store) ChunkUploaded(ctx context.Context, metadata FileMetadataWithContentItem, resumable Resumable) (bool, error) {
+	part := generateUploadPart(metadata.FileMetaData, resumable)
+	uploaded, err := s.bucket.PartExists(ctx, part)
+	if err != nil {
+		log.Error(ctx, "failed to check whether chunk has been uploaded to s3", err, log.Data{"s3-upload-part": part})
+		return false, ErrS3CheckPart
+	}
+
+	return uploaded, nil
+}
+
+// UploadedParts lists the chunks received so far for the in progress upload of the file path, so that a client can
+// resume an interrupted upload from the chunks that are missing
+func (s Store) UploadedParts(ctx context.Context, path string) (*UploadedParts, error) {
+	logData := log.Data{"path": path}
+
+	upload, found, err := s.bucket.ListUploadedParts(ctx, path)
+	if err != nil {
+		log.Error(ctx, "failed to list uploaded parts in s3", err, logData)
+		return nil, ErrS3ListParts
+	}
+
+	if !found {
+		// files are only registered once every chunk has been received, so a registered file has finished uploading
+		headers := filesSDK.Headers{Authorization: getAuthTokenFromContext(ctx)}
+		if _, err := s.files.GetFile(ctx, path, headers); err != nil {
+			if apiErr, ok := err.(*filesSDK.APIError); ok && apiErr.StatusCode == http.StatusNotFound {
+				return nil, ErrUploadNotFound
+			}
+			log.Error(ctx, "failed to get file metadata", err, logData)
+			return nil, mapFilesAPIError(err)
 		}
+		return nil, ErrUploadComplete
+	}
 
-		return true, s.files.MarkFileUploaded(ctx, baseMetadata.Path, strings.Trim(*head.ETag, "\""), filesSDK.Headers{Authorization: authToken})
+	uploaded := &UploadedParts{Path: path, Parts: make([]UploadedPart, 0, len(upload.Parts))}
+	for _, part := range upload.Parts {
+		uploaded.Parts = append(uploaded.Parts, UploadedPart{
+			ChunkNumber: part.PartNumber,
+			SizeInBytes: part.SizeInBytes,
+			ETag:        part.ETag,
+		})
 	}
 
-	return false, nil
+	return uploaded, nil
 }
 
-func generateUploadPart(metadata filesAPI.FileMetaData, resumable Resumable) *s3client.UploadPartRequest {
-	return &s3client.UploadPartRequest{
-		UploadKey:   metadata.Path,
-		Type:        resumable.Type,
-		ChunkNumber: resumable.CurrentChunk,
-		TotalChunks: resumable.TotalChunks,
+// AbortUpload abandons an in progress upload, aborting the multipart upload in S3 and removing the file from Files API
+// if it had been registered. Uploads that have completed cannot be aborted. A registered file that is still being
+// verified is deleted from the bucket before it is removed from Files API, and its verification is only removed after
+// that, so that if the service stops part way through the file is cleaned up when its verification is retried.
+func (s Store) AbortUpload(ctx context.Context, path string) error {
+	headers := filesSDK.Headers{Authorization: getAuthTokenFromContext(ctx)}
+	logData := log.Data{"path": path}
+
+	registered := true
+	storedMetadata, err := s.files.GetFile(ctx, path, headers)
+	if err != nil {
+		if apiErr, ok := err.(*filesSDK.APIError); !ok || apiErr.StatusCode != http.StatusNotFound {
+			log.Error(ctx, "failed to get file metadata", err, logData)
+			return mapFilesAPIError(err)
+		}
+		registered = false
+	} else if storedMetadata.State != filesAPIStore.StateCreated {
+		log.Warn(ctx, "attempted to abort an upload that has completed", log.Data{"path": path, "state": storedMetadata.State})
+		return ErrUploadComplete
+	}
+
+	aborted, err := s.bucket.AbortMultipartUpload(ctx, path)
+	if err != nil {
+		log.Error(ctx, "failed to abort multipart upload in s3", err, logData)
+		return ErrS3Abort
+	}
+	s.RemoveUploadRecords(ctx, path)
+
+	if registered {
+		if err := s.bucket.Delete(ctx, path); err != nil {
+			log.Error(ctx, "failed to delete abandoned file from s3", err, logData)
+			return ErrS3Abort
+		}
+		if err := s.files.DeleteFile(ctx, path, headers); err != nil {
+			log.Error(ctx, "failed to remove abandoned file from dp-files-api", err, logData)
+			return mapFilesAPIError(err)
+		}
+		s.removeVerification(ctx, path)
+	}
+
+	if !aborted && !registered {
+		return ErrUploadNotFound
+	}
+
+	log.Info(ctx, "upload aborted", logData)
+	return nil
+}
+
+// PublishFile marks the file at the path as published in Files API, once it has been checked that the file has been
+// uploaded and the complete object is in S3. If there is a public bucket, the file is then copied to it in the
+// background, and publishing a file that has already been published copies it again if the copy is missing.
+func (s Store) PublishFile(ctx context.Context, path string) error {
+	headers := filesSDK.Headers{Authorization: getAuthTokenFromContext(ctx)}
+	logData := log.Data{"path": path}
+
+	storedMetadata, err := s.files.GetFile(ctx, path, headers)
+	if err != nil {
+		log.Error(ctx, "failed to get file metadata", err, logData)
+		if apiErr, ok := err.(*filesSDK.APIError); ok && apiErr.StatusCode == http.StatusNotFound {
+			return ErrFilesAPINotFound
+		}
+		return mapFilesAPIError(err)
+	}
+
+	logData["state"] = storedMetadata.State
+	switch storedMetadata.State {
+	case filesAPIStore.StateUploaded:
+	case filesAPIStore.StatePublished:
+		if copyStatus := s.publicCopyStatus(ctx, storedMetadata); copyStatus != nil && copyStatus.State == CopyStateNotCopied {
+			log.Info(ctx, "copying file that has already been published to public bucket", logData)
+			s.copyPublishedFile(ctx, path)
+			return nil
+		}
+		log.Warn(ctx, "attempted to publish a file that has already been published", logData)
+		return ErrFileAlreadyPublished
+	case filesAPIStore.StateMoved:
+		log.Warn(ctx, "attempted to publish a file that has already been published", logData)
+		return ErrFileAlreadyPublished
+	default:
+		log.Warn(ctx, "attempted to publish a file that has not been uploaded", logData)
+		return ErrUploadNotComplete
+	}
+	if !storedMetadata.IsPublishable {
+		log.Warn(ctx, "attempted to publish a file that is not publishable", logData)
+		return ErrFileNotPublishable
+	}
+
+	if err := s.checkUploadComplete(ctx, storedMetadata); err != nil {
+		return err
+	}
+
+	if err := s.files.MarkFilePublished(ctx, path, headers); err != nil {
+		log.Error(ctx, "failed to mark file as published in dp-files-api", err, logData)
+		if apiErr, ok := err.(*filesSDK.APIError); ok && apiErr.StatusCode == http.StatusNotFound {
+			return ErrFilesAPINotFound
+		}
+		return mapFilesAPIError(err)
+	}
+
+	log.Info(ctx, "file published", logData)
+	if s.public != nil {
+		s.copyPublishedFile(ctx, path)
+	}
+
+	return nil
+}
+
+// checkUploadComplete checks that the object for the file is in S3, that no multipart upload for it is still in
+// progress and that it is the size the file was registered with
+func (s Store) checkUploadComplete(ctx context.Context, storedMetadata *filesAPITypes.StoredRegisteredMetaData) error {
+	logData := log.Data{"path": storedMetadata.Path}
+
+	_, inProgress, err := s.bucket.ListUploadedParts(ctx, storedMetadata.Path)
+	if err != nil {
+		log.Error(ctx, "failed to list uploaded parts in s3", err, logData)
+		return ErrS3ListParts
+	}
+	if inProgress {
+		log.Warn(ctx, "attempted to publish a file whose multipart upload is still in progress", logData)
+		return ErrUploadNotComplete
+	}
+
+	head, err := s.bucket.Head(ctx, storedMetadata.Path)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			log.Warn(ctx, "attempted to publish a file that is not in s3", logData)
+			return ErrUploadNotComplete
+		}
+		log.Error(ctx, "failed to get file info from s3", err, logData)
+		return ErrS3Head
+	}
+
+	if storedMetadata.SizeInBytes > 0 && uint64(head.SizeInBytes) != storedMetadata.SizeInBytes {
+		logData["size_in_bytes"] = storedMetadata.SizeInBytes
+		logData["content_length"] = head.SizeInBytes
+		log.Warn(ctx, "attempted to publish a file that does not match its registered size", logData)
+		return ErrUploadNotComplete
+	}
+
+	return nil
+}
+
+// checkPathNotReserved checks that the path is not under a prefix that the service keeps its records, or quarantined
+// files, under, so that uploading a file cannot replace them
+func (s Store) checkPathNotReserved(ctx context.Context, path string) error {
+	if storage.IsReserved(path, s.cfg.QuarantinePrefix) {
+		log.Warn(ctx, "attempted to upload to a reserved path", log.Data{"path": path})
+		return ErrReservedPath
+	}
+	return nil
+}
+
+func generateUploadPart(metadata filesAPI.FileMetaData, resumable Resumable) *storage.PartRequest {
+	return &storage.PartRequest{
+		Key:         metadata.Path,
+		ContentType: resumable.Type,
+		PartNumber:  resumable.CurrentChunk,
+		TotalParts:  resumable.TotalChunks,
 		FileName:    resumable.FileName,
+		Size:        resumable.CurrentChunkSize,
 	}
 }
 
@@ -212,9 +775,11 @@ func newStatusMessage(val bool, err error) StatusMessage {
 	return msg
 }
 
-func getAuthTokenFromContext(ctx context.Context, cfg *config.Config) string {
-	if authStr, ok := ctx.Value(config.AuthContextKey).(string); ok && authStr != "" {
+// getAuthTokenFromContext returns the caller's auth token, which is sent on to Files API so that it checks the
+// caller's permissions. If the caller didn't send one, no token is sent rather than the service's own.
+func getAuthTokenFromContext(ctx context.Context) string {
+	if authStr, ok := ctx.Value(config.AuthContextKey).(string); ok {
 		return strings.TrimPrefix(authStr, "Bearer ")
 	}
-	return cfg.ServiceAuthToken
+	return ""
 }
@@ -1,13 +1,22 @@
 package files_test
 
 import (
+	"bytes"
 	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
 	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
-	"github.com/ONSdigital/dp-upload-service/aws"
 	"github.com/ONSdigital/dp-upload-service/config"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/ONSdigital/dp-upload-service/filesystem"
+	"github.com/ONSdigital/dp-upload-service/storage"
 
 	"github.com/stretchr/testify/suite"
 
@@ -15,23 +24,24 @@ import (
 	filesAPITypes "github.com/ONSdigital/dp-files-api/files"
 	filesSDK "github.com/ONSdigital/dp-files-api/sdk"
 	s3client "github.com/ONSdigital/dp-s3/v3"
-	mock_aws "github.com/ONSdigital/dp-upload-service/aws/mock"
 	"github.com/ONSdigital/dp-upload-service/files"
 	mock_files "github.com/ONSdigital/dp-upload-service/files/mock"
+	mock_storage "github.com/ONSdigital/dp-upload-service/storage/mock"
 )
 
 var (
 	firstResumable = files.Resumable{CurrentChunk: 1}
 	lastResumable  = files.Resumable{CurrentChunk: 2}
-	content        = []byte("CONTENT")
+	content        = bytes.NewReader([]byte("CONTENT"))
+	textMetadata   = files.FileMetadataWithContentItem{FileMetaData: filesAPI.FileMetaData{Type: "text/plain"}}
 )
 
 type StoreSuite struct {
 	suite.Suite
 
-	mockS3    *mock_aws.S3ClienterMock
+	mockS3    *mock_storage.DriverMock
 	mockFiles *mock_files.FilesClienterMock
-	bucket    *aws.Bucket
+	bucket    *storage.Bucket
 }
 
 func TestStore(t *testing.T) {
@@ -44,25 +54,53 @@ func (s *StoreSuite) SetupTest() {
 		RegisterFileFunc: func(ctx context.Context, metadata filesAPITypes.StoredRegisteredMetaData, headers filesSDK.Headers) error {
 			return nil
 		},
-		MarkFileUploadedFunc: func(ctx context.Context, path string, etag string, headers filesSDK.Headers) error {
+		MarkFileUploadedWithChecksumFunc: func(ctx context.Context, path, etag, checksum string, headers filesSDK.Headers) error {
+			return nil
+		},
+		MarkFileQuarantinedFunc: func(ctx context.Context, path string, headers filesSDK.Headers) error {
 			return nil
 		},
 		GetFileFunc: func(ctx context.Context, path string, headers filesSDK.Headers) (*filesAPITypes.StoredRegisteredMetaData, error) {
 			return &filesAPITypes.StoredRegisteredMetaData{Path: path}, nil
 		},
+		DeleteFileFunc: func(ctx context.Context, path string, headers filesSDK.Headers) error {
+			return nil
+		},
 	}
 
-	s.mockS3 = &mock_aws.S3ClienterMock{
-		HeadFunc: func(ctx context.Context, key string) (*s3.HeadObjectOutput, error) {
-			size := int64(100)
-			etag := "head-object-etag"
-			return &s3.HeadObjectOutput{ContentLength: &size, ETag: &etag}, nil
+	s.mockS3 = &mock_storage.DriverMock{
+		HeadFunc: func(ctx context.Context, key string) (storage.ObjectInfo, error) {
+			return storage.ObjectInfo{SizeInBytes: 100, ETag: "head-object-etag"}, nil
+		},
+		UploadPartFunc: func(ctx context.Context, req *storage.PartRequest, payload io.Reader) (storage.PartResponse, error) {
+			return storage.PartResponse{ETag: "uploaded-part-etag", AllPartsUploaded: true}, nil
+		},
+		GetFunc: func(ctx context.Context, key string) (io.ReadCloser, *int64, error) {
+			return io.NopCloser(strings.NewReader("CONTENT")), nil, nil
+		},
+		ListUploadedPartsFunc: func(ctx context.Context, key string) (storage.MultipartUploadParts, bool, error) {
+			return storage.MultipartUploadParts{}, false, nil
+		},
+		DeleteFunc: func(ctx context.Context, key string) error {
+			return nil
 		},
-		UploadPartFunc: func(ctx context.Context, req *s3client.UploadPartRequest, payload []byte) (s3client.MultipartUploadResponse, error) {
-			return s3client.MultipartUploadResponse{Etag: "uploaded-part-etag", AllPartsUploaded: true}, nil
+		PutFunc: func(ctx context.Context, key, contentType string, content io.Reader) error {
+			return nil
 		},
 	}
-	s.bucket = aws.NewBucket("region", "name", s.mockS3)
+	s.bucket = storage.NewBucket("name", s.mockS3)
+}
+
+// deletedKeys returns the keys deleted from the bucket, other than the owner recorded for an upload in progress, which
+// is removed whenever an upload of more than one chunk is completed
+func (s *StoreSuite) deletedKeys() []string {
+	keys := []string{}
+	for _, call := range s.mockS3.DeleteCalls() {
+		if !strings.HasPrefix(call.Key, ".upload-owners/") {
+			keys = append(keys, call.Key)
+		}
+	}
+	return keys
 }
 
 // Upload
@@ -76,6 +114,44 @@ func (s *StoreSuite) TestFileUploadIsRegisteredWithFilesApi() {
 	s.Len(s.mockFiles.RegisterFileCalls(), 1)
 }
 
+func (s *StoreSuite) TestFileUploadSendsCallersTokenToFilesApi() {
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{ServiceAuthToken: "service-token"})
+	ctx := context.WithValue(context.Background(), config.AuthContextKey, "Bearer user-token")
+
+	_, err := store.UploadFile(ctx, files.FileMetadataWithContentItem{
+		FileMetaData: filesAPI.FileMetaData{},
+	}, lastResumable, content)
+	s.NoError(err)
+	s.Equal("user-token", s.mockFiles.RegisterFileCalls()[0].Headers.Authorization)
+	s.Equal("user-token", s.mockFiles.MarkFileUploadedWithChecksumCalls()[0].Headers.Authorization)
+}
+
+func (s *StoreSuite) TestFileUploadWithoutATokenDoesNotSendTheServiceToken() {
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{ServiceAuthToken: "service-token"})
+	ctx := context.WithValue(context.Background(), config.AuthContextKey, "")
+
+	_, err := store.UploadFile(ctx, files.FileMetadataWithContentItem{
+		FileMetaData: filesAPI.FileMetaData{},
+	}, lastResumable, content)
+	s.NoError(err)
+	s.Empty(s.mockFiles.RegisterFileCalls()[0].Headers.Authorization)
+	s.Empty(s.mockFiles.MarkFileUploadedWithChecksumCalls()[0].Headers.Authorization)
+}
+
+func (s *StoreSuite) TestFileUploadUnderReservedPrefixIsRejected() {
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{QuarantinePrefix: "quarantine/"})
+
+	for _, path := range []string{"quarantine/data/file.csv", ".verifications/data/file.csv", "data/../.owners/file.csv"} {
+		metadata := files.FileMetadataWithContentItem{FileMetaData: filesAPI.FileMetaData{Path: path, Type: "text/plain"}}
+
+		_, err := store.UploadFile(context.Background(), metadata, firstResumable, content)
+
+		s.ErrorIs(err, files.ErrReservedPath, path)
+	}
+	s.Len(s.mockFiles.RegisterFileCalls(), 0)
+	s.Len(s.mockS3.UploadPartCalls(), 0)
+}
+
 func (s *StoreSuite) TestFileRegistrationFailsWithFilesApi() {
 	expectedError := errors.New("registration error")
 	s.mockFiles.RegisterFileFunc = func(ctx context.Context, metadata filesAPITypes.StoredRegisteredMetaData, headers filesSDK.Headers) error {
@@ -84,15 +160,29 @@ func (s *StoreSuite) TestFileRegistrationFailsWithFilesApi() {
 
 	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
 
-	_, err := store.UploadFile(context.Background(), files.FileMetadataWithContentItem{
-		FileMetaData: filesAPI.FileMetaData{},
-	}, firstResumable, content)
+	_, err := store.UploadFile(context.Background(), textMetadata, firstResumable, content)
 	s.Equal(expectedError, err)
+	s.Require().Len(s.mockS3.DeleteCalls(), 2)
+	s.Equal(textMetadata.Path, s.mockS3.DeleteCalls()[0].Key)
+	s.Equal(".verifications/"+textMetadata.Path, s.mockS3.DeleteCalls()[1].Key)
+}
+
+func (s *StoreSuite) TestDuplicateFileIsNotDeleted() {
+	s.mockFiles.RegisterFileFunc = func(ctx context.Context, metadata filesAPITypes.StoredRegisteredMetaData, headers filesSDK.Headers) error {
+		return filesAPI.ErrFileAlreadyRegistered
+	}
+
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	_, err := store.UploadFile(context.Background(), textMetadata, firstResumable, content)
+	s.ErrorIs(err, filesAPI.ErrFileAlreadyRegistered)
+	s.Require().Len(s.mockS3.DeleteCalls(), 1)
+	s.Equal(".verifications/"+textMetadata.Path, s.mockS3.DeleteCalls()[0].Key)
 }
 
 func (s *StoreSuite) TestUploadPartReturnsAnError() {
-	s.mockS3.UploadPartFunc = func(ctx context.Context, req *s3client.UploadPartRequest, payload []byte) (s3client.MultipartUploadResponse, error) {
-		return s3client.MultipartUploadResponse{}, s3client.NewError(errors.New("broken"), nil)
+	s.mockS3.UploadPartFunc = func(ctx context.Context, req *storage.PartRequest, payload io.Reader) (storage.PartResponse, error) {
+		return storage.PartResponse{}, s3client.NewError(errors.New("broken"), nil)
 	}
 
 	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
@@ -104,8 +194,8 @@ func (s *StoreSuite) TestUploadPartReturnsAnError() {
 }
 
 func (s *StoreSuite) TestUploadChunkTooSmallReturnsErrChuckTooSmall() {
-	s.mockS3.UploadPartFunc = func(ctx context.Context, req *s3client.UploadPartRequest, payload []byte) (s3client.MultipartUploadResponse, error) {
-		return s3client.MultipartUploadResponse{}, s3client.NewChunkTooSmallError(errors.New("chunk size below minimum 5MB"), nil)
+	s.mockS3.UploadPartFunc = func(ctx context.Context, req *storage.PartRequest, payload io.Reader) (storage.PartResponse, error) {
+		return storage.PartResponse{}, s3client.NewError(storage.ErrPartTooSmall, nil)
 	}
 
 	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
@@ -117,8 +207,8 @@ func (s *StoreSuite) TestUploadChunkTooSmallReturnsErrChuckTooSmall() {
 }
 
 func (s *StoreSuite) TestHeadReturnsAnError() {
-	s.mockS3.HeadFunc = func(ctx context.Context, key string) (*s3.HeadObjectOutput, error) {
-		return nil, errors.New("head error")
+	s.mockS3.HeadFunc = func(ctx context.Context, key string) (storage.ObjectInfo, error) {
+		return storage.ObjectInfo{}, errors.New("head error")
 	}
 
 	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
@@ -130,9 +220,8 @@ func (s *StoreSuite) TestHeadReturnsAnError() {
 }
 
 func (s *StoreSuite) TestHeadReturnsNoEtag() {
-	s.mockS3.HeadFunc = func(ctx context.Context, key string) (*s3.HeadObjectOutput, error) {
-		size := int64(100)
-		return &s3.HeadObjectOutput{ContentLength: &size}, nil
+	s.mockS3.HeadFunc = func(ctx context.Context, key string) (storage.ObjectInfo, error) {
+		return storage.ObjectInfo{SizeInBytes: 100}, nil
 	}
 
 	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
@@ -145,7 +234,7 @@ func (s *StoreSuite) TestHeadReturnsNoEtag() {
 
 func (s *StoreSuite) TestErrorMarkingAsUploaded() {
 	expectedError := errors.New("marking error")
-	s.mockFiles.MarkFileUploadedFunc = func(ctx context.Context, path string, etag string, headers filesSDK.Headers) error {
+	s.mockFiles.MarkFileUploadedWithChecksumFunc = func(ctx context.Context, path, etag, checksum string, headers filesSDK.Headers) error {
 		return expectedError
 	}
 	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
@@ -156,8 +245,170 @@ func (s *StoreSuite) TestErrorMarkingAsUploaded() {
 	s.Equal(expectedError, err)
 }
 
+func (s *StoreSuite) TestUploadPublishesEvents() {
+	hub := files.NewHub()
+	events, unsubscribe := hub.Subscribe("data/file.csv")
+	defer unsubscribe()
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{}).WithEvents(hub)
+
+	_, err := store.UploadFile(context.Background(), files.FileMetadataWithContentItem{
+		FileMetaData: filesAPI.FileMetaData{Path: "data/file.csv"},
+	}, files.Resumable{CurrentChunk: 2, TotalChunks: 2}, content)
+	s.NoError(err)
+
+	published := receivedEvents(events)
+	s.Require().Len(published, 3)
+	s.Equal(files.EventChunkReceived, published[0].Type)
+	s.Equal("data/file.csv", published[0].Path)
+	s.Equal(int32(2), published[0].ChunkNumber)
+	s.Equal(2, published[0].TotalChunks)
+	s.Equal(files.EventRegistered, published[1].Type)
+	s.Equal(files.EventUploaded, published[2].Type)
+}
+
+func (s *StoreSuite) TestUploadPublishesFailedEvent() {
+	s.mockFiles.MarkFileUploadedWithChecksumFunc = func(ctx context.Context, path, etag, checksum string, headers filesSDK.Headers) error {
+		return errors.New("marking error")
+	}
+	hub := files.NewHub()
+	events, unsubscribe := hub.Subscribe("data/file.csv")
+	defer unsubscribe()
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{}).WithEvents(hub)
+
+	_, err := store.UploadFile(context.Background(), files.FileMetadataWithContentItem{
+		FileMetaData: filesAPI.FileMetaData{Path: "data/file.csv"},
+	}, lastResumable, content)
+	s.Error(err)
+
+	published := receivedEvents(events)
+	s.Require().Len(published, 3)
+	s.Equal(files.EventRegistered, published[1].Type)
+	s.Equal(files.EventFailed, published[2].Type)
+	s.Equal("marking error", published[2].Error)
+}
+
+func (s *StoreSuite) TestFailedChunkPublishesOnlyFailedEvent() {
+	s.mockS3.UploadPartFunc = func(ctx context.Context, req *storage.PartRequest, payload io.Reader) (storage.PartResponse, error) {
+		return storage.PartResponse{}, s3client.NewError(errors.New("broken"), nil)
+	}
+	hub := files.NewHub()
+	events, unsubscribe := hub.Subscribe("data/file.csv")
+	defer unsubscribe()
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{}).WithEvents(hub)
+
+	_, err := store.UploadFile(context.Background(), files.FileMetadataWithContentItem{
+		FileMetaData: filesAPI.FileMetaData{Path: "data/file.csv"},
+	}, lastResumable, content)
+	s.Equal(files.ErrS3Upload, err)
+
+	published := receivedEvents(events)
+	s.Require().Len(published, 1)
+	s.Equal(files.EventFailed, published[0].Type)
+	s.Equal(files.ErrS3Upload.Error(), published[0].Error)
+}
+
+func (s *StoreSuite) TestUploadOfTypeNotAllowedIsRejected() {
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{UploadAllowedTypes: []string{"text/csv"}})
+
+	_, err := store.UploadFile(context.Background(), files.FileMetadataWithContentItem{
+		FileMetaData: filesAPI.FileMetaData{Path: "data/file.exe", Type: "application/x-msdownload"},
+	}, lastResumable, strings.NewReader("CONTENT"))
+
+	s.Equal(files.ErrUnsupportedMediaType, err)
+	s.Len(s.mockS3.UploadPartCalls(), 0)
+}
+
+func (s *StoreSuite) TestUploadWhoseContentDoesNotMatchItsTypeIsRejected() {
+	hub := files.NewHub()
+	events, unsubscribe := hub.Subscribe("data/file.csv")
+	defer unsubscribe()
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{UploadAllowedTypes: []string{"text/csv"}}).WithEvents(hub)
+
+	_, err := store.UploadFile(context.Background(), files.FileMetadataWithContentItem{
+		FileMetaData: filesAPI.FileMetaData{Path: "data/file.csv", Type: "text/csv"},
+	}, firstResumable, strings.NewReader("%PDF-1.7\n"))
+
+	s.Equal(files.ErrUnsupportedMediaType, err)
+	s.Len(s.mockS3.UploadPartCalls(), 0)
+	published := receivedEvents(events)
+	s.Require().Len(published, 1)
+	s.Equal(files.EventFailed, published[0].Type)
+}
+
+func (s *StoreSuite) TestSniffedChunkIsStoredInFull() {
+	chunk := strings.Repeat("name,count\n", 100)
+	var stored []byte
+	s.mockS3.UploadPartFunc = func(ctx context.Context, req *storage.PartRequest, payload io.Reader) (storage.PartResponse, error) {
+		var err error
+		stored, err = io.ReadAll(payload)
+		return storage.PartResponse{AllPartsUploaded: false}, err
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{UploadAllowedTypes: []string{"text/csv"}})
+
+	_, err := store.UploadFile(context.Background(), files.FileMetadataWithContentItem{
+		FileMetaData: filesAPI.FileMetaData{Path: "data/file.csv", Type: "text/csv"},
+	}, firstResumable, strings.NewReader(chunk))
+
+	s.NoError(err)
+	s.Equal(chunk, string(stored))
+}
+
+func (s *StoreSuite) TestContentIsSniffedWithTheDefaultConfig() {
+	cfg, err := config.Get()
+	s.Require().NoError(err)
+	store := files.NewStore(s.mockFiles, s.bucket, cfg)
+
+	_, err = store.UploadFile(context.Background(), files.FileMetadataWithContentItem{
+		FileMetaData: filesAPI.FileMetaData{Path: "data/file.csv", Type: "text/csv"},
+	}, firstResumable, strings.NewReader("%PDF-1.7\n"))
+
+	s.Equal(files.ErrUnsupportedMediaType, err)
+	s.Len(s.mockS3.UploadPartCalls(), 0)
+}
+
+func (s *StoreSuite) TestContentOfTypeThatCannotBeRecognisedIsNotSniffed() {
+	s.mockS3.UploadPartFunc = func(ctx context.Context, req *storage.PartRequest, payload io.Reader) (storage.PartResponse, error) {
+		return storage.PartResponse{AllPartsUploaded: false}, nil
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{UploadAllowedTypes: []string{"application/octet-stream"}})
+
+	_, err := store.UploadFile(context.Background(), files.FileMetadataWithContentItem{
+		FileMetaData: filesAPI.FileMetaData{Path: "data/file.bin", Type: "application/octet-stream"},
+	}, firstResumable, strings.NewReader("name,count\n"))
+
+	s.NoError(err)
+	s.Len(s.mockS3.UploadPartCalls(), 1)
+}
+
+func (s *StoreSuite) TestOnlyTheFirstChunkIsSniffed() {
+	s.mockS3.UploadPartFunc = func(ctx context.Context, req *storage.PartRequest, payload io.Reader) (storage.PartResponse, error) {
+		return storage.PartResponse{AllPartsUploaded: false}, nil
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	_, err := store.UploadFile(context.Background(), files.FileMetadataWithContentItem{
+		FileMetaData: filesAPI.FileMetaData{Path: "data/file.csv", Type: "text/csv"},
+	}, lastResumable, strings.NewReader("\x00\x01\x02\x03"))
+
+	s.NoError(err)
+	s.Len(s.mockS3.UploadPartCalls(), 1)
+}
+
+// receivedEvents returns the events already published to the channel without waiting for more
+func receivedEvents(events <-chan files.UploadEvent) []files.UploadEvent {
+	var received []files.UploadEvent
+	for {
+		select {
+		case event := <-events:
+			received = append(received, event)
+		default:
+			return received
+		}
+	}
+}
+
 func (s *StoreSuite) TestMarkingAsUploadedAddsCorrectEtag() {
-	s.mockFiles.MarkFileUploadedFunc = func(ctx context.Context, path string, etag string, headers filesSDK.Headers) error {
+	s.mockFiles.MarkFileUploadedWithChecksumFunc = func(ctx context.Context, path, etag, checksum string, headers filesSDK.Headers) error {
 		s.Equal("head-object-etag", etag)
 		return nil
 	}
@@ -169,117 +420,1173 @@ func (s *StoreSuite) TestMarkingAsUploadedAddsCorrectEtag() {
 	s.NoError(err)
 }
 
-// Status
-func (s *StoreSuite) TestStatusHappyPath() {
+func (s *StoreSuite) TestMarkingAsUploadedSendsFileChecksum() {
 	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
-	response, err := store.Status(context.Background(), "valid")
+
+	_, err := store.UploadFile(context.Background(), files.FileMetadataWithContentItem{
+		FileMetaData: filesAPI.FileMetaData{Path: "test/path"},
+	}, lastResumable, content)
 	s.NoError(err)
-	s.True(response.FileContent.Value)
-	s.Len(s.mockFiles.GetFileCalls(), 1)
-	s.Len(s.mockS3.HeadCalls(), 1)
+	s.Len(s.mockS3.GetCalls(), 1)
+	s.Equal("test/path", s.mockS3.GetCalls()[0].Key)
+	digest := sha256.Sum256([]byte("CONTENT"))
+	s.Require().Len(s.mockFiles.MarkFileUploadedWithChecksumCalls(), 1)
+	s.Equal("head-object-etag", s.mockFiles.MarkFileUploadedWithChecksumCalls()[0].Etag)
+	s.Equal(base64.StdEncoding.EncodeToString(digest[:]), s.mockFiles.MarkFileUploadedWithChecksumCalls()[0].Checksum)
 }
 
-func (s *StoreSuite) TestStatusWhenErrorOnFilesAPIGetCall() {
-	s.mockFiles.GetFileFunc = func(ctx context.Context, path string, headers filesSDK.Headers) (*filesAPITypes.StoredRegisteredMetaData, error) {
-		return nil, errors.New("downstream error")
+// getRecorder records the keys of the objects read from the driver
+type getRecorder struct {
+	storage.Driver
+	mu   sync.Mutex
+	keys []string
+}
+
+func (g *getRecorder) Get(ctx context.Context, key string) (io.ReadCloser, *int64, error) {
+	g.mu.Lock()
+	g.keys = append(g.keys, key)
+	g.mu.Unlock()
+	return g.Driver.Get(ctx, key)
+}
+
+func (s *StoreSuite) TestChecksumIsCalculatedAsChunksArriveInOrder() {
+	driver := &getRecorder{Driver: filesystem.NewClient(s.T().TempDir(), "bucket")}
+	store := files.NewStore(s.mockFiles, storage.NewBucket("bucket", driver), &config.Config{})
+	first := bytes.Repeat([]byte("a"), storage.MinPartSize)
+	metadata := files.FileMetadataWithContentItem{
+		FileMetaData: filesAPI.FileMetaData{Path: "data/file.csv", SizeInBytes: storage.MinPartSize + 4},
 	}
-	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
-	_, err := store.Status(context.Background(), "invalid-path")
-	s.Equal(files.ErrFilesAPINotFound, err)
-	s.Len(s.mockFiles.GetFileCalls(), 1)
-	s.Len(s.mockS3.HeadCalls(), 0)
+
+	completed, err := store.UploadFile(context.Background(), metadata, files.Resumable{CurrentChunk: 1, TotalChunks: 2}, bytes.NewReader(first))
+	s.Require().NoError(err)
+	s.False(completed)
+	completed, err = store.UploadFile(context.Background(), metadata, files.Resumable{CurrentChunk: 2, TotalChunks: 2}, strings.NewReader("last"))
+	s.Require().NoError(err)
+	s.True(completed)
+
+	digest := sha256.Sum256(append(first, "last"...))
+	s.Require().Len(s.mockFiles.MarkFileUploadedWithChecksumCalls(), 1)
+	s.Equal(base64.StdEncoding.EncodeToString(digest[:]), s.mockFiles.MarkFileUploadedWithChecksumCalls()[0].Checksum)
+	s.NotContains(driver.keys, "data/file.csv", "the completed file should not be read back")
+	_, _, err = driver.Get(context.Background(), ".running-checksums/data/file.csv")
+	s.ErrorIs(err, storage.ErrNotFound)
 }
 
-func (s *StoreSuite) TestStatusStillReturnedIfBucketReadFails() {
-	s.mockS3.HeadFunc = func(ctx context.Context, key string) (*s3.HeadObjectOutput, error) {
-		return nil, errors.New("downstream error")
+func (s *StoreSuite) TestChecksumIsCalculatedFromTheCompletedFileWhenChunksArriveOutOfOrder() {
+	driver := &getRecorder{Driver: filesystem.NewClient(s.T().TempDir(), "bucket")}
+	store := files.NewStore(s.mockFiles, storage.NewBucket("bucket", driver), &config.Config{})
+	first := bytes.Repeat([]byte("a"), storage.MinPartSize)
+	metadata := files.FileMetadataWithContentItem{
+		FileMetaData: filesAPI.FileMetaData{Path: "data/file.csv", SizeInBytes: storage.MinPartSize + 4},
 	}
-	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
-	response, err := store.Status(context.Background(), "valid")
-	s.NoError(err)
-	s.False(response.FileContent.Value)
-	s.NotEmpty(response.FileContent.Err)
-	s.Len(s.mockFiles.GetFileCalls(), 1)
-	s.Len(s.mockS3.HeadCalls(), 1)
+
+	_, err := store.UploadFile(context.Background(), metadata, files.Resumable{CurrentChunk: 2, TotalChunks: 2}, strings.NewReader("last"))
+	s.Require().NoError(err)
+	completed, err := store.UploadFile(context.Background(), metadata, files.Resumable{CurrentChunk: 1, TotalChunks: 2}, bytes.NewReader(first))
+	s.Require().NoError(err)
+	s.True(completed)
+
+	digest := sha256.Sum256(append(first, "last"...))
+	s.Require().Len(s.mockFiles.MarkFileUploadedWithChecksumCalls(), 1)
+	s.Equal(base64.StdEncoding.EncodeToString(digest[:]), s.mockFiles.MarkFileUploadedWithChecksumCalls()[0].Checksum)
+	s.Contains(driver.keys, "data/file.csv")
 }
 
-func (s *StoreSuite) TestNotAllPartsUploaded() {
-	s.mockS3.UploadPartFunc = func(ctx context.Context, req *s3client.UploadPartRequest, payload []byte) (s3client.MultipartUploadResponse, error) {
-		return s3client.MultipartUploadResponse{Etag: "uploaded-part-etag", AllPartsUploaded: false}, nil
+func (s *StoreSuite) TestChunkSentAgainDiscardsTheRunningChecksum() {
+	driver := &getRecorder{Driver: filesystem.NewClient(s.T().TempDir(), "bucket")}
+	store := files.NewStore(s.mockFiles, storage.NewBucket("bucket", driver), &config.Config{})
+	chunk := bytes.Repeat([]byte("a"), storage.MinPartSize)
+	metadata := files.FileMetadataWithContentItem{
+		FileMetaData: filesAPI.FileMetaData{Path: "data/file.csv", SizeInBytes: 2*storage.MinPartSize + 4},
 	}
+
+	for _, number := range []int32{1, 2, 2} {
+		_, err := store.UploadFile(context.Background(), metadata, files.Resumable{CurrentChunk: number, TotalChunks: 3}, bytes.NewReader(chunk))
+		s.Require().NoError(err)
+	}
+	completed, err := store.UploadFile(context.Background(), metadata, files.Resumable{CurrentChunk: 3, TotalChunks: 3}, strings.NewReader("last"))
+	s.Require().NoError(err)
+	s.True(completed)
+
+	digest := sha256.Sum256(append(append(chunk, chunk...), "last"...))
+	s.Require().Len(s.mockFiles.MarkFileUploadedWithChecksumCalls(), 1)
+	s.Equal(base64.StdEncoding.EncodeToString(digest[:]), s.mockFiles.MarkFileUploadedWithChecksumCalls()[0].Checksum)
+	s.Contains(driver.keys, "data/file.csv")
+}
+
+func (s *StoreSuite) TestFileChecksumMatches() {
+	digest := sha256.Sum256([]byte("CONTENT"))
+	resumable := files.Resumable{CurrentChunk: 2, FileChecksum: base64.StdEncoding.EncodeToString(digest[:])}
 	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
 	flag, err := store.UploadFile(context.Background(), files.FileMetadataWithContentItem{
 		FileMetaData: filesAPI.FileMetaData{},
-	}, lastResumable, content)
+	}, resumable, content)
 	s.NoError(err)
-	s.False(flag)
-	s.Len(s.mockFiles.RegisterFileCalls(), 0)
-	s.Len(s.mockS3.HeadCalls(), 0)
-	s.Len(s.mockFiles.MarkFileUploadedCalls(), 0)
+	s.True(flag)
+	s.Len(s.mockFiles.MarkFileUploadedWithChecksumCalls(), 1)
 }
 
-func (s *StoreSuite) TestAllPartsUploaded() {
-	s.mockS3.UploadPartFunc = func(ctx context.Context, req *s3client.UploadPartRequest, payload []byte) (s3client.MultipartUploadResponse, error) {
-		return s3client.MultipartUploadResponse{Etag: "uploaded-part-etag", AllPartsUploaded: true}, nil
-	}
+func (s *StoreSuite) TestFileChecksumMismatch() {
+	digest := sha256.Sum256([]byte("OTHER CONTENT"))
+	resumable := files.Resumable{CurrentChunk: 2, FileChecksum: base64.StdEncoding.EncodeToString(digest[:])}
 	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
 	flag, err := store.UploadFile(context.Background(), files.FileMetadataWithContentItem{
-		FileMetaData: filesAPI.FileMetaData{},
-	}, lastResumable, content)
-	s.NoError(err)
+		FileMetaData: filesAPI.FileMetaData{Path: "data/file.csv"},
+	}, resumable, content)
+	s.ErrorIs(err, files.ErrFileChecksumMismatch)
 	s.True(flag)
 	s.Len(s.mockFiles.RegisterFileCalls(), 1)
-	s.Len(s.mockS3.HeadCalls(), 1)
-	s.Len(s.mockFiles.MarkFileUploadedCalls(), 1)
+	s.Len(s.mockFiles.DeleteFileCalls(), 1)
+	s.Equal("data/file.csv", s.mockS3.DeleteCalls()[0].Key)
+	s.Len(s.mockFiles.MarkFileUploadedWithChecksumCalls(), 0)
 }
 
-func (s *StoreSuite) TestUploadFileWithContentItem() {
-	var capturedMetadata filesAPITypes.StoredRegisteredMetaData
-	s.mockFiles.RegisterFileFunc = func(ctx context.Context, metadata filesAPITypes.StoredRegisteredMetaData, headers filesSDK.Headers) error {
-		capturedMetadata = metadata
-		return nil
+func (s *StoreSuite) TestChunkOfCompletedUploadIsRejected() {
+	s.mockS3.UploadPartFunc = func(ctx context.Context, req *storage.PartRequest, payload io.Reader) (storage.PartResponse, error) {
+		return storage.PartResponse{}, s3client.NewError(storage.ErrAlreadyUploaded, nil)
 	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	flag, err := store.UploadFile(context.Background(), files.FileMetadataWithContentItem{
+		FileMetaData: filesAPI.FileMetaData{Path: "data/file.csv"},
+	}, lastResumable, strings.NewReader("CONTENT"))
+
+	s.ErrorIs(err, files.ErrUploadComplete)
+	s.False(flag)
+	s.Len(s.mockFiles.RegisterFileCalls(), 0)
+}
 
+func (s *StoreSuite) TestFirstChunkOfStoredFileIsADuplicate() {
+	s.mockS3.UploadPartFunc = func(ctx context.Context, req *storage.PartRequest, payload io.Reader) (storage.PartResponse, error) {
+		return storage.PartResponse{}, s3client.NewError(storage.ErrAlreadyUploaded, nil)
+	}
 	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
 
-	metadata := files.FileMetadataWithContentItem{
-		FileMetaData: filesAPI.FileMetaData{
-			Path: "test/path",
-		},
-		ContentItem: &files.ContentItem{
-			DatasetID: "test-dataset",
-			Edition:   "time-series",
-			Version:   "1",
-		},
+	_, err := store.UploadFile(context.Background(), files.FileMetadataWithContentItem{
+		FileMetaData: filesAPI.FileMetaData{Path: "data/file.csv"},
+	}, firstResumable, strings.NewReader("CONTENT"))
+
+	s.ErrorIs(err, filesAPI.ErrFileAlreadyRegistered)
+	s.Len(s.mockS3.DeleteCalls(), 0)
+}
+
+func (s *StoreSuite) TestUploadLargerThanMaxFileSizeIsRejected() {
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{MaxUploadFileSize: 100})
+
+	_, err := store.UploadFile(context.Background(), files.FileMetadataWithContentItem{
+		FileMetaData: filesAPI.FileMetaData{Path: "data/file.csv", SizeInBytes: 101},
+	}, firstResumable, strings.NewReader("CONTENT"))
+
+	s.ErrorIs(err, files.ErrFileTooLarge)
+	s.Len(s.mockS3.UploadPartCalls(), 0)
+}
+
+func (s *StoreSuite) TestChunkLargerThanDeclaredSizeIsRejected() {
+	s.mockS3.UploadPartFunc = func(ctx context.Context, req *storage.PartRequest, payload io.Reader) (storage.PartResponse, error) {
+		_, err := io.ReadAll(payload)
+		return storage.PartResponse{}, err
 	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
 
-	_, err := store.UploadFile(context.Background(), metadata, lastResumable, content)
+	_, err := store.UploadFile(context.Background(), files.FileMetadataWithContentItem{
+		FileMetaData: filesAPI.FileMetaData{Path: "data/file.csv", SizeInBytes: 3},
+	}, lastResumable, strings.NewReader("CONTENT"))
 
-	s.NoError(err)
-	s.NotNil(capturedMetadata.ContentItem)
-	s.Equal("test-dataset", capturedMetadata.ContentItem.DatasetID)
-	s.Equal("time-series", capturedMetadata.ContentItem.Edition)
-	s.Equal("1", capturedMetadata.ContentItem.Version)
+	s.ErrorIs(err, files.ErrSizeMismatch)
+	s.Len(s.mockFiles.RegisterFileCalls(), 0)
 }
 
-func (s *StoreSuite) TestUploadFileWithoutContentItem() {
-	var capturedMetadata filesAPITypes.StoredRegisteredMetaData
-	s.mockFiles.RegisterFileFunc = func(ctx context.Context, metadata filesAPITypes.StoredRegisteredMetaData, headers filesSDK.Headers) error {
-		capturedMetadata = metadata
-		return nil
+func (s *StoreSuite) TestChunkShorterThanItsDeclaredSizeIsRejected() {
+	s.mockS3.UploadPartFunc = func(ctx context.Context, req *storage.PartRequest, payload io.Reader) (storage.PartResponse, error) {
+		_, err := io.ReadAll(payload)
+		return storage.PartResponse{}, err
 	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+	resumable := firstResumable
+	resumable.CurrentChunkSize = 10
+
+	_, err := store.UploadFile(context.Background(), files.FileMetadataWithContentItem{
+		FileMetaData: filesAPI.FileMetaData{Path: "data/file.csv", SizeInBytes: 100},
+	}, resumable, strings.NewReader("CONTENT"))
+
+	s.ErrorIs(err, files.ErrSizeMismatch)
+	s.Require().Len(s.mockS3.UploadPartCalls(), 1)
+	s.Equal(int64(10), s.mockS3.UploadPartCalls()[0].Req.Size)
+}
 
+func (s *StoreSuite) TestCompletedFileSizeMismatch() {
+	s.mockS3.DeleteFunc = func(ctx context.Context, key string) error {
+		return nil
+	}
 	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
 
+	flag, err := store.UploadFile(context.Background(), files.FileMetadataWithContentItem{
+		FileMetaData: filesAPI.FileMetaData{Path: "data/file.csv", SizeInBytes: 14794},
+	}, lastResumable, strings.NewReader("CONTENT"))
+
+	s.ErrorIs(err, files.ErrSizeMismatch)
+	s.False(flag)
+	s.Equal([]string{"data/file.csv"}, s.deletedKeys())
+	s.Len(s.mockFiles.RegisterFileCalls(), 0)
+	s.Len(s.mockFiles.MarkFileUploadedWithChecksumCalls(), 0)
+}
+
+func (s *StoreSuite) TestCompletedFileMatchingDeclaredSize() {
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{MaxUploadFileSize: 100})
+
+	flag, err := store.UploadFile(context.Background(), files.FileMetadataWithContentItem{
+		FileMetaData: filesAPI.FileMetaData{Path: "data/file.csv", SizeInBytes: 100},
+	}, lastResumable, strings.NewReader("CONTENT"))
+
+	s.NoError(err)
+	s.True(flag)
+	s.Len(s.mockFiles.MarkFileUploadedWithChecksumCalls(), 1)
+}
+
+func (s *StoreSuite) TestChunksReceivedByDifferentInstancesAreCompletedOnce() {
+	// two instances of the service sharing the same bucket, each with its own driver
+	root := s.T().TempDir()
+	stores := []files.Store{
+		files.NewStore(s.mockFiles, storage.NewBucket("bucket", filesystem.NewClient(root, "bucket")), &config.Config{}),
+		files.NewStore(s.mockFiles, storage.NewBucket("bucket", filesystem.NewClient(root, "bucket")), &config.Config{}),
+	}
+	const totalChunks = 16
+	chunk := bytes.Repeat([]byte("a"), storage.MinPartSize)
 	metadata := files.FileMetadataWithContentItem{
-		FileMetaData: filesAPI.FileMetaData{
-			Path: "test/path",
-		},
-		ContentItem: nil,
+		FileMetaData: filesAPI.FileMetaData{Path: "data/file.csv", SizeInBytes: totalChunks * storage.MinPartSize},
 	}
 
-	_, err := store.UploadFile(context.Background(), metadata, lastResumable, content)
+	completed := make([]bool, totalChunks)
+	errs := make([]error, totalChunks)
+	var wg sync.WaitGroup
+	for i := range totalChunks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resumable := files.Resumable{CurrentChunk: int32(i + 1), TotalChunks: totalChunks}
+			completed[i], errs[i] = stores[i%len(stores)].UploadFile(context.Background(), metadata, resumable, bytes.NewReader(chunk))
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		s.NoError(err)
+	}
+	completions := 0
+	for _, c := range completed {
+		if c {
+			completions++
+		}
+	}
+	s.Equal(1, completions)
+	s.Len(s.mockFiles.RegisterFileCalls(), 1)
+	s.Require().Len(s.mockFiles.MarkFileUploadedWithChecksumCalls(), 1)
+	digest := sha256.Sum256(bytes.Repeat(chunk, totalChunks))
+	s.Equal(base64.StdEncoding.EncodeToString(digest[:]), s.mockFiles.MarkFileUploadedWithChecksumCalls()[0].Checksum)
+
+	head, err := filesystem.NewClient(root, "bucket").Head(context.Background(), "data/file.csv")
+	s.Require().NoError(err)
+	s.Equal(int64(totalChunks*storage.MinPartSize), head.SizeInBytes)
+	s.True(strings.HasSuffix(head.ETag, "-16"))
+}
+
+func (s *StoreSuite) TestInvalidFileChecksum() {
+	resumable := files.Resumable{CurrentChunk: 1, FileChecksum: "not-a-checksum"}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	_, err := store.UploadFile(context.Background(), files.FileMetadataWithContentItem{
+		FileMetaData: filesAPI.FileMetaData{},
+	}, resumable, content)
+	s.ErrorIs(err, files.ErrInvalidFileChecksum)
+	s.Len(s.mockS3.UploadPartCalls(), 0)
+}
+
+func (s *StoreSuite) TestErrorCalculatingFileChecksum() {
+	s.mockS3.GetFunc = func(ctx context.Context, key string) (io.ReadCloser, *int64, error) {
+		return nil, nil, errors.New("get error")
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	_, err := store.UploadFile(context.Background(), files.FileMetadataWithContentItem{
+		FileMetaData: filesAPI.FileMetaData{},
+	}, lastResumable, content)
+	s.ErrorIs(err, files.ErrS3Download)
+	s.Len(s.mockFiles.MarkFileUploadedWithChecksumCalls(), 0)
+}
+
+func (s *StoreSuite) TestChunkUploaded() {
+	s.mockS3.PartExistsFunc = func(ctx context.Context, req *storage.PartRequest) (bool, error) {
+		return true, nil
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	uploaded, err := store.ChunkUploaded(context.Background(), files.FileMetadataWithContentItem{
+		FileMetaData: filesAPI.FileMetaData{Path: "data/file.csv"},
+	}, files.Resumable{CurrentChunk: 2, TotalChunks: 3})
 
 	s.NoError(err)
-	s.Nil(capturedMetadata.ContentItem)
+	s.True(uploaded)
+	s.Require().Len(s.mockS3.PartExistsCalls(), 1)
+	s.Equal("data/file.csv", s.mockS3.PartExistsCalls()[0].Req.Key)
+	s.Equal(int32(2), s.mockS3.PartExistsCalls()[0].Req.PartNumber)
+}
+
+func (s *StoreSuite) TestChunkNotUploaded() {
+	s.mockS3.PartExistsFunc = func(ctx context.Context, req *storage.PartRequest) (bool, error) {
+		return false, nil
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	uploaded, err := store.ChunkUploaded(context.Background(), files.FileMetadataWithContentItem{}, firstResumable)
+
+	s.NoError(err)
+	s.False(uploaded)
+}
+
+func (s *StoreSuite) TestErrorCheckingChunkUploaded() {
+	s.mockS3.PartExistsFunc = func(ctx context.Context, req *storage.PartRequest) (bool, error) {
+		return false, errors.New("s3 error")
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	_, err := store.ChunkUploaded(context.Background(), files.FileMetadataWithContentItem{}, firstResumable)
+
+	s.ErrorIs(err, files.ErrS3CheckPart)
+}
+
+func (s *StoreSuite) TestAbortUnregisteredUpload() {
+	s.mockFiles.GetFileFunc = func(ctx context.Context, path string, headers filesSDK.Headers) (*filesAPITypes.StoredRegisteredMetaData, error) {
+		return nil, &filesSDK.APIError{StatusCode: http.StatusNotFound}
+	}
+	s.mockS3.AbortMultipartUploadFunc = func(ctx context.Context, key string) (bool, error) {
+		return true, nil
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	err := store.AbortUpload(context.Background(), "data/file.csv")
+
+	s.NoError(err)
+	s.Require().Len(s.mockS3.AbortMultipartUploadCalls(), 1)
+	s.Equal("data/file.csv", s.mockS3.AbortMultipartUploadCalls()[0].Key)
+	s.Len(s.mockFiles.DeleteFileCalls(), 0)
+}
+
+func (s *StoreSuite) TestAbortRegisteredUploadDeletesFile() {
+	s.mockFiles.GetFileFunc = func(ctx context.Context, path string, headers filesSDK.Headers) (*filesAPITypes.StoredRegisteredMetaData, error) {
+		return &filesAPITypes.StoredRegisteredMetaData{Path: path, State: "CREATED"}, nil
+	}
+	s.mockFiles.DeleteFileFunc = func(ctx context.Context, path string, headers filesSDK.Headers) error {
+		return nil
+	}
+	s.mockS3.AbortMultipartUploadFunc = func(ctx context.Context, key string) (bool, error) {
+		return false, nil
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	err := store.AbortUpload(context.Background(), "data/file.csv")
+
+	s.NoError(err)
+	s.Require().Len(s.mockFiles.DeleteFileCalls(), 1)
+	s.Equal("data/file.csv", s.mockFiles.DeleteFileCalls()[0].Path)
+}
+
+func (s *StoreSuite) TestAbortUploadDuringVerificationDeletesFileBeforeFilesAPIRecord() {
+	driver := filesystem.NewClient(s.T().TempDir(), "bucket")
+	queue := &mock_files.QueueMock{EnqueueFunc: func(ctx context.Context, path string) {}}
+	store := files.NewStore(s.mockFiles, storage.NewBucket("bucket", driver), &config.Config{}).WithVerificationQueue(queue)
+	metadata := files.FileMetadataWithContentItem{FileMetaData: filesAPI.FileMetaData{Path: "data/file.csv", SizeInBytes: 7}}
+	completed, err := store.UploadFile(context.Background(), metadata, files.Resumable{CurrentChunk: 1, TotalChunks: 1}, strings.NewReader("CONTENT"))
+	s.Require().NoError(err)
+	s.Require().True(completed)
+	s.Require().Len(queue.EnqueueCalls(), 1)
+
+	s.mockFiles.GetFileFunc = func(ctx context.Context, path string, headers filesSDK.Headers) (*filesAPITypes.StoredRegisteredMetaData, error) {
+		return &filesAPITypes.StoredRegisteredMetaData{Path: path, State: "CREATED"}, nil
+	}
+	var fileDeletedFirst bool
+	s.mockFiles.DeleteFileFunc = func(ctx context.Context, path string, headers filesSDK.Headers) error {
+		_, err := driver.Head(ctx, path)
+		fileDeletedFirst = errors.Is(err, storage.ErrNotFound)
+		return nil
+	}
+
+	err = store.AbortUpload(context.Background(), "data/file.csv")
+
+	s.NoError(err)
+	s.Require().Len(s.mockFiles.DeleteFileCalls(), 1)
+	s.True(fileDeletedFirst, "the file should be deleted from the bucket before it is removed from Files API")
+	_, _, err = driver.Get(context.Background(), ".verifications/data/file.csv")
+	s.ErrorIs(err, storage.ErrNotFound)
+
+	s.NoError(store.VerifyFile(context.Background(), "data/file.csv"))
+	s.Len(s.mockFiles.MarkFileUploadedWithChecksumCalls(), 0)
+}
+
+func (s *StoreSuite) TestAbortUploadKeepsFilesAPIRecordWhenFileCannotBeDeleted() {
+	s.mockFiles.GetFileFunc = func(ctx context.Context, path string, headers filesSDK.Headers) (*filesAPITypes.StoredRegisteredMetaData, error) {
+		return &filesAPITypes.StoredRegisteredMetaData{Path: path, State: "CREATED"}, nil
+	}
+	s.mockS3.AbortMultipartUploadFunc = func(ctx context.Context, key string) (bool, error) {
+		return false, nil
+	}
+	s.mockS3.DeleteFunc = func(ctx context.Context, key string) error {
+		if key == "data/file.csv" {
+			return errors.New("s3 error")
+		}
+		return nil
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	err := store.AbortUpload(context.Background(), "data/file.csv")
+
+	s.ErrorIs(err, files.ErrS3Abort)
+	s.Len(s.mockFiles.DeleteFileCalls(), 0)
+}
+
+func (s *StoreSuite) TestAbortUnknownUpload() {
+	s.mockFiles.GetFileFunc = func(ctx context.Context, path string, headers filesSDK.Headers) (*filesAPITypes.StoredRegisteredMetaData, error) {
+		return nil, &filesSDK.APIError{StatusCode: http.StatusNotFound}
+	}
+	s.mockS3.AbortMultipartUploadFunc = func(ctx context.Context, key string) (bool, error) {
+		return false, nil
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	err := store.AbortUpload(context.Background(), "data/file.csv")
+
+	s.ErrorIs(err, files.ErrUploadNotFound)
+}
+
+func (s *StoreSuite) TestAbortCompletedUpload() {
+	s.mockFiles.GetFileFunc = func(ctx context.Context, path string, headers filesSDK.Headers) (*filesAPITypes.StoredRegisteredMetaData, error) {
+		return &filesAPITypes.StoredRegisteredMetaData{Path: path, State: "UPLOADED"}, nil
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	err := store.AbortUpload(context.Background(), "data/file.csv")
+
+	s.ErrorIs(err, files.ErrUploadComplete)
+	s.Len(s.mockS3.AbortMultipartUploadCalls(), 0)
+	s.Len(s.mockFiles.DeleteFileCalls(), 0)
+}
+
+func (s *StoreSuite) TestAbortUploadFilesAPIError() {
+	s.mockFiles.GetFileFunc = func(ctx context.Context, path string, headers filesSDK.Headers) (*filesAPITypes.StoredRegisteredMetaData, error) {
+		return nil, &filesSDK.APIError{StatusCode: http.StatusForbidden}
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	err := store.AbortUpload(context.Background(), "data/file.csv")
+
+	s.ErrorIs(err, files.ErrFilesForbidden)
+	s.Len(s.mockS3.AbortMultipartUploadCalls(), 0)
+}
+
+func (s *StoreSuite) TestAbortUploadS3Error() {
+	s.mockFiles.GetFileFunc = func(ctx context.Context, path string, headers filesSDK.Headers) (*filesAPITypes.StoredRegisteredMetaData, error) {
+		return nil, &filesSDK.APIError{StatusCode: http.StatusNotFound}
+	}
+	s.mockS3.AbortMultipartUploadFunc = func(ctx context.Context, key string) (bool, error) {
+		return false, errors.New("s3 error")
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	err := store.AbortUpload(context.Background(), "data/file.csv")
+
+	s.ErrorIs(err, files.ErrS3Abort)
+}
+
+func (s *StoreSuite) TestUploadedParts() {
+	s.mockS3.ListUploadedPartsFunc = func(ctx context.Context, key string) (storage.MultipartUploadParts, bool, error) {
+		return storage.MultipartUploadParts{
+			UploadID: "upload-id",
+			Parts: []storage.UploadedPart{
+				{PartNumber: 1, SizeInBytes: 5242880, ETag: "etag-1"},
+				{PartNumber: 3, SizeInBytes: 10, ETag: "etag-3"},
+			},
+		}, true, nil
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	parts, err := store.UploadedParts(context.Background(), "data/file.csv")
+
+	s.NoError(err)
+	s.Equal(&files.UploadedParts{
+		Path: "data/file.csv",
+		Parts: []files.UploadedPart{
+			{ChunkNumber: 1, SizeInBytes: 5242880, ETag: "etag-1"},
+			{ChunkNumber: 3, SizeInBytes: 10, ETag: "etag-3"},
+		},
+	}, parts)
+	s.Equal("data/file.csv", s.mockS3.ListUploadedPartsCalls()[0].Key)
+	s.Len(s.mockFiles.GetFileCalls(), 0)
+}
+
+func (s *StoreSuite) TestUploadedPartsUnknownUpload() {
+	s.mockS3.ListUploadedPartsFunc = func(ctx context.Context, key string) (storage.MultipartUploadParts, bool, error) {
+		return storage.MultipartUploadParts{}, false, nil
+	}
+	s.mockFiles.GetFileFunc = func(ctx context.Context, path string, headers filesSDK.Headers) (*filesAPITypes.StoredRegisteredMetaData, error) {
+		return nil, &filesSDK.APIError{StatusCode: http.StatusNotFound}
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	_, err := store.UploadedParts(context.Background(), "data/file.csv")
+
+	s.ErrorIs(err, files.ErrUploadNotFound)
+}
+
+func (s *StoreSuite) TestUploadedPartsCompletedUpload() {
+	s.mockS3.ListUploadedPartsFunc = func(ctx context.Context, key string) (storage.MultipartUploadParts, bool, error) {
+		return storage.MultipartUploadParts{}, false, nil
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	_, err := store.UploadedParts(context.Background(), "data/file.csv")
+
+	s.ErrorIs(err, files.ErrUploadComplete)
+}
+
+func (s *StoreSuite) TestUploadedPartsFilesAPIError() {
+	s.mockS3.ListUploadedPartsFunc = func(ctx context.Context, key string) (storage.MultipartUploadParts, bool, error) {
+		return storage.MultipartUploadParts{}, false, nil
+	}
+	s.mockFiles.GetFileFunc = func(ctx context.Context, path string, headers filesSDK.Headers) (*filesAPITypes.StoredRegisteredMetaData, error) {
+		return nil, &filesSDK.APIError{StatusCode: http.StatusUnauthorized}
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	_, err := store.UploadedParts(context.Background(), "data/file.csv")
+
+	s.ErrorIs(err, files.ErrFilesUnauthorised)
+}
+
+func (s *StoreSuite) TestUploadedPartsS3Error() {
+	s.mockS3.ListUploadedPartsFunc = func(ctx context.Context, key string) (storage.MultipartUploadParts, bool, error) {
+		return storage.MultipartUploadParts{}, false, errors.New("s3 error")
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	_, err := store.UploadedParts(context.Background(), "data/file.csv")
+
+	s.ErrorIs(err, files.ErrS3ListParts)
+}
+
+func (s *StoreSuite) TestChunkChecksumMatches() {
+	s.mockS3.UploadPartFunc = func(ctx context.Context, req *storage.PartRequest, payload io.Reader) (storage.PartResponse, error) {
+		_, err := io.ReadAll(payload)
+		return storage.PartResponse{AllPartsUploaded: false}, err
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	sum := sha256.Sum256([]byte("CONTENT"))
+	resumable := files.Resumable{CurrentChunk: 1, ChunkChecksum: base64.StdEncoding.EncodeToString(sum[:])}
+	_, err := store.UploadFile(context.Background(), textMetadata, resumable, strings.NewReader("CONTENT"))
+	s.NoError(err)
+}
+
+func (s *StoreSuite) TestChunkChecksumMismatch() {
+	s.mockS3.UploadPartFunc = func(ctx context.Context, req *storage.PartRequest, payload io.Reader) (storage.PartResponse, error) {
+		if _, err := io.ReadAll(payload); err != nil {
+			return storage.PartResponse{}, s3client.NewError(err, nil)
+		}
+		return storage.PartResponse{AllPartsUploaded: true}, nil
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	sum := sha256.Sum256([]byte("OTHER CONTENT"))
+	resumable := files.Resumable{CurrentChunk: 1, ChunkChecksum: base64.StdEncoding.EncodeToString(sum[:])}
+	_, err := store.UploadFile(context.Background(), textMetadata, resumable, strings.NewReader("CONTENT"))
+	s.Equal(files.ErrChecksumMismatch, err)
+	s.Len(s.mockFiles.RegisterFileCalls(), 0)
+}
+
+func (s *StoreSuite) TestChunkChecksumMD5() {
+	s.mockS3.UploadPartFunc = func(ctx context.Context, req *storage.PartRequest, payload io.Reader) (storage.PartResponse, error) {
+		_, err := io.ReadAll(payload)
+		return storage.PartResponse{AllPartsUploaded: false}, err
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	sum := md5.Sum([]byte("CONTENT"))
+	resumable := files.Resumable{CurrentChunk: 1, ChunkChecksum: base64.StdEncoding.EncodeToString(sum[:]), ChunkChecksumAlgorithm: "MD5"}
+	_, err := store.UploadFile(context.Background(), textMetadata, resumable, strings.NewReader("CONTENT"))
+	s.NoError(err)
+}
+
+func (s *StoreSuite) TestInvalidChunkChecksum() {
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	resumable := files.Resumable{CurrentChunk: 1, ChunkChecksum: "not-base64!"}
+	_, err := store.UploadFile(context.Background(), textMetadata, resumable, strings.NewReader("CONTENT"))
+	s.Equal(files.ErrInvalidChecksum, err)
+	s.Len(s.mockS3.UploadPartCalls(), 0)
+}
+
+// Status
+func (s *StoreSuite) TestStatusHappyPath() {
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+	response, err := store.Status(context.Background(), "valid")
+	s.NoError(err)
+	s.True(response.FileContent.Value)
+	s.Len(s.mockFiles.GetFileCalls(), 1)
+	s.Len(s.mockS3.HeadCalls(), 1)
+}
+
+func (s *StoreSuite) TestStatusWhenErrorOnFilesAPIGetCall() {
+	s.mockFiles.GetFileFunc = func(ctx context.Context, path string, headers filesSDK.Headers) (*filesAPITypes.StoredRegisteredMetaData, error) {
+		return nil, errors.New("downstream error")
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+	_, err := store.Status(context.Background(), "invalid-path")
+	s.Equal(files.ErrFilesAPINotFound, err)
+	s.Len(s.mockFiles.GetFileCalls(), 1)
+	s.Len(s.mockS3.HeadCalls(), 0)
+}
+
+func (s *StoreSuite) TestStatusStillReturnedIfBucketReadFails() {
+	s.mockS3.HeadFunc = func(ctx context.Context, key string) (storage.ObjectInfo, error) {
+		return storage.ObjectInfo{}, errors.New("downstream error")
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+	response, err := store.Status(context.Background(), "valid")
+	s.NoError(err)
+	s.False(response.FileContent.Value)
+	s.NotEmpty(response.FileContent.Err)
+	s.Len(s.mockFiles.GetFileCalls(), 1)
+	s.Len(s.mockS3.HeadCalls(), 1)
+}
+
+func (s *StoreSuite) TestStatusOfUnregisteredUploadInProgress() {
+	first := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	last := first.Add(time.Minute)
+	s.mockFiles.GetFileFunc = func(ctx context.Context, path string, headers filesSDK.Headers) (*filesAPITypes.StoredRegisteredMetaData, error) {
+		return nil, &filesSDK.APIError{StatusCode: http.StatusNotFound}
+	}
+	s.mockS3.ListUploadedPartsFunc = func(ctx context.Context, key string) (storage.MultipartUploadParts, bool, error) {
+		return storage.MultipartUploadParts{
+			UploadID: "upload-id",
+			Parts: []storage.UploadedPart{
+				{PartNumber: 1, SizeInBytes: 100, LastModified: first},
+				{PartNumber: 2, SizeInBytes: 100, LastModified: last},
+			},
+		}, true, nil
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	response, err := store.Status(context.Background(), "data/file.csv")
+
+	s.NoError(err)
+	s.Equal("data/file.csv", response.Metadata.Path)
+	s.False(response.FileContent.Value)
+	s.Equal(&files.UploadProgress{
+		UploadID:           "upload-id",
+		PartsReceived:      2,
+		BytesReceived:      200,
+		LastPartReceivedAt: &last,
+	}, response.Progress)
+	s.Len(s.mockS3.HeadCalls(), 0)
+}
+
+func (s *StoreSuite) TestStatusWhenNoUploadInProgressAndFileNotRegistered() {
+	s.mockFiles.GetFileFunc = func(ctx context.Context, path string, headers filesSDK.Headers) (*filesAPITypes.StoredRegisteredMetaData, error) {
+		return nil, &filesSDK.APIError{StatusCode: http.StatusNotFound}
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	_, err := store.Status(context.Background(), "data/file.csv")
+
+	s.Equal(files.ErrFilesAPINotFound, err)
+}
+
+func (s *StoreSuite) TestStatusExpectsTotalChunksOnceLastChunkArrives() {
+	s.mockFiles.GetFileFunc = func(ctx context.Context, path string, headers filesSDK.Headers) (*filesAPITypes.StoredRegisteredMetaData, error) {
+		return nil, &filesSDK.APIError{StatusCode: http.StatusNotFound}
+	}
+	s.mockS3.ListUploadedPartsFunc = func(ctx context.Context, key string) (storage.MultipartUploadParts, bool, error) {
+		return storage.MultipartUploadParts{
+			UploadID: "upload-id",
+			Parts: []storage.UploadedPart{
+				{PartNumber: 1, SizeInBytes: 100},
+				{PartNumber: 4, SizeInBytes: 10},
+			},
+		}, true, nil
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	response, err := store.Status(context.Background(), "data/file.csv")
+
+	s.NoError(err)
+	s.Equal(4, response.Progress.ExpectedTotalChunks)
+}
+
+func (s *StoreSuite) TestStatusOfRegisteredUploadInProgress() {
+	s.mockFiles.GetFileFunc = func(ctx context.Context, path string, headers filesSDK.Headers) (*filesAPITypes.StoredRegisteredMetaData, error) {
+		return &filesAPITypes.StoredRegisteredMetaData{Path: path, SizeInBytes: 250, State: "CREATED"}, nil
+	}
+	s.mockS3.ListUploadedPartsFunc = func(ctx context.Context, key string) (storage.MultipartUploadParts, bool, error) {
+		return storage.MultipartUploadParts{
+			UploadID: "upload-id",
+			Parts:    []storage.UploadedPart{{PartNumber: 1, SizeInBytes: 100}},
+		}, true, nil
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	response, err := store.Status(context.Background(), "data/file.csv")
+
+	s.NoError(err)
+	s.Require().NotNil(response.Progress)
+	s.Equal(3, response.Progress.ExpectedTotalChunks)
+	s.Equal(1, response.Progress.PartsReceived)
+}
+
+func (s *StoreSuite) TestStatusHasNoProgressOnceUploadComplete() {
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	response, err := store.Status(context.Background(), "valid")
+
+	s.NoError(err)
+	s.Nil(response.Progress)
+}
+
+func (s *StoreSuite) TestStatusStillReturnedIfListingPartsFails() {
+	s.mockS3.ListUploadedPartsFunc = func(ctx context.Context, key string) (storage.MultipartUploadParts, bool, error) {
+		return storage.MultipartUploadParts{}, false, errors.New("s3 error")
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	response, err := store.Status(context.Background(), "valid")
+
+	s.NoError(err)
+	s.True(response.FileContent.Value)
+	s.Nil(response.Progress)
+}
+
+func (s *StoreSuite) givenFilesInCollection(paths ...string) {
+	s.mockFiles.GetFilesMetadataFunc = func(ctx context.Context, collectionID, bundleID string, headers filesSDK.Headers) ([]filesAPITypes.StoredRegisteredMetaData, error) {
+		metadata := make([]filesAPITypes.StoredRegisteredMetaData, 0, len(paths))
+		for i, path := range paths {
+			state := "UPLOADED"
+			if i%2 == 1 {
+				state = "PUBLISHED"
+			}
+			metadata = append(metadata, filesAPITypes.StoredRegisteredMetaData{Path: path, State: state})
+		}
+		return metadata, nil
+	}
+}
+
+func (s *StoreSuite) TestCollectionStatus() {
+	s.givenFilesInCollection("c.csv", "a.csv", "b.csv")
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{StatusCheckConcurrency: 2})
+
+	batch, err := store.CollectionStatus(context.Background(), "collection-1", 0, 10)
+
+	s.NoError(err)
+	s.Equal("collection-1", s.mockFiles.GetFilesMetadataCalls()[0].CollectionID)
+	s.Empty(s.mockFiles.GetFilesMetadataCalls()[0].BundleID)
+	s.Equal(3, batch.Count)
+	s.Equal(3, batch.TotalCount)
+	s.Equal(map[string]int{"UPLOADED": 2, "PUBLISHED": 1}, batch.States)
+	s.Require().Len(batch.Items, 3)
+	s.Equal("a.csv", batch.Items[0].Metadata.Path)
+	s.Equal("b.csv", batch.Items[1].Metadata.Path)
+	s.Equal("c.csv", batch.Items[2].Metadata.Path)
+	s.True(batch.Items[0].FileContent.Value)
+	s.Len(s.mockS3.HeadCalls(), 3)
+}
+
+func (s *StoreSuite) TestBundleStatus() {
+	s.givenFilesInCollection("a.csv")
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	batch, err := store.BundleStatus(context.Background(), "bundle-1", 0, 10)
+
+	s.NoError(err)
+	s.Equal("bundle-1", s.mockFiles.GetFilesMetadataCalls()[0].BundleID)
+	s.Empty(s.mockFiles.GetFilesMetadataCalls()[0].CollectionID)
+	s.Equal(1, batch.Count)
+}
+
+func (s *StoreSuite) TestCollectionStatusIsPaged() {
+	s.givenFilesInCollection("a.csv", "b.csv", "c.csv", "d.csv", "e.csv")
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	batch, err := store.CollectionStatus(context.Background(), "collection-1", 2, 2)
+
+	s.NoError(err)
+	s.Equal(2, batch.Count)
+	s.Equal(2, batch.Offset)
+	s.Equal(2, batch.Limit)
+	s.Equal(5, batch.TotalCount)
+	s.Equal(map[string]int{"UPLOADED": 3, "PUBLISHED": 2}, batch.States)
+	s.Equal("c.csv", batch.Items[0].Metadata.Path)
+	s.Equal("d.csv", batch.Items[1].Metadata.Path)
+	s.Len(s.mockS3.HeadCalls(), 2)
+}
+
+func (s *StoreSuite) TestCollectionStatusPastLastPage() {
+	s.givenFilesInCollection("a.csv")
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	batch, err := store.CollectionStatus(context.Background(), "collection-1", 10, 10)
+
+	s.NoError(err)
+	s.Equal(0, batch.Count)
+	s.Equal(1, batch.TotalCount)
+	s.Empty(batch.Items)
+}
+
+func (s *StoreSuite) TestCollectionStatusWhenBucketReadFails() {
+	s.givenFilesInCollection("a.csv")
+	s.mockS3.HeadFunc = func(ctx context.Context, key string) (storage.ObjectInfo, error) {
+		return storage.ObjectInfo{}, errors.New("downstream error")
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	batch, err := store.CollectionStatus(context.Background(), "collection-1", 0, 10)
+
+	s.NoError(err)
+	s.False(batch.Items[0].FileContent.Value)
+	s.NotEmpty(batch.Items[0].FileContent.Err)
+}
+
+func (s *StoreSuite) TestCollectionStatusFilesAPIError() {
+	s.mockFiles.GetFilesMetadataFunc = func(ctx context.Context, collectionID, bundleID string, headers filesSDK.Headers) ([]filesAPITypes.StoredRegisteredMetaData, error) {
+		return nil, &filesSDK.APIError{StatusCode: http.StatusForbidden}
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	_, err := store.CollectionStatus(context.Background(), "collection-1", 0, 10)
+
+	s.ErrorIs(err, files.ErrFilesForbidden)
+	s.Len(s.mockS3.HeadCalls(), 0)
+}
+
+func (s *StoreSuite) TestNotAllPartsUploaded() {
+	s.mockS3.UploadPartFunc = func(ctx context.Context, req *storage.PartRequest, payload io.Reader) (storage.PartResponse, error) {
+		return storage.PartResponse{ETag: "uploaded-part-etag", AllPartsUploaded: false}, nil
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+	flag, err := store.UploadFile(context.Background(), files.FileMetadataWithContentItem{
+		FileMetaData: filesAPI.FileMetaData{},
+	}, lastResumable, content)
+	s.NoError(err)
+	s.False(flag)
+	s.Len(s.mockFiles.RegisterFileCalls(), 0)
+	s.Len(s.mockS3.HeadCalls(), 0)
+	s.Len(s.mockFiles.MarkFileUploadedWithChecksumCalls(), 0)
+}
+
+func (s *StoreSuite) TestAllPartsUploaded() {
+	s.mockS3.UploadPartFunc = func(ctx context.Context, req *storage.PartRequest, payload io.Reader) (storage.PartResponse, error) {
+		return storage.PartResponse{ETag: "uploaded-part-etag", AllPartsUploaded: true}, nil
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+	flag, err := store.UploadFile(context.Background(), files.FileMetadataWithContentItem{
+		FileMetaData: filesAPI.FileMetaData{},
+	}, lastResumable, content)
+	s.NoError(err)
+	s.True(flag)
+	s.Len(s.mockFiles.RegisterFileCalls(), 1)
+	s.Len(s.mockS3.HeadCalls(), 1)
+	s.Len(s.mockFiles.MarkFileUploadedWithChecksumCalls(), 1)
+}
+
+func (s *StoreSuite) TestUploadFileWithContentItem() {
+	var capturedMetadata filesAPITypes.StoredRegisteredMetaData
+	s.mockFiles.RegisterFileFunc = func(ctx context.Context, metadata filesAPITypes.StoredRegisteredMetaData, headers filesSDK.Headers) error {
+		capturedMetadata = metadata
+		return nil
+	}
+
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	metadata := files.FileMetadataWithContentItem{
+		FileMetaData: filesAPI.FileMetaData{
+			Path: "test/path",
+		},
+		ContentItem: &files.ContentItem{
+			DatasetID: "test-dataset",
+			Edition:   "time-series",
+			Version:   "1",
+		},
+	}
+
+	_, err := store.UploadFile(context.Background(), metadata, lastResumable, content)
+
+	s.NoError(err)
+	s.NotNil(capturedMetadata.ContentItem)
+	s.Equal("test-dataset", capturedMetadata.ContentItem.DatasetID)
+	s.Equal("time-series", capturedMetadata.ContentItem.Edition)
+	s.Equal("1", capturedMetadata.ContentItem.Version)
+}
+
+func (s *StoreSuite) TestUploadFileWithoutContentItem() {
+	var capturedMetadata filesAPITypes.StoredRegisteredMetaData
+	s.mockFiles.RegisterFileFunc = func(ctx context.Context, metadata filesAPITypes.StoredRegisteredMetaData, headers filesSDK.Headers) error {
+		capturedMetadata = metadata
+		return nil
+	}
+
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	metadata := files.FileMetadataWithContentItem{
+		FileMetaData: filesAPI.FileMetaData{
+			Path: "test/path",
+		},
+		ContentItem: nil,
+	}
+
+	_, err := store.UploadFile(context.Background(), metadata, lastResumable, content)
+
+	s.NoError(err)
+	s.Nil(capturedMetadata.ContentItem)
+}
+
+func (s *StoreSuite) uploadedFile() {
+	s.mockFiles.GetFileFunc = func(ctx context.Context, path string, headers filesSDK.Headers) (*filesAPITypes.StoredRegisteredMetaData, error) {
+		return &filesAPITypes.StoredRegisteredMetaData{Path: path, State: "UPLOADED", IsPublishable: true, SizeInBytes: 100}, nil
+	}
+	s.mockFiles.MarkFilePublishedFunc = func(ctx context.Context, path string, headers filesSDK.Headers) error {
+		return nil
+	}
+}
+
+func (s *StoreSuite) TestPublishFile() {
+	s.uploadedFile()
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	err := store.PublishFile(context.Background(), "data/file.csv")
+
+	s.NoError(err)
+	s.Require().Len(s.mockFiles.MarkFilePublishedCalls(), 1)
+	s.Equal("data/file.csv", s.mockFiles.MarkFilePublishedCalls()[0].Path)
+	s.Equal("data/file.csv", s.mockS3.HeadCalls()[0].Key)
+}
+
+func (s *StoreSuite) TestPublishUnknownFile() {
+	s.mockFiles.GetFileFunc = func(ctx context.Context, path string, headers filesSDK.Headers) (*filesAPITypes.StoredRegisteredMetaData, error) {
+		return nil, &filesSDK.APIError{StatusCode: http.StatusNotFound}
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	err := store.PublishFile(context.Background(), "data/file.csv")
+
+	s.ErrorIs(err, files.ErrFilesAPINotFound)
+	s.Len(s.mockFiles.MarkFilePublishedCalls(), 0)
+}
+
+func (s *StoreSuite) TestPublishFilesAPIError() {
+	s.mockFiles.GetFileFunc = func(ctx context.Context, path string, headers filesSDK.Headers) (*filesAPITypes.StoredRegisteredMetaData, error) {
+		return nil, &filesSDK.APIError{StatusCode: http.StatusUnauthorized}
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	err := store.PublishFile(context.Background(), "data/file.csv")
+
+	s.ErrorIs(err, files.ErrFilesUnauthorised)
+}
+
+func (s *StoreSuite) TestPublishFileInWrongState() {
+	tests := map[string]error{
+		"CREATED":   files.ErrUploadNotComplete,
+		"PUBLISHED": files.ErrFileAlreadyPublished,
+		"MOVED":     files.ErrFileAlreadyPublished,
+	}
+
+	for state, expectedErr := range tests {
+		s.mockFiles.GetFileFunc = func(ctx context.Context, path string, headers filesSDK.Headers) (*filesAPITypes.StoredRegisteredMetaData, error) {
+			return &filesAPITypes.StoredRegisteredMetaData{Path: path, State: state, IsPublishable: true}, nil
+		}
+		store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+		err := store.PublishFile(context.Background(), "data/file.csv")
+
+		s.ErrorIs(err, expectedErr, state)
+	}
+	s.Len(s.mockFiles.MarkFilePublishedCalls(), 0)
+}
+
+func (s *StoreSuite) TestPublishFileThatIsNotPublishable() {
+	s.mockFiles.GetFileFunc = func(ctx context.Context, path string, headers filesSDK.Headers) (*filesAPITypes.StoredRegisteredMetaData, error) {
+		return &filesAPITypes.StoredRegisteredMetaData{Path: path, State: "UPLOADED"}, nil
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	err := store.PublishFile(context.Background(), "data/file.csv")
+
+	s.ErrorIs(err, files.ErrFileNotPublishable)
+}
+
+func (s *StoreSuite) TestPublishFileWithUploadInProgress() {
+	s.uploadedFile()
+	s.mockS3.ListUploadedPartsFunc = func(ctx context.Context, key string) (storage.MultipartUploadParts, bool, error) {
+		return storage.MultipartUploadParts{UploadID: "upload-id"}, true, nil
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	err := store.PublishFile(context.Background(), "data/file.csv")
+
+	s.ErrorIs(err, files.ErrUploadNotComplete)
+	s.Len(s.mockFiles.MarkFilePublishedCalls(), 0)
+}
+
+func (s *StoreSuite) TestPublishFileMissingFromBucket() {
+	s.uploadedFile()
+	s.mockS3.HeadFunc = func(ctx context.Context, key string) (storage.ObjectInfo, error) {
+		return storage.ObjectInfo{}, s3client.NewError(storage.ErrNotFound, nil)
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	err := store.PublishFile(context.Background(), "data/file.csv")
+
+	s.ErrorIs(err, files.ErrUploadNotComplete)
+}
+
+func (s *StoreSuite) TestPublishFileHeadError() {
+	s.uploadedFile()
+	s.mockS3.HeadFunc = func(ctx context.Context, key string) (storage.ObjectInfo, error) {
+		return storage.ObjectInfo{}, errors.New("head error")
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	err := store.PublishFile(context.Background(), "data/file.csv")
+
+	s.ErrorIs(err, files.ErrS3Head)
+}
+
+func (s *StoreSuite) TestPublishFileWithWrongSizeInBucket() {
+	s.uploadedFile()
+	s.mockS3.HeadFunc = func(ctx context.Context, key string) (storage.ObjectInfo, error) {
+		return storage.ObjectInfo{SizeInBytes: 50}, nil
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	err := store.PublishFile(context.Background(), "data/file.csv")
+
+	s.ErrorIs(err, files.ErrUploadNotComplete)
+	s.Len(s.mockFiles.MarkFilePublishedCalls(), 0)
+}
+
+func (s *StoreSuite) TestPublishFileMarkPublishedError() {
+	s.uploadedFile()
+	s.mockFiles.MarkFilePublishedFunc = func(ctx context.Context, path string, headers filesSDK.Headers) error {
+		return &filesSDK.APIError{StatusCode: http.StatusForbidden}
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	err := store.PublishFile(context.Background(), "data/file.csv")
+
+	s.ErrorIs(err, files.ErrFilesForbidden)
+}
+
+func (s *StoreSuite) TestFileOwnerIsTheCollectionTheFileIsRegisteredIn() {
+	collectionID := "collection-1"
+	s.mockFiles.GetFileFunc = func(ctx context.Context, path string, headers filesSDK.Headers) (*filesAPITypes.StoredRegisteredMetaData, error) {
+		return &filesAPITypes.StoredRegisteredMetaData{Path: path, CollectionID: &collectionID}, nil
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	collection, bundle, err := store.FileOwner(context.WithValue(context.Background(), config.AuthContextKey, "Bearer caller-token"), "data/file.csv")
+
+	s.NoError(err)
+	s.Equal(&collectionID, collection)
+	s.Nil(bundle)
+	s.Equal("caller-token", s.mockFiles.GetFileCalls()[0].Headers.Authorization)
+}
+
+func (s *StoreSuite) TestUnregisteredFileHasNoOwner() {
+	s.mockFiles.GetFileFunc = func(ctx context.Context, path string, headers filesSDK.Headers) (*filesAPITypes.StoredRegisteredMetaData, error) {
+		return nil, &filesSDK.APIError{StatusCode: http.StatusNotFound}
+	}
+	s.mockS3.GetFunc = func(ctx context.Context, key string) (io.ReadCloser, *int64, error) {
+		return nil, nil, storage.ErrNotFound
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	collection, bundle, err := store.FileOwner(context.Background(), "data/file.csv")
+
+	s.NoError(err)
+	s.Nil(collection)
+	s.Nil(bundle)
+	s.Equal(".upload-owners/data/file.csv", s.mockS3.GetCalls()[0].Key)
+}
+
+func (s *StoreSuite) TestUploadInProgressIsOwnedByTheCollectionItWasStartedFor() {
+	var mu sync.Mutex
+	records := map[string][]byte{}
+	s.mockS3.PutFunc = func(ctx context.Context, key, contentType string, content io.Reader) error {
+		mu.Lock()
+		defer mu.Unlock()
+		records[key], _ = io.ReadAll(content)
+		return nil
+	}
+	s.mockS3.GetFunc = func(ctx context.Context, key string) (io.ReadCloser, *int64, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		record, ok := records[key]
+		if !ok {
+			return nil, nil, storage.ErrNotFound
+		}
+		return io.NopCloser(bytes.NewReader(record)), nil, nil
+	}
+	s.mockS3.UploadPartFunc = func(ctx context.Context, req *storage.PartRequest, payload io.Reader) (storage.PartResponse, error) {
+		return storage.PartResponse{ETag: "uploaded-part-etag"}, nil
+	}
+	s.mockFiles.GetFileFunc = func(ctx context.Context, path string, headers filesSDK.Headers) (*filesAPITypes.StoredRegisteredMetaData, error) {
+		return nil, &filesSDK.APIError{StatusCode: http.StatusNotFound}
+	}
+	collectionID := "collection-1"
+	metadata := files.FileMetadataWithContentItem{FileMetaData: filesAPI.FileMetaData{Path: "data/file.csv", CollectionID: &collectionID}}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	_, err := store.UploadFile(context.Background(), metadata, firstResumable, content)
+	s.Require().NoError(err)
+
+	collection, bundle, err := store.FileOwner(context.Background(), "data/file.csv")
+
+	s.NoError(err)
+	s.Equal(&collectionID, collection)
+	s.Nil(bundle)
+	s.JSONEq(`{"collection_id": "collection-1"}`, string(records[".upload-owners/data/file.csv"]))
+}
+
+func (s *StoreSuite) TestOwnerOfUploadIsRemovedWhenItIsCompleted() {
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	_, err := store.UploadFile(context.Background(), files.FileMetadataWithContentItem{
+		FileMetaData: filesAPI.FileMetaData{Path: "data/file.csv"},
+	}, lastResumable, content)
+
+	s.NoError(err)
+	s.Require().NotEmpty(s.mockS3.DeleteCalls())
+	s.Equal(".upload-owners/data/file.csv", s.mockS3.DeleteCalls()[len(s.mockS3.DeleteCalls())-1].Key)
+}
+
+func (s *StoreSuite) TestFileOwnerOfUploadCannotBeRead() {
+	s.mockFiles.GetFileFunc = func(ctx context.Context, path string, headers filesSDK.Headers) (*filesAPITypes.StoredRegisteredMetaData, error) {
+		return nil, &filesSDK.APIError{StatusCode: http.StatusNotFound}
+	}
+	s.mockS3.GetFunc = func(ctx context.Context, key string) (io.ReadCloser, *int64, error) {
+		return nil, nil, errors.New("s3 unavailable")
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	_, _, err := store.FileOwner(context.Background(), "data/file.csv")
+
+	s.ErrorIs(err, files.ErrS3Download)
+}
+
+func (s *StoreSuite) TestFileOwnerFilesAPIError() {
+	s.mockFiles.GetFileFunc = func(ctx context.Context, path string, headers filesSDK.Headers) (*filesAPITypes.StoredRegisteredMetaData, error) {
+		return nil, &filesSDK.APIError{StatusCode: http.StatusInternalServerError}
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	_, _, err := store.FileOwner(context.Background(), "data/file.csv")
+
+	s.ErrorIs(err, files.ErrFilesServer)
 }
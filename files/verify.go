@@ -0,0 +1,292 @@
+package files
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	filesSDK "github.com/ONSdigital/dp-files-api/sdk"
+	filesAPIStore "github.com/ONSdigital/dp-files-api/store"
+	"github.com/ONSdigital/dp-upload-service/scan"
+	"github.com/ONSdigital/dp-upload-service/storage"
+	"github.com/ONSdigital/log.go/v2/log"
+)
+
+// verificationPrefix is the prefix of the records of the files that have been completed, but not yet verified and
+// marked as uploaded
+const verificationPrefix = storage.VerificationPrefix
+
+//go:generate moq -out mock/queue.go -pkg mock_files . Queue
+
+// Queue processes the work for a path in the background
+type Queue interface {
+	Enqueue(ctx context.Context, path string)
+}
+
+// verification is recorded in the bucket when a file is completed, and removed once the file has been verified and
+// marked as uploaded, so that a file whose verification fails, or is interrupted, is verified again later by any
+// instance of the service
+// instance of the service. The checksum is the one calculated as the chunks of the file were received, if they arrived
+// in order, and the file checksum is the one sent by the client, if there was one.
+type verification struct {
+	ETag         string `json:"etag"`
+	Checksum     string `json:"checksum,omitempty"`
+	FileChecksum string `json:"file_checksum,omitempty"`
+}
+
+// WithVerificationQueue returns a copy of the store that verifies each completed file in the background, rather than
+// before the request that completed it returns. The queue must process each path with VerifyFile.
+func (s Store) WithVerificationQueue(queue Queue) Store {
+	s.verifications = queue
+	return s
+}
+
+// recordVerification records that the completed file needs to be verified
+func (s Store) recordVerification(ctx context.Context, path string, record verification) error {
+	if err := putJSON(ctx, s.bucket, verificationPrefix+path, record); err != nil {
+		log.Error(ctx, "failed to record verification of completed file", err, log.Data{"path": path})
+		return ErrS3Upload
+	}
+	return nil
+}
+
+// removeVerification removes the record of the file's verification once there is nothing more to do, logging any
+// error as the file is only verified again if the record is left behind
+func (s Store) removeVerification(ctx context.Context, path string) {
+	if err := s.bucket.Delete(ctx, verificationPrefix+path); err != nil {
+		log.Error(ctx, "failed to remove record of verification", err, log.Data{"path": path})
+	}
+}
+
+// verify verifies the completed file in the background if there is a queue, otherwise before returning
+func (s Store) verify(ctx context.Context, path string, record verification) error {
+	if s.verifications != nil {
+		s.verifications.Enqueue(ctx, path)
+		return nil
+	}
+	return s.verifyFile(ctx, path, record)
+}
+
+// VerifyFile verifies the file at the path if its verification has been recorded and it has not yet been marked as
+// uploaded, so that it can be called for the same file more than once
+func (s Store) VerifyFile(ctx context.Context, path string) error {
+	logData := log.Data{"path": path}
+
+	var record verification
+	found, err := getJSON(ctx, s.bucket, verificationPrefix+path, &record)
+	if err != nil {
+		log.Error(ctx, "failed to read record of verification", err, logData)
+		return ErrS3Download
+	}
+	if !found {
+		return nil
+	}
+
+	headers := filesSDK.Headers{Authorization: getAuthTokenFromContext(ctx)}
+	storedMetadata, err := s.files.GetFile(ctx, path, headers)
+	if err != nil {
+		if apiErr, ok := err.(*filesSDK.APIError); ok && apiErr.StatusCode == http.StatusNotFound {
+			// the file failed to be registered after its verification was recorded
+			log.Warn(ctx, "discarding completed file that was never registered", logData)
+			s.discardCompletedFile(ctx, path)
+			s.removeVerification(ctx, path)
+			return nil
+		}
+		log.Error(ctx, "failed to get file metadata", err, logData)
+		return mapFilesAPIError(err)
+	}
+	if storedMetadata.State != filesAPIStore.StateCreated {
+		s.removeVerification(ctx, path)
+		return nil
+	}
+
+	return s.verifyFile(ctx, path, record)
+}
+
+// PendingVerifications lists the paths of the files whose verification was recorded more than
+// VERIFICATION_RETRY_INTERVAL ago, which have either failed to be verified or are being verified by an instance that
+// has stopped
+func (s Store) PendingVerifications(ctx context.Context) ([]string, error) {
+	return s.pendingRecords(ctx, verificationPrefix, s.cfg.VerificationRetryInterval)
+}
+
+// pendingRecords lists the paths of the records with the prefix that were stored more than the interval ago
+func (s Store) pendingRecords(ctx context.Context, prefix string, interval time.Duration) ([]string, error) {
+	objects, err := s.bucket.List(ctx, prefix)
+	if err != nil {
+		log.Error(ctx, "failed to list records of pending work", err, log.Data{"prefix": prefix})
+		return nil, err
+	}
+
+	threshold := time.Now().Add(-interval)
+	var paths []string
+	for _, object := range objects {
+		if object.LastModified.Before(threshold) {
+			paths = append(paths, object.Key[len(prefix):])
+		}
+	}
+	return paths, nil
+}
+
+// verifyFile checks the whole file checksum of the completed file against the checksum sent with the upload, if there
+// was one, then marks the file as uploaded in Files API along with its checksum. The file is streamed from the bucket
+// if it has to be scanned for malware, or if its checksum could not be calculated as its chunks were received. An
+// infected file is quarantined, and a file that does not match is removed from the bucket and from Files API so that
+// it can be uploaded again.
+func (s Store) verifyFile(ctx context.Context, path string, record verification) error {
+	headers := filesSDK.Headers{Authorization: getAuthTokenFromContext(ctx)}
+	logData := log.Data{"path": path, "etag": record.ETag}
+
+	checksum := record.Checksum
+	var body io.ReadCloser
+	var err error
+	if s.scanner != nil || checksum == "" {
+		body, _, err = s.bucket.Get(ctx, path)
+	} else {
+		// the file is not read back, but must still be in the bucket
+		_, err = s.bucket.Head(ctx, path)
+	}
+	if errors.Is(err, storage.ErrNotFound) {
+		return s.verifyMissingFile(ctx, path)
+	}
+	if err != nil {
+		log.Error(ctx, "failed to get completed file from s3 to verify", err, logData)
+		return ErrS3Download
+	}
+
+	if body != nil {
+		var result scan.Result
+		checksum, result, err = s.inspectFile(ctx, path, body)
+		if closeErr := body.Close(); closeErr != nil {
+			log.Error(ctx, "error closing s3 object body", closeErr, logData)
+		}
+		if err != nil {
+			return err
+		}
+		if result.Infected {
+			return s.quarantineFile(ctx, path, result.Signature)
+		}
+	}
+
+	if record.FileChecksum != "" && record.FileChecksum != checksum {
+		logData["expected"] = record.FileChecksum
+		logData["actual"] = checksum
+		log.Error(ctx, "completed file does not match file checksum", ErrFileChecksumMismatch, logData)
+		s.rejectCompletedFile(ctx, path, ErrFileChecksumMismatch)
+		return ErrFileChecksumMismatch
+	}
+
+	if err := s.files.MarkFileUploadedWithChecksum(ctx, path, record.ETag, checksum, headers); err != nil {
+		log.Error(ctx, "failed to mark file as uploaded in dp-files-api", err, logData)
+		return mapFilesAPIError(err)
+	}
+	s.events.Publish(UploadEvent{Type: EventUploaded, Path: path})
+	s.removeVerification(ctx, path)
+
+	return nil
+}
+
+// verifyMissingFile finishes the verification of a completed file that is no longer in the bucket. An infected file is
+// deleted before its verification is removed when it is quarantined, and an aborted file is deleted before it is
+// removed from Files API, so either can be left part way through if the service stops. A quarantined file is marked as
+// quarantined, and an aborted file is removed from Files API, along with its verification.
+func (s Store) verifyMissingFile(ctx context.Context, path string) error {
+	logData := log.Data{"path": path}
+
+	quarantined, err := s.isQuarantined(ctx, path)
+	if err != nil {
+		log.Error(ctx, "failed to check whether missing file has been quarantined", err, logData)
+		return ErrS3Head
+	}
+	if quarantined {
+		if err := s.markFileQuarantined(ctx, path); err != nil {
+			return err
+		}
+		s.removeVerification(ctx, path)
+		return nil
+	}
+
+	log.Warn(ctx, "discarding completed file that is no longer in the bucket", logData)
+	s.rejectCompletedFile(ctx, path, ErrUploadNotFound)
+	return nil
+}
+
+// inspectFile reads the content of the completed file once, scanning it for malware as its base64 encoded SHA256
+// checksum is calculated. The checksum is not calculated for an infected file.
+func (s Store) inspectFile(ctx context.Context, path string, content io.Reader) (string, scan.Result, error) {
+	logData := log.Data{"path": path}
+
+	if s.scanner == nil {
+		checksum, err := sha256Checksum(content)
+		if err != nil {
+			log.Error(ctx, "failed to calculate checksum of completed file", err, logData)
+			return "", scan.Result{}, ErrS3Download
+		}
+		return checksum, scan.Result{}, nil
+	}
+
+	h := sha256.New()
+	result, err := s.scanSegments(ctx, io.TeeReader(content, h))
+	if err != nil {
+		log.Error(ctx, "failed to scan completed file", err, logData)
+		return "", scan.Result{}, ErrScanFailed
+	}
+	if result.Infected {
+		return "", result, nil
+	}
+
+	// the scanner does not have to read the content through to the end
+	if _, err := io.Copy(h, content); err != nil {
+		log.Error(ctx, "failed to calculate checksum of completed file", err, logData)
+		return "", scan.Result{}, ErrS3Download
+	}
+	return base64.StdEncoding.EncodeToString(h.Sum(nil)), result, nil
+}
+
+// rejectCompletedFile removes a registered file that failed to be verified from Files API as well as the bucket, then
+// publishes the reason it was rejected
+func (s Store) rejectCompletedFile(ctx context.Context, path string, reason error) {
+	headers := filesSDK.Headers{Authorization: getAuthTokenFromContext(ctx)}
+	if err := s.files.DeleteFile(ctx, path, headers); err != nil {
+		log.Error(ctx, "failed to remove rejected file from dp-files-api", err, log.Data{"path": path})
+	}
+	s.discardCompletedFile(ctx, path)
+	s.removeVerification(ctx, path)
+	s.events.Publish(UploadEvent{Type: EventFailed, Path: path, Error: reason.Error()})
+}
+
+// putJSON stores the value as a JSON object with the key
+func putJSON(ctx context.Context, bucket *storage.Bucket, key string, v interface{}) error {
+	content, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return bucket.Put(ctx, key, "application/json", bytes.NewReader(content))
+}
+
+// getJSON reads the JSON object with the key into the value, returning false if there is no object with the key
+func getJSON(ctx context.Context, bucket *storage.Bucket, key string, v interface{}) (bool, error) {
+	body, _, err := bucket.Get(ctx, key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err := body.Close(); err != nil {
+			log.Error(ctx, "error closing s3 object body", err, log.Data{"key": key})
+		}
+	}()
+
+	if err := json.NewDecoder(body).Decode(v); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,179 @@
+package files_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	filesAPI "github.com/ONSdigital/dp-api-clients-go/v2/files"
+	filesAPITypes "github.com/ONSdigital/dp-files-api/files"
+	filesSDK "github.com/ONSdigital/dp-files-api/sdk"
+	filesAPIStore "github.com/ONSdigital/dp-files-api/store"
+	"github.com/ONSdigital/dp-upload-service/config"
+	"github.com/ONSdigital/dp-upload-service/files"
+	mock_files "github.com/ONSdigital/dp-upload-service/files/mock"
+	"github.com/ONSdigital/dp-upload-service/storage"
+)
+
+// givenVerificationRecorded makes the bucket return a record of the file's verification, and the file's content, for
+// a file that has been registered but not yet marked as uploaded
+func (s *StoreSuite) givenVerificationRecorded(record string) {
+	s.mockFiles.GetFileFunc = func(ctx context.Context, path string, headers filesSDK.Headers) (*filesAPITypes.StoredRegisteredMetaData, error) {
+		return &filesAPITypes.StoredRegisteredMetaData{Path: path, State: filesAPIStore.StateCreated}, nil
+	}
+	s.mockS3.GetFunc = func(ctx context.Context, key string) (io.ReadCloser, *int64, error) {
+		if key == ".verifications/data/file.csv" {
+			return io.NopCloser(strings.NewReader(record)), nil, nil
+		}
+		return io.NopCloser(strings.NewReader("CONTENT")), nil, nil
+	}
+}
+
+func (s *StoreSuite) TestCompletedFileIsVerifiedInTheBackground() {
+	queue := &mock_files.QueueMock{EnqueueFunc: func(ctx context.Context, path string) {}}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{}).WithVerificationQueue(queue)
+
+	flag, err := store.UploadFile(context.Background(), files.FileMetadataWithContentItem{
+		FileMetaData: filesAPI.FileMetaData{Path: "data/file.csv"},
+	}, lastResumable, content)
+
+	s.NoError(err)
+	s.True(flag)
+	s.Require().Len(queue.EnqueueCalls(), 1)
+	s.Equal("data/file.csv", queue.EnqueueCalls()[0].Path)
+	s.Equal(".verifications/data/file.csv", s.mockS3.PutCalls()[0].Key)
+	s.Len(s.mockS3.GetCalls(), 0)
+	s.Len(s.mockFiles.MarkFileUploadedWithChecksumCalls(), 0)
+}
+
+func (s *StoreSuite) TestVerifyFileMarksRecordedFileAsUploaded() {
+	s.givenVerificationRecorded(`{"etag":"recorded-etag"}`)
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	err := store.VerifyFile(context.Background(), "data/file.csv")
+
+	s.NoError(err)
+	s.Require().Len(s.mockFiles.MarkFileUploadedWithChecksumCalls(), 1)
+	s.Equal("recorded-etag", s.mockFiles.MarkFileUploadedWithChecksumCalls()[0].Etag)
+	s.Require().Len(s.mockS3.DeleteCalls(), 1)
+	s.Equal(".verifications/data/file.csv", s.mockS3.DeleteCalls()[0].Key)
+}
+
+func (s *StoreSuite) TestVerifyFileWithoutRecordDoesNothing() {
+	s.mockS3.GetFunc = func(ctx context.Context, key string) (io.ReadCloser, *int64, error) {
+		return nil, nil, storage.ErrNotFound
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	err := store.VerifyFile(context.Background(), "data/file.csv")
+
+	s.NoError(err)
+	s.Len(s.mockFiles.GetFileCalls(), 0)
+	s.Len(s.mockFiles.MarkFileUploadedWithChecksumCalls(), 0)
+}
+
+func (s *StoreSuite) TestVerifyFileThatIsAlreadyUploadedRemovesRecord() {
+	s.givenVerificationRecorded(`{"etag":"recorded-etag"}`)
+	s.mockFiles.GetFileFunc = func(ctx context.Context, path string, headers filesSDK.Headers) (*filesAPITypes.StoredRegisteredMetaData, error) {
+		return &filesAPITypes.StoredRegisteredMetaData{Path: path, State: filesAPIStore.StateUploaded}, nil
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	err := store.VerifyFile(context.Background(), "data/file.csv")
+
+	s.NoError(err)
+	s.Len(s.mockFiles.MarkFileUploadedWithChecksumCalls(), 0)
+	s.Require().Len(s.mockS3.DeleteCalls(), 1)
+	s.Equal(".verifications/data/file.csv", s.mockS3.DeleteCalls()[0].Key)
+}
+
+func (s *StoreSuite) TestVerifyFileThatWasNeverRegisteredDiscardsFile() {
+	s.givenVerificationRecorded(`{"etag":"recorded-etag"}`)
+	s.mockFiles.GetFileFunc = func(ctx context.Context, path string, headers filesSDK.Headers) (*filesAPITypes.StoredRegisteredMetaData, error) {
+		return nil, &filesSDK.APIError{StatusCode: http.StatusNotFound}
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	err := store.VerifyFile(context.Background(), "data/file.csv")
+
+	s.NoError(err)
+	s.Len(s.mockFiles.MarkFileUploadedWithChecksumCalls(), 0)
+	s.Require().Len(s.mockS3.DeleteCalls(), 2)
+	s.Equal("data/file.csv", s.mockS3.DeleteCalls()[0].Key)
+	s.Equal(".verifications/data/file.csv", s.mockS3.DeleteCalls()[1].Key)
+}
+
+func (s *StoreSuite) TestVerifyFileOfAbortedFileRemovesItFromFilesAPI() {
+	s.givenVerificationRecorded(`{"etag":"recorded-etag"}`)
+	s.mockS3.GetFunc = func(ctx context.Context, key string) (io.ReadCloser, *int64, error) {
+		if key == ".verifications/data/file.csv" {
+			return io.NopCloser(strings.NewReader(`{"etag":"recorded-etag"}`)), nil, nil
+		}
+		return nil, nil, storage.ErrNotFound
+	}
+	s.mockS3.HeadFunc = func(ctx context.Context, key string) (storage.ObjectInfo, error) {
+		return storage.ObjectInfo{}, storage.ErrNotFound
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{QuarantinePrefix: "quarantine/"})
+
+	err := store.VerifyFile(context.Background(), "data/file.csv")
+
+	s.NoError(err)
+	s.Len(s.mockFiles.MarkFileUploadedWithChecksumCalls(), 0)
+	s.Require().Len(s.mockFiles.DeleteFileCalls(), 1)
+	s.Equal("data/file.csv", s.mockFiles.DeleteFileCalls()[0].Path)
+	s.Require().Len(s.mockS3.DeleteCalls(), 2)
+	s.Equal(".verifications/data/file.csv", s.mockS3.DeleteCalls()[1].Key)
+}
+
+func (s *StoreSuite) TestVerifyFileKeepsRecordWhenMissingFileCannotBeChecked() {
+	s.givenVerificationRecorded(`{"etag":"recorded-etag"}`)
+	s.mockS3.GetFunc = func(ctx context.Context, key string) (io.ReadCloser, *int64, error) {
+		if key == ".verifications/data/file.csv" {
+			return io.NopCloser(strings.NewReader(`{"etag":"recorded-etag"}`)), nil, nil
+		}
+		return nil, nil, storage.ErrNotFound
+	}
+	s.mockS3.HeadFunc = func(ctx context.Context, key string) (storage.ObjectInfo, error) {
+		return storage.ObjectInfo{}, errors.New("unavailable")
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{QuarantinePrefix: "quarantine/"})
+
+	err := store.VerifyFile(context.Background(), "data/file.csv")
+
+	s.ErrorIs(err, files.ErrS3Head)
+	s.Len(s.mockFiles.DeleteFileCalls(), 0)
+	s.Len(s.mockS3.DeleteCalls(), 0)
+}
+
+func (s *StoreSuite) TestVerifyFileKeepsRecordWhenMarkingAsUploadedFails() {
+	s.givenVerificationRecorded(`{"etag":"recorded-etag"}`)
+	s.mockFiles.MarkFileUploadedWithChecksumFunc = func(ctx context.Context, path, etag, checksum string, headers filesSDK.Headers) error {
+		return errors.New("unavailable")
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{})
+
+	err := store.VerifyFile(context.Background(), "data/file.csv")
+
+	s.Error(err)
+	s.Len(s.mockS3.DeleteCalls(), 0)
+}
+
+func (s *StoreSuite) TestPendingVerificationsAreOlderThanRetryInterval() {
+	s.mockS3.ListFunc = func(ctx context.Context, prefix string) ([]storage.ObjectSummary, error) {
+		return []storage.ObjectSummary{
+			{Key: ".verifications/data/old.csv", LastModified: time.Now().Add(-time.Hour)},
+			{Key: ".verifications/data/new.csv", LastModified: time.Now()},
+		}, nil
+	}
+	store := files.NewStore(s.mockFiles, s.bucket, &config.Config{VerificationRetryInterval: time.Minute})
+
+	paths, err := store.PendingVerifications(context.Background())
+
+	s.NoError(err)
+	s.Equal([]string{"data/old.csv"}, paths)
+	s.Equal(".verifications/", s.mockS3.ListCalls()[0].Prefix)
+}
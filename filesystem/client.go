@@ -0,0 +1,784 @@
+package filesystem
+
+import (
+	"context"
+	"crypto/md5" //nolint:gosec // MD5 is used to calculate ETags the same way as S3
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ONSdigital/dp-healthcheck/healthcheck"
+	"github.com/ONSdigital/dp-upload-service/storage"
+	"github.com/ONSdigital/log.go/v2/log"
+)
+
+const (
+	objectsDir  = "objects"
+	metadataDir = "metadata"
+	uploadsDir  = "uploads"
+	uploadFile  = "upload.json"
+	lockFile    = ".lock"
+	partSuffix  = ".part"
+	etagSuffix  = ".etag"
+	tempPrefix  = ".tmp-"
+	dirPerm     = 0o755
+	filePerm    = 0o644
+	msgHealthy  = "filesystem storage is available"
+)
+
+// upload describes a multipart upload that has been started but not completed or aborted
+type upload struct {
+	UploadID    string    `json:"upload_id"`
+	Key         string    `json:"key"`
+	ContentType string    `json:"content_type"`
+	Initiated   time.Time `json:"initiated"`
+}
+
+// object describes a completed object, so that it can be described by Head in the same way as S3
+type object struct {
+	ETag         string    `json:"etag"`
+	ContentType  string    `json:"content_type"`
+	LastModified time.Time `json:"last_modified"`
+}
+
+// Client is a storage.Driver that stores each bucket in a directory on disk, so that the service can be run without
+// S3 or localstack. Multipart uploads are kept in their own directory until every part has been received, then
+// assembled into the object with an ETag calculated the same way as S3's multipart ETag.
+// Changes to multipart uploads are made while holding a lock on a file in the bucket's directory, so several instances
+// of the service can share the same root path.
+type Client struct {
+	root       string
+	bucketName string
+	mutex      *sync.Mutex
+}
+
+var _ storage.Driver = (*Client)(nil)
+
+// NewClient creates a new Client for the given bucket name, storing the bucket in a directory under the root path
+func NewClient(root, bucketName string) *Client {
+	return &Client{
+		root:       root,
+		bucketName: bucketName,
+		mutex:      &sync.Mutex{},
+	}
+}
+
+// UploadPart writes the payload as a part of the multipart upload for the requested key, creating the multipart upload
+// if needed. Once all parts have been received the multipart upload is completed.
+func (cli *Client) UploadPart(ctx context.Context, req *storage.PartRequest, payload io.Reader) (storage.PartResponse, error) {
+	logData := log.Data{
+		"chunk_number": req.PartNumber,
+		"max_chunks":   req.TotalParts,
+		"file_name":    req.FileName,
+		"bucket_name":  cli.bucketName,
+	}
+
+	dir, err := cli.uploadDir(req.Key)
+	if err != nil {
+		return storage.PartResponse{}, wrapError(err, req.Key, cli.bucketName)
+	}
+
+	// the part is written to a temporary file first so that a payload that fails to read leaves nothing behind
+	if err := os.MkdirAll(filepath.Join(cli.bucketDir(), uploadsDir), dirPerm); err != nil {
+		return storage.PartResponse{}, wrapError(fmt.Errorf("error creating uploads directory: %w", err), req.Key, cli.bucketName)
+	}
+	tmp, etag, err := writeTemp(filepath.Join(cli.bucketDir(), uploadsDir), payload)
+	if err != nil {
+		return storage.PartResponse{}, wrapError(fmt.Errorf("error reading part: %w", err), req.Key, cli.bucketName)
+	}
+	defer removeIfExists(tmp)
+
+	unlock, err := cli.lock()
+	if err != nil {
+		return storage.PartResponse{}, wrapError(err, req.Key, cli.bucketName)
+	}
+	defer unlock()
+
+	if _, err := cli.getOrCreateUpload(req); err != nil {
+		return storage.PartResponse{}, wrapError(err, req.Key, cli.bucketName)
+	}
+
+	partPath := filepath.Join(dir, strconv.Itoa(int(req.PartNumber))+partSuffix)
+	if err := os.Rename(tmp, partPath); err != nil {
+		return storage.PartResponse{}, wrapError(fmt.Errorf("error uploading part: %w", err), req.Key, cli.bucketName)
+	}
+	if err := os.WriteFile(filepath.Join(dir, strconv.Itoa(int(req.PartNumber))+etagSuffix), []byte(etag), filePerm); err != nil {
+		return storage.PartResponse{}, wrapError(fmt.Errorf("error uploading part: %w", err), req.Key, cli.bucketName)
+	}
+
+	log.Info(ctx, "chunk accepted", logData)
+
+	allPartsUploaded, err := cli.completeIfAllPartsUploaded(req)
+	if err != nil {
+		return storage.PartResponse{}, err
+	}
+
+	return storage.PartResponse{
+		ETag:             etag,
+		AllPartsUploaded: allPartsUploaded,
+	}, nil
+}
+
+// CheckPartUploaded reports whether the requested part has been uploaded, completing the multipart upload if every
+// part has been received, in the same way as the dp-s3 client
+func (cli *Client) CheckPartUploaded(ctx context.Context, req *storage.PartRequest) (bool, error) {
+	logData := log.Data{
+		"chunk_number": req.PartNumber,
+		"max_chunks":   req.TotalParts,
+		"file_name":    req.FileName,
+		"bucket_name":  cli.bucketName,
+		"identifier":   req.Key,
+	}
+
+	unlock, err := cli.lock()
+	if err != nil {
+		return false, wrapError(err, req.Key, cli.bucketName)
+	}
+	defer unlock()
+
+	_, parts, found, err := cli.readUpload(req.Key)
+	if err != nil {
+		return false, wrapError(err, req.Key, cli.bucketName)
+	}
+	if !found {
+		return false, wrapError(storage.ErrNotUploaded, req.Key, cli.bucketName)
+	}
+
+	if len(parts) == req.TotalParts {
+		return cli.completeIfAllPartsUploaded(req)
+	}
+
+	for _, part := range parts {
+		if part.PartNumber == req.PartNumber {
+			log.Info(ctx, "chunk already uploaded", logData)
+			return true, nil
+		}
+	}
+
+	return false, wrapError(storage.ErrPartNotFound, req.Key, cli.bucketName)
+}
+
+// PartExists reports whether the requested part has been uploaded to the in progress multipart upload for the key,
+// without ever completing the multipart upload
+func (cli *Client) PartExists(ctx context.Context, req *storage.PartRequest) (bool, error) {
+	unlock, err := cli.lock()
+	if err != nil {
+		return false, wrapError(err, req.Key, cli.bucketName)
+	}
+	defer unlock()
+
+	_, parts, found, err := cli.readUpload(req.Key)
+	if err != nil {
+		return false, wrapError(err, req.Key, cli.bucketName)
+	}
+
+	for _, part := range parts {
+		if found && part.PartNumber == req.PartNumber {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ListUploadedParts returns the ID of the in progress multipart upload for the key and the parts uploaded to it so far,
+// in part number order. It returns false if there is no multipart upload in progress.
+func (cli *Client) ListUploadedParts(ctx context.Context, key string) (storage.MultipartUploadParts, bool, error) {
+	unlock, err := cli.lock()
+	if err != nil {
+		return storage.MultipartUploadParts{}, false, wrapError(err, key, cli.bucketName)
+	}
+	defer unlock()
+
+	u, parts, found, err := cli.readUpload(key)
+	if err != nil || !found {
+		return storage.MultipartUploadParts{}, false, wrapError(err, key, cli.bucketName)
+	}
+
+	return storage.MultipartUploadParts{UploadID: u.UploadID, Parts: parts}, true, nil
+}
+
+// AbortMultipartUpload aborts the in progress multipart upload for the key, discarding any parts uploaded so far.
+// It returns false if there was no multipart upload in progress.
+func (cli *Client) AbortMultipartUpload(ctx context.Context, key string) (bool, error) {
+	unlock, err := cli.lock()
+	if err != nil {
+		return false, wrapError(err, key, cli.bucketName)
+	}
+	defer unlock()
+
+	_, _, found, err := cli.readUpload(key)
+	if err != nil || !found {
+		return false, wrapError(err, key, cli.bucketName)
+	}
+
+	if err := cli.removeUpload(key); err != nil {
+		return false, wrapError(err, key, cli.bucketName)
+	}
+
+	log.Info(ctx, "multipart upload aborted", log.Data{"key": key, "bucket_name": cli.bucketName})
+	return true, nil
+}
+
+// AbortMultipartUploadByID aborts the multipart upload with the given ID, discarding any parts uploaded so far
+func (cli *Client) AbortMultipartUploadByID(ctx context.Context, key, uploadID string) error {
+	unlock, err := cli.lock()
+	if err != nil {
+		return wrapError(err, key, cli.bucketName)
+	}
+	defer unlock()
+
+	u, _, found, err := cli.readUpload(key)
+	if err != nil {
+		return wrapError(err, key, cli.bucketName)
+	}
+	if !found || u.UploadID != uploadID {
+		return wrapError(fmt.Errorf("multipart upload %q does not exist", uploadID), key, cli.bucketName)
+	}
+
+	if err := cli.removeUpload(key); err != nil {
+		return wrapError(err, key, cli.bucketName)
+	}
+
+	log.Info(ctx, "multipart upload aborted", log.Data{"key": key, "upload_id": uploadID, "bucket_name": cli.bucketName})
+	return nil
+}
+
+// ListMultipartUploads returns every multipart upload in the bucket that has been started but not completed or aborted
+func (cli *Client) ListMultipartUploads(ctx context.Context) ([]storage.MultipartUpload, error) {
+	unlock, err := cli.lock()
+	if err != nil {
+		return nil, fmt.Errorf("%s: error fetching multipart list: %w", cli.bucketName, err)
+	}
+	defer unlock()
+
+	entries, err := os.ReadDir(filepath.Join(cli.bucketDir(), uploadsDir))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: error fetching multipart list: %w", cli.bucketName, err)
+	}
+
+	var uploads []storage.MultipartUpload
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		var u upload
+		if err := readJSON(filepath.Join(cli.bucketDir(), uploadsDir, entry.Name(), uploadFile), &u); err != nil {
+			continue
+		}
+		uploads = append(uploads, storage.MultipartUpload{Key: u.Key, UploadID: u.UploadID, Initiated: u.Initiated})
+	}
+
+	return uploads, nil
+}
+
+// Checker reports the storage as healthy if the directory for the bucket exists or can be created
+func (cli *Client) Checker(ctx context.Context, state *healthcheck.CheckState) error {
+	if err := os.MkdirAll(cli.bucketDir(), dirPerm); err != nil {
+		return state.Update(healthcheck.StatusCritical, err.Error(), 0)
+	}
+	return state.Update(healthcheck.StatusOK, msgHealthy, 0)
+}
+
+// Head describes the object with the key, returning storage.ErrNotFound if it does not exist
+func (cli *Client) Head(ctx context.Context, key string) (storage.ObjectInfo, error) {
+	dataPath, metadataPath, err := cli.objectPaths(key)
+	if err != nil {
+		return storage.ObjectInfo{}, wrapError(err, key, cli.bucketName)
+	}
+
+	info, err := os.Stat(dataPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return storage.ObjectInfo{}, wrapError(storage.ErrNotFound, key, cli.bucketName)
+	}
+	if err != nil {
+		return storage.ObjectInfo{}, wrapError(err, key, cli.bucketName)
+	}
+
+	var obj object
+	if err := readJSON(metadataPath, &obj); err != nil {
+		return storage.ObjectInfo{}, wrapError(err, key, cli.bucketName)
+	}
+
+	return storage.ObjectInfo{
+		SizeInBytes:  info.Size(),
+		ETag:         obj.ETag,
+		ContentType:  obj.ContentType,
+		LastModified: obj.LastModified,
+	}, nil
+}
+
+// Get returns the content of the object with the key and its size, returning storage.ErrNotFound if it does not exist
+func (cli *Client) Get(ctx context.Context, key string) (io.ReadCloser, *int64, error) {
+	dataPath, _, err := cli.objectPaths(key)
+	if err != nil {
+		return nil, nil, wrapError(err, key, cli.bucketName)
+	}
+
+	file, err := os.Open(dataPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil, wrapError(storage.ErrNotFound, key, cli.bucketName)
+	}
+	if err != nil {
+		return nil, nil, wrapError(err, key, cli.bucketName)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, nil, wrapError(err, key, cli.bucketName)
+	}
+	size := info.Size()
+
+	return file, &size, nil
+}
+
+// Delete removes the object with the key. As with S3, deleting an object that does not exist is not an error.
+func (cli *Client) Delete(ctx context.Context, key string) error {
+	dataPath, metadataPath, err := cli.objectPaths(key)
+	if err != nil {
+		return wrapError(err, key, cli.bucketName)
+	}
+
+	unlock, err := cli.lock()
+	if err != nil {
+		return wrapError(err, key, cli.bucketName)
+	}
+	defer unlock()
+
+	for _, path := range []string{dataPath, metadataPath} {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return wrapError(err, key, cli.bucketName)
+		}
+	}
+	return nil
+}
+
+// Put stores the content as the object with the key, replacing any existing object
+func (cli *Client) Put(ctx context.Context, key, contentType string, content io.Reader) error {
+	if err := cli.putObject(key, content, object{ContentType: contentType, LastModified: time.Now().UTC()}); err != nil {
+		return wrapError(err, key, cli.bucketName)
+	}
+	return nil
+}
+
+// List returns every object in the bucket whose key starts with the prefix, ordered by key
+func (cli *Client) List(ctx context.Context, prefix string) ([]storage.ObjectSummary, error) {
+	entries, err := os.ReadDir(filepath.Join(cli.bucketDir(), objectsDir))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: error listing objects: %w", cli.bucketName, err)
+	}
+
+	var objects []storage.ObjectSummary
+	for _, entry := range entries {
+		key, err := url.PathUnescape(entry.Name())
+		if err != nil || entry.IsDir() || strings.HasPrefix(entry.Name(), tempPrefix) || !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		var obj object
+		if err := readJSON(filepath.Join(cli.bucketDir(), metadataDir, entry.Name()+".json"), &obj); err != nil {
+			continue
+		}
+		objects = append(objects, storage.ObjectSummary{Key: key, LastModified: obj.LastModified})
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+
+	return objects, nil
+}
+
+// CopyFrom copies the object with the source key from the source bucket, which must be stored under the same root
+// path, to the key in this bucket, returning the ETag of the copy
+func (cli *Client) CopyFrom(ctx context.Context, sourceBucket, sourceKey, key string) (string, error) {
+	source := NewClient(cli.root, sourceBucket)
+	head, err := source.Head(ctx, sourceKey)
+	if err != nil {
+		return "", err
+	}
+	body, _, err := source.Get(ctx, sourceKey)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err := body.Close(); err != nil {
+			log.Error(ctx, "error closing source object", err, log.Data{"key": sourceKey, "source_bucket": sourceBucket})
+		}
+	}()
+
+	if err := cli.putObject(key, body, object{ETag: head.ETag, ContentType: head.ContentType, LastModified: time.Now().UTC()}); err != nil {
+		return "", wrapError(err, key, cli.bucketName)
+	}
+
+	return head.ETag, nil
+}
+
+// URL returns a file URL for the object with the key
+func (cli *Client) URL(key string) (string, error) {
+	dataPath, _, err := cli.objectPaths(key)
+	if err != nil {
+		return "", err
+	}
+	absPath, err := filepath.Abs(dataPath)
+	if err != nil {
+		return "", err
+	}
+	return (&url.URL{Scheme: "file", Path: absPath}).String(), nil
+}
+
+// PresignGet always returns storage.ErrPresignNotSupported, as objects on disk can't be downloaded with a URL
+func (cli *Client) PresignGet(ctx context.Context, key string, expires time.Duration, contentDisposition string) (string, error) {
+	return "", storage.ErrPresignNotSupported
+}
+
+// CreateMultipartUpload starts a multipart upload for the key, returning its upload ID, or
+// storage.ErrUploadInProgress if one has already been started. As parts can't be uploaded to a URL, they can only be
+// added with UploadPart.
+func (cli *Client) CreateMultipartUpload(ctx context.Context, key, contentType string) (string, error) {
+	unlock, err := cli.lock()
+	if err != nil {
+		return "", wrapError(err, key, cli.bucketName)
+	}
+	defer unlock()
+
+	_, _, found, err := cli.readUpload(key)
+	if err != nil {
+		return "", wrapError(err, key, cli.bucketName)
+	}
+	if found {
+		return "", wrapError(storage.ErrUploadInProgress, key, cli.bucketName)
+	}
+
+	u, err := cli.createUpload(key, contentType)
+	if err != nil {
+		return "", wrapError(err, key, cli.bucketName)
+	}
+	return u.UploadID, nil
+}
+
+// PresignUploadPart always returns storage.ErrPresignNotSupported, as parts can't be uploaded to disk with a URL
+func (cli *Client) PresignUploadPart(ctx context.Context, key, uploadID string, partNumber int32, expires time.Duration) (string, error) {
+	return "", storage.ErrPresignNotSupported
+}
+
+// CompleteMultipartUpload assembles every part uploaded to the multipart upload with the ID into the object, returning
+// storage.ErrNotUploaded if it has already been completed or aborted
+func (cli *Client) CompleteMultipartUpload(ctx context.Context, key, uploadID string) error {
+	unlock, err := cli.lock()
+	if err != nil {
+		return wrapError(err, key, cli.bucketName)
+	}
+	defer unlock()
+
+	u, parts, found, err := cli.readUpload(key)
+	if err != nil {
+		return wrapError(err, key, cli.bucketName)
+	}
+	if !found || u.UploadID != uploadID {
+		return wrapError(storage.ErrNotUploaded, key, cli.bucketName)
+	}
+
+	if err := cli.assemble(u, parts); err != nil {
+		return wrapError(err, key, cli.bucketName)
+	}
+	return nil
+}
+
+// completeIfAllPartsUploaded assembles the parts into the object once every part has been received, returning true
+// only to the request that completed it. The caller must hold the lock.
+func (cli *Client) completeIfAllPartsUploaded(req *storage.PartRequest) (bool, error) {
+	u, parts, found, err := cli.readUpload(req.Key)
+	if err != nil {
+		return false, wrapError(err, req.Key, cli.bucketName)
+	}
+	if !found || len(parts) != req.TotalParts {
+		return false, nil
+	}
+
+	if err := cli.assemble(u, parts); err != nil {
+		return false, wrapError(err, req.Key, cli.bucketName)
+	}
+
+	return true, nil
+}
+
+// assemble writes the parts of the multipart upload into the object, with the same ETag as S3, then removes the
+// multipart upload. The caller must hold the lock.
+func (cli *Client) assemble(u upload, parts []storage.UploadedPart) error {
+	dir, err := cli.uploadDir(u.Key)
+	if err != nil {
+		return err
+	}
+
+	digests := md5.New() //nolint:gosec
+	readers := make([]io.Reader, 0, len(parts))
+	for i, part := range parts {
+		if i < len(parts)-1 && part.SizeInBytes < storage.MinPartSize {
+			return storage.ErrPartTooSmall
+		}
+
+		digest, err := hex.DecodeString(part.ETag)
+		if err != nil {
+			return fmt.Errorf("error reading part etag: %w", err)
+		}
+		digests.Write(digest)
+
+		file, err := os.Open(filepath.Join(dir, strconv.Itoa(int(part.PartNumber))+partSuffix))
+		if err != nil {
+			return fmt.Errorf("error reading part: %w", err)
+		}
+		defer file.Close()
+		readers = append(readers, file)
+	}
+
+	etag := fmt.Sprintf("%s-%d", hex.EncodeToString(digests.Sum(nil)), len(parts))
+	obj := object{ETag: etag, ContentType: u.ContentType, LastModified: time.Now().UTC()}
+	if err := cli.putObject(u.Key, io.MultiReader(readers...), obj); err != nil {
+		return fmt.Errorf("error completing multipart upload: %w", err)
+	}
+
+	return cli.removeUpload(u.Key)
+}
+
+// getOrCreateUpload returns the in progress multipart upload for the requested key, creating one if none exists, or
+// storage.ErrAlreadyUploaded if the object has already been stored. The caller must hold the lock.
+func (cli *Client) getOrCreateUpload(req *storage.PartRequest) (upload, error) {
+	u, _, found, err := cli.readUpload(req.Key)
+	if err != nil || found {
+		return u, err
+	}
+
+	// the object is only stored once its multipart upload completes, so this is a part sent again after that
+	dataPath, _, err := cli.objectPaths(req.Key)
+	if err != nil {
+		return upload{}, err
+	}
+	if _, err := os.Stat(dataPath); err == nil {
+		return upload{}, storage.ErrAlreadyUploaded
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return upload{}, err
+	}
+
+	return cli.createUpload(req.Key, req.ContentType)
+}
+
+// createUpload starts a new multipart upload for the key, replacing any in progress multipart upload for it.
+// The caller must hold the lock.
+func (cli *Client) createUpload(key, contentType string) (upload, error) {
+	if err := cli.removeUpload(key); err != nil {
+		return upload{}, err
+	}
+
+	dir, err := cli.uploadDir(key)
+	if err != nil {
+		return upload{}, err
+	}
+	if err := os.MkdirAll(dir, dirPerm); err != nil {
+		return upload{}, fmt.Errorf("error creating multipart upload: %w", err)
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return upload{}, fmt.Errorf("error creating multipart upload: %w", err)
+	}
+
+	u := upload{
+		UploadID:    hex.EncodeToString(id),
+		Key:         key,
+		ContentType: contentType,
+		Initiated:   time.Now().UTC(),
+	}
+	if err := writeJSON(filepath.Join(dir, uploadFile), u); err != nil {
+		return upload{}, fmt.Errorf("error creating multipart upload: %w", err)
+	}
+
+	return u, nil
+}
+
+// readUpload returns the in progress multipart upload for the key and its parts in part number order, or false if
+// there is none. The caller must hold the lock.
+func (cli *Client) readUpload(key string) (upload, []storage.UploadedPart, bool, error) {
+	dir, err := cli.uploadDir(key)
+	if err != nil {
+		return upload{}, nil, false, err
+	}
+
+	var u upload
+	if err := readJSON(filepath.Join(dir, uploadFile), &u); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return upload{}, nil, false, nil
+		}
+		return upload{}, nil, false, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return upload{}, nil, false, fmt.Errorf("error listing parts: %w", err)
+	}
+
+	var parts []storage.UploadedPart
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), etagSuffix)
+		if !ok {
+			continue
+		}
+		partNumber, err := strconv.Atoi(name)
+		if err != nil {
+			continue
+		}
+
+		etag, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return upload{}, nil, false, fmt.Errorf("error listing parts: %w", err)
+		}
+		info, err := os.Stat(filepath.Join(dir, name+partSuffix))
+		if err != nil {
+			return upload{}, nil, false, fmt.Errorf("error listing parts: %w", err)
+		}
+
+		parts = append(parts, storage.UploadedPart{
+			PartNumber:   int32(partNumber),
+			SizeInBytes:  info.Size(),
+			ETag:         string(etag),
+			LastModified: info.ModTime().UTC(),
+		})
+	}
+	sort.Slice(parts, func(i, j int) bool { return parts[i].PartNumber < parts[j].PartNumber })
+
+	return u, parts, true, nil
+}
+
+// removeUpload discards the multipart upload for the key. The caller must hold the lock.
+func (cli *Client) removeUpload(key string) error {
+	dir, err := cli.uploadDir(key)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
+
+// putObject writes the content and description of the object, replacing any existing object with the key. If the
+// description has no ETag, the MD5 digest of the content is used, as it is for an object that is not multipart.
+func (cli *Client) putObject(key string, content io.Reader, obj object) error {
+	dataPath, metadataPath, err := cli.objectPaths(key)
+	if err != nil {
+		return err
+	}
+
+	for _, dir := range []string{filepath.Dir(dataPath), filepath.Dir(metadataPath)} {
+		if err := os.MkdirAll(dir, dirPerm); err != nil {
+			return err
+		}
+	}
+
+	tmp, digest, err := writeTemp(filepath.Dir(dataPath), content)
+	if err != nil {
+		return err
+	}
+	defer removeIfExists(tmp)
+
+	if obj.ETag == "" {
+		obj.ETag = digest
+	}
+
+	if err := writeJSON(metadataPath, obj); err != nil {
+		return err
+	}
+	return os.Rename(tmp, dataPath)
+}
+
+func (cli *Client) bucketDir() string {
+	return filepath.Join(cli.root, cli.bucketName)
+}
+
+// objectPaths returns the paths of the content and description of the object with the key. Keys are escaped so that
+// each object is a single file, whatever the key contains.
+func (cli *Client) objectPaths(key string) (string, string, error) {
+	name, err := escapeKey(key)
+	if err != nil {
+		return "", "", err
+	}
+	return filepath.Join(cli.bucketDir(), objectsDir, name), filepath.Join(cli.bucketDir(), metadataDir, name+".json"), nil
+}
+
+func (cli *Client) uploadDir(key string) (string, error) {
+	name, err := escapeKey(key)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cli.bucketDir(), uploadsDir, name), nil
+}
+
+func escapeKey(key string) (string, error) {
+	name := url.PathEscape(key)
+	if key == "" || name == "." || name == ".." {
+		return "", fmt.Errorf("invalid key %q", key)
+	}
+	return name, nil
+}
+
+// writeTemp writes the content to a new temporary file in the directory, returning its path and the hex encoded MD5
+// digest of the content
+func writeTemp(dir string, content io.Reader) (string, string, error) {
+	file, err := os.CreateTemp(dir, tempPrefix+"*")
+	if err != nil {
+		return "", "", err
+	}
+
+	digest := md5.New() //nolint:gosec
+	_, err = io.Copy(io.MultiWriter(file, digest), content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		removeIfExists(file.Name())
+		return "", "", err
+	}
+
+	return file.Name(), hex.EncodeToString(digest.Sum(nil)), nil
+}
+
+func removeIfExists(path string) {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Error(context.Background(), "failed to remove temporary file", err, log.Data{"path": path})
+	}
+}
+
+func readJSON(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func writeJSON(path string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, filePerm)
+}
+
+// wrapError adds the bucket and key to an error, which can still be matched with errors.Is, such as against the
+// storage package errors
+func wrapError(err error, key, bucketName string) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("%s/%s: %w", bucketName, key, err)
+}
@@ -0,0 +1,279 @@
+package filesystem_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5" //nolint:gosec
+	"encoding/hex"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-healthcheck/healthcheck"
+	"github.com/ONSdigital/dp-upload-service/filesystem"
+	"github.com/ONSdigital/dp-upload-service/storage"
+	"github.com/stretchr/testify/suite"
+)
+
+type ClientSuite struct {
+	suite.Suite
+	root   string
+	client *filesystem.Client
+}
+
+func TestClient(t *testing.T) {
+	suite.Run(t, new(ClientSuite))
+}
+
+func (s *ClientSuite) SetupTest() {
+	s.root = s.T().TempDir()
+	s.client = filesystem.NewClient(s.root, "bucket")
+}
+
+func (s *ClientSuite) uploadPart(chunkNumber int32, totalChunks int, content []byte) (storage.PartResponse, error) {
+	return s.client.UploadPart(context.Background(), &storage.PartRequest{
+		Key:         "data/file.csv",
+		ContentType: "text/csv",
+		PartNumber:  chunkNumber,
+		TotalParts:  totalChunks,
+		FileName:    "file.csv",
+	}, bytes.NewReader(content))
+}
+
+func md5Hex(content []byte) string {
+	sum := md5.Sum(content) //nolint:gosec
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *ClientSuite) TestUploadPartsAreAssembledIntoObject() {
+	first := bytes.Repeat([]byte("a"), storage.MinPartSize)
+	second := []byte("the last part")
+
+	resp, err := s.uploadPart(2, 2, second)
+	s.Require().NoError(err)
+	s.False(resp.AllPartsUploaded)
+	s.Equal(md5Hex(second), resp.ETag)
+
+	resp, err = s.uploadPart(1, 2, first)
+	s.Require().NoError(err)
+	s.True(resp.AllPartsUploaded)
+
+	body, size, err := s.client.Get(context.Background(), "data/file.csv")
+	s.Require().NoError(err)
+	defer body.Close()
+	content, _ := io.ReadAll(body)
+	s.Equal(append(first, second...), content)
+	s.Equal(int64(len(first)+len(second)), *size)
+
+	head, err := s.client.Head(context.Background(), "data/file.csv")
+	s.Require().NoError(err)
+	digests, _ := hex.DecodeString(md5Hex(first) + md5Hex(second))
+	s.Equal(md5Hex(digests)+"-2", head.ETag)
+	s.Equal("text/csv", head.ContentType)
+	s.Equal(int64(len(content)), head.SizeInBytes)
+
+	_, found, err := s.client.ListUploadedParts(context.Background(), "data/file.csv")
+	s.NoError(err)
+	s.False(found)
+}
+
+func (s *ClientSuite) TestUploadPartOfStoredObjectIsRejected() {
+	_, err := s.uploadPart(1, 1, []byte("the only part"))
+	s.Require().NoError(err)
+
+	_, err = s.uploadPart(1, 1, []byte("the only part"))
+
+	s.ErrorIs(err, storage.ErrAlreadyUploaded)
+	_, found, _ := s.client.ListUploadedParts(context.Background(), "data/file.csv")
+	s.False(found)
+}
+
+func (s *ClientSuite) TestUploadPartBelowMinimumSizeIsRejected() {
+	_, err := s.uploadPart(1, 2, []byte("too small"))
+	s.Require().NoError(err)
+
+	_, err = s.uploadPart(2, 2, []byte("last"))
+
+	s.ErrorIs(err, storage.ErrPartTooSmall)
+}
+
+func (s *ClientSuite) TestListUploadedPartsOfInProgressUpload() {
+	_, err := s.uploadPart(3, 4, []byte("third"))
+	s.Require().NoError(err)
+	_, err = s.uploadPart(1, 4, []byte("first"))
+	s.Require().NoError(err)
+
+	parts, found, err := s.client.ListUploadedParts(context.Background(), "data/file.csv")
+
+	s.NoError(err)
+	s.True(found)
+	s.NotEmpty(parts.UploadID)
+	s.Require().Len(parts.Parts, 2)
+	s.Equal(int32(1), parts.Parts[0].PartNumber)
+	s.Equal(int64(5), parts.Parts[0].SizeInBytes)
+	s.Equal(md5Hex([]byte("first")), parts.Parts[0].ETag)
+	s.Equal(int32(3), parts.Parts[1].PartNumber)
+
+	uploads, err := s.client.ListMultipartUploads(context.Background())
+	s.NoError(err)
+	s.Equal([]storage.MultipartUpload{{Key: "data/file.csv", UploadID: parts.UploadID, Initiated: uploads[0].Initiated}}, uploads)
+}
+
+func (s *ClientSuite) TestCheckPartUploaded() {
+	req := &storage.PartRequest{Key: "data/file.csv", PartNumber: 1, TotalParts: 2}
+
+	_, err := s.client.CheckPartUploaded(context.Background(), req)
+	s.ErrorIs(err, storage.ErrNotUploaded)
+
+	_, err = s.uploadPart(1, 2, []byte("first"))
+	s.Require().NoError(err)
+
+	uploaded, err := s.client.CheckPartUploaded(context.Background(), req)
+	s.NoError(err)
+	s.True(uploaded)
+
+	req.PartNumber = 2
+	_, err = s.client.CheckPartUploaded(context.Background(), req)
+	s.ErrorIs(err, storage.ErrPartNotFound)
+}
+
+func (s *ClientSuite) TestAbortMultipartUploadByID() {
+	_, err := s.uploadPart(1, 2, []byte("first"))
+	s.Require().NoError(err)
+	parts, _, _ := s.client.ListUploadedParts(context.Background(), "data/file.csv")
+
+	err = s.client.AbortMultipartUploadByID(context.Background(), "data/file.csv", "unknown")
+	s.Require().Error(err)
+
+	s.NoError(s.client.AbortMultipartUploadByID(context.Background(), "data/file.csv", parts.UploadID))
+
+	_, found, err := s.client.ListUploadedParts(context.Background(), "data/file.csv")
+	s.NoError(err)
+	s.False(found)
+}
+
+func (s *ClientSuite) TestMissingObjectIsNotFound() {
+	_, err := s.client.Head(context.Background(), "data/missing.csv")
+	s.ErrorIs(err, storage.ErrNotFound)
+
+	_, _, err = s.client.Get(context.Background(), "data/missing.csv")
+	s.ErrorIs(err, storage.ErrNotFound)
+
+	s.NoError(s.client.Delete(context.Background(), "data/missing.csv"))
+}
+
+func (s *ClientSuite) TestKeysCannotEscapeBucket() {
+	_, err := s.client.Head(context.Background(), "..")
+
+	s.Error(err)
+	s.NotErrorIs(err, storage.ErrNotFound)
+}
+
+func (s *ClientSuite) TestCopyFromAndDelete() {
+	_, err := s.uploadPart(1, 1, []byte("content"))
+	s.Require().NoError(err)
+	source, _ := s.client.Head(context.Background(), "data/file.csv")
+	public := filesystem.NewClient(s.root, "public")
+
+	etag, err := public.CopyFrom(context.Background(), "bucket", "data/file.csv", "data/file.csv")
+
+	s.Require().NoError(err)
+	s.Equal(source.ETag, etag)
+	body, _, err := public.Get(context.Background(), "data/file.csv")
+	s.Require().NoError(err)
+	content, _ := io.ReadAll(body)
+	body.Close()
+	s.Equal("content", string(content))
+
+	s.NoError(public.Delete(context.Background(), "data/file.csv"))
+	_, err = public.Head(context.Background(), "data/file.csv")
+	s.ErrorIs(err, storage.ErrNotFound)
+}
+
+func (s *ClientSuite) TestCopyFromToAnotherKey() {
+	_, err := s.uploadPart(1, 1, []byte("content"))
+	s.Require().NoError(err)
+
+	_, err = s.client.CopyFrom(context.Background(), "bucket", "data/file.csv", "quarantine/data/file.csv")
+
+	s.Require().NoError(err)
+	body, _, err := s.client.Get(context.Background(), "quarantine/data/file.csv")
+	s.Require().NoError(err)
+	content, _ := io.ReadAll(body)
+	body.Close()
+	s.Equal("content", string(content))
+}
+
+func (s *ClientSuite) TestPutAndListByPrefix() {
+	ctx := context.Background()
+	s.Require().NoError(s.client.Put(ctx, "records/data/b.json", "application/json", bytes.NewReader([]byte(`{"b":2}`))))
+	s.Require().NoError(s.client.Put(ctx, "records/data/a.json", "application/json", bytes.NewReader([]byte(`{"a":1}`))))
+	_, err := s.uploadPart(1, 1, []byte("content"))
+	s.Require().NoError(err)
+
+	objects, err := s.client.List(ctx, "records/")
+
+	s.Require().NoError(err)
+	s.Require().Len(objects, 2)
+	s.Equal("records/data/a.json", objects[0].Key)
+	s.Equal("records/data/b.json", objects[1].Key)
+	s.False(objects[0].LastModified.IsZero())
+
+	head, err := s.client.Head(ctx, "records/data/a.json")
+	s.Require().NoError(err)
+	s.Equal(md5Hex([]byte(`{"a":1}`)), head.ETag)
+	s.Equal("application/json", head.ContentType)
+}
+
+func (s *ClientSuite) TestCreateMultipartUploadWithUploadInProgress() {
+	_, err := s.uploadPart(1, 2, []byte("first"))
+	s.Require().NoError(err)
+
+	_, err = filesystem.NewClient(s.root, "bucket").CreateMultipartUpload(context.Background(), "data/file.csv", "text/csv")
+
+	s.ErrorIs(err, storage.ErrUploadInProgress)
+	parts, found, _ := s.client.ListUploadedParts(context.Background(), "data/file.csv")
+	s.True(found)
+	s.Len(parts.Parts, 1)
+}
+
+func (s *ClientSuite) TestCompleteMultipartUpload() {
+	uploadID, err := s.client.CreateMultipartUpload(context.Background(), "data/file.csv", "text/csv")
+	s.Require().NoError(err)
+
+	s.ErrorIs(s.client.CompleteMultipartUpload(context.Background(), "data/file.csv", "unknown"), storage.ErrNotUploaded)
+
+	_, err = s.uploadPart(1, 2, []byte("first"))
+	s.Require().NoError(err)
+	parts, _, _ := s.client.ListUploadedParts(context.Background(), "data/file.csv")
+	s.Equal(uploadID, parts.UploadID)
+
+	s.Require().NoError(s.client.CompleteMultipartUpload(context.Background(), "data/file.csv", uploadID))
+
+	head, err := s.client.Head(context.Background(), "data/file.csv")
+	s.Require().NoError(err)
+	digest, _ := hex.DecodeString(md5Hex([]byte("first")))
+	s.Equal(md5Hex(digest)+"-1", head.ETag)
+	s.Equal("text/csv", head.ContentType)
+	s.ErrorIs(s.client.CompleteMultipartUpload(context.Background(), "data/file.csv", uploadID), storage.ErrNotUploaded)
+}
+
+func (s *ClientSuite) TestURLAndPresignGet() {
+	url, err := s.client.URL("data/file.csv")
+	s.NoError(err)
+	s.Equal("file://"+s.root+"/bucket/objects/data%252Ffile.csv", url)
+
+	_, err = s.client.PresignGet(context.Background(), "data/file.csv", time.Minute, "")
+	s.ErrorIs(err, storage.ErrPresignNotSupported)
+
+	_, err = s.client.PresignUploadPart(context.Background(), "data/file.csv", "upload-id", 1, time.Minute)
+	s.ErrorIs(err, storage.ErrPresignNotSupported)
+}
+
+func (s *ClientSuite) TestChecker() {
+	state := healthcheck.NewCheckState("filesystem")
+
+	s.NoError(s.client.Checker(context.Background(), state))
+
+	s.Equal(healthcheck.StatusOK, state.Status())
+}
@@ -0,0 +1,41 @@
+//go:build unix
+
+package filesystem
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// lock takes the lock on the bucket, which is shared by every Client storing the bucket under the same root path,
+// including those of other instances of the service, so that a multipart upload is only created and completed once
+// whichever instance receives its parts. The returned function releases the lock.
+func (cli *Client) lock() (func(), error) {
+	cli.mutex.Lock()
+
+	file, err := openLockFile(cli.bucketDir())
+	if err == nil {
+		err = syscall.Flock(int(file.Fd()), syscall.LOCK_EX)
+		if err != nil {
+			_ = file.Close()
+		}
+	}
+	if err != nil {
+		cli.mutex.Unlock()
+		return nil, err
+	}
+
+	return func() {
+		_ = syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+		_ = file.Close()
+		cli.mutex.Unlock()
+	}, nil
+}
+
+func openLockFile(dir string) (*os.File, error) {
+	if err := os.MkdirAll(dir, dirPerm); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(filepath.Join(dir, lockFile), os.O_CREATE|os.O_RDWR, filePerm)
+}
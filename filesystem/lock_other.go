@@ -0,0 +1,11 @@
+//go:build !unix
+
+package filesystem
+
+// lock takes the lock on the bucket. File locks are only supported on unix, so elsewhere the lock is only shared by
+// the Client and a bucket can only be used by a single instance of the service. The returned function releases the
+// lock.
+func (cli *Client) lock() (func(), error) {
+	cli.mutex.Lock()
+	return cli.mutex.Unlock, nil
+}
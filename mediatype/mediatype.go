@@ -0,0 +1,178 @@
+package mediatype
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// SniffLen is the number of bytes at the start of a file that its content type is sniffed from
+const SniffLen = 512
+
+// oleSignature starts Microsoft Compound File Binary files, which older Office formats are stored in
+var oleSignature = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+
+// oleTypes are the media types stored as Compound File Binary files
+var oleTypes = map[string]bool{
+	"application/vnd.ms-excel":      true,
+	"application/msword":            true,
+	"application/vnd.ms-powerpoint": true,
+}
+
+// signatureTypes are the media types, other than text, zip and Compound File Binary based ones, whose signatures are
+// recognised by http.DetectContentType
+var signatureTypes = map[string]bool{
+	"application/pdf":        true,
+	"application/postscript": true,
+	"application/gzip":       true,
+	"application/x-gzip":     true,
+	"application/ogg":        true,
+	"image/png":              true,
+	"image/jpeg":             true,
+	"image/gif":              true,
+	"image/webp":             true,
+	"image/bmp":              true,
+	"audio/mpeg":             true,
+	"audio/wave":             true,
+	"video/mp4":              true,
+	"video/webm":             true,
+}
+
+// Policy is the list of media types that can be uploaded to a route
+type Policy struct {
+	allowed []string
+}
+
+// NewPolicy returns a policy that allows the media types, which may include wildcards such as image/* or */*. If no
+// media types are given, every type is allowed.
+func NewPolicy(allowed []string) Policy {
+	normalised := make([]string, 0, len(allowed))
+	for _, mediaType := range allowed {
+		if mediaType = strings.ToLower(strings.TrimSpace(mediaType)); mediaType != "" {
+			normalised = append(normalised, mediaType)
+		}
+	}
+	return Policy{allowed: normalised}
+}
+
+// Allows reports whether the media type, ignoring any parameters such as charset, can be uploaded
+func (p Policy) Allows(mediaType string) bool {
+	if len(p.allowed) == 0 {
+		return true
+	}
+
+	declared, _, err := mime.ParseMediaType(mediaType)
+	if err != nil {
+		return false
+	}
+
+	for _, allowed := range p.allowed {
+		if allowed == "*/*" || allowed == declared {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(allowed, "*"); ok && strings.HasSuffix(prefix, "/") && strings.HasPrefix(declared, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AllowsAll reports whether every media type can be uploaded
+func (p Policy) AllowsAll() bool {
+	return len(p.allowed) == 0 || slices.Contains(p.allowed, "*/*")
+}
+
+// SniffsContent reports whether the content of a file declared as the media type should be sniffed to check that it
+// matches, which it is whenever the declared type can be reliably recognised. Content is sniffed even when every type
+// is allowed, as a file is served with the type it was declared as, so a file declared as a type it is not, such as an
+// executable declared as text/csv, would otherwise be downloaded as that type.
+func (p Policy) SniffsContent(mediaType string) bool {
+	return Sniffable(mediaType)
+}
+
+// Sniffable reports whether files of the media type can be reliably recognised from their first bytes: text types,
+// which are told apart from binary data, and formats with a signature that http.DetectContentType recognises
+func Sniffable(mediaType string) bool {
+	declared, _, err := mime.ParseMediaType(mediaType)
+	if err != nil {
+		return false
+	}
+	return isText(declared) || isZip(declared) || oleTypes[declared] || signatureTypes[declared]
+}
+
+// Matches reports whether the first bytes of a file, up to SniffLen, are consistent with its declared media type. The
+// content type is sniffed with http.DetectContentType, which recognises the signatures of common binary formats and
+// otherwise distinguishes text from binary data, so declared text types match any text content and zip based formats
+// such as xlsx and docx match any zip file. Declared types that are not Sniffable, such as application/octet-stream,
+// match any content.
+func Matches(declaredType string, head []byte) bool {
+	if len(head) == 0 {
+		return true
+	}
+
+	declared, _, err := mime.ParseMediaType(declaredType)
+	if err != nil {
+		return false
+	}
+	if !Sniffable(declared) {
+		return true
+	}
+
+	if bytes.HasPrefix(head, oleSignature) {
+		return oleTypes[declared]
+	}
+
+	sniffed, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+	switch {
+	case sniffed == declared:
+		return true
+	case sniffed == "text/plain":
+		// browsers declare CSV files as application/vnd.ms-excel when Excel is installed
+		return isText(declared) || declared == "application/vnd.ms-excel"
+	case sniffed == "text/xml":
+		return isXML(declared)
+	case sniffed == "application/zip":
+		return isZip(declared)
+	case sniffed == "application/x-gzip":
+		return declared == "application/gzip"
+	}
+
+	return false
+}
+
+// Sniff reads the first SniffLen bytes of the reader, returning them along with a reader that reads all of the
+// original content, including the bytes that were sniffed
+func Sniff(r io.Reader) ([]byte, io.Reader, error) {
+	head := make([]byte, SniffLen)
+	n, err := io.ReadFull(r, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, nil, err
+	}
+	head = head[:n]
+
+	return head, io.MultiReader(bytes.NewReader(head), r), nil
+}
+
+func isText(mediaType string) bool {
+	return strings.HasPrefix(mediaType, "text/") ||
+		mediaType == "application/json" ||
+		mediaType == "application/csv" ||
+		strings.HasSuffix(mediaType, "+json") ||
+		isXML(mediaType)
+}
+
+func isXML(mediaType string) bool {
+	return mediaType == "text/xml" || mediaType == "application/xml" || strings.HasSuffix(mediaType, "+xml")
+}
+
+func isZip(mediaType string) bool {
+	return mediaType == "application/zip" ||
+		mediaType == "application/x-zip-compressed" ||
+		mediaType == "application/epub+zip" ||
+		strings.HasPrefix(mediaType, "application/vnd.openxmlformats-officedocument.") ||
+		strings.HasPrefix(mediaType, "application/vnd.oasis.opendocument.")
+}
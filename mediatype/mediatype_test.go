@@ -0,0 +1,164 @@
+package mediatype_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/ONSdigital/dp-upload-service/mediatype"
+	"github.com/stretchr/testify/suite"
+)
+
+var (
+	csvContent  = []byte("name,count\nbrian,1\nruss,2\n")
+	pdfContent  = []byte("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n1 0 obj\n")
+	pngContent  = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	zipContent  = []byte("PK\x03\x04\x14\x00\x06\x00\x08\x00")
+	oleContent  = []byte("\xd0\xcf\x11\xe0\xa1\xb1\x1a\xe1\x00\x00\x00\x00")
+	exeContent  = []byte("MZ\x90\x00\x03\x00\x00\x00\x04\x00\x00\x00\xff\xff\x00\x00")
+	xmlContent  = []byte(`<?xml version="1.0" encoding="UTF-8"?><data></data>`)
+	htmlContent = []byte("<!DOCTYPE html><html><body><script>alert(1)</script></body></html>")
+)
+
+type MediaTypeSuite struct {
+	suite.Suite
+}
+
+func TestMediaType(t *testing.T) {
+	suite.Run(t, new(MediaTypeSuite))
+}
+
+func (s *MediaTypeSuite) TestPolicyAllowsListedTypes() {
+	policy := mediatype.NewPolicy([]string{"text/csv", " Application/PDF ", "image/*"})
+
+	s.True(policy.Allows("text/csv"))
+	s.True(policy.Allows("text/csv; charset=utf-8"))
+	s.True(policy.Allows("application/pdf"))
+	s.True(policy.Allows("image/png"))
+	s.False(policy.Allows("application/x-msdownload"))
+	s.False(policy.Allows("text/plain"))
+	s.False(policy.Allows("imagepng"))
+	s.False(policy.Allows(""))
+}
+
+func (s *MediaTypeSuite) TestPolicyWithWildcardAllowsEveryType() {
+	policy := mediatype.NewPolicy([]string{"*/*"})
+
+	s.True(policy.Allows("application/x-msdownload"))
+	s.False(policy.Allows("not a media type"))
+}
+
+func (s *MediaTypeSuite) TestEmptyPolicyAllowsEveryType() {
+	policy := mediatype.NewPolicy(nil)
+
+	s.True(policy.Allows("application/x-msdownload"))
+	s.True(policy.Allows(""))
+}
+
+func (s *MediaTypeSuite) TestPolicyAllowsAll() {
+	s.True(mediatype.NewPolicy(nil).AllowsAll())
+	s.True(mediatype.NewPolicy([]string{"text/csv", "*/*"}).AllowsAll())
+	s.False(mediatype.NewPolicy([]string{"text/csv", "image/*"}).AllowsAll())
+}
+
+func (s *MediaTypeSuite) TestPolicySniffsContentOfSniffableTypesWhenTypesAreRestricted() {
+	policy := mediatype.NewPolicy([]string{"text/csv", "application/pdf", "application/octet-stream"})
+
+	s.True(policy.SniffsContent("text/csv"))
+	s.True(policy.SniffsContent("application/pdf"))
+	s.False(policy.SniffsContent("application/octet-stream"))
+}
+
+func (s *MediaTypeSuite) TestPolicySniffsContentOfSniffableTypesWhenEveryTypeIsAllowed() {
+	for _, policy := range []mediatype.Policy{mediatype.NewPolicy(nil), mediatype.NewPolicy([]string{"*/*"})} {
+		s.True(policy.SniffsContent("text/csv"))
+		s.True(policy.SniffsContent("application/pdf"))
+		s.False(policy.SniffsContent("application/octet-stream"))
+	}
+}
+
+func (s *MediaTypeSuite) TestSniffable() {
+	s.True(mediatype.Sniffable("text/csv; charset=utf-8"))
+	s.True(mediatype.Sniffable("application/json"))
+	s.True(mediatype.Sniffable("application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"))
+	s.True(mediatype.Sniffable("application/msword"))
+	s.True(mediatype.Sniffable("image/png"))
+	s.False(mediatype.Sniffable("application/octet-stream"))
+	s.False(mediatype.Sniffable("application/x-parquet"))
+	s.False(mediatype.Sniffable("not a media type"))
+}
+
+func (s *MediaTypeSuite) TestMatches() {
+	tests := []struct {
+		declared string
+		content  []byte
+		matches  bool
+	}{
+		{"text/csv", csvContent, true},
+		{"text/csv; charset=utf-8", csvContent, true},
+		{"application/json", []byte(`{"key": "value"}`), true},
+		{"application/vnd.ms-excel", csvContent, true},
+		{"application/pdf", pdfContent, true},
+		{"image/png", pngContent, true},
+		{"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", zipContent, true},
+		{"application/zip", zipContent, true},
+		{"application/vnd.ms-excel", oleContent, true},
+		{"application/xml", xmlContent, true},
+		{"text/html", htmlContent, true},
+		{"text/csv", exeContent, false},
+		{"text/csv", pdfContent, false},
+		{"text/csv", htmlContent, false},
+		{"text/csv", xmlContent, false},
+		{"text/csv", zipContent, false},
+		{"text/csv", oleContent, false},
+		{"image/png", csvContent, false},
+		{"application/pdf", pngContent, false},
+		{"application/zip", exeContent, false},
+		{"not a media type", csvContent, false},
+		{"application/octet-stream", csvContent, true},
+		{"application/octet-stream", exeContent, true},
+		{"application/x-parquet", csvContent, true},
+	}
+
+	for _, test := range tests {
+		s.Equal(test.matches, mediatype.Matches(test.declared, test.content), "%s: %q", test.declared, test.content)
+	}
+}
+
+func (s *MediaTypeSuite) TestEmptyFileMatchesAnyType() {
+	s.True(mediatype.Matches("image/png", nil))
+}
+
+func (s *MediaTypeSuite) TestSniffReturnsTheStartOfTheContent() {
+	content := bytes.Repeat([]byte("a"), mediatype.SniffLen*2)
+
+	head, r, err := mediatype.Sniff(bytes.NewReader(content))
+
+	s.NoError(err)
+	s.Equal(content[:mediatype.SniffLen], head)
+	all, _ := io.ReadAll(r)
+	s.Equal(content, all)
+}
+
+func (s *MediaTypeSuite) TestSniffShortContent() {
+	head, r, err := mediatype.Sniff(strings.NewReader("short"))
+
+	s.NoError(err)
+	s.Equal([]byte("short"), head)
+	all, _ := io.ReadAll(r)
+	s.Equal([]byte("short"), all)
+}
+
+func (s *MediaTypeSuite) TestSniffReadError() {
+	_, _, err := mediatype.Sniff(io.MultiReader(strings.NewReader("partial"), &errorReader{}))
+
+	s.Error(err)
+}
+
+type errorReader struct{}
+
+func (e *errorReader) Read(p []byte) (int, error) {
+	return 0, errors.New("broken")
+}
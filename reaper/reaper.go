@@ -0,0 +1,117 @@
+package reaper
+
+import (
+	"context"
+	"time"
+
+	"github.com/ONSdigital/dp-upload-service/storage"
+	"github.com/ONSdigital/log.go/v2/log"
+)
+
+//go:generate moq -out mock/uploads.go -pkg mock_reaper . MultipartUploads
+
+// MultipartUploads lists and aborts the incomplete multipart uploads in a bucket
+type MultipartUploads interface {
+	ListMultipartUploads(ctx context.Context) ([]storage.MultipartUpload, error)
+	AbortMultipartUploadByID(ctx context.Context, key, uploadID string) error
+}
+
+// Reaper periodically aborts multipart uploads that were started but never completed, so that the parts uploaded so
+// far stop being stored
+type Reaper struct {
+	uploads  MultipartUploads
+	cleanup  func(ctx context.Context, path string)
+	interval time.Duration
+	maxAge   time.Duration
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// New creates a Reaper that checks for abandoned multipart uploads every interval, aborting those started more than
+// maxAge ago
+func New(uploads MultipartUploads, interval, maxAge time.Duration) *Reaper {
+	return &Reaper{
+		uploads:  uploads,
+		interval: interval,
+		maxAge:   maxAge,
+		done:     make(chan struct{}),
+	}
+}
+
+// WithCleanup returns the reaper with a function that is called with the path of each upload once it has been aborted,
+// to remove anything else kept for the upload
+func (r *Reaper) WithCleanup(cleanup func(ctx context.Context, path string)) *Reaper {
+	r.cleanup = cleanup
+	return r
+}
+
+// Start runs the reaper in the background until Stop is called or the context is done
+func (r *Reaper) Start(ctx context.Context) {
+	ctx, r.cancel = context.WithCancel(ctx)
+
+	go func() {
+		defer close(r.done)
+
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				r.Reap(ctx) // nolint // errors are logged by Reap
+			}
+		}
+	}()
+}
+
+// Stop stops the reaper, waiting for any run in progress to finish. It does nothing if the reaper was not started.
+func (r *Reaper) Stop() {
+	if r.cancel == nil {
+		return
+	}
+	r.cancel()
+	<-r.done
+}
+
+// Reap aborts every multipart upload started more than maxAge ago and returns the paths that were cleaned up. A
+// failure to abort one upload does not stop the others from being aborted; the last error is returned.
+func (r *Reaper) Reap(ctx context.Context) ([]string, error) {
+	uploads, err := r.uploads.ListMultipartUploads(ctx)
+	if err != nil {
+		log.Error(ctx, "failed to list multipart uploads", err)
+		return nil, err
+	}
+
+	threshold := time.Now().Add(-r.maxAge)
+	var reaped []string
+	var lastErr error
+
+	for _, upload := range uploads {
+		if !upload.Initiated.Before(threshold) {
+			continue
+		}
+
+		logData := log.Data{"path": upload.Key, "upload_id": upload.UploadID, "initiated": upload.Initiated}
+		if err := r.uploads.AbortMultipartUploadByID(ctx, upload.Key, upload.UploadID); err != nil {
+			log.Error(ctx, "failed to abort abandoned multipart upload", err, logData)
+			lastErr = err
+			continue
+		}
+
+		log.Info(ctx, "aborted abandoned multipart upload", logData)
+		if r.cleanup != nil {
+			r.cleanup(ctx, upload.Key)
+		}
+		reaped = append(reaped, upload.Key)
+	}
+
+	log.Info(ctx, "finished reaping abandoned multipart uploads", log.Data{
+		"paths":   reaped,
+		"checked": len(uploads),
+		"max_age": r.maxAge.String(),
+	})
+
+	return reaped, lastErr
+}
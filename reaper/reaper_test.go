@@ -0,0 +1,159 @@
+package reaper_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-upload-service/reaper"
+	mock_reaper "github.com/ONSdigital/dp-upload-service/reaper/mock"
+	"github.com/ONSdigital/dp-upload-service/storage"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestReap(t *testing.T) {
+	Convey("Given a bucket with an old and a recent incomplete multipart upload", t, func() {
+		uploads := &mock_reaper.MultipartUploadsMock{
+			ListMultipartUploadsFunc: func(ctx context.Context) ([]storage.MultipartUpload, error) {
+				return []storage.MultipartUpload{
+					{Key: "data/old.csv", UploadID: "old-id", Initiated: time.Now().Add(-48 * time.Hour)},
+					{Key: "data/recent.csv", UploadID: "recent-id", Initiated: time.Now().Add(-time.Hour)},
+				}, nil
+			},
+			AbortMultipartUploadByIDFunc: func(ctx context.Context, key, uploadID string) error {
+				return nil
+			},
+		}
+		r := reaper.New(uploads, time.Hour, 24*time.Hour)
+
+		Convey("When Reap is called", func() {
+			reaped, err := r.Reap(context.Background())
+
+			Convey("Then only the upload older than the max age is aborted", func() {
+				So(err, ShouldBeNil)
+				So(reaped, ShouldResemble, []string{"data/old.csv"})
+				So(uploads.AbortMultipartUploadByIDCalls(), ShouldHaveLength, 1)
+				So(uploads.AbortMultipartUploadByIDCalls()[0].Key, ShouldEqual, "data/old.csv")
+				So(uploads.AbortMultipartUploadByIDCalls()[0].UploadID, ShouldEqual, "old-id")
+			})
+		})
+	})
+
+	Convey("Given a reaper with a cleanup function", t, func() {
+		uploads := &mock_reaper.MultipartUploadsMock{
+			ListMultipartUploadsFunc: func(ctx context.Context) ([]storage.MultipartUpload, error) {
+				return []storage.MultipartUpload{
+					{Key: "data/old.csv", UploadID: "old-id", Initiated: time.Now().Add(-48 * time.Hour)},
+					{Key: "data/failed.csv", UploadID: "failed-id", Initiated: time.Now().Add(-48 * time.Hour)},
+					{Key: "data/recent.csv", UploadID: "recent-id", Initiated: time.Now().Add(-time.Hour)},
+				}, nil
+			},
+			AbortMultipartUploadByIDFunc: func(ctx context.Context, key, uploadID string) error {
+				if key == "data/failed.csv" {
+					return errors.New("abort failed")
+				}
+				return nil
+			},
+		}
+		var cleaned []string
+		r := reaper.New(uploads, time.Hour, 24*time.Hour).WithCleanup(func(ctx context.Context, path string) {
+			cleaned = append(cleaned, path)
+		})
+
+		Convey("When Reap is called", func() {
+			_, _ = r.Reap(context.Background())
+
+			Convey("Then only the uploads that were aborted are cleaned up", func() {
+				So(cleaned, ShouldResemble, []string{"data/old.csv"})
+			})
+		})
+	})
+
+	Convey("Given aborting one of the old uploads fails", t, func() {
+		abortErr := errors.New("abort failed")
+		uploads := &mock_reaper.MultipartUploadsMock{
+			ListMultipartUploadsFunc: func(ctx context.Context) ([]storage.MultipartUpload, error) {
+				return []storage.MultipartUpload{
+					{Key: "data/first.csv", UploadID: "first-id", Initiated: time.Now().Add(-48 * time.Hour)},
+					{Key: "data/second.csv", UploadID: "second-id", Initiated: time.Now().Add(-48 * time.Hour)},
+				}, nil
+			},
+			AbortMultipartUploadByIDFunc: func(ctx context.Context, key, uploadID string) error {
+				if key == "data/first.csv" {
+					return abortErr
+				}
+				return nil
+			},
+		}
+		r := reaper.New(uploads, time.Hour, 24*time.Hour)
+
+		Convey("When Reap is called", func() {
+			reaped, err := r.Reap(context.Background())
+
+			Convey("Then the other uploads are still aborted and the error is returned", func() {
+				So(err, ShouldEqual, abortErr)
+				So(reaped, ShouldResemble, []string{"data/second.csv"})
+				So(uploads.AbortMultipartUploadByIDCalls(), ShouldHaveLength, 2)
+			})
+		})
+	})
+
+	Convey("Given listing the multipart uploads fails", t, func() {
+		listErr := errors.New("list failed")
+		uploads := &mock_reaper.MultipartUploadsMock{
+			ListMultipartUploadsFunc: func(ctx context.Context) ([]storage.MultipartUpload, error) {
+				return nil, listErr
+			},
+		}
+		r := reaper.New(uploads, time.Hour, 24*time.Hour)
+
+		Convey("When Reap is called", func() {
+			reaped, err := r.Reap(context.Background())
+
+			Convey("Then the error is returned and nothing is aborted", func() {
+				So(err, ShouldEqual, listErr)
+				So(reaped, ShouldBeEmpty)
+				So(uploads.AbortMultipartUploadByIDCalls(), ShouldHaveLength, 0)
+			})
+		})
+	})
+}
+
+func TestStartStop(t *testing.T) {
+	Convey("Given a started reaper with a short interval", t, func() {
+		listed := make(chan struct{}, 1)
+		uploads := &mock_reaper.MultipartUploadsMock{
+			ListMultipartUploadsFunc: func(ctx context.Context) ([]storage.MultipartUpload, error) {
+				select {
+				case listed <- struct{}{}:
+				default:
+				}
+				return nil, nil
+			},
+		}
+		r := reaper.New(uploads, 10*time.Millisecond, 24*time.Hour)
+		r.Start(context.Background())
+
+		Convey("Then it reaps on each interval and stops when asked", func() {
+			select {
+			case <-listed:
+			case <-time.After(time.Second):
+				So("reaper did not run", ShouldBeEmpty)
+			}
+
+			r.Stop()
+			calls := len(uploads.ListMultipartUploadsCalls())
+			time.Sleep(50 * time.Millisecond)
+			So(uploads.ListMultipartUploadsCalls(), ShouldHaveLength, calls)
+		})
+	})
+
+	Convey("Given a reaper that has not been started", t, func() {
+		r := reaper.New(&mock_reaper.MultipartUploadsMock{}, time.Hour, 24*time.Hour)
+
+		Convey("Then Stop returns straight away", func() {
+			r.Stop()
+		})
+	})
+}
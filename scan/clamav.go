@@ -0,0 +1,172 @@
+package scan
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/ONSdigital/dp-healthcheck/healthcheck"
+	"github.com/ONSdigital/log.go/v2/log"
+)
+
+// chunkSize is the size of the chunks that content is streamed to clamd in
+const chunkSize = 64 * 1024
+
+const msgHealthy = "clamd is responding"
+
+var ErrUnexpectedResponse = errors.New("unexpected response from clamd")
+
+// ClamAV scans files with a clamd daemon, streaming them over its INSTREAM command so that clamd does not need access
+// to the files itself
+type ClamAV struct {
+	addr    string
+	timeout time.Duration
+}
+
+// NewClamAV returns a Scanner for the clamd daemon listening on the address, which is either host:port or the path of
+// a unix socket. Each scan must be completed within the timeout.
+func NewClamAV(addr string, timeout time.Duration) *ClamAV {
+	return &ClamAV{
+		addr:    addr,
+		timeout: timeout,
+	}
+}
+
+// Scan streams the content to clamd and returns its verdict. clamd responds with an error if the content is larger
+// than its StreamMaxLength setting.
+func (c *ClamAV) Scan(ctx context.Context, content io.Reader) (Result, error) {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return Result{}, err
+	}
+	defer closeConn(ctx, conn)
+
+	readErr, writeErr := stream(conn, content)
+	if readErr != nil {
+		return Result{}, fmt.Errorf("failed to read content to scan: %w", readErr)
+	}
+
+	// clamd stops reading and responds with an error when the stream is too long, so its response is read even if
+	// the content could not be sent in full
+	reply, err := readReply(conn)
+	if err != nil {
+		if writeErr != nil {
+			return Result{}, writeErr
+		}
+		return Result{}, err
+	}
+
+	reply = strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case reply == "OK":
+		return Result{}, writeErr
+	case strings.HasSuffix(reply, " FOUND"):
+		return Result{Infected: true, Signature: strings.TrimSuffix(reply, " FOUND")}, nil
+	case strings.HasSuffix(reply, " ERROR"):
+		return Result{}, fmt.Errorf("clamd failed to scan content: %s", strings.TrimSuffix(reply, " ERROR"))
+	}
+
+	return Result{}, fmt.Errorf("%w: %q", ErrUnexpectedResponse, reply)
+}
+
+// Checker reports clamd as healthy if it responds to a PING command
+func (c *ClamAV) Checker(ctx context.Context, state *healthcheck.CheckState) error {
+	if err := c.ping(ctx); err != nil {
+		return state.Update(healthcheck.StatusCritical, err.Error(), 0)
+	}
+	return state.Update(healthcheck.StatusOK, msgHealthy, 0)
+}
+
+func (c *ClamAV) ping(ctx context.Context) error {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeConn(ctx, conn)
+
+	if _, err := conn.Write([]byte("zPING\x00")); err != nil {
+		return err
+	}
+	reply, err := readReply(conn)
+	if err != nil {
+		return err
+	}
+	if reply != "PONG" {
+		return fmt.Errorf("%w: %q", ErrUnexpectedResponse, reply)
+	}
+	return nil
+}
+
+// dial connects to clamd with a deadline of the timeout or the context's deadline, whichever is sooner
+func (c *ClamAV) dial(ctx context.Context) (net.Conn, error) {
+	network := "tcp"
+	if strings.HasPrefix(c.addr, "/") {
+		network = "unix"
+	}
+
+	deadline := time.Now().Add(c.timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+
+	dialer := net.Dialer{Deadline: deadline}
+	conn, err := dialer.DialContext(ctx, network, c.addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to clamd: %w", err)
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		closeConn(ctx, conn)
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// stream sends the content with the INSTREAM command, as chunks that are each prefixed with their length and are
+// followed by a zero length chunk. It returns any error reading the content separately from any error sending it.
+func stream(conn net.Conn, content io.Reader) (readErr, writeErr error) {
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 4+chunkSize)
+	for {
+		n, err := content.Read(buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf, uint32(n))
+			if _, err := conn.Write(buf[:4+n]); err != nil {
+				return nil, err
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err, nil
+		}
+	}
+
+	_, err := conn.Write([]byte{0, 0, 0, 0})
+	return nil, err
+}
+
+func closeConn(ctx context.Context, conn net.Conn) {
+	if err := conn.Close(); err != nil {
+		log.Error(ctx, "error closing connection to clamd", err)
+	}
+}
+
+// readReply reads a null terminated reply from clamd
+func readReply(conn net.Conn) (string, error) {
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && (err != io.EOF || reply == "") {
+		return "", err
+	}
+	return strings.TrimSpace(strings.TrimSuffix(reply, "\x00")), nil
+}
@@ -0,0 +1,156 @@
+package scan_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-healthcheck/healthcheck"
+	"github.com/ONSdigital/dp-upload-service/scan"
+	"github.com/stretchr/testify/suite"
+)
+
+type ClamAVSuite struct {
+	suite.Suite
+
+	listener net.Listener
+	received chan []byte
+}
+
+func TestClamAV(t *testing.T) {
+	suite.Run(t, new(ClamAVSuite))
+}
+
+func (s *ClamAVSuite) TearDownTest() {
+	s.listener.Close()
+}
+
+// givenClamdReplies starts a stub clamd that responds to each INSTREAM command with the reply, sending the content it
+// received to the received channel, and to each PING command with PONG
+func (s *ClamAVSuite) givenClamdReplies(reply string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	s.Require().NoError(err)
+	received := make(chan []byte, 10)
+	s.listener, s.received = listener, received
+	go serveClamd(listener, reply, received)
+}
+
+func serveClamd(listener net.Listener, reply string, received chan<- []byte) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+
+		r := bufio.NewReader(conn)
+		command, _ := r.ReadString(0)
+		switch command {
+		case "zPING\x00":
+			conn.Write([]byte("PONG\x00"))
+		case "zINSTREAM\x00":
+			var content bytes.Buffer
+			for {
+				var size uint32
+				if err := binary.Read(r, binary.BigEndian, &size); err != nil || size == 0 {
+					break
+				}
+				io.CopyN(&content, r, int64(size))
+			}
+			received <- content.Bytes()
+			conn.Write([]byte(reply + "\x00"))
+		}
+		conn.Close()
+	}
+}
+
+func (s *ClamAVSuite) scanner() *scan.ClamAV {
+	return scan.NewClamAV(s.listener.Addr().String(), time.Second)
+}
+
+func (s *ClamAVSuite) TestCleanContent() {
+	s.givenClamdReplies("stream: OK")
+	content := strings.Repeat("name,count\n", 10000)
+
+	result, err := s.scanner().Scan(context.Background(), strings.NewReader(content))
+
+	s.NoError(err)
+	s.False(result.Infected)
+	s.Equal(content, string(<-s.received))
+}
+
+func (s *ClamAVSuite) TestInfectedContent() {
+	s.givenClamdReplies("stream: Eicar-Test-Signature FOUND")
+
+	result, err := s.scanner().Scan(context.Background(), strings.NewReader("content"))
+
+	s.NoError(err)
+	s.True(result.Infected)
+	s.Equal("Eicar-Test-Signature", result.Signature)
+}
+
+func (s *ClamAVSuite) TestClamdError() {
+	s.givenClamdReplies("INSTREAM size limit exceeded. ERROR")
+
+	_, err := s.scanner().Scan(context.Background(), strings.NewReader("content"))
+
+	s.ErrorContains(err, "INSTREAM size limit exceeded.")
+}
+
+func (s *ClamAVSuite) TestUnexpectedResponse() {
+	s.givenClamdReplies("UNKNOWN COMMAND")
+
+	_, err := s.scanner().Scan(context.Background(), strings.NewReader("content"))
+
+	s.ErrorIs(err, scan.ErrUnexpectedResponse)
+}
+
+func (s *ClamAVSuite) TestContentReadError() {
+	s.givenClamdReplies("stream: OK")
+
+	_, err := s.scanner().Scan(context.Background(), io.MultiReader(strings.NewReader("partial"), &errorReader{}))
+
+	s.ErrorContains(err, "broken")
+}
+
+func (s *ClamAVSuite) TestClamdUnavailable() {
+	s.givenClamdReplies("stream: OK")
+	s.listener.Close()
+
+	_, err := s.scanner().Scan(context.Background(), strings.NewReader("content"))
+
+	s.ErrorContains(err, "failed to connect to clamd")
+}
+
+func (s *ClamAVSuite) TestCheckerHealthy() {
+	s.givenClamdReplies("stream: OK")
+	state := healthcheck.NewCheckState("ClamAV")
+
+	err := s.scanner().Checker(context.Background(), state)
+
+	s.NoError(err)
+	s.Equal(healthcheck.StatusOK, state.Status())
+}
+
+func (s *ClamAVSuite) TestCheckerUnavailable() {
+	s.givenClamdReplies("stream: OK")
+	s.listener.Close()
+	state := healthcheck.NewCheckState("ClamAV")
+
+	err := s.scanner().Checker(context.Background(), state)
+
+	s.NoError(err)
+	s.Equal(healthcheck.StatusCritical, state.Status())
+}
+
+type errorReader struct{}
+
+func (e *errorReader) Read(p []byte) (int, error) {
+	return 0, errors.New("broken")
+}
@@ -0,0 +1,22 @@
+package scan
+
+import (
+	"context"
+	"io"
+
+	"github.com/ONSdigital/dp-healthcheck/healthcheck"
+)
+
+//go:generate moq -out mock/scanner.go -pkg mock_scan . Scanner
+
+// Result is the verdict of scanning a file for malware
+type Result struct {
+	Infected  bool
+	Signature string
+}
+
+// Scanner scans the content of files for malware
+type Scanner interface {
+	Scan(ctx context.Context, content io.Reader) (Result, error)
+	Checker(ctx context.Context, state *healthcheck.CheckState) error
+}
@@ -2,6 +2,8 @@ package sdk
 
 import (
 	"context"
+	"net/url"
+	"path"
 
 	"github.com/ONSdigital/dp-api-clients-go/v2/health"
 	"github.com/ONSdigital/dp-healthcheck/healthcheck"
@@ -9,24 +11,66 @@ import (
 
 const (
 	serviceName = "dp-upload-service"
+	uploadPath  = "/upload-new"
 )
 
 type Client struct {
-	hcCli *health.Client
+	hcCli       *health.Client
+	concurrency int
+	progress    ProgressFunc
+	retry       retryPolicy
 }
 
-// New creates a new instance of Client with the provided upload service URL
-func New(uploadServiceURL string) *Client {
-	return &Client{
-		hcCli: health.NewClient(serviceName, uploadServiceURL),
+// Option configures optional behaviour of a Client
+type Option func(*Client)
+
+// WithConcurrency sets the number of chunks Upload sends to the upload service at the same time. Values less than 1
+// are treated as 1, which uploads the chunks one after another.
+func WithConcurrency(concurrency int) Option {
+	return func(cli *Client) {
+		if concurrency < 1 {
+			concurrency = 1
+		}
+		cli.concurrency = concurrency
 	}
 }
 
+// New creates a new instance of Client with the provided upload service URL
+func New(uploadServiceURL string, opts ...Option) *Client {
+	return newClient(health.NewClient(serviceName, uploadServiceURL), opts)
+}
+
 // NewWithHealthClient creates a new instance of Client using an existing health.Client
-func NewWithHealthClient(hcCli *health.Client) *Client {
-	return &Client{
-		hcCli: health.NewClientWithClienter(serviceName, hcCli.URL, hcCli.Client),
+func NewWithHealthClient(hcCli *health.Client, opts ...Option) *Client {
+	return newClient(health.NewClientWithClienter(serviceName, hcCli.URL, hcCli.Client), opts)
+}
+
+func newClient(hcCli *health.Client, opts []Option) *Client {
+	cli := &Client{
+		hcCli:       hcCli,
+		concurrency: 1,
+	}
+	for _, opt := range opts {
+		opt(cli)
 	}
+
+	if cli.retry.maxRetries > 0 {
+		cli.disableClienterRetries(uploadPath)
+	}
+
+	return cli
+}
+
+// disableClienterRetries stops the underlying dp-net client retrying requests to the path, for requests the Client
+// retries itself
+func (cli *Client) disableClienterRetries(requestPath string) {
+	u, err := url.Parse(cli.hcCli.URL)
+	if err != nil {
+		return
+	}
+
+	paths := append(cli.hcCli.Client.GetPathsWithNoRetries(), path.Join("/", u.Path, requestPath))
+	cli.hcCli.Client.SetPathsWithNoRetries(paths)
 }
 
 // URL returns the URL used by the Client
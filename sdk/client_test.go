@@ -126,3 +126,39 @@ func TestNewWithHealthClient(t *testing.T) {
 		})
 	})
 }
+
+func TestWithConcurrency(t *testing.T) {
+	t.Parallel()
+
+	Convey("Given an upload service Client created without options", t, func() {
+		client := New(uploadServiceURL)
+
+		Convey("Then chunks are uploaded one at a time", func() {
+			So(client.concurrency, ShouldEqual, 1)
+		})
+	})
+
+	Convey("Given an upload service Client created WithConcurrency", t, func() {
+		client := New(uploadServiceURL, WithConcurrency(4))
+
+		Convey("Then the concurrency is set", func() {
+			So(client.concurrency, ShouldEqual, 4)
+		})
+	})
+
+	Convey("Given an upload service Client created from a health.Client WithConcurrency", t, func() {
+		client := NewWithHealthClient(health.NewClient(serviceName, uploadServiceURL), WithConcurrency(3))
+
+		Convey("Then the concurrency is set", func() {
+			So(client.concurrency, ShouldEqual, 3)
+		})
+	})
+
+	Convey("Given an upload service Client created WithConcurrency less than 1", t, func() {
+		client := New(uploadServiceURL, WithConcurrency(0))
+
+		Convey("Then chunks are uploaded one at a time", func() {
+			So(client.concurrency, ShouldEqual, 1)
+		})
+	})
+}
@@ -0,0 +1,45 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Delete aborts an in progress upload of the file at the provided path via the /upload-new/files endpoint
+func (cli *Client) Delete(ctx context.Context, path string, headers Headers) error {
+	uploadURL, err := url.Parse(fmt.Sprintf("%s/upload-new/files", cli.hcCli.URL))
+	if err != nil {
+		return err
+	}
+	uploadURL = uploadURL.JoinPath(strings.TrimPrefix(path, "/"))
+
+	req, err := http.NewRequest(http.MethodDelete, uploadURL.String(), http.NoBody)
+	if err != nil {
+		return err
+	}
+
+	headers.Add(req)
+
+	resp, err := cli.hcCli.Client.Do(ctx, req)
+	if err != nil {
+		closeResponseBody(ctx, resp)
+		return err
+	}
+	defer closeResponseBody(ctx, resp)
+
+	if resp.StatusCode != http.StatusNoContent {
+		jsonErrors, err := unmarshalJsonErrors(resp.Body)
+		if err != nil {
+			return err
+		}
+		return &APIError{
+			StatusCode: resp.StatusCode,
+			Errors:     jsonErrors,
+		}
+	}
+
+	return nil
+}
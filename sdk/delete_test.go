@@ -0,0 +1,77 @@
+package sdk
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDelete_Success(t *testing.T) {
+	t.Parallel()
+
+	Convey("Given a client and a path", t, func() {
+		mockClienter := newMockClienter(&http.Response{StatusCode: http.StatusNoContent, Body: http.NoBody}, nil)
+		client := newMockUploadServiceClient(mockClienter)
+
+		Convey("When Delete is called", func() {
+			err := client.Delete(context.Background(), "/path/to/data.csv", Headers{ServiceAuthToken: "token"})
+
+			Convey("Then no error is returned", func() {
+				So(err, ShouldBeNil)
+			})
+
+			Convey("And the mock clienter's Do method is called once with the correct request details", func() {
+				So(mockClienter.DoCalls(), ShouldHaveLength, 1)
+				actualCall := mockClienter.DoCalls()[0]
+				So(actualCall.Req.Method, ShouldEqual, http.MethodDelete)
+				So(actualCall.Req.URL.String(), ShouldEqual, uploadServiceURL+"/upload-new/files/path/to/data.csv")
+				So(actualCall.Req.Header.Get("Authorization"), ShouldEqual, "Bearer token")
+			})
+		})
+	})
+}
+
+func TestDelete_Failure(t *testing.T) {
+	t.Parallel()
+
+	Convey("When the Client's Do() function fails", t, func() {
+		expectedDoErr := errors.New("intentional Do error")
+		mockClienter := newMockClienter(nil, expectedDoErr)
+		client := newMockUploadServiceClient(mockClienter)
+
+		Convey("And Delete is called", func() {
+			err := client.Delete(context.Background(), "path/to/data.csv", Headers{})
+
+			Convey("Then the expected error is returned", func() {
+				So(err, ShouldEqual, expectedDoErr)
+			})
+		})
+	})
+
+	Convey("When the upload service returns an unexpected status code", t, func() {
+		body := `{"errors":[{"code":"NotFound","description":"no upload in progress for this path"}]}`
+		mockClienter := newMockClienter(
+			&http.Response{
+				StatusCode: http.StatusNotFound,
+				Body:       io.NopCloser(bytes.NewReader([]byte(body))),
+			}, nil)
+		client := newMockUploadServiceClient(mockClienter)
+
+		Convey("And Delete is called", func() {
+			err := client.Delete(context.Background(), "path/to/data.csv", Headers{})
+
+			Convey("Then an APIError is returned with the expected details", func() {
+				apiErr, ok := err.(*APIError)
+				So(ok, ShouldBeTrue)
+				So(apiErr.StatusCode, ShouldEqual, http.StatusNotFound)
+				So(apiErr.Errors, ShouldNotBeNil)
+				So(apiErr.Errors.Error[0].Code, ShouldEqual, "NotFound")
+			})
+		})
+	})
+}
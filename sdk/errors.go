@@ -1,6 +1,7 @@
 package sdk
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/ONSdigital/dp-upload-service/api"
@@ -22,11 +23,31 @@ func (e *APIError) Error() string {
 
 	for _, err := range e.Errors.Error {
 		errorMessage += fmt.Sprintf("\n  - code: %s, description: %s", err.Code, err.Description)
+		if err.Field != "" {
+			errorMessage += fmt.Sprintf(", field: %s, rule: %s", err.Field, err.Rule)
+		}
 	}
 	return errorMessage
 }
 
+// FieldErrors returns the errors that describe an invalid request field, so that callers can report each of them
+// against the field that caused it
+func (e *APIError) FieldErrors() []api.JsonError {
+	if e.Errors == nil {
+		return nil
+	}
+
+	var fieldErrors []api.JsonError
+	for _, err := range e.Errors.Error {
+		if err.Field != "" {
+			fieldErrors = append(fieldErrors, err)
+		}
+	}
+	return fieldErrors
+}
+
 // List of errors that can be returned by the SDK
 var (
-	ErrFileTooLarge = fmt.Errorf("file too large, max file size: %d MB", maxFileSize>>20)
+	ErrFileTooLarge       = fmt.Errorf("file too large, max file size: %d MB", maxFileSize>>20)
+	ErrUploadNotCompleted = errors.New("upload service did not report the file as completed")
 )
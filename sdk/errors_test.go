@@ -28,6 +28,18 @@ func TestAPIError_Error(t *testing.T) {
 			},
 			expected: "API error: status code 400\n  - code: ValidationError, description: IsPublishable required\n  - code: ValidationError, description: Path is required",
 		},
+		{
+			name: "APIError with field errors",
+			apiError: &APIError{
+				StatusCode: 400,
+				Errors: &api.JsonErrors{
+					Error: []api.JsonError{
+						{Code: "ValidationError", Description: "path is required", Field: "path", Rule: "required"},
+					},
+				},
+			},
+			expected: "API error: status code 400\n  - code: ValidationError, description: path is required, field: path, rule: required",
+		},
 		{
 			name: "APIError with no errors",
 			apiError: &APIError{
@@ -60,3 +72,39 @@ func TestAPIError_Error(t *testing.T) {
 		})
 	}
 }
+
+func TestAPIError_FieldErrors(t *testing.T) {
+	Convey("Given an APIError with field and non-field errors", t, func() {
+		apiError := &APIError{
+			StatusCode: 400,
+			Errors: &api.JsonErrors{
+				Error: []api.JsonError{
+					{Code: "ValidationError", Description: "path is required", Field: "path", Rule: "required"},
+					{Code: "FileForm", Description: "http: no such file"},
+					{Code: "DecodingMetadata", Description: "resumableTotalSize must be a whole number", Field: "resumableTotalSize", Rule: "type", Value: "abc"},
+				},
+			},
+		}
+
+		Convey("When FieldErrors is called", func() {
+			fieldErrors := apiError.FieldErrors()
+
+			Convey("Then only the errors describing a field are returned", func() {
+				So(fieldErrors, ShouldHaveLength, 2)
+				So(fieldErrors[0].Field, ShouldEqual, "path")
+				So(fieldErrors[1].Field, ShouldEqual, "resumableTotalSize")
+				So(fieldErrors[1].Value, ShouldEqual, "abc")
+			})
+		})
+	})
+
+	Convey("Given an APIError with no errors", t, func() {
+		apiError := &APIError{StatusCode: 500}
+
+		Convey("When FieldErrors is called", func() {
+			Convey("Then no field errors are returned", func() {
+				So(apiError.FieldErrors(), ShouldBeEmpty)
+			})
+		})
+	})
+}
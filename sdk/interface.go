@@ -7,6 +7,7 @@ import (
 	"github.com/ONSdigital/dp-api-clients-go/v2/health"
 	"github.com/ONSdigital/dp-healthcheck/healthcheck"
 	"github.com/ONSdigital/dp-upload-service/api"
+	"github.com/ONSdigital/dp-upload-service/files"
 )
 
 //go:generate moq -out ./mocks/client.go -pkg mocks . Clienter
@@ -17,4 +18,9 @@ type Clienter interface {
 	URL() string
 
 	Upload(ctx context.Context, fileContent io.ReadCloser, metadata api.Metadata, headers Headers) error
+	ResumeUpload(ctx context.Context, fileContent io.ReadSeeker, metadata api.Metadata, headers Headers) error
+	UploadDirect(ctx context.Context, fileContent io.ReadCloser, metadata api.Metadata, headers Headers) error
+	UploadedParts(ctx context.Context, path string, headers Headers) (*files.UploadedParts, error)
+	Delete(ctx context.Context, path string, headers Headers) error
+	Publish(ctx context.Context, path string, headers Headers) error
 }
@@ -8,6 +8,7 @@ import (
 	"github.com/ONSdigital/dp-api-clients-go/v2/health"
 	"github.com/ONSdigital/dp-healthcheck/healthcheck"
 	"github.com/ONSdigital/dp-upload-service/api"
+	"github.com/ONSdigital/dp-upload-service/files"
 	"github.com/ONSdigital/dp-upload-service/sdk"
 	"io"
 	"sync"
@@ -26,15 +27,30 @@ var _ sdk.Clienter = &ClienterMock{}
 //			CheckerFunc: func(ctx context.Context, check *healthcheck.CheckState) error {
 //				panic("mock out the Checker method")
 //			},
+//			DeleteFunc: func(ctx context.Context, path string, headers sdk.Headers) error {
+//				panic("mock out the Delete method")
+//			},
 //			HealthFunc: func() *health.Client {
 //				panic("mock out the Health method")
 //			},
+//			PublishFunc: func(ctx context.Context, path string, headers sdk.Headers) error {
+//				panic("mock out the Publish method")
+//			},
+//			ResumeUploadFunc: func(ctx context.Context, fileContent io.ReadSeeker, metadata api.Metadata, headers sdk.Headers) error {
+//				panic("mock out the ResumeUpload method")
+//			},
 //			URLFunc: func() string {
 //				panic("mock out the URL method")
 //			},
 //			UploadFunc: func(ctx context.Context, fileContent io.ReadCloser, metadata api.Metadata, headers sdk.Headers) error {
 //				panic("mock out the Upload method")
 //			},
+//			UploadDirectFunc: func(ctx context.Context, fileContent io.ReadCloser, metadata api.Metadata, headers sdk.Headers) error {
+//				panic("mock out the UploadDirect method")
+//			},
+//			UploadedPartsFunc: func(ctx context.Context, path string, headers sdk.Headers) (*files.UploadedParts, error) {
+//				panic("mock out the UploadedParts method")
+//			},
 //		}
 //
 //		// use mockedClienter in code that requires sdk.Clienter
@@ -45,15 +61,30 @@ type ClienterMock struct {
 	// CheckerFunc mocks the Checker method.
 	CheckerFunc func(ctx context.Context, check *healthcheck.CheckState) error
 
+	// DeleteFunc mocks the Delete method.
+	DeleteFunc func(ctx context.Context, path string, headers sdk.Headers) error
+
 	// HealthFunc mocks the Health method.
 	HealthFunc func() *health.Client
 
+	// PublishFunc mocks the Publish method.
+	PublishFunc func(ctx context.Context, path string, headers sdk.Headers) error
+
+	// ResumeUploadFunc mocks the ResumeUpload method.
+	ResumeUploadFunc func(ctx context.Context, fileContent io.ReadSeeker, metadata api.Metadata, headers sdk.Headers) error
+
 	// URLFunc mocks the URL method.
 	URLFunc func() string
 
 	// UploadFunc mocks the Upload method.
 	UploadFunc func(ctx context.Context, fileContent io.ReadCloser, metadata api.Metadata, headers sdk.Headers) error
 
+	// UploadDirectFunc mocks the UploadDirect method.
+	UploadDirectFunc func(ctx context.Context, fileContent io.ReadCloser, metadata api.Metadata, headers sdk.Headers) error
+
+	// UploadedPartsFunc mocks the UploadedParts method.
+	UploadedPartsFunc func(ctx context.Context, path string, headers sdk.Headers) (*files.UploadedParts, error)
+
 	// calls tracks calls to the methods.
 	calls struct {
 		// Checker holds details about calls to the Checker method.
@@ -63,9 +94,38 @@ type ClienterMock struct {
 			// Check is the check argument value.
 			Check *healthcheck.CheckState
 		}
+		// Delete holds details about calls to the Delete method.
+		Delete []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Path is the path argument value.
+			Path string
+			// Headers is the headers argument value.
+			Headers sdk.Headers
+		}
 		// Health holds details about calls to the Health method.
 		Health []struct {
 		}
+		// Publish holds details about calls to the Publish method.
+		Publish []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Path is the path argument value.
+			Path string
+			// Headers is the headers argument value.
+			Headers sdk.Headers
+		}
+		// ResumeUpload holds details about calls to the ResumeUpload method.
+		ResumeUpload []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// FileContent is the fileContent argument value.
+			FileContent io.ReadSeeker
+			// Metadata is the metadata argument value.
+			Metadata api.Metadata
+			// Headers is the headers argument value.
+			Headers sdk.Headers
+		}
 		// URL holds details about calls to the URL method.
 		URL []struct {
 		}
@@ -80,11 +140,36 @@ type ClienterMock struct {
 			// Headers is the headers argument value.
 			Headers sdk.Headers
 		}
+		// UploadDirect holds details about calls to the UploadDirect method.
+		UploadDirect []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// FileContent is the fileContent argument value.
+			FileContent io.ReadCloser
+			// Metadata is the metadata argument value.
+			Metadata api.Metadata
+			// Headers is the headers argument value.
+			Headers sdk.Headers
+		}
+		// UploadedParts holds details about calls to the UploadedParts method.
+		UploadedParts []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Path is the path argument value.
+			Path string
+			// Headers is the headers argument value.
+			Headers sdk.Headers
+		}
 	}
-	lockChecker sync.RWMutex
-	lockHealth  sync.RWMutex
-	lockURL     sync.RWMutex
-	lockUpload  sync.RWMutex
+	lockChecker       sync.RWMutex
+	lockDelete        sync.RWMutex
+	lockHealth        sync.RWMutex
+	lockPublish       sync.RWMutex
+	lockResumeUpload  sync.RWMutex
+	lockURL           sync.RWMutex
+	lockUpload        sync.RWMutex
+	lockUploadDirect  sync.RWMutex
+	lockUploadedParts sync.RWMutex
 }
 
 // Checker calls CheckerFunc.
@@ -123,6 +208,46 @@ func (mock *ClienterMock) CheckerCalls() []struct {
 	return calls
 }
 
+// Delete calls DeleteFunc.
+func (mock *ClienterMock) Delete(ctx context.Context, path string, headers sdk.Headers) error {
+	if mock.DeleteFunc == nil {
+		panic("ClienterMock.DeleteFunc: method is nil but Clienter.Delete was just called")
+	}
+	callInfo := struct {
+		Ctx     context.Context
+		Path    string
+		Headers sdk.Headers
+	}{
+		Ctx:     ctx,
+		Path:    path,
+		Headers: headers,
+	}
+	mock.lockDelete.Lock()
+	mock.calls.Delete = append(mock.calls.Delete, callInfo)
+	mock.lockDelete.Unlock()
+	return mock.DeleteFunc(ctx, path, headers)
+}
+
+// DeleteCalls gets all the calls that were made to Delete.
+// Check the length with:
+//
+//	len(mockedClienter.DeleteCalls())
+func (mock *ClienterMock) DeleteCalls() []struct {
+	Ctx     context.Context
+	Path    string
+	Headers sdk.Headers
+} {
+	var calls []struct {
+		Ctx     context.Context
+		Path    string
+		Headers sdk.Headers
+	}
+	mock.lockDelete.RLock()
+	calls = mock.calls.Delete
+	mock.lockDelete.RUnlock()
+	return calls
+}
+
 // Health calls HealthFunc.
 func (mock *ClienterMock) Health() *health.Client {
 	if mock.HealthFunc == nil {
@@ -150,6 +275,90 @@ func (mock *ClienterMock) HealthCalls() []struct {
 	return calls
 }
 
+// Publish calls PublishFunc.
+func (mock *ClienterMock) Publish(ctx context.Context, path string, headers sdk.Headers) error {
+	if mock.PublishFunc == nil {
+		panic("ClienterMock.PublishFunc: method is nil but Clienter.Publish was just called")
+	}
+	callInfo := struct {
+		Ctx     context.Context
+		Path    string
+		Headers sdk.Headers
+	}{
+		Ctx:     ctx,
+		Path:    path,
+		Headers: headers,
+	}
+	mock.lockPublish.Lock()
+	mock.calls.Publish = append(mock.calls.Publish, callInfo)
+	mock.lockPublish.Unlock()
+	return mock.PublishFunc(ctx, path, headers)
+}
+
+// PublishCalls gets all the calls that were made to Publish.
+// Check the length with:
+//
+//	len(mockedClienter.PublishCalls())
+func (mock *ClienterMock) PublishCalls() []struct {
+	Ctx     context.Context
+	Path    string
+	Headers sdk.Headers
+} {
+	var calls []struct {
+		Ctx     context.Context
+		Path    string
+		Headers sdk.Headers
+	}
+	mock.lockPublish.RLock()
+	calls = mock.calls.Publish
+	mock.lockPublish.RUnlock()
+	return calls
+}
+
+// ResumeUpload calls ResumeUploadFunc.
+func (mock *ClienterMock) ResumeUpload(ctx context.Context, fileContent io.ReadSeeker, metadata api.Metadata, headers sdk.Headers) error {
+	if mock.ResumeUploadFunc == nil {
+		panic("ClienterMock.ResumeUploadFunc: method is nil but Clienter.ResumeUpload was just called")
+	}
+	callInfo := struct {
+		Ctx         context.Context
+		FileContent io.ReadSeeker
+		Metadata    api.Metadata
+		Headers     sdk.Headers
+	}{
+		Ctx:         ctx,
+		FileContent: fileContent,
+		Metadata:    metadata,
+		Headers:     headers,
+	}
+	mock.lockResumeUpload.Lock()
+	mock.calls.ResumeUpload = append(mock.calls.ResumeUpload, callInfo)
+	mock.lockResumeUpload.Unlock()
+	return mock.ResumeUploadFunc(ctx, fileContent, metadata, headers)
+}
+
+// ResumeUploadCalls gets all the calls that were made to ResumeUpload.
+// Check the length with:
+//
+//	len(mockedClienter.ResumeUploadCalls())
+func (mock *ClienterMock) ResumeUploadCalls() []struct {
+	Ctx         context.Context
+	FileContent io.ReadSeeker
+	Metadata    api.Metadata
+	Headers     sdk.Headers
+} {
+	var calls []struct {
+		Ctx         context.Context
+		FileContent io.ReadSeeker
+		Metadata    api.Metadata
+		Headers     sdk.Headers
+	}
+	mock.lockResumeUpload.RLock()
+	calls = mock.calls.ResumeUpload
+	mock.lockResumeUpload.RUnlock()
+	return calls
+}
+
 // URL calls URLFunc.
 func (mock *ClienterMock) URL() string {
 	if mock.URLFunc == nil {
@@ -220,3 +429,87 @@ func (mock *ClienterMock) UploadCalls() []struct {
 	mock.lockUpload.RUnlock()
 	return calls
 }
+
+// UploadDirect calls UploadDirectFunc.
+func (mock *ClienterMock) UploadDirect(ctx context.Context, fileContent io.ReadCloser, metadata api.Metadata, headers sdk.Headers) error {
+	if mock.UploadDirectFunc == nil {
+		panic("ClienterMock.UploadDirectFunc: method is nil but Clienter.UploadDirect was just called")
+	}
+	callInfo := struct {
+		Ctx         context.Context
+		FileContent io.ReadCloser
+		Metadata    api.Metadata
+		Headers     sdk.Headers
+	}{
+		Ctx:         ctx,
+		FileContent: fileContent,
+		Metadata:    metadata,
+		Headers:     headers,
+	}
+	mock.lockUploadDirect.Lock()
+	mock.calls.UploadDirect = append(mock.calls.UploadDirect, callInfo)
+	mock.lockUploadDirect.Unlock()
+	return mock.UploadDirectFunc(ctx, fileContent, metadata, headers)
+}
+
+// UploadDirectCalls gets all the calls that were made to UploadDirect.
+// Check the length with:
+//
+//	len(mockedClienter.UploadDirectCalls())
+func (mock *ClienterMock) UploadDirectCalls() []struct {
+	Ctx         context.Context
+	FileContent io.ReadCloser
+	Metadata    api.Metadata
+	Headers     sdk.Headers
+} {
+	var calls []struct {
+		Ctx         context.Context
+		FileContent io.ReadCloser
+		Metadata    api.Metadata
+		Headers     sdk.Headers
+	}
+	mock.lockUploadDirect.RLock()
+	calls = mock.calls.UploadDirect
+	mock.lockUploadDirect.RUnlock()
+	return calls
+}
+
+// UploadedParts calls UploadedPartsFunc.
+func (mock *ClienterMock) UploadedParts(ctx context.Context, path string, headers sdk.Headers) (*files.UploadedParts, error) {
+	if mock.UploadedPartsFunc == nil {
+		panic("ClienterMock.UploadedPartsFunc: method is nil but Clienter.UploadedParts was just called")
+	}
+	callInfo := struct {
+		Ctx     context.Context
+		Path    string
+		Headers sdk.Headers
+	}{
+		Ctx:     ctx,
+		Path:    path,
+		Headers: headers,
+	}
+	mock.lockUploadedParts.Lock()
+	mock.calls.UploadedParts = append(mock.calls.UploadedParts, callInfo)
+	mock.lockUploadedParts.Unlock()
+	return mock.UploadedPartsFunc(ctx, path, headers)
+}
+
+// UploadedPartsCalls gets all the calls that were made to UploadedParts.
+// Check the length with:
+//
+//	len(mockedClienter.UploadedPartsCalls())
+func (mock *ClienterMock) UploadedPartsCalls() []struct {
+	Ctx     context.Context
+	Path    string
+	Headers sdk.Headers
+} {
+	var calls []struct {
+		Ctx     context.Context
+		Path    string
+		Headers sdk.Headers
+	}
+	mock.lockUploadedParts.RLock()
+	calls = mock.calls.UploadedParts
+	mock.lockUploadedParts.RUnlock()
+	return calls
+}
@@ -0,0 +1,54 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ONSdigital/dp-upload-service/files"
+)
+
+// UploadedParts lists the chunks received so far for the in progress upload of the file at the provided path via the
+// /upload-new/files endpoint
+func (cli *Client) UploadedParts(ctx context.Context, path string, headers Headers) (*files.UploadedParts, error) {
+	partsURL, err := url.Parse(fmt.Sprintf("%s/upload-new/files", cli.hcCli.URL))
+	if err != nil {
+		return nil, err
+	}
+	partsURL = partsURL.JoinPath(strings.TrimPrefix(path, "/"), "parts")
+
+	req, err := http.NewRequest(http.MethodGet, partsURL.String(), http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+
+	headers.Add(req)
+
+	resp, err := cli.hcCli.Client.Do(ctx, req)
+	if err != nil {
+		closeResponseBody(ctx, resp)
+		return nil, err
+	}
+	defer closeResponseBody(ctx, resp)
+
+	if resp.StatusCode != http.StatusOK {
+		jsonErrors, err := unmarshalJsonErrors(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		return nil, &APIError{
+			StatusCode: resp.StatusCode,
+			Errors:     jsonErrors,
+		}
+	}
+
+	var parts files.UploadedParts
+	if err := json.NewDecoder(resp.Body).Decode(&parts); err != nil {
+		return nil, err
+	}
+
+	return &parts, nil
+}
@@ -0,0 +1,82 @@
+package sdk
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/ONSdigital/dp-upload-service/files"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestUploadedParts_Success(t *testing.T) {
+	t.Parallel()
+
+	Convey("Given a client and a path with an upload in progress", t, func() {
+		body := `{"path":"path/to/data.csv","parts":[{"chunk_number":1,"size_in_bytes":5242880,"etag":"etag-1"}]}`
+		mockClienter := newMockClienter(&http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader([]byte(body)))}, nil)
+		client := newMockUploadServiceClient(mockClienter)
+
+		Convey("When UploadedParts is called", func() {
+			parts, err := client.UploadedParts(context.Background(), "/path/to/data.csv", Headers{ServiceAuthToken: "token"})
+
+			Convey("Then the uploaded parts are returned", func() {
+				So(err, ShouldBeNil)
+				So(parts, ShouldResemble, &files.UploadedParts{
+					Path:  "path/to/data.csv",
+					Parts: []files.UploadedPart{{ChunkNumber: 1, SizeInBytes: 5242880, ETag: "etag-1"}},
+				})
+			})
+
+			Convey("And the mock clienter's Do method is called once with the correct request details", func() {
+				So(mockClienter.DoCalls(), ShouldHaveLength, 1)
+				actualCall := mockClienter.DoCalls()[0]
+				So(actualCall.Req.Method, ShouldEqual, http.MethodGet)
+				So(actualCall.Req.URL.String(), ShouldEqual, uploadServiceURL+"/upload-new/files/path/to/data.csv/parts")
+				So(actualCall.Req.Header.Get("Authorization"), ShouldEqual, "Bearer token")
+			})
+		})
+	})
+}
+
+func TestUploadedParts_Failure(t *testing.T) {
+	t.Parallel()
+
+	Convey("When the Client's Do() function fails", t, func() {
+		expectedDoErr := errors.New("intentional Do error")
+		mockClienter := newMockClienter(nil, expectedDoErr)
+		client := newMockUploadServiceClient(mockClienter)
+
+		Convey("And UploadedParts is called", func() {
+			_, err := client.UploadedParts(context.Background(), "path/to/data.csv", Headers{})
+
+			Convey("Then the expected error is returned", func() {
+				So(err, ShouldEqual, expectedDoErr)
+			})
+		})
+	})
+
+	Convey("When the upload service returns an unexpected status code", t, func() {
+		body := `{"errors":[{"code":"NotFound","description":"no upload in progress for this path"}]}`
+		mockClienter := newMockClienter(
+			&http.Response{
+				StatusCode: http.StatusNotFound,
+				Body:       io.NopCloser(bytes.NewReader([]byte(body))),
+			}, nil)
+		client := newMockUploadServiceClient(mockClienter)
+
+		Convey("And UploadedParts is called", func() {
+			_, err := client.UploadedParts(context.Background(), "path/to/data.csv", Headers{})
+
+			Convey("Then an APIError is returned with the expected details", func() {
+				apiErr, ok := err.(*APIError)
+				So(ok, ShouldBeTrue)
+				So(apiErr.StatusCode, ShouldEqual, http.StatusNotFound)
+				So(apiErr.Errors.Error[0].Code, ShouldEqual, "NotFound")
+			})
+		})
+	})
+}
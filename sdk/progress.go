@@ -0,0 +1,121 @@
+package sdk
+
+import (
+	"sync"
+	"time"
+)
+
+// UploadProgress describes how far an Upload has got. It is reported after each chunk has been accepted by the upload
+// service and once more, with Done set, when Upload returns.
+type UploadProgress struct {
+	Path        string
+	ChunkNumber int
+	TotalChunks int
+	ChunkBytes  int
+	BytesSent   int
+	TotalBytes  int
+	Elapsed     time.Duration
+
+	// Skipped is set when the chunk had already been received by the upload service, so was not sent again by
+	// ResumeUpload. Skipped chunks are still counted in BytesSent.
+	Skipped bool
+
+	// Done is only set on the final event
+	Done bool
+	// Completed is set on the final event when the upload service responded with 201 Created, meaning the whole file
+	// has been received and registered
+	Completed bool
+	// Err is the error returned by Upload, if any
+	Err error
+}
+
+// ProgressFunc is called with the progress of an Upload. Calls are never made at the same time, even when chunks are
+// uploaded in parallel, but chunks may be reported out of order.
+type ProgressFunc func(UploadProgress)
+
+// WithProgress sets a function to be called with the progress of each Upload made by the Client
+func WithProgress(fn ProgressFunc) Option {
+	return func(cli *Client) {
+		cli.progress = fn
+	}
+}
+
+// progressTracker accumulates the progress of a single Upload and reports it to a ProgressFunc
+type progressTracker struct {
+	mu       sync.Mutex
+	report   ProgressFunc
+	started  time.Time
+	progress UploadProgress
+}
+
+// newProgressTracker returns a progressTracker for the upload, or nil if there is nothing to report to
+func newProgressTracker(report ProgressFunc, path string, totalBytes, totalChunks int) *progressTracker {
+	if report == nil {
+		return nil
+	}
+
+	return &progressTracker{
+		report:  report,
+		started: time.Now(),
+		progress: UploadProgress{
+			Path:        path,
+			TotalChunks: totalChunks,
+			TotalBytes:  totalBytes,
+		},
+	}
+}
+
+// chunkSent reports that the chunk has been accepted by the upload service
+func (t *progressTracker) chunkSent(chunkNumber, chunkBytes int) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.record(chunkNumber, chunkBytes, false)
+}
+
+// chunkSkipped reports that the chunk had already been uploaded so was not sent again
+func (t *progressTracker) chunkSkipped(chunkNumber, chunkBytes int) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.record(chunkNumber, chunkBytes, true)
+}
+
+func (t *progressTracker) record(chunkNumber, chunkBytes int, skipped bool) {
+	t.progress.ChunkNumber = chunkNumber
+	t.progress.ChunkBytes = chunkBytes
+	t.progress.BytesSent += chunkBytes
+	t.progress.Skipped = skipped
+	t.progress.Elapsed = time.Since(t.started)
+	t.report(t.progress)
+}
+
+// finish reports the outcome of the upload
+func (t *progressTracker) finish(completed bool, err error) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.progress.Skipped = false
+	t.progress.Done = true
+	t.progress.Completed = completed
+	t.progress.Err = err
+	t.progress.Elapsed = time.Since(t.started)
+	t.report(t.progress)
+}
+
+// chunkLength returns the number of bytes in the chunk of a file of the given size
+func chunkLength(chunkNumber, sizeInBytes int) int {
+	return min(chunkSize, sizeInBytes-(chunkNumber-1)*chunkSize)
+}
@@ -0,0 +1,126 @@
+package sdk
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/ONSdigital/dp-api-clients-go/v2/health"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestUpload_Progress(t *testing.T) {
+	t.Parallel()
+
+	Convey("Given a client created WithProgress and a file of several chunks", t, func() {
+		content := bytes.Repeat([]byte("a"), chunkSize*2+10)
+		metadata := validMetadata
+		metadata.SizeInBytes = len(content)
+
+		var events []UploadProgress
+		report := func(p UploadProgress) {
+			events = append(events, p)
+		}
+
+		Convey("When Upload is called and the upload service completes the file", func() {
+			mockClienter, _ := newChunkRecordingClienter(func(chunkNumber, _ int) int {
+				if chunkNumber == 3 {
+					return http.StatusCreated
+				}
+				return http.StatusOK
+			})
+			client := NewWithHealthClient(health.NewClientWithClienter(serviceName, uploadServiceURL, mockClienter), WithProgress(report))
+
+			err := client.Upload(context.Background(), io.NopCloser(bytes.NewReader(content)), metadata, Headers{})
+			So(err, ShouldBeNil)
+
+			Convey("Then progress is reported for each chunk followed by a final event", func() {
+				So(events, ShouldHaveLength, 4)
+			})
+
+			Convey("And each chunk event describes the chunk sent", func() {
+				So(events[0].ChunkNumber, ShouldEqual, 1)
+				So(events[0].ChunkBytes, ShouldEqual, chunkSize)
+				So(events[0].BytesSent, ShouldEqual, chunkSize)
+				So(events[2].ChunkNumber, ShouldEqual, 3)
+				So(events[2].ChunkBytes, ShouldEqual, 10)
+				So(events[2].BytesSent, ShouldEqual, len(content))
+				for _, event := range events {
+					So(event.Path, ShouldEqual, metadata.Path)
+					So(event.TotalChunks, ShouldEqual, 3)
+					So(event.TotalBytes, ShouldEqual, len(content))
+				}
+				So(events[2].Done, ShouldBeFalse)
+			})
+
+			Convey("And the final event reports the file as completed", func() {
+				final := events[3]
+				So(final.Done, ShouldBeTrue)
+				So(final.Completed, ShouldBeTrue)
+				So(final.Err, ShouldBeNil)
+				So(final.Elapsed, ShouldBeGreaterThanOrEqualTo, events[2].Elapsed)
+			})
+		})
+
+		Convey("When Upload is called and the upload service does not respond with 201", func() {
+			mockClienter, _ := newChunkRecordingClienter(func(int, int) int { return http.StatusOK })
+			client := NewWithHealthClient(health.NewClientWithClienter(serviceName, uploadServiceURL, mockClienter), WithProgress(report))
+
+			err := client.Upload(context.Background(), io.NopCloser(bytes.NewReader(content)), metadata, Headers{})
+
+			Convey("Then ErrUploadNotCompleted is returned", func() {
+				So(err, ShouldEqual, ErrUploadNotCompleted)
+			})
+
+			Convey("And the final event does not report the file as completed", func() {
+				final := events[len(events)-1]
+				So(final.Done, ShouldBeTrue)
+				So(final.Completed, ShouldBeFalse)
+				So(final.Err, ShouldEqual, ErrUploadNotCompleted)
+			})
+		})
+
+		Convey("When a chunk is rejected by the upload service", func() {
+			mockClienter, _ := newChunkRecordingClienter(func(chunkNumber, _ int) int {
+				if chunkNumber == 2 {
+					return http.StatusInternalServerError
+				}
+				return http.StatusOK
+			})
+			client := NewWithHealthClient(health.NewClientWithClienter(serviceName, uploadServiceURL, mockClienter), WithProgress(report))
+
+			err := client.Upload(context.Background(), io.NopCloser(bytes.NewReader(content)), metadata, Headers{})
+
+			Convey("Then only the accepted chunks are reported", func() {
+				So(events, ShouldHaveLength, 2)
+				So(events[0].ChunkNumber, ShouldEqual, 1)
+			})
+
+			Convey("And the final event carries the error", func() {
+				final := events[1]
+				So(final.Done, ShouldBeTrue)
+				So(final.Completed, ShouldBeFalse)
+				So(final.Err, ShouldEqual, err)
+			})
+		})
+	})
+}
+
+func TestChunkLength(t *testing.T) {
+	t.Parallel()
+
+	Convey("Given a file that does not fill its last chunk", t, func() {
+		size := chunkSize*2 + 10
+
+		Convey("Then every chunk but the last is full", func() {
+			So(chunkLength(1, size), ShouldEqual, chunkSize)
+			So(chunkLength(2, size), ShouldEqual, chunkSize)
+		})
+
+		Convey("And the last chunk holds the remainder", func() {
+			So(chunkLength(3, size), ShouldEqual, 10)
+		})
+	})
+}
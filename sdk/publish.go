@@ -0,0 +1,45 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Publish marks the uploaded file at the provided path as published via the /upload-new/files endpoint
+func (cli *Client) Publish(ctx context.Context, path string, headers Headers) error {
+	publishURL, err := url.Parse(fmt.Sprintf("%s/upload-new/files", cli.hcCli.URL))
+	if err != nil {
+		return err
+	}
+	publishURL = publishURL.JoinPath(strings.TrimPrefix(path, "/"), "publish")
+
+	req, err := http.NewRequest(http.MethodPost, publishURL.String(), http.NoBody)
+	if err != nil {
+		return err
+	}
+
+	headers.Add(req)
+
+	resp, err := cli.hcCli.Client.Do(ctx, req)
+	if err != nil {
+		closeResponseBody(ctx, resp)
+		return err
+	}
+	defer closeResponseBody(ctx, resp)
+
+	if resp.StatusCode != http.StatusNoContent {
+		jsonErrors, err := unmarshalJsonErrors(resp.Body)
+		if err != nil {
+			return err
+		}
+		return &APIError{
+			StatusCode: resp.StatusCode,
+			Errors:     jsonErrors,
+		}
+	}
+
+	return nil
+}
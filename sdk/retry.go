@@ -0,0 +1,155 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// retryableStatusCodes are the response statuses that indicate a chunk may be accepted if it is sent again
+var retryableStatusCodes = map[int]bool{
+	http.StatusRequestTimeout:      true,
+	http.StatusTooManyRequests:     true,
+	http.StatusInternalServerError: true,
+	http.StatusBadGateway:          true,
+	http.StatusServiceUnavailable:  true,
+	http.StatusGatewayTimeout:      true,
+}
+
+// unprocessedStatusCodes are the retryable response statuses with which the upload service turns a chunk away before
+// handling it
+var unprocessedStatusCodes = map[int]bool{
+	http.StatusTooManyRequests:    true,
+	http.StatusServiceUnavailable: true,
+}
+
+// nonRetryableErrorCodes are the error codes returned by the upload service that will not succeed however many times
+// the chunk is sent, even when the response status would otherwise be retried
+var nonRetryableErrorCodes = map[string]bool{
+	"ValidationError":       true,
+	"RemoteValidationError": true,
+	"DuplicateFile":         true,
+	"Unauthorised":          true,
+}
+
+// RetryError is returned when a chunk could not be uploaded after being retried. The error from the final attempt,
+// such as an *APIError, can be retrieved with errors.As.
+type RetryError struct {
+	Attempts int
+	Err      error
+}
+
+// Error implements the error interface for RetryError
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("failed after %d attempts: %s", e.Attempts, e.Err)
+}
+
+// Unwrap returns the error from the final attempt
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+type retryPolicy struct {
+	maxRetries     int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+}
+
+// WithRetries retries chunks that fail with a network error or a retryable status (408, 429, 500, 502, 503 or 504)
+// up to maxRetries times, waiting an exponentially increasing, jittered time between attempts that starts at
+// initialBackoff and is capped at maxBackoff. Chunks rejected as ValidationError, RemoteValidationError,
+// DuplicateFile or Unauthorised are never retried.
+//
+// The last chunk of a file completes the upload, so it is only retried after a 429 or 503, when the upload service has
+// turned it away without handling it. After a network error or any other status the chunk may already have completed
+// the upload, which sending it again can't do a second time, so the error is returned instead.
+//
+// The Client takes over retrying chunk uploads from the underlying dp-net client, so that it does not also retry them.
+func WithRetries(maxRetries int, initialBackoff, maxBackoff time.Duration) Option {
+	return func(cli *Client) {
+		cli.retry = retryPolicy{
+			maxRetries:     max(maxRetries, 0),
+			initialBackoff: initialBackoff,
+			maxBackoff:     max(maxBackoff, initialBackoff),
+		}
+	}
+}
+
+// do calls attempt until it succeeds, fails with an error that retryable rejects or the retries are used up
+func (p retryPolicy) do(ctx context.Context, retryable func(context.Context, error) bool, attempt func() (int, error)) (int, error) {
+	for attempts := 1; ; attempts++ {
+		statusCode, err := attempt()
+		if err == nil {
+			return statusCode, nil
+		}
+		if attempts > p.maxRetries || !retryable(ctx, err) {
+			return 0, retryError(attempts, err)
+		}
+
+		timer := time.NewTimer(p.backoff(attempts))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return 0, retryError(attempts, err)
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff returns how long to wait after the given attempt, doubling with each attempt up to the maximum and
+// randomised between half and all of that so that clients retrying together do not stay in step
+func (p retryPolicy) backoff(attempts int) time.Duration {
+	wait := p.initialBackoff
+	for i := 1; i < attempts && wait < p.maxBackoff; i++ {
+		wait *= 2
+	}
+	wait = min(wait, p.maxBackoff)
+
+	half := wait / 2
+	if half <= 0 {
+		return wait
+	}
+	return half + rand.N(half)
+}
+
+// retryError wraps the error in a RetryError if the request was attempted more than once
+func retryError(attempts int, err error) error {
+	if attempts == 1 {
+		return err
+	}
+	return &RetryError{Attempts: attempts, Err: err}
+}
+
+// isRetryable reports whether sending the request again might succeed
+func isRetryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		if apiErr.Errors != nil {
+			for _, jsonErr := range apiErr.Errors.Error {
+				if nonRetryableErrorCodes[jsonErr.Code] {
+					return false
+				}
+			}
+		}
+		return retryableStatusCodes[apiErr.StatusCode]
+	}
+
+	// errors sending the request or reading the response are returned by the http client as url.Errors
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
+// isRetryableLastChunk reports whether sending the last chunk of a file again might succeed, which is only the case
+// when the upload service turned it away without handling it
+func isRetryableLastChunk(ctx context.Context, err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && unprocessedStatusCodes[apiErr.StatusCode] && isRetryable(ctx, err)
+}
@@ -0,0 +1,249 @@
+package sdk
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-api-clients-go/v2/health"
+	dphttp "github.com/ONSdigital/dp-net/v3/http"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type mockResponse struct {
+	status int
+	body   string
+	err    error
+}
+
+// newSequenceClienter returns a mock clienter that responds to each request with the next of the given responses,
+// repeating the last once they run out, and records the body of each request
+func newSequenceClienter(responses ...mockResponse) (*dphttp.ClienterMock, *[]string) {
+	var bodies []string
+	mockClienter := newMockClienter(nil, nil)
+	mockClienter.DoFunc = func(_ context.Context, req *http.Request) (*http.Response, error) {
+		body, _ := io.ReadAll(req.Body)
+		bodies = append(bodies, string(body))
+
+		response := responses[min(len(bodies), len(responses))-1]
+		if response.err != nil {
+			return nil, response.err
+		}
+		return &http.Response{StatusCode: response.status, Body: io.NopCloser(bytes.NewReader([]byte(response.body)))}, nil
+	}
+	return mockClienter, &bodies
+}
+
+func newRetryingClient(mockClienter *dphttp.ClienterMock) *Client {
+	return NewWithHealthClient(health.NewClientWithClienter(serviceName, uploadServiceURL, mockClienter), WithRetries(2, time.Millisecond, 2*time.Millisecond))
+}
+
+func TestUpload_Retries(t *testing.T) {
+	t.Parallel()
+
+	fileContent := func() io.ReadCloser {
+		return io.NopCloser(bytes.NewReader([]byte("This is some test file content.")))
+	}
+	// the first of two chunks is retried in the same way as any chunk but the last
+	twoChunks := bytes.Repeat([]byte("a"), chunkSize+1)
+	twoChunkMetadata := validMetadata
+	twoChunkMetadata.SizeInBytes = len(twoChunks)
+	networkErr := &url.Error{Op: "Post", URL: uploadServiceURL, Err: errors.New("connection reset by peer")}
+	unavailable := mockResponse{status: http.StatusServiceUnavailable, body: `{"errors":[{"code":"UploadCapacityExceeded"}]}`}
+
+	Convey("Given a client created WithRetries", t, func() {
+		Convey("When a chunk fails with a retryable status and then succeeds", func() {
+			mockClienter, bodies := newSequenceClienter(unavailable, mockResponse{status: http.StatusCreated})
+			err := newRetryingClient(mockClienter).Upload(context.Background(), fileContent(), validMetadata, Headers{})
+
+			Convey("Then no error is returned", func() {
+				So(err, ShouldBeNil)
+			})
+
+			Convey("And the same chunk is sent again", func() {
+				So(*bodies, ShouldHaveLength, 2)
+				So((*bodies)[1], ShouldEqual, (*bodies)[0])
+				So((*bodies)[1], ShouldContainSubstring, "This is some test file content.")
+			})
+		})
+
+		Convey("When a chunk fails with a network error and then succeeds", func() {
+			mockClienter, bodies := newSequenceClienter(mockResponse{err: networkErr}, mockResponse{status: http.StatusCreated})
+			err := newRetryingClient(mockClienter).Upload(context.Background(), io.NopCloser(bytes.NewReader(twoChunks)), twoChunkMetadata, Headers{})
+
+			Convey("Then the chunk is retried and no error is returned", func() {
+				So(err, ShouldBeNil)
+				So(*bodies, ShouldHaveLength, 3)
+				So((*bodies)[1], ShouldEqual, (*bodies)[0])
+			})
+		})
+
+		Convey("When the last chunk fails with a network error", func() {
+			mockClienter, bodies := newSequenceClienter(mockResponse{err: networkErr}, mockResponse{status: http.StatusCreated})
+			err := newRetryingClient(mockClienter).Upload(context.Background(), fileContent(), validMetadata, Headers{})
+
+			Convey("Then the chunk is not retried, as it may already have completed the upload", func() {
+				So(*bodies, ShouldHaveLength, 1)
+			})
+
+			Convey("And the error is returned", func() {
+				So(errors.Is(err, networkErr), ShouldBeTrue)
+			})
+		})
+
+		Convey("When the last chunk fails with a server error", func() {
+			mockClienter, bodies := newSequenceClienter(mockResponse{status: http.StatusInternalServerError, body: `{"errors":[{"code":"InternalError"}]}`}, mockResponse{status: http.StatusCreated})
+			err := newRetryingClient(mockClienter).Upload(context.Background(), fileContent(), validMetadata, Headers{})
+
+			Convey("Then the chunk is not retried and the APIError is returned", func() {
+				So(*bodies, ShouldHaveLength, 1)
+				apiErr, ok := err.(*APIError)
+				So(ok, ShouldBeTrue)
+				So(apiErr.StatusCode, ShouldEqual, http.StatusInternalServerError)
+			})
+		})
+
+		Convey("When the last chunk is accepted without completing the upload", func() {
+			mockClienter, bodies := newSequenceClienter(mockResponse{status: http.StatusOK})
+			err := newRetryingClient(mockClienter).Upload(context.Background(), fileContent(), validMetadata, Headers{})
+
+			Convey("Then ErrUploadNotCompleted is returned without retrying the chunk", func() {
+				So(err, ShouldEqual, ErrUploadNotCompleted)
+				So(*bodies, ShouldHaveLength, 1)
+			})
+		})
+
+		Convey("When a chunk keeps failing with a retryable status", func() {
+			mockClienter, bodies := newSequenceClienter(unavailable)
+			err := newRetryingClient(mockClienter).Upload(context.Background(), fileContent(), validMetadata, Headers{})
+
+			Convey("Then the chunk is sent until the retries are used up", func() {
+				So(*bodies, ShouldHaveLength, 3)
+			})
+
+			Convey("And a RetryError wrapping the last APIError is returned", func() {
+				var retryErr *RetryError
+				So(errors.As(err, &retryErr), ShouldBeTrue)
+				So(retryErr.Attempts, ShouldEqual, 3)
+
+				var apiErr *APIError
+				So(errors.As(err, &apiErr), ShouldBeTrue)
+				So(apiErr.StatusCode, ShouldEqual, http.StatusServiceUnavailable)
+			})
+		})
+
+		Convey("When a proxy responds with a retryable status and no JSON body", func() {
+			mockClienter, bodies := newSequenceClienter(mockResponse{status: http.StatusBadGateway, body: "<html>Bad Gateway</html>"}, mockResponse{status: http.StatusCreated})
+			err := newRetryingClient(mockClienter).Upload(context.Background(), io.NopCloser(bytes.NewReader(twoChunks)), twoChunkMetadata, Headers{})
+
+			Convey("Then the chunk is retried", func() {
+				So(err, ShouldBeNil)
+				So(*bodies, ShouldHaveLength, 3)
+			})
+		})
+
+		for _, code := range []string{"ValidationError", "RemoteValidationError", "DuplicateFile", "Unauthorised"} {
+			Convey("When a chunk is rejected with "+code, func() {
+				mockClienter, bodies := newSequenceClienter(mockResponse{status: http.StatusInternalServerError, body: `{"errors":[{"code":"` + code + `"}]}`})
+				err := newRetryingClient(mockClienter).Upload(context.Background(), fileContent(), validMetadata, Headers{})
+
+				Convey("Then the chunk is not retried", func() {
+					So(*bodies, ShouldHaveLength, 1)
+				})
+
+				Convey("And the APIError is returned", func() {
+					apiErr, ok := err.(*APIError)
+					So(ok, ShouldBeTrue)
+					So(apiErr.Errors.Error[0].Code, ShouldEqual, code)
+				})
+			})
+		}
+
+		Convey("When a chunk is rejected with a status that is not retryable", func() {
+			mockClienter, bodies := newSequenceClienter(mockResponse{status: http.StatusBadRequest, body: `{"errors":[{"code":"ChecksumMismatch"}]}`})
+			_ = newRetryingClient(mockClienter).Upload(context.Background(), fileContent(), validMetadata, Headers{})
+
+			Convey("Then the chunk is not retried", func() {
+				So(*bodies, ShouldHaveLength, 1)
+			})
+		})
+
+		Convey("When the context is cancelled", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			mockClienter, bodies := newSequenceClienter(unavailable)
+			_ = newRetryingClient(mockClienter).Upload(ctx, fileContent(), validMetadata, Headers{})
+
+			Convey("Then the chunk is not retried", func() {
+				So(*bodies, ShouldHaveLength, 1)
+			})
+		})
+	})
+
+	Convey("Given a client created without retries", t, func() {
+		mockClienter, bodies := newSequenceClienter(unavailable)
+		client := newMockUploadServiceClient(mockClienter)
+
+		Convey("When a chunk fails with a retryable status", func() {
+			err := client.Upload(context.Background(), fileContent(), validMetadata, Headers{})
+
+			Convey("Then the chunk is not retried and the APIError is returned", func() {
+				So(*bodies, ShouldHaveLength, 1)
+				_, ok := err.(*APIError)
+				So(ok, ShouldBeTrue)
+			})
+		})
+	})
+}
+
+func TestWithRetries(t *testing.T) {
+	t.Parallel()
+
+	Convey("Given an upload service Client created WithRetries", t, func() {
+		mockClienter := newMockClienter(nil, nil)
+		newRetryingClient(mockClienter)
+
+		Convey("Then the underlying client no longer retries chunk uploads", func() {
+			calls := mockClienter.SetPathsWithNoRetriesCalls()
+			So(calls[len(calls)-1].Strings, ShouldContain, "/upload-new")
+		})
+	})
+
+	Convey("Given an upload service Client created without retries", t, func() {
+		mockClienter := newMockClienter(nil, nil)
+		newMockUploadServiceClient(mockClienter)
+
+		Convey("Then the underlying client still retries chunk uploads", func() {
+			for _, call := range mockClienter.SetPathsWithNoRetriesCalls() {
+				So(call.Strings, ShouldNotContain, "/upload-new")
+			}
+		})
+	})
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	t.Parallel()
+
+	Convey("Given a retry policy", t, func() {
+		policy := retryPolicy{maxRetries: 5, initialBackoff: 100 * time.Millisecond, maxBackoff: time.Second}
+
+		Convey("Then the backoff doubles with each attempt, randomised between half and all of it", func() {
+			for i := 0; i < 20; i++ {
+				So(policy.backoff(1), ShouldBeBetweenOrEqual, 50*time.Millisecond, 100*time.Millisecond)
+				So(policy.backoff(3), ShouldBeBetweenOrEqual, 200*time.Millisecond, 400*time.Millisecond)
+			}
+		})
+
+		Convey("And the backoff is capped at the maximum", func() {
+			for i := 0; i < 20; i++ {
+				So(policy.backoff(10), ShouldBeBetweenOrEqual, 500*time.Millisecond, time.Second)
+				So(policy.backoff(100), ShouldBeBetweenOrEqual, 500*time.Millisecond, time.Second)
+			}
+		})
+	})
+}
@@ -0,0 +1,208 @@
+package sdk
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+
+	"github.com/ONSdigital/dp-upload-service/api"
+	"github.com/ONSdigital/dp-upload-service/files"
+	"golang.org/x/sync/errgroup"
+)
+
+const sessionsPath = uploadPath + "/sessions"
+
+// UploadDirect uploads a file in chunks straight to the static files bucket rather than through the upload service.
+// An upload session is created via the /upload-new/sessions endpoint, each chunk is sent to the presigned URL returned
+// for it, and the session is then completed with the checksum of the whole file, which registers the file and marks it
+// as uploaded. Chunks are sent in parallel when the Client is created WithConcurrency. An APIError with status 501 is
+// returned if the upload service's storage does not support direct uploads, in which case Upload can be used instead.
+func (cli *Client) UploadDirect(ctx context.Context, fileContent io.ReadCloser, metadata api.Metadata, headers Headers) error {
+	totalChunks := (metadata.SizeInBytes + chunkSize - 1) / chunkSize
+	progress := newProgressTracker(cli.progress, metadata.Path, metadata.SizeInBytes, totalChunks)
+
+	completed, err := cli.uploadDirect(ctx, fileContent, metadata, totalChunks, headers, progress)
+	progress.finish(completed, err)
+
+	return err
+}
+
+// uploadDirect sends every chunk of the file to its presigned URL, returning true once the upload session has been
+// completed
+func (cli *Client) uploadDirect(ctx context.Context, fileContent io.Reader, metadata api.Metadata, totalChunks int, headers Headers, progress *progressTracker) (bool, error) {
+	if metadata.SizeInBytes > maxFileSize {
+		return false, ErrFileTooLarge
+	}
+	if totalChunks == 0 {
+		return false, nil
+	}
+
+	session, err := cli.createUploadSession(ctx, metadata, totalChunks, headers)
+	if err != nil {
+		return false, err
+	}
+
+	presignedURLs := make(map[int]string, len(session.Parts))
+	for _, part := range session.Parts {
+		presignedURLs[int(part.ChunkNumber)] = part.URL
+	}
+
+	// the whole file checksum is built up as the chunks are read and sent when the session is completed
+	fileHash := sha256.New()
+	hashedContent := io.TeeReader(fileContent, fileHash)
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(cli.concurrency)
+
+	var readErr error
+	for i := 1; i <= totalChunks && gctx.Err() == nil; i++ {
+		presignedURL, ok := presignedURLs[i]
+		if !ok {
+			readErr = fmt.Errorf("upload session has no presigned URL for chunk %d", i)
+			break
+		}
+
+		length := chunkLength(i, metadata.SizeInBytes)
+		chunk := make([]byte, length)
+		if _, err := io.ReadFull(hashedContent, chunk); err != nil {
+			readErr = err
+			break
+		}
+
+		g.Go(func() error {
+			if _, err := cli.retry.do(gctx, isRetryable, func() (int, error) {
+				return cli.putChunk(gctx, presignedURL, chunk)
+			}); err != nil {
+				return err
+			}
+			progress.chunkSent(i, length)
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return false, err
+	}
+	if readErr != nil {
+		return false, readErr
+	}
+
+	chunkInfo := ChunkInfo{Total: totalChunks, FileChecksum: base64.StdEncoding.EncodeToString(fileHash.Sum(nil))}
+	if err := cli.completeUploadSession(ctx, session.UploadID, metadata, chunkInfo, headers); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// createUploadSession starts an upload session for the file, returning the presigned URL for each of its chunks
+func (cli *Client) createUploadSession(ctx context.Context, metadata api.Metadata, totalChunks int, headers Headers) (*files.UploadSession, error) {
+	resp, err := cli.postSessionForm(ctx, cli.hcCli.URL+sessionsPath, metadata, ChunkInfo{Total: totalChunks}, headers)
+	if err != nil {
+		return nil, err
+	}
+	defer closeResponseBody(ctx, resp)
+
+	if resp.StatusCode != http.StatusCreated {
+		jsonErrors, err := unmarshalJsonErrors(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		return nil, &APIError{
+			StatusCode: resp.StatusCode,
+			Errors:     jsonErrors,
+		}
+	}
+
+	var session files.UploadSession
+	if err := json.NewDecoder(resp.Body).Decode(&session); err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+// completeUploadSession completes the upload session once every chunk has been sent to its presigned URL
+func (cli *Client) completeUploadSession(ctx context.Context, uploadID string, metadata api.Metadata, chunkInfo ChunkInfo, headers Headers) error {
+	completeURL, err := url.JoinPath(cli.hcCli.URL, sessionsPath, url.PathEscape(uploadID), "complete")
+	if err != nil {
+		return err
+	}
+
+	resp, err := cli.postSessionForm(ctx, completeURL, metadata, chunkInfo, headers)
+	if err != nil {
+		return err
+	}
+	defer closeResponseBody(ctx, resp)
+
+	if resp.StatusCode != http.StatusCreated {
+		jsonErrors, err := unmarshalJsonErrors(resp.Body)
+		if err != nil {
+			return err
+		}
+		return &APIError{
+			StatusCode: resp.StatusCode,
+			Errors:     jsonErrors,
+		}
+	}
+
+	return nil
+}
+
+// postSessionForm sends the metadata fields to an upload session endpoint as a multipart form
+func (cli *Client) postSessionForm(ctx context.Context, sessionURL string, metadata api.Metadata, chunkInfo ChunkInfo, headers Headers) (*http.Response, error) {
+	reqBuff := &bytes.Buffer{}
+	formWriter := multipart.NewWriter(reqBuff)
+	if err := writeMetadataFormFields(formWriter, metadata, chunkInfo); err != nil {
+		return nil, err
+	}
+	if err := formWriter.Close(); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, sessionURL, reqBuff)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", formWriter.FormDataContentType())
+	headers.Add(req)
+
+	resp, err := cli.hcCli.Client.Do(ctx, req)
+	if err != nil {
+		closeResponseBody(ctx, resp)
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// putChunk makes a single attempt at sending a chunk to its presigned URL. The request goes straight to the storage
+// rather than the upload service, so the auth headers are not sent with it.
+func (cli *Client) putChunk(ctx context.Context, presignedURL string, chunk []byte) (int, error) {
+	req, err := http.NewRequest(http.MethodPut, presignedURL, bytes.NewReader(chunk))
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := cli.hcCli.Client.Do(ctx, req)
+	if err != nil {
+		closeResponseBody(ctx, resp)
+		return 0, err
+	}
+	defer closeResponseBody(ctx, resp)
+
+	// the storage responds with its own errors rather than the upload service's JSON errors, so only the status is kept
+	if resp.StatusCode != http.StatusOK {
+		return 0, &APIError{StatusCode: resp.StatusCode}
+	}
+
+	return resp.StatusCode, nil
+}
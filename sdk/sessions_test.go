@@ -0,0 +1,203 @@
+package sdk
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ONSdigital/dp-api-clients-go/v2/health"
+	dphttp "github.com/ONSdigital/dp-net/v3/http"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const presignedURL = "https://bucket.s3.amazonaws.com/path/to/data.csv"
+
+// directUpload records the requests made by UploadDirect
+type directUpload struct {
+	mu       sync.Mutex
+	created  *http.Request
+	chunks   map[string][]byte
+	complete *http.Request
+}
+
+// newDirectUploadClienter returns a mock clienter that creates an upload session with a presigned URL for each of
+// totalChunks chunks, accepts the chunks with putStatus and completes the session with completeStatus
+func newDirectUploadClienter(totalChunks, putStatus, completeStatus int) (*dphttp.ClienterMock, *directUpload) {
+	upload := &directUpload{chunks: map[string][]byte{}}
+
+	parts := make([]string, 0, totalChunks)
+	for i := 1; i <= totalChunks; i++ {
+		parts = append(parts, fmt.Sprintf(`{"chunk_number":%d,"url":"%s?partNumber=%d&uploadId=upload-id"}`, i, presignedURL, i))
+	}
+	sessionBody := fmt.Sprintf(`{"upload_id":"upload-id","path":"path/to/data.csv","parts":[%s]}`, strings.Join(parts, ","))
+
+	mockClienter := newMockClienter(nil, nil)
+	mockClienter.DoFunc = func(_ context.Context, req *http.Request) (*http.Response, error) {
+		upload.mu.Lock()
+		defer upload.mu.Unlock()
+
+		switch {
+		case req.Method == http.MethodPut:
+			chunk, _ := io.ReadAll(req.Body)
+			upload.chunks[req.URL.Query().Get("partNumber")] = chunk
+			return &http.Response{StatusCode: putStatus, Body: http.NoBody}, nil
+		case strings.HasSuffix(req.URL.Path, "/complete"):
+			if err := req.ParseMultipartForm(chunkSize); err != nil {
+				return nil, err
+			}
+			upload.complete = req
+			return &http.Response{StatusCode: completeStatus, Body: io.NopCloser(strings.NewReader(`{"errors":[{"code":"PartsMissing"}]}`))}, nil
+		default:
+			if err := req.ParseMultipartForm(chunkSize); err != nil {
+				return nil, err
+			}
+			upload.created = req
+			return &http.Response{StatusCode: http.StatusCreated, Body: io.NopCloser(strings.NewReader(sessionBody))}, nil
+		}
+	}
+
+	return mockClienter, upload
+}
+
+func TestUploadDirect(t *testing.T) {
+	t.Parallel()
+
+	Convey("Given a client and a file of several chunks", t, func() {
+		content := append(bytes.Repeat([]byte("a"), chunkSize*2), []byte("end")...)
+		metadata := validMetadata
+		metadata.SizeInBytes = len(content)
+
+		Convey("When UploadDirect is called", func() {
+			mockClienter, upload := newDirectUploadClienter(3, http.StatusOK, http.StatusCreated)
+			client := NewWithHealthClient(health.NewClientWithClienter(serviceName, uploadServiceURL, mockClienter), WithConcurrency(2))
+
+			err := client.UploadDirect(context.Background(), io.NopCloser(bytes.NewReader(content)), metadata, Headers{ServiceAuthToken: "token"})
+
+			Convey("Then no error is returned", func() {
+				So(err, ShouldBeNil)
+			})
+
+			Convey("And an upload session is created for every chunk", func() {
+				So(upload.created.URL.String(), ShouldEqual, uploadServiceURL+"/upload-new/sessions")
+				So(upload.created.FormValue("path"), ShouldEqual, "path/to/data.csv")
+				So(upload.created.FormValue("resumableTotalChunks"), ShouldEqual, "3")
+				So(upload.created.Header.Get("Authorization"), ShouldEqual, "Bearer token")
+			})
+
+			Convey("And each chunk is sent to its presigned URL without the auth headers", func() {
+				So(upload.chunks, ShouldHaveLength, 3)
+				So(upload.chunks["1"], ShouldResemble, content[:chunkSize])
+				So(upload.chunks["3"], ShouldResemble, []byte("end"))
+				for _, call := range mockClienter.DoCalls() {
+					if call.Req.Method == http.MethodPut {
+						So(call.Req.Header.Get("Authorization"), ShouldBeEmpty)
+						So(call.Req.ContentLength, ShouldBeGreaterThan, 0)
+					}
+				}
+			})
+
+			Convey("And the session is completed with the checksum of the whole file", func() {
+				sum := sha256.Sum256(content)
+				So(upload.complete.URL.String(), ShouldEqual, uploadServiceURL+"/upload-new/sessions/upload-id/complete")
+				So(upload.complete.FormValue("fileChecksum"), ShouldEqual, base64.StdEncoding.EncodeToString(sum[:]))
+				So(upload.complete.FormValue("resumableTotalChunks"), ShouldEqual, "3")
+			})
+		})
+
+		Convey("When a chunk is rejected by the storage", func() {
+			mockClienter, upload := newDirectUploadClienter(3, http.StatusForbidden, http.StatusCreated)
+			client := newMockUploadServiceClient(mockClienter)
+
+			err := client.UploadDirect(context.Background(), io.NopCloser(bytes.NewReader(content)), metadata, Headers{})
+
+			Convey("Then an APIError with the storage's status is returned", func() {
+				apiErr, ok := err.(*APIError)
+				So(ok, ShouldBeTrue)
+				So(apiErr.StatusCode, ShouldEqual, http.StatusForbidden)
+			})
+
+			Convey("And the session is not completed", func() {
+				So(upload.complete, ShouldBeNil)
+			})
+		})
+
+		Convey("When the session cannot be completed", func() {
+			mockClienter, _ := newDirectUploadClienter(3, http.StatusOK, http.StatusConflict)
+			client := newMockUploadServiceClient(mockClienter)
+
+			err := client.UploadDirect(context.Background(), io.NopCloser(bytes.NewReader(content)), metadata, Headers{})
+
+			Convey("Then an APIError is returned", func() {
+				apiErr, ok := err.(*APIError)
+				So(ok, ShouldBeTrue)
+				So(apiErr.StatusCode, ShouldEqual, http.StatusConflict)
+				So(apiErr.Errors.Error[0].Code, ShouldEqual, "PartsMissing")
+			})
+		})
+
+		Convey("When the session does not have a presigned URL for every chunk", func() {
+			mockClienter, upload := newDirectUploadClienter(2, http.StatusOK, http.StatusCreated)
+			client := newMockUploadServiceClient(mockClienter)
+
+			err := client.UploadDirect(context.Background(), io.NopCloser(bytes.NewReader(content)), metadata, Headers{})
+
+			Convey("Then an error is returned and the session is not completed", func() {
+				So(err, ShouldNotBeNil)
+				So(upload.complete, ShouldBeNil)
+			})
+		})
+
+		Convey("When the file is shorter than its declared size", func() {
+			mockClienter, upload := newDirectUploadClienter(3, http.StatusOK, http.StatusCreated)
+			client := newMockUploadServiceClient(mockClienter)
+
+			err := client.UploadDirect(context.Background(), io.NopCloser(bytes.NewReader(content[:chunkSize])), metadata, Headers{})
+
+			Convey("Then an error is returned and the session is not completed", func() {
+				So(err, ShouldNotBeNil)
+				So(upload.complete, ShouldBeNil)
+			})
+		})
+	})
+}
+
+func TestUploadDirect_Failure(t *testing.T) {
+	t.Parallel()
+
+	Convey("When the upload service's storage does not support direct uploads", t, func() {
+		body := `{"errors":[{"code":"DirectUploadUnsupported","description":"storage does not support uploading chunks directly"}]}`
+		mockClienter := newMockClienter(&http.Response{StatusCode: http.StatusNotImplemented, Body: io.NopCloser(strings.NewReader(body))}, nil)
+		client := newMockUploadServiceClient(mockClienter)
+
+		err := client.UploadDirect(context.Background(), io.NopCloser(bytes.NewReader([]byte("a"))), validMetadata, Headers{})
+
+		Convey("Then an APIError with status 501 is returned", func() {
+			apiErr, ok := err.(*APIError)
+			So(ok, ShouldBeTrue)
+			So(apiErr.StatusCode, ShouldEqual, http.StatusNotImplemented)
+			So(apiErr.Errors.Error[0].Code, ShouldEqual, "DirectUploadUnsupported")
+			So(mockClienter.DoCalls(), ShouldHaveLength, 1)
+		})
+	})
+
+	Convey("When the file is too large", t, func() {
+		mockClienter := newMockClienter(nil, nil)
+		client := newMockUploadServiceClient(mockClienter)
+		metadata := validMetadata
+		metadata.SizeInBytes = maxFileSize + 1
+
+		err := client.UploadDirect(context.Background(), io.NopCloser(bytes.NewReader([]byte("a"))), metadata, Headers{})
+
+		Convey("Then ErrFileTooLarge is returned without creating a session", func() {
+			So(err, ShouldEqual, ErrFileTooLarge)
+			So(mockClienter.DoCalls(), ShouldHaveLength, 0)
+		})
+	})
+}
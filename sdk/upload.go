@@ -3,14 +3,20 @@ package sdk
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"mime/multipart"
 	"net/http"
 	"path/filepath"
 	"strconv"
+	"sync/atomic"
 
 	"github.com/ONSdigital/dp-upload-service/api"
+	"golang.org/x/sync/errgroup"
 )
 
 const (
@@ -20,62 +26,225 @@ const (
 )
 
 type ChunkInfo struct {
-	Current int
-	Total   int
+	Current      int
+	Total        int
+	Size         int
+	Checksum     string
+	FileChecksum string
 }
 
-// Upload uploads a file in chunks to the upload service via the /upload-new endpoint with the provided metadata and headers
+// Upload uploads a file in chunks to the upload service via the /upload-new endpoint with the provided metadata and headers.
+// The first chunk is sent on its own so that the upload has been started before any other chunk arrives, and the last
+// chunk is held back until every other chunk has been uploaded as it carries the checksum of the whole file. The chunks
+// in between are read ahead and sent in parallel when the Client is created WithConcurrency. ErrUploadNotCompleted is
+// returned if the upload service did not respond to the last chunk sent with 201 Created.
 func (cli *Client) Upload(ctx context.Context, fileContent io.ReadCloser, metadata api.Metadata, headers Headers) error {
-	if metadata.SizeInBytes > maxFileSize {
-		return ErrFileTooLarge
-	}
+	totalChunks := (metadata.SizeInBytes + chunkSize - 1) / chunkSize
+	progress := newProgressTracker(cli.progress, metadata.Path, metadata.SizeInBytes, totalChunks)
+
+	completed, err := cli.upload(ctx, fileContent, metadata, totalChunks, nil, headers, progress)
+	progress.finish(completed, err)
+
+	return err
+}
 
+// ResumeUpload continues an interrupted upload of a file, such as one left behind when a process restarted part way
+// through Upload. The chunks the upload service has already received are not sent again, although the whole file is
+// still read from the start to calculate its checksum. If no upload is in progress for the path the file is uploaded
+// from the beginning, and an APIError with status 409 is returned if the upload has already completed.
+func (cli *Client) ResumeUpload(ctx context.Context, fileContent io.ReadSeeker, metadata api.Metadata, headers Headers) error {
 	totalChunks := (metadata.SizeInBytes + chunkSize - 1) / chunkSize
-	chunkInfo := ChunkInfo{Total: totalChunks}
+	progress := newProgressTracker(cli.progress, metadata.Path, metadata.SizeInBytes, totalChunks)
 
-	for i := 1; i <= totalChunks; i++ {
-		chunkInfo.Current = i
+	completed, err := cli.resume(ctx, fileContent, metadata, totalChunks, headers, progress)
+	progress.finish(completed, err)
 
-		reqBody, contentType, err := createUploadRequestBody(chunkInfo, fileContent, metadata)
-		if err != nil {
-			return err
-		}
+	return err
+}
 
-		req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/upload-new", cli.hcCli.URL), reqBody)
-		if err != nil {
-			return err
+// resume finds the chunks that have already been received and uploads the rest
+func (cli *Client) resume(ctx context.Context, fileContent io.ReadSeeker, metadata api.Metadata, totalChunks int, headers Headers, progress *progressTracker) (bool, error) {
+	if metadata.SizeInBytes > maxFileSize {
+		return false, ErrFileTooLarge
+	}
+
+	uploaded := make(map[int]bool)
+	parts, err := cli.UploadedParts(ctx, metadata.Path, headers)
+	if err != nil {
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+			return false, err
 		}
+	} else {
+		for _, part := range parts.Parts {
+			// a part of a different size was sent for another version of the file, so is uploaded again to replace it
+			chunkNumber := int(part.ChunkNumber)
+			if chunkNumber <= totalChunks && part.SizeInBytes == int64(chunkLength(chunkNumber, metadata.SizeInBytes)) {
+				uploaded[chunkNumber] = true
+			}
+		}
+	}
 
-		req.Header.Set("Content-Type", contentType)
-		headers.Add(req)
+	if _, err := fileContent.Seek(0, io.SeekStart); err != nil {
+		return false, err
+	}
+
+	return cli.upload(ctx, fileContent, metadata, totalChunks, uploaded, headers, progress)
+}
 
-		resp, err := cli.hcCli.Client.Do(ctx, req)
+// upload sends the chunks of the file that are not already uploaded, returning true if the upload service reported
+// the file as complete
+func (cli *Client) upload(ctx context.Context, fileContent io.Reader, metadata api.Metadata, totalChunks int, uploaded map[int]bool, headers Headers, progress *progressTracker) (bool, error) {
+	if metadata.SizeInBytes > maxFileSize {
+		return false, ErrFileTooLarge
+	}
+	if totalChunks == 0 {
+		return false, nil
+	}
+
+	// the whole file checksum is built up as the chunks are read and sent with the last chunk
+	fileHash := sha256.New()
+	hashedContent := io.TeeReader(fileContent, fileHash)
+
+	var completed atomic.Bool
+
+	// readChunk reads the next chunk of the file, returning a function that sends it to the upload service, or nil if
+	// the chunk has already been uploaded
+	readChunk := func(chunkNumber int) (func(context.Context) error, error) {
+		length := chunkLength(chunkNumber, metadata.SizeInBytes)
+		if uploaded[chunkNumber] {
+			if _, err := io.CopyN(io.Discard, hashedContent, int64(length)); err != nil {
+				return nil, err
+			}
+			progress.chunkSkipped(chunkNumber, length)
+			return nil, nil
+		}
+
+		reqBody, contentType, err := createUploadRequestBody(ChunkInfo{Current: chunkNumber, Total: totalChunks}, hashedContent, metadata, fileHash)
 		if err != nil {
-			closeResponseBody(ctx, resp)
-			return err
+			return nil, err
 		}
 
-		statusCode := resp.StatusCode
-		if statusCode != http.StatusOK && statusCode != http.StatusCreated {
-			jsonErrors, err := unmarshalJsonErrors(resp.Body)
+		return func(ctx context.Context) error {
+			statusCode, err := cli.uploadChunk(ctx, reqBody.Bytes(), contentType, headers, chunkNumber == totalChunks)
 			if err != nil {
 				return err
 			}
-			closeResponseBody(ctx, resp)
-			return &APIError{
-				StatusCode: statusCode,
-				Errors:     jsonErrors,
+			if statusCode == http.StatusCreated {
+				completed.Store(true)
 			}
+			progress.chunkSent(chunkNumber, length)
+			return nil
+		}, nil
+	}
+
+	if err := readAndSendChunk(ctx, readChunk, 1); err != nil {
+		return false, err
+	}
+	if totalChunks == 1 {
+		return checkCompleted(completed.Load())
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(cli.concurrency)
+
+	var readErr error
+	for i := 2; i < totalChunks && gctx.Err() == nil; i++ {
+		send, err := readChunk(i)
+		if err != nil {
+			readErr = err
+			break
+		}
+		if send != nil {
+			g.Go(func() error {
+				return send(gctx)
+			})
 		}
+	}
+
+	if err := g.Wait(); err != nil {
+		return false, err
+	}
+	if readErr != nil {
+		return false, readErr
+	}
+
+	if err := readAndSendChunk(ctx, readChunk, totalChunks); err != nil {
+		return false, err
+	}
+
+	return checkCompleted(completed.Load())
+}
+
+// checkCompleted returns ErrUploadNotCompleted if no chunk was reported as completing the file once every chunk has
+// been sent, as a file that has been uploaded in full must have been completed by the last chunk to arrive
+func checkCompleted(completed bool) (bool, error) {
+	if !completed {
+		return false, ErrUploadNotCompleted
+	}
+	return true, nil
+}
+
+// readAndSendChunk reads a chunk with readChunk and sends it straight away, unless it has already been uploaded
+func readAndSendChunk(ctx context.Context, readChunk func(int) (func(context.Context) error, error), chunkNumber int) error {
+	send, err := readChunk(chunkNumber)
+	if err != nil || send == nil {
+		return err
+	}
+	return send(ctx)
+}
 
+// uploadChunk sends a request body created by createUploadRequestBody to the upload service, retrying it according
+// to the Client's retry policy, which only retries the last chunk if it was not handled. It returns the response status
+// code, or an APIError if the chunk was not accepted.
+func (cli *Client) uploadChunk(ctx context.Context, reqBody []byte, contentType string, headers Headers, last bool) (int, error) {
+	retryable := isRetryable
+	if last {
+		retryable = isRetryableLastChunk
+	}
+
+	return cli.retry.do(ctx, retryable, func() (int, error) {
+		return cli.postChunk(ctx, bytes.NewReader(reqBody), contentType, headers)
+	})
+}
+
+// postChunk makes a single attempt at sending a chunk to the upload service
+func (cli *Client) postChunk(ctx context.Context, reqBody io.Reader, contentType string, headers Headers) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, cli.hcCli.URL+uploadPath, reqBody)
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", contentType)
+	headers.Add(req)
+
+	resp, err := cli.hcCli.Client.Do(ctx, req)
+	if err != nil {
 		closeResponseBody(ctx, resp)
+		return 0, err
+	}
+	defer closeResponseBody(ctx, resp)
+
+	statusCode := resp.StatusCode
+	if statusCode != http.StatusOK && statusCode != http.StatusCreated {
+		// proxies in front of the upload service can respond without a JSON body, and the status alone is still
+		// needed to decide whether to retry
+		jsonErrors, err := unmarshalJsonErrors(resp.Body)
+		if err != nil && !retryableStatusCodes[statusCode] {
+			return 0, err
+		}
+		return 0, &APIError{
+			StatusCode: statusCode,
+			Errors:     jsonErrors,
+		}
 	}
 
-	return nil
+	return statusCode, nil
 }
 
-// createUploadRequestBody creates a multipart/form-data request body for the given chunk and metadata
-func createUploadRequestBody(chunkInfo ChunkInfo, fileContent io.ReadCloser, metadata api.Metadata) (*bytes.Buffer, string, error) {
+// createUploadRequestBody creates a multipart/form-data request body for the given chunk and metadata.
+// When fileHash is provided and this is the last chunk, the checksum of the whole file is included.
+func createUploadRequestBody(chunkInfo ChunkInfo, fileContent io.Reader, metadata api.Metadata, fileHash hash.Hash) (*bytes.Buffer, string, error) {
 	reqBuff := &bytes.Buffer{}
 	formWriter := multipart.NewWriter(reqBuff)
 
@@ -84,6 +253,16 @@ func createUploadRequestBody(chunkInfo ChunkInfo, fileContent io.ReadCloser, met
 		return nil, "", err
 	}
 
+	chunkInfo.Size = contentChunkLength
+	chunkInfo.Checksum, err = chunkChecksum(contentChunk)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if fileHash != nil && chunkInfo.Current == chunkInfo.Total {
+		chunkInfo.FileChecksum = base64.StdEncoding.EncodeToString(fileHash.Sum(nil))
+	}
+
 	err = writeMetadataFormFields(formWriter, metadata, chunkInfo)
 	if err != nil {
 		return nil, "", err
@@ -101,8 +280,21 @@ func createUploadRequestBody(chunkInfo ChunkInfo, fileContent io.ReadCloser, met
 	return reqBuff, formWriter.FormDataContentType(), nil
 }
 
-// chunkReader reads a chunk of data from the provided fileContent ReadCloser
-func chunkReader(fileContent io.ReadCloser) (io.Reader, int, error) {
+// chunkChecksum returns the base64 encoded SHA256 digest of the chunk, leaving the chunk ready to be read again
+func chunkChecksum(chunk io.ReadSeeker) (string, error) {
+	hash := sha256.New()
+	if _, err := io.Copy(hash, chunk); err != nil {
+		return "", err
+	}
+	if _, err := chunk.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(hash.Sum(nil)), nil
+}
+
+// chunkReader reads a chunk of data from the provided fileContent Reader
+func chunkReader(fileContent io.Reader) (io.ReadSeeker, int, error) {
 	readBuff := make([]byte, chunkSize)
 	bytesRead, err := io.ReadFull(fileContent, readBuff)
 	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
@@ -146,6 +338,20 @@ func writeMetadataFormFields(formWriter *multipart.Writer, metadata api.Metadata
 		"resumableFilename":    filepath.Base(metadata.Path),
 	}
 
+	// the service streams a chunk of a known size straight through to S3
+	if chunkInfo.Size > 0 {
+		formFields["resumableCurrentChunkSize"] = strconv.Itoa(chunkInfo.Size)
+	}
+
+	if chunkInfo.Checksum != "" {
+		formFields["resumableChunkChecksum"] = chunkInfo.Checksum
+		formFields["resumableChunkChecksumAlgorithm"] = "SHA256"
+	}
+
+	if chunkInfo.FileChecksum != "" {
+		formFields["fileChecksum"] = chunkInfo.FileChecksum
+	}
+
 	if metadata.DatasetID != "" {
 		formFields["datasetId"] = metadata.DatasetID
 	}
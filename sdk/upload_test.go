@@ -3,15 +3,21 @@ package sdk
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
 	"errors"
 	"io"
 	"mime/multipart"
 	"net/http"
+	"net/url"
 	"path/filepath"
 	"strconv"
+	"sync"
 	"testing"
 	"testing/iotest"
 
+	"github.com/ONSdigital/dp-api-clients-go/v2/health"
+	dphttp "github.com/ONSdigital/dp-net/v3/http"
 	"github.com/ONSdigital/dp-upload-service/api"
 	. "github.com/smartystreets/goconvey/convey"
 )
@@ -78,6 +84,200 @@ func TestUpload_Success(t *testing.T) {
 	})
 }
 
+// newChunkRecordingClienter returns a mock clienter that records the form fields of each chunk it receives, responding
+// with the status returned by respond for the chunk number and total number of chunks
+func newChunkRecordingClienter(respond func(chunkNumber, totalChunks int) int) (*dphttp.ClienterMock, func() []url.Values) {
+	var mu sync.Mutex
+	var chunks []url.Values
+
+	mockClienter := newMockClienter(nil, nil)
+	mockClienter.DoFunc = func(_ context.Context, req *http.Request) (*http.Response, error) {
+		if err := req.ParseMultipartForm(chunkSize * 2); err != nil {
+			return nil, err
+		}
+		chunkNumber, _ := strconv.Atoi(req.FormValue("resumableChunkNumber"))
+		totalChunks, _ := strconv.Atoi(req.FormValue("resumableTotalChunks"))
+
+		mu.Lock()
+		chunks = append(chunks, req.MultipartForm.Value)
+		mu.Unlock()
+
+		return &http.Response{StatusCode: respond(chunkNumber, totalChunks), Body: io.NopCloser(bytes.NewReader([]byte(`{"errors":[]}`)))}, nil
+	}
+
+	return mockClienter, func() []url.Values {
+		mu.Lock()
+		defer mu.Unlock()
+		return chunks
+	}
+}
+
+func TestUpload_Concurrent(t *testing.T) {
+	t.Parallel()
+
+	Convey("Given a client created WithConcurrency and a file of several chunks", t, func() {
+		content := bytes.Repeat([]byte("a"), chunkSize*5+1)
+		metadata := validMetadata
+		metadata.SizeInBytes = len(content)
+
+		Convey("When Upload is called", func() {
+			mockClienter, chunks := newChunkRecordingClienter(func(int, int) int { return http.StatusCreated })
+			client := NewWithHealthClient(health.NewClientWithClienter(serviceName, uploadServiceURL, mockClienter), WithConcurrency(3))
+
+			err := client.Upload(context.Background(), io.NopCloser(bytes.NewReader(content)), metadata, Headers{})
+
+			Convey("Then no error is returned", func() {
+				So(err, ShouldBeNil)
+			})
+
+			Convey("And every chunk is uploaded once", func() {
+				So(chunks(), ShouldHaveLength, 6)
+				uploaded := map[string]bool{}
+				for _, chunk := range chunks() {
+					uploaded[chunk.Get("resumableChunkNumber")] = true
+				}
+				So(uploaded, ShouldHaveLength, 6)
+			})
+
+			Convey("And the first chunk is uploaded first", func() {
+				So(chunks()[0].Get("resumableChunkNumber"), ShouldEqual, "1")
+			})
+
+			Convey("And the last chunk is uploaded last with the checksum of the whole file", func() {
+				sum := sha256.Sum256(content)
+				last := chunks()[len(chunks())-1]
+				So(last.Get("resumableChunkNumber"), ShouldEqual, "6")
+				So(last.Get("fileChecksum"), ShouldEqual, base64.StdEncoding.EncodeToString(sum[:]))
+			})
+		})
+
+		Convey("When a chunk in the middle of the file is rejected", func() {
+			mockClienter, chunks := newChunkRecordingClienter(func(chunkNumber, _ int) int {
+				if chunkNumber == 3 {
+					return http.StatusInternalServerError
+				}
+				return http.StatusCreated
+			})
+			client := NewWithHealthClient(health.NewClientWithClienter(serviceName, uploadServiceURL, mockClienter), WithConcurrency(3))
+
+			err := client.Upload(context.Background(), io.NopCloser(bytes.NewReader(content)), metadata, Headers{})
+
+			Convey("Then an APIError is returned", func() {
+				apiErr, ok := err.(*APIError)
+				So(ok, ShouldBeTrue)
+				So(apiErr.StatusCode, ShouldEqual, http.StatusInternalServerError)
+			})
+
+			Convey("And the last chunk is not uploaded", func() {
+				for _, chunk := range chunks() {
+					So(chunk.Get("resumableChunkNumber"), ShouldNotEqual, "6")
+				}
+			})
+		})
+	})
+}
+
+// newResumingClienter returns a chunk recording mock clienter that responds to requests for the uploaded parts with
+// the given status and body, and reports the file as completed by its last chunk
+func newResumingClienter(partsStatus int, partsBody string) (*dphttp.ClienterMock, func() []url.Values) {
+	mockClienter, chunks := newChunkRecordingClienter(func(chunkNumber, totalChunks int) int {
+		if chunkNumber == totalChunks {
+			return http.StatusCreated
+		}
+		return http.StatusOK
+	})
+	uploadChunk := mockClienter.DoFunc
+	mockClienter.DoFunc = func(ctx context.Context, req *http.Request) (*http.Response, error) {
+		if req.Method == http.MethodGet {
+			return &http.Response{StatusCode: partsStatus, Body: io.NopCloser(bytes.NewReader([]byte(partsBody)))}, nil
+		}
+		return uploadChunk(ctx, req)
+	}
+	return mockClienter, chunks
+}
+
+func TestResumeUpload(t *testing.T) {
+	t.Parallel()
+
+	Convey("Given a file of several chunks that has been partly uploaded", t, func() {
+		content := bytes.Repeat([]byte("a"), chunkSize*3+10)
+		metadata := validMetadata
+		metadata.SizeInBytes = len(content)
+		fileContent := bytes.NewReader(content)
+		_, _ = fileContent.Seek(100, io.SeekStart)
+
+		Convey("When ResumeUpload is called", func() {
+			parts := `{"path":"path/to/data.csv","parts":[{"chunk_number":1,"size_in_bytes":5242880},{"chunk_number":3,"size_in_bytes":5242880}]}`
+			mockClienter, chunks := newResumingClienter(http.StatusOK, parts)
+			client := NewWithHealthClient(health.NewClientWithClienter(serviceName, uploadServiceURL, mockClienter))
+
+			err := client.ResumeUpload(context.Background(), fileContent, metadata, Headers{})
+
+			Convey("Then no error is returned", func() {
+				So(err, ShouldBeNil)
+			})
+
+			Convey("And the uploaded parts are requested for the path", func() {
+				So(mockClienter.DoCalls()[0].Req.URL.String(), ShouldEqual, uploadServiceURL+"/upload-new/files/path/to/data.csv/parts")
+			})
+
+			Convey("And only the missing chunks are uploaded", func() {
+				So(chunks(), ShouldHaveLength, 2)
+				So(chunks()[0].Get("resumableChunkNumber"), ShouldEqual, "2")
+				So(chunks()[1].Get("resumableChunkNumber"), ShouldEqual, "4")
+			})
+
+			Convey("And the last chunk carries the checksum of the whole file", func() {
+				sum := sha256.Sum256(content)
+				So(chunks()[1].Get("fileChecksum"), ShouldEqual, base64.StdEncoding.EncodeToString(sum[:]))
+			})
+		})
+
+		Convey("When ResumeUpload is called and a part of the wrong size has been uploaded", func() {
+			parts := `{"path":"path/to/data.csv","parts":[{"chunk_number":1,"size_in_bytes":5242880},{"chunk_number":2,"size_in_bytes":10}]}`
+			mockClienter, chunks := newResumingClienter(http.StatusOK, parts)
+			client := NewWithHealthClient(health.NewClientWithClienter(serviceName, uploadServiceURL, mockClienter))
+
+			err := client.ResumeUpload(context.Background(), fileContent, metadata, Headers{})
+
+			Convey("Then the part is uploaded again", func() {
+				So(err, ShouldBeNil)
+				So(chunks(), ShouldHaveLength, 3)
+				So(chunks()[0].Get("resumableChunkNumber"), ShouldEqual, "2")
+			})
+		})
+
+		Convey("When ResumeUpload is called and there is no upload in progress", func() {
+			mockClienter, chunks := newResumingClienter(http.StatusNotFound, `{"errors":[{"code":"NotFound"}]}`)
+			client := NewWithHealthClient(health.NewClientWithClienter(serviceName, uploadServiceURL, mockClienter))
+
+			err := client.ResumeUpload(context.Background(), fileContent, metadata, Headers{})
+
+			Convey("Then every chunk is uploaded", func() {
+				So(err, ShouldBeNil)
+				So(chunks(), ShouldHaveLength, 4)
+			})
+		})
+
+		Convey("When ResumeUpload is called and the upload has already completed", func() {
+			mockClienter, chunks := newResumingClienter(http.StatusConflict, `{"errors":[{"code":"UploadComplete"}]}`)
+			client := NewWithHealthClient(health.NewClientWithClienter(serviceName, uploadServiceURL, mockClienter))
+
+			err := client.ResumeUpload(context.Background(), fileContent, metadata, Headers{})
+
+			Convey("Then an APIError is returned", func() {
+				apiErr, ok := err.(*APIError)
+				So(ok, ShouldBeTrue)
+				So(apiErr.StatusCode, ShouldEqual, http.StatusConflict)
+			})
+
+			Convey("And no chunks are uploaded", func() {
+				So(chunks(), ShouldHaveLength, 0)
+			})
+		})
+	})
+}
+
 func TestUpload_Failure(t *testing.T) {
 	t.Parallel()
 
@@ -193,7 +393,7 @@ func TestCreateUploadRequestBody(t *testing.T) {
 		metadata := validMetadata
 
 		Convey("When createUploadRequestBody is called", func() {
-			reqBuff, contentType, err := createUploadRequestBody(chunkInfo, fileContent, metadata)
+			reqBuff, contentType, err := createUploadRequestBody(chunkInfo, fileContent, metadata, nil)
 
 			Convey("Then no error is returned", func() {
 				So(err, ShouldBeNil)
@@ -216,13 +416,44 @@ func TestCreateUploadRequestBody(t *testing.T) {
 				So(body, ShouldContainSubstring, strconv.Itoa(chunkInfo.Total))
 			})
 
+			Convey("And the request body contains the size of the chunk", func() {
+				body := reqBuff.String()
+				So(body, ShouldContainSubstring, "resumableCurrentChunkSize")
+				So(body, ShouldContainSubstring, strconv.Itoa(len("This is some test file content.")))
+			})
+
+			Convey("And the request body contains the SHA256 checksum of the chunk", func() {
+				sum := sha256.Sum256([]byte("This is some test file content."))
+				body := reqBuff.String()
+				So(body, ShouldContainSubstring, "resumableChunkChecksum")
+				So(body, ShouldContainSubstring, base64.StdEncoding.EncodeToString(sum[:]))
+			})
+
 			Convey("And the content type is correctly set", func() {
 				So(contentType, ShouldStartWith, "multipart/form-data; boundary=")
 			})
+
+			Convey("And the request body does not contain a file checksum", func() {
+				So(reqBuff.String(), ShouldNotContainSubstring, "fileChecksum")
+			})
+		})
+
+		Convey("When createUploadRequestBody is called for the last chunk with a file hash", func() {
+			chunkInfo.Current = chunkInfo.Total
+			fileHash := sha256.New()
+			reqBuff, _, err := createUploadRequestBody(chunkInfo, io.TeeReader(fileContent, fileHash), metadata, fileHash)
+
+			Convey("Then the request body contains the SHA256 checksum of the whole file", func() {
+				So(err, ShouldBeNil)
+				sum := sha256.Sum256([]byte("This is some test file content."))
+				body := reqBuff.String()
+				So(body, ShouldContainSubstring, "fileChecksum")
+				So(body, ShouldContainSubstring, base64.StdEncoding.EncodeToString(sum[:]))
+			})
 		})
 
 		Convey("When createUploadRequestBody is called with a reader that returns an error", func() {
-			reqBuff, contentType, err := createUploadRequestBody(chunkInfo, brokenReader, metadata)
+			reqBuff, contentType, err := createUploadRequestBody(chunkInfo, brokenReader, metadata, nil)
 
 			Convey("Then the expected error is returned", func() {
 				So(err, ShouldNotBeNil)
@@ -240,6 +471,31 @@ func TestCreateUploadRequestBody(t *testing.T) {
 	})
 }
 
+func TestChunkChecksum(t *testing.T) {
+	t.Parallel()
+
+	Convey("Given a chunk of data", t, func() {
+		data := []byte("chunk data")
+		chunk := bytes.NewReader(data)
+
+		Convey("When chunkChecksum is called", func() {
+			checksum, err := chunkChecksum(chunk)
+
+			Convey("Then the base64 encoded SHA256 digest is returned", func() {
+				sum := sha256.Sum256(data)
+				So(err, ShouldBeNil)
+				So(checksum, ShouldEqual, base64.StdEncoding.EncodeToString(sum[:]))
+			})
+
+			Convey("And the chunk can be read again from the start", func() {
+				remaining, err := io.ReadAll(chunk)
+				So(err, ShouldBeNil)
+				So(remaining, ShouldResemble, data)
+			})
+		})
+	})
+}
+
 func TestChunkReader(t *testing.T) {
 	t.Parallel()
 
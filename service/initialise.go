@@ -4,17 +4,29 @@ import (
 	"context"
 	"net/http"
 
+	"github.com/ONSdigital/dp-authorisation/v2/authorisation"
 	"github.com/ONSdigital/dp-healthcheck/healthcheck"
 	dphttp "github.com/ONSdigital/dp-net/v3/http"
-	dps3 "github.com/ONSdigital/dp-s3/v3"
 	dpaws "github.com/ONSdigital/dp-upload-service/aws"
 	"github.com/ONSdigital/dp-upload-service/config"
+	"github.com/ONSdigital/dp-upload-service/filesystem"
+	"github.com/ONSdigital/dp-upload-service/storage"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	awsConfig "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 )
 
+// storageDrivers holds the storage drivers that can be chosen with STORAGE_BACKEND
+var storageDrivers = newStorageDrivers()
+
+func newStorageDrivers() *storage.Registry {
+	drivers := storage.NewRegistry()
+	drivers.Register(config.StorageBackendS3, newS3Driver)
+	drivers.Register(config.StorageBackendFilesystem, newFilesystemDriver)
+	return drivers
+}
+
 // ExternalServiceList holds the initialiser and initialisation state of external services.
 type ExternalServiceList struct {
 	S3Uploaded  bool
@@ -40,8 +52,8 @@ func (e *ExternalServiceList) GetHTTPServer(bindAddr string, router http.Handler
 	return s
 }
 
-// GetS3Uploaded creates a S3 client and sets the S3Uploaded flag to true
-func (e *ExternalServiceList) GetS3Uploaded(ctx context.Context, cfg *config.Config) (dpaws.S3Clienter, error) {
+// GetS3Uploaded creates a storage driver for the uploaded bucket and sets the S3Uploaded flag to true
+func (e *ExternalServiceList) GetS3Uploaded(ctx context.Context, cfg *config.Config) (storage.Driver, error) {
 	s3, err := e.Init.DoGetS3Uploaded(ctx, cfg)
 	if err != nil {
 		return nil, err
@@ -50,10 +62,21 @@ func (e *ExternalServiceList) GetS3Uploaded(ctx context.Context, cfg *config.Con
 	return s3, nil
 }
 
-func (e *ExternalServiceList) GetS3StaticFileUploader(ctx context.Context, cfg *config.Config) (dpaws.S3Clienter, error) {
+func (e *ExternalServiceList) GetS3StaticFileUploader(ctx context.Context, cfg *config.Config) (storage.Driver, error) {
 	return e.Init.DoGetStaticFileS3Uploader(ctx, cfg)
 }
 
+// GetS3Public creates a storage driver for the public bucket that published files are copied to
+func (e *ExternalServiceList) GetS3Public(ctx context.Context, cfg *config.Config) (storage.Driver, error) {
+	return e.Init.DoGetS3Public(ctx, cfg)
+}
+
+// GetAuthorisationMiddleware creates the middleware that checks callers have permission to use the routes it wraps.
+// It lets every request through if authorisation is not enabled.
+func (e *ExternalServiceList) GetAuthorisationMiddleware(ctx context.Context, authorisationConfig *authorisation.Config) (authorisation.Middleware, error) {
+	return e.Init.DoGetAuthorisationMiddleware(ctx, authorisationConfig)
+}
+
 // GetHealthCheck creates a healthcheck with versionInfo and sets teh HealthCheck flag to true
 func (e *ExternalServiceList) GetHealthCheck(cfg *config.Config, buildTime, gitCommit, version string) (HealthChecker, error) {
 	hc, err := e.Init.DoGetHealthCheck(cfg, buildTime, gitCommit, version)
@@ -71,44 +94,56 @@ func (e *Init) DoGetHTTPServer(bindAddr string, router http.Handler) HTTPServer
 	return s
 }
 
-// DoGetS3Uploaded returns a S3Client
-func (e *Init) DoGetS3Uploaded(ctx context.Context, cfg *config.Config) (dpaws.S3Clienter, error) {
-	return generateS3Client(ctx, cfg, cfg.UploadBucketName)
+// DoGetS3Uploaded returns the configured storage driver for the uploaded bucket
+func (e *Init) DoGetS3Uploaded(ctx context.Context, cfg *config.Config) (storage.Driver, error) {
+	return storageDrivers.New(ctx, cfg, cfg.UploadBucketName)
 }
 
-// DoGetStaticFileS3Uploader returns a S3Client
-func (e *Init) DoGetStaticFileS3Uploader(ctx context.Context, cfg *config.Config) (dpaws.S3Clienter, error) {
-	return generateS3Client(ctx, cfg, cfg.StaticFilesEncryptedBucketName)
+// DoGetStaticFileS3Uploader returns the configured storage driver for the static files bucket
+func (e *Init) DoGetStaticFileS3Uploader(ctx context.Context, cfg *config.Config) (storage.Driver, error) {
+	return storageDrivers.New(ctx, cfg, cfg.StaticFilesEncryptedBucketName)
 }
 
-func generateS3Client(ctx context.Context, cfg *config.Config, bucketName string) (dpaws.S3Clienter, error) {
-	var AWSConfig aws.Config
-	var err error
-	var client *dps3.Client
-	if cfg.LocalstackHost != "" {
+// DoGetS3Public returns the configured storage driver for the public bucket
+func (e *Init) DoGetS3Public(ctx context.Context, cfg *config.Config) (storage.Driver, error) {
+	return storageDrivers.New(ctx, cfg, cfg.PublicBucketName)
+}
+
+// DoGetAuthorisationMiddleware returns the permissions checking middleware, or a middleware that does no checks if
+// authorisation is not enabled
+func (e *Init) DoGetAuthorisationMiddleware(ctx context.Context, authorisationConfig *authorisation.Config) (authorisation.Middleware, error) {
+	return authorisation.NewFeatureFlaggedMiddleware(ctx, authorisationConfig, nil)
+}
+
+// newFilesystemDriver stores the bucket in a directory under FILESYSTEM_STORAGE_PATH
+func newFilesystemDriver(ctx context.Context, cfg *config.Config, bucketName string) (storage.Driver, error) {
+	return filesystem.NewClient(cfg.FilesystemStoragePath, bucketName), nil
+}
 
-		AWSConfig, err = awsConfig.LoadDefaultConfig(
+// newS3Driver stores the bucket in S3, or in localstack if LOCALSTACK_HOST is set
+func newS3Driver(ctx context.Context, cfg *config.Config, bucketName string) (storage.Driver, error) {
+	if cfg.LocalstackHost != "" {
+		AWSConfig, err := awsConfig.LoadDefaultConfig(
 			ctx,
 			awsConfig.WithRegion(cfg.AwsRegion),
 			awsConfig.WithCredentialsProvider(credentials.NewStaticCredentialsProvider("test", "test", "")),
 		)
-
 		if err != nil {
 			return nil, err
 		}
 
-		client = dps3.NewClientWithConfig(bucketName, AWSConfig, func(options *s3.Options) {
+		return dpaws.NewClient(bucketName, AWSConfig, func(options *s3.Options) {
 			options.BaseEndpoint = aws.String(cfg.LocalstackHost)
 			options.UsePathStyle = true
-		})
-		return client, nil
+		}), nil
 	}
 
-	s3Client, err := dps3.NewClient(ctx, cfg.AwsRegion, bucketName)
+	AWSConfig, err := awsConfig.LoadDefaultConfig(ctx, awsConfig.WithRegion(cfg.AwsRegion))
 	if err != nil {
 		return nil, err
 	}
-	return s3Client, nil
+
+	return dpaws.NewClient(bucketName, AWSConfig), nil
 }
 
 // DoGetHealthCheck creates a healthcheck with versionInfo
@@ -6,11 +6,13 @@ import (
 	"testing"
 	"time"
 
-	"github.com/ONSdigital/dp-upload-service/aws"
-	mock_aws "github.com/ONSdigital/dp-upload-service/aws/mock"
+	"github.com/ONSdigital/dp-authorisation/v2/authorisation"
 	"github.com/ONSdigital/dp-upload-service/config"
+	"github.com/ONSdigital/dp-upload-service/filesystem"
 	"github.com/ONSdigital/dp-upload-service/service"
 	mock_service "github.com/ONSdigital/dp-upload-service/service/mock"
+	"github.com/ONSdigital/dp-upload-service/storage"
+	mock_storage "github.com/ONSdigital/dp-upload-service/storage/mock"
 
 	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
@@ -125,9 +127,9 @@ func TestGetS3Uploaded(t *testing.T) {
 
 	Convey("Given a service list that includes a mocked s3Client", t, func() {
 
-		s3UploadedMock := &mock_aws.S3ClienterMock{}
+		s3UploadedMock := &mock_storage.DriverMock{}
 		newServiceMock := &mock_service.InitialiserMock{
-			DoGetS3UploadedFunc: func(ctx context.Context, cfg *config.Config) (aws.S3Clienter, error) {
+			DoGetS3UploadedFunc: func(ctx context.Context, cfg *config.Config) (storage.Driver, error) {
 				return s3UploadedMock, nil
 			},
 		}
@@ -145,7 +147,7 @@ func TestGetS3Uploaded(t *testing.T) {
 
 	Convey("Given a service list returns nil for mocked S3 client", t, func() {
 		newServiceMock := &mock_service.InitialiserMock{
-			DoGetS3UploadedFunc: func(ctx context.Context, cfg *config.Config) (aws.S3Clienter, error) {
+			DoGetS3UploadedFunc: func(ctx context.Context, cfg *config.Config) (storage.Driver, error) {
 				return nil, errS3Uploaded
 			},
 		}
@@ -202,3 +204,48 @@ func TestGetHealthCheck(t *testing.T) {
 		})
 	})
 }
+
+func TestInitAuthorisationMiddleware(t *testing.T) {
+
+	Convey("Given authorisation is not enabled", t, func() {
+		authorisationCfg := &authorisation.Config{Enabled: false}
+
+		Convey("When DoGetAuthorisationMiddleware is called", func() {
+			middleware, err := (&service.Init{}).DoGetAuthorisationMiddleware(ctx, authorisationCfg)
+
+			Convey("Then a middleware that does no permissions checks is returned", func() {
+				So(err, ShouldBeNil)
+				So(middleware, ShouldHaveSameTypeAs, &authorisation.NoopMiddleware{})
+			})
+		})
+	})
+}
+
+func TestInitStorageDrivers(t *testing.T) {
+
+	Convey("Given the filesystem storage backend is configured", t, func() {
+		filesystemCfg := &config.Config{StorageBackend: config.StorageBackendFilesystem, FilesystemStoragePath: t.TempDir(), UploadBucketName: "uploaded"}
+
+		Convey("When DoGetS3Uploaded is called", func() {
+			driver, err := (&service.Init{}).DoGetS3Uploaded(ctx, filesystemCfg)
+
+			Convey("Then a filesystem driver is returned", func() {
+				So(err, ShouldBeNil)
+				So(driver, ShouldHaveSameTypeAs, &filesystem.Client{})
+			})
+		})
+	})
+
+	Convey("Given an unknown storage backend is configured", t, func() {
+		unknownCfg := &config.Config{StorageBackend: "tape"}
+
+		Convey("When DoGetS3Uploaded is called", func() {
+			driver, err := (&service.Init{}).DoGetS3Uploaded(ctx, unknownCfg)
+
+			Convey("Then an unknown driver error is returned", func() {
+				So(driver, ShouldBeNil)
+				So(errors.Is(err, storage.ErrUnknownDriver), ShouldBeTrue)
+			})
+		})
+	})
+}
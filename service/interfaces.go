@@ -4,9 +4,10 @@ import (
 	"context"
 	"net/http"
 
+	"github.com/ONSdigital/dp-authorisation/v2/authorisation"
 	"github.com/ONSdigital/dp-healthcheck/healthcheck"
-	"github.com/ONSdigital/dp-upload-service/aws"
 	"github.com/ONSdigital/dp-upload-service/config"
+	"github.com/ONSdigital/dp-upload-service/storage"
 )
 
 //go:generate moq -out mock/initialiser.go -pkg mock_service . Initialiser
@@ -17,8 +18,10 @@ import (
 type Initialiser interface {
 	DoGetHTTPServer(bindAddr string, router http.Handler) HTTPServer
 	DoGetHealthCheck(cfg *config.Config, buildTime, gitCommit, version string) (HealthChecker, error)
-	DoGetS3Uploaded(ctx context.Context, cfg *config.Config) (aws.S3Clienter, error)
-	DoGetStaticFileS3Uploader(ctx context.Context, cfg *config.Config) (aws.S3Clienter, error)
+	DoGetS3Uploaded(ctx context.Context, cfg *config.Config) (storage.Driver, error)
+	DoGetStaticFileS3Uploader(ctx context.Context, cfg *config.Config) (storage.Driver, error)
+	DoGetS3Public(ctx context.Context, cfg *config.Config) (storage.Driver, error)
+	DoGetAuthorisationMiddleware(ctx context.Context, authorisationConfig *authorisation.Config) (authorisation.Middleware, error)
 }
 
 // HTTPServer defines the required methods from the HTTP server
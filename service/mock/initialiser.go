@@ -5,9 +5,10 @@ package mock_service
 
 import (
 	"context"
-	dpaws "github.com/ONSdigital/dp-upload-service/aws"
+	"github.com/ONSdigital/dp-authorisation/v2/authorisation"
 	"github.com/ONSdigital/dp-upload-service/config"
 	"github.com/ONSdigital/dp-upload-service/service"
+	"github.com/ONSdigital/dp-upload-service/storage"
 	"net/http"
 	"sync"
 )
@@ -22,16 +23,22 @@ var _ service.Initialiser = &InitialiserMock{}
 //
 //		// make and configure a mocked service.Initialiser
 //		mockedInitialiser := &InitialiserMock{
+//			DoGetAuthorisationMiddlewareFunc: func(ctx context.Context, authorisationConfig *authorisation.Config) (authorisation.Middleware, error) {
+//				panic("mock out the DoGetAuthorisationMiddleware method")
+//			},
 //			DoGetHTTPServerFunc: func(bindAddr string, router http.Handler) service.HTTPServer {
 //				panic("mock out the DoGetHTTPServer method")
 //			},
 //			DoGetHealthCheckFunc: func(cfg *config.Config, buildTime string, gitCommit string, version string) (service.HealthChecker, error) {
 //				panic("mock out the DoGetHealthCheck method")
 //			},
-//			DoGetS3UploadedFunc: func(ctx context.Context, cfg *config.Config) (dpaws.S3Clienter, error) {
+//			DoGetS3PublicFunc: func(ctx context.Context, cfg *config.Config) (storage.Driver, error) {
+//				panic("mock out the DoGetS3Public method")
+//			},
+//			DoGetS3UploadedFunc: func(ctx context.Context, cfg *config.Config) (storage.Driver, error) {
 //				panic("mock out the DoGetS3Uploaded method")
 //			},
-//			DoGetStaticFileS3UploaderFunc: func(ctx context.Context, cfg *config.Config) (dpaws.S3Clienter, error) {
+//			DoGetStaticFileS3UploaderFunc: func(ctx context.Context, cfg *config.Config) (storage.Driver, error) {
 //				panic("mock out the DoGetStaticFileS3Uploader method")
 //			},
 //		}
@@ -41,20 +48,33 @@ var _ service.Initialiser = &InitialiserMock{}
 //
 //	}
 type InitialiserMock struct {
+	// DoGetAuthorisationMiddlewareFunc mocks the DoGetAuthorisationMiddleware method.
+	DoGetAuthorisationMiddlewareFunc func(ctx context.Context, authorisationConfig *authorisation.Config) (authorisation.Middleware, error)
+
 	// DoGetHTTPServerFunc mocks the DoGetHTTPServer method.
 	DoGetHTTPServerFunc func(bindAddr string, router http.Handler) service.HTTPServer
 
 	// DoGetHealthCheckFunc mocks the DoGetHealthCheck method.
 	DoGetHealthCheckFunc func(cfg *config.Config, buildTime string, gitCommit string, version string) (service.HealthChecker, error)
 
+	// DoGetS3PublicFunc mocks the DoGetS3Public method.
+	DoGetS3PublicFunc func(ctx context.Context, cfg *config.Config) (storage.Driver, error)
+
 	// DoGetS3UploadedFunc mocks the DoGetS3Uploaded method.
-	DoGetS3UploadedFunc func(ctx context.Context, cfg *config.Config) (dpaws.S3Clienter, error)
+	DoGetS3UploadedFunc func(ctx context.Context, cfg *config.Config) (storage.Driver, error)
 
 	// DoGetStaticFileS3UploaderFunc mocks the DoGetStaticFileS3Uploader method.
-	DoGetStaticFileS3UploaderFunc func(ctx context.Context, cfg *config.Config) (dpaws.S3Clienter, error)
+	DoGetStaticFileS3UploaderFunc func(ctx context.Context, cfg *config.Config) (storage.Driver, error)
 
 	// calls tracks calls to the methods.
 	calls struct {
+		// DoGetAuthorisationMiddleware holds details about calls to the DoGetAuthorisationMiddleware method.
+		DoGetAuthorisationMiddleware []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// AuthorisationConfig is the authorisationConfig argument value.
+			AuthorisationConfig *authorisation.Config
+		}
 		// DoGetHTTPServer holds details about calls to the DoGetHTTPServer method.
 		DoGetHTTPServer []struct {
 			// BindAddr is the bindAddr argument value.
@@ -73,6 +93,13 @@ type InitialiserMock struct {
 			// Version is the version argument value.
 			Version string
 		}
+		// DoGetS3Public holds details about calls to the DoGetS3Public method.
+		DoGetS3Public []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Cfg is the cfg argument value.
+			Cfg *config.Config
+		}
 		// DoGetS3Uploaded holds details about calls to the DoGetS3Uploaded method.
 		DoGetS3Uploaded []struct {
 			// Ctx is the ctx argument value.
@@ -88,10 +115,48 @@ type InitialiserMock struct {
 			Cfg *config.Config
 		}
 	}
-	lockDoGetHTTPServer           sync.RWMutex
-	lockDoGetHealthCheck          sync.RWMutex
-	lockDoGetS3Uploaded           sync.RWMutex
-	lockDoGetStaticFileS3Uploader sync.RWMutex
+	lockDoGetAuthorisationMiddleware sync.RWMutex
+	lockDoGetHTTPServer              sync.RWMutex
+	lockDoGetHealthCheck             sync.RWMutex
+	lockDoGetS3Public                sync.RWMutex
+	lockDoGetS3Uploaded              sync.RWMutex
+	lockDoGetStaticFileS3Uploader    sync.RWMutex
+}
+
+// DoGetAuthorisationMiddleware calls DoGetAuthorisationMiddlewareFunc.
+func (mock *InitialiserMock) DoGetAuthorisationMiddleware(ctx context.Context, authorisationConfig *authorisation.Config) (authorisation.Middleware, error) {
+	if mock.DoGetAuthorisationMiddlewareFunc == nil {
+		panic("InitialiserMock.DoGetAuthorisationMiddlewareFunc: method is nil but Initialiser.DoGetAuthorisationMiddleware was just called")
+	}
+	callInfo := struct {
+		Ctx                 context.Context
+		AuthorisationConfig *authorisation.Config
+	}{
+		Ctx:                 ctx,
+		AuthorisationConfig: authorisationConfig,
+	}
+	mock.lockDoGetAuthorisationMiddleware.Lock()
+	mock.calls.DoGetAuthorisationMiddleware = append(mock.calls.DoGetAuthorisationMiddleware, callInfo)
+	mock.lockDoGetAuthorisationMiddleware.Unlock()
+	return mock.DoGetAuthorisationMiddlewareFunc(ctx, authorisationConfig)
+}
+
+// DoGetAuthorisationMiddlewareCalls gets all the calls that were made to DoGetAuthorisationMiddleware.
+// Check the length with:
+//
+//	len(mockedInitialiser.DoGetAuthorisationMiddlewareCalls())
+func (mock *InitialiserMock) DoGetAuthorisationMiddlewareCalls() []struct {
+	Ctx                 context.Context
+	AuthorisationConfig *authorisation.Config
+} {
+	var calls []struct {
+		Ctx                 context.Context
+		AuthorisationConfig *authorisation.Config
+	}
+	mock.lockDoGetAuthorisationMiddleware.RLock()
+	calls = mock.calls.DoGetAuthorisationMiddleware
+	mock.lockDoGetAuthorisationMiddleware.RUnlock()
+	return calls
 }
 
 // DoGetHTTPServer calls DoGetHTTPServerFunc.
@@ -174,8 +239,44 @@ func (mock *InitialiserMock) DoGetHealthCheckCalls() []struct {
 	return calls
 }
 
+// DoGetS3Public calls DoGetS3PublicFunc.
+func (mock *InitialiserMock) DoGetS3Public(ctx context.Context, cfg *config.Config) (storage.Driver, error) {
+	if mock.DoGetS3PublicFunc == nil {
+		panic("InitialiserMock.DoGetS3PublicFunc: method is nil but Initialiser.DoGetS3Public was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Cfg *config.Config
+	}{
+		Ctx: ctx,
+		Cfg: cfg,
+	}
+	mock.lockDoGetS3Public.Lock()
+	mock.calls.DoGetS3Public = append(mock.calls.DoGetS3Public, callInfo)
+	mock.lockDoGetS3Public.Unlock()
+	return mock.DoGetS3PublicFunc(ctx, cfg)
+}
+
+// DoGetS3PublicCalls gets all the calls that were made to DoGetS3Public.
+// Check the length with:
+//
+//	len(mockedInitialiser.DoGetS3PublicCalls())
+func (mock *InitialiserMock) DoGetS3PublicCalls() []struct {
+	Ctx context.Context
+	Cfg *config.Config
+} {
+	var calls []struct {
+		Ctx context.Context
+		Cfg *config.Config
+	}
+	mock.lockDoGetS3Public.RLock()
+	calls = mock.calls.DoGetS3Public
+	mock.lockDoGetS3Public.RUnlock()
+	return calls
+}
+
 // DoGetS3Uploaded calls DoGetS3UploadedFunc.
-func (mock *InitialiserMock) DoGetS3Uploaded(ctx context.Context, cfg *config.Config) (dpaws.S3Clienter, error) {
+func (mock *InitialiserMock) DoGetS3Uploaded(ctx context.Context, cfg *config.Config) (storage.Driver, error) {
 	if mock.DoGetS3UploadedFunc == nil {
 		panic("InitialiserMock.DoGetS3UploadedFunc: method is nil but Initialiser.DoGetS3Uploaded was just called")
 	}
@@ -211,7 +312,7 @@ func (mock *InitialiserMock) DoGetS3UploadedCalls() []struct {
 }
 
 // DoGetStaticFileS3Uploader calls DoGetStaticFileS3UploaderFunc.
-func (mock *InitialiserMock) DoGetStaticFileS3Uploader(ctx context.Context, cfg *config.Config) (dpaws.S3Clienter, error) {
+func (mock *InitialiserMock) DoGetStaticFileS3Uploader(ctx context.Context, cfg *config.Config) (storage.Driver, error) {
 	if mock.DoGetStaticFileS3UploaderFunc == nil {
 		panic("InitialiserMock.DoGetStaticFileS3UploaderFunc: method is nil but Initialiser.DoGetStaticFileS3Uploader was just called")
 	}
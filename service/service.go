@@ -4,12 +4,16 @@ import (
 	"context"
 	"net/http"
 
-	filesSDK "github.com/ONSdigital/dp-files-api/sdk"
+	"github.com/ONSdigital/dp-authorisation/v2/authorisation"
 	"github.com/ONSdigital/dp-upload-service/api"
-	"github.com/ONSdigital/dp-upload-service/aws"
 	"github.com/ONSdigital/dp-upload-service/config"
 	"github.com/ONSdigital/dp-upload-service/files"
+	"github.com/ONSdigital/dp-upload-service/mediatype"
+	"github.com/ONSdigital/dp-upload-service/reaper"
+	"github.com/ONSdigital/dp-upload-service/scan"
+	"github.com/ONSdigital/dp-upload-service/storage"
 	"github.com/ONSdigital/dp-upload-service/upload"
+	"github.com/ONSdigital/dp-upload-service/worker"
 	"github.com/ONSdigital/log.go/v2/log"
 
 	"github.com/gorilla/mux"
@@ -30,12 +34,18 @@ import (
 
 // Service contains all the configs, server and clients to run the dp-upload-service API
 type Service struct {
-	config      *config.Config
-	server      HTTPServer
-	router      *mux.Router
-	serviceList *ExternalServiceList
-	healthCheck HealthChecker
-	uploader    *upload.Uploader
+	config         *config.Config
+	server         HTTPServer
+	router         *mux.Router
+	serviceList    *ExternalServiceList
+	healthCheck    HealthChecker
+	authMiddleware authorisation.Middleware
+	uploader       *upload.Uploader
+	reaper         *reaper.Reaper
+	publicReaper   *reaper.Reaper
+	verifier       *worker.Pool
+	publicCopier   *worker.Pool
+	uploadEvents   *files.Hub
 }
 
 // Run the service
@@ -60,17 +70,35 @@ func Run(ctx context.Context, serviceList *ExternalServiceList, buildTime, gitCo
 		log.Fatal(ctx, "failed to initialise S3 client for uploaded bucket", err)
 		return nil, err
 	}
-	uploadBucket := aws.NewBucket(cfg.AwsRegion, cfg.UploadBucketName, s3Uploaded)
+	uploadBucket := storage.NewBucket(cfg.UploadBucketName, s3Uploaded)
 
 	s3StaticFileUploader, err := serviceList.GetS3StaticFileUploader(ctx, cfg)
 	if err != nil {
 		log.Fatal(ctx, "failed to initialise Static File S3 client for uploaded bucket", err)
 		return nil, err
 	}
-	staticBucket := aws.NewBucket(cfg.AwsRegion, cfg.StaticFilesEncryptedBucketName, s3StaticFileUploader)
+	staticBucket := storage.NewBucket(cfg.StaticFilesEncryptedBucketName, s3StaticFileUploader)
+
+	// Published files are only copied to a public bucket if one has been configured
+	var s3Public storage.Driver
+	var publicBucket *storage.Bucket
+	if cfg.PublicBucketName != "" {
+		s3Public, err = serviceList.GetS3Public(ctx, cfg)
+		if err != nil {
+			log.Fatal(ctx, "failed to initialise S3 client for public bucket", err)
+			return nil, err
+		}
+		publicBucket = storage.NewBucket(cfg.PublicBucketName, s3Public)
+	}
+
+	// Files are only scanned for malware if a clamd address has been configured
+	var scanner scan.Scanner
+	if cfg.ClamAVAddr != "" {
+		scanner = scan.NewClamAV(cfg.ClamAVAddr, cfg.ClamAVTimeout)
+	}
 
 	// Create Uploader with S3 client
-	uploader := upload.New(uploadBucket)
+	uploader := upload.New(uploadBucket, cfg.PresignedURLExpiry, mediatype.NewPolicy(cfg.DatasetUploadAllowedTypes), cfg.DatasetMaxUploadFileSize)
 
 	hc, err := serviceList.GetHealthCheck(cfg, buildTime, gitCommit, version)
 	if err != nil {
@@ -78,21 +106,86 @@ func Run(ctx context.Context, serviceList *ExternalServiceList, buildTime, gitCo
 		return nil, err
 	}
 
-	if err := registerCheckers(ctx, hc, s3StaticFileUploader); err != nil {
+	authMiddleware, err := serviceList.GetAuthorisationMiddleware(ctx, &cfg.AuthConfig)
+	if err != nil {
+		log.Fatal(ctx, "could not instantiate authorisation middleware", err)
+		return nil, err
+	}
+
+	if err := registerCheckers(ctx, cfg, hc, s3StaticFileUploader, s3Public, scanner, authMiddleware); err != nil {
 		log.Fatal(ctx, "unable to register checkers", err)
 		return nil, err
 	}
 
+	// Every route other than the health check requires a permission, which is checked against the collection the
+	// request is for when one is given
+	require := func(permission string, handlerFunc http.HandlerFunc) http.HandlerFunc {
+		return authMiddleware.RequireWithAttributes(permission, handlerFunc, api.CollectionIDAttributes)
+	}
+
 	r.StrictSlash(true).Path("/health").Methods(http.MethodGet).HandlerFunc(hc.Handler)
-	r.Path("/upload").Methods(http.MethodGet).HandlerFunc(uploader.CheckUploaded)
-	r.Path("/upload").Methods(http.MethodPost).HandlerFunc(uploader.Upload)
-	r.Path("/upload/{id}").Methods(http.MethodGet).HandlerFunc(uploader.GetS3URL)
+	r.Path("/upload").Methods(http.MethodGet).HandlerFunc(require("static-files:create", uploader.CheckUploaded))
+	r.Path("/upload").Methods(http.MethodPost).HandlerFunc(require("static-files:create", uploader.Upload))
+	r.Path("/upload/{id}").Methods(http.MethodGet).HandlerFunc(require("static-files:read", uploader.GetS3URL))
+	r.Path("/upload/{id}/presigned").Methods(http.MethodGet).HandlerFunc(authMiddleware.RequireWithAttributes("static-files:read", uploader.GetPresignedURL, uploader.ObjectAttributes))
 
 	// v1 DO NOT USE IN PRODUCTION YET!
-	filesAPIClient := filesSDK.New(cfg.FilesAPIURL)
-	store := files.NewStore(filesAPIClient, staticBucket, cfg)
-	r.Path("/upload-new").Methods(http.MethodPost).HandlerFunc(api.CreateV1UploadHandler(store.UploadFile))
-	r.Path("/upload-new/files/{path:.*?}/status").Methods(http.MethodGet).HandlerFunc(api.StatusHandler(store))
+	filesAPIClient := files.NewClient(cfg.FilesAPIURL)
+	uploadEvents := files.NewHub()
+	store := files.NewStore(filesAPIClient, staticBucket, cfg).WithEvents(uploadEvents)
+	// Published files are copied to the public bucket in the background, and any copy left behind by an instance of
+	// the service is retried
+	var publicCopier *worker.Pool
+	if publicBucket != nil {
+		store = store.WithPublicBucket(publicBucket)
+		publicCopier = worker.New("public-copy", cfg.PublicCopyWorkers, cfg.PublicCopyRetryInterval, store.CopyToPublicBucket, store.PendingPublicCopies)
+		store = store.WithPublicCopyQueue(publicCopier)
+	}
+	if scanner != nil {
+		store = store.WithScanner(scanner)
+	}
+	// Completed files are verified and marked as uploaded in the background, as reading them back can take longer than
+	// the request that completed them is allowed
+	verifier := worker.New("verification", cfg.VerificationWorkers, cfg.VerificationRetryInterval, store.VerifyFile, store.PendingVerifications)
+	store = store.WithVerificationQueue(verifier)
+	// requests for a file that has already been uploaded are authorised for the collection or bundle it is registered
+	// in, rather than the one the caller gives
+	requireFile := func(permission string, handlerFunc http.HandlerFunc) http.HandlerFunc {
+		return api.ResolveFileOwner(store.FileOwner, authMiddleware.RequireWithAttributes(permission, handlerFunc, api.FileAttributes))
+	}
+	inFlightLimiter := api.NewInFlightLimiter(cfg.MaxInFlightUploadBytes)
+	r.Path("/upload-new").Methods(http.MethodGet, http.MethodHead).HandlerFunc(require("static-files:create", api.CreateV1CheckChunkHandler(store.ChunkUploaded)))
+	r.Path("/upload-new").Methods(http.MethodPost).HandlerFunc(require("static-files:create", inFlightLimiter.Limit(api.CreateV1UploadHandler(store.UploadFile))))
+	r.Path("/upload-new/sessions").Methods(http.MethodPost).HandlerFunc(require("static-files:create", api.CreateUploadSessionHandler(store.CreateUploadSession)))
+	r.Path("/upload-new/sessions/{id}/complete").Methods(http.MethodPost).HandlerFunc(require("static-files:create", api.CompleteUploadSessionHandler(store.CompleteUploadSession)))
+	r.Path("/upload-new/files/{path:.*?}/status").Methods(http.MethodGet).HandlerFunc(requireFile("static-files:read", api.StatusHandler(store)))
+	r.Path("/upload-new/files/{path:.*?}/events").Methods(http.MethodGet).HandlerFunc(requireFile("static-files:read", api.UploadEventsHandler(uploadEvents.Subscribe, cfg.EventsHeartbeatInterval)))
+	r.Path("/upload-new/files/{path:.*?}/publish").Methods(http.MethodPost).HandlerFunc(requireFile("static-files:update", api.PublishHandler(store.PublishFile)))
+	r.Path("/upload-new/files/{path:.*?}/parts").Methods(http.MethodGet).HandlerFunc(requireFile("static-files:read", api.UploadedPartsHandler(store.UploadedParts)))
+	r.Path("/upload-new/collections/{id}/status").Methods(http.MethodGet).HandlerFunc(authMiddleware.RequireWithAttributes("static-files:read", api.BatchStatusHandler(store.CollectionStatus), api.CollectionPathAttributes))
+	r.Path("/upload-new/bundles/{id}/status").Methods(http.MethodGet).HandlerFunc(authMiddleware.RequireWithAttributes("static-files:read", api.BatchStatusHandler(store.BundleStatus), api.BundlePathAttributes))
+	r.Path("/upload-new/files/{path:.*}").Methods(http.MethodDelete).HandlerFunc(requireFile("static-files:update", api.AbortUploadHandler(store.AbortUpload)))
+
+	// Abort multipart uploads to the static files bucket, and multipart copies to the public bucket, that were never
+	// completed
+	uploadReaper := reaper.New(s3StaticFileUploader, cfg.MultipartUploadReaperInterval, cfg.MultipartUploadMaxAge).WithCleanup(store.RemoveUploadRecords)
+	if cfg.MultipartUploadReaperInterval > 0 {
+		uploadReaper.Start(ctx)
+	}
+	var publicReaper *reaper.Reaper
+	if s3Public != nil {
+		publicReaper = reaper.New(s3Public, cfg.MultipartUploadReaperInterval, cfg.MultipartUploadMaxAge)
+		if cfg.MultipartUploadReaperInterval > 0 {
+			publicReaper.Start(ctx)
+		}
+	}
+
+	// Work left behind by any instance of the service is retried with the service's own token
+	serviceCtx := context.WithValue(ctx, config.AuthContextKey, "Bearer "+cfg.ServiceAuthToken)
+	verifier.Start(serviceCtx)
+	if publicCopier != nil {
+		publicCopier.Start(serviceCtx)
+	}
 
 	hc.Start(ctx)
 
@@ -104,12 +197,18 @@ func Run(ctx context.Context, serviceList *ExternalServiceList, buildTime, gitCo
 	}()
 
 	return &Service{
-		config:      cfg,
-		router:      r,
-		healthCheck: hc,
-		serviceList: serviceList,
-		server:      s,
-		uploader:    uploader,
+		config:         cfg,
+		router:         r,
+		healthCheck:    hc,
+		serviceList:    serviceList,
+		server:         s,
+		authMiddleware: authMiddleware,
+		uploader:       uploader,
+		reaper:         uploadReaper,
+		publicReaper:   publicReaper,
+		verifier:       verifier,
+		publicCopier:   publicCopier,
+		uploadEvents:   uploadEvents,
 	}, nil
 }
 
@@ -130,12 +229,30 @@ func (svc *Service) Close(ctx context.Context) error {
 			svc.healthCheck.Stop()
 		}
 
+		// end the upload event streams, which would otherwise keep the server from shutting down
+		svc.uploadEvents.Close()
+
 		// stop any incoming requests before closing any outbound connections
 		if err := svc.server.Shutdown(ctx); err != nil {
 			log.Error(ctx, "failed to shutdown http server", err)
 			hasShutdownError = true
 		}
 
+		// stop the reapers and the background work before their S3 clients go away
+		svc.reaper.Stop()
+		svc.verifier.Stop()
+		if svc.publicCopier != nil {
+			svc.publicCopier.Stop()
+		}
+		if svc.publicReaper != nil {
+			svc.publicReaper.Stop()
+		}
+
+		if err := svc.authMiddleware.Close(ctx); err != nil {
+			log.Error(ctx, "failed to close authorisation middleware", err)
+			hasShutdownError = true
+		}
+
 	}()
 
 	// wait for shutdown success (via cancel) or failure (timeout)
@@ -157,8 +274,12 @@ func (svc *Service) Close(ctx context.Context) error {
 }
 
 func registerCheckers(ctx context.Context,
+	cfg *config.Config,
 	hc HealthChecker,
-	s3Uploaded aws.S3Clienter) (err error) {
+	s3Uploaded storage.Driver,
+	s3Public storage.Driver,
+	scanner scan.Scanner,
+	authMiddleware authorisation.Middleware) (err error) {
 
 	hasErrors := false
 
@@ -167,6 +288,32 @@ func registerCheckers(ctx context.Context,
 		log.Error(ctx, "error adding check for s3Private uploaded bucket", err)
 	}
 
+	if s3Public != nil {
+		if err := hc.AddCheck("S3 public bucket", s3Public.Checker); err != nil {
+			hasErrors = true
+			log.Error(ctx, "error adding check for s3 public bucket", err)
+		}
+	}
+
+	if scanner != nil {
+		if err := hc.AddCheck("ClamAV", scanner.Checker); err != nil {
+			hasErrors = true
+			log.Error(ctx, "error adding check for clamav", err)
+		}
+	}
+
+	if cfg.AuthConfig.Enabled {
+		if err := hc.AddCheck("permissions cache health check", authMiddleware.HealthCheck); err != nil {
+			hasErrors = true
+			log.Error(ctx, "error adding check for permissions cache", err)
+		}
+
+		if err := hc.AddCheck("jwt keys state health check", authMiddleware.IdentityHealthCheck); err != nil {
+			hasErrors = true
+			log.Error(ctx, "error adding check for jwt keys", err)
+		}
+	}
+
 	if hasErrors {
 		return errors.New("Error(s) registering checkers for healthcheck")
 	}
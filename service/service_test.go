@@ -3,15 +3,18 @@ package service_test
 import (
 	"context"
 	"net/http"
+	"os"
 	"sync"
 	"testing"
 
+	"github.com/ONSdigital/dp-authorisation/v2/authorisation"
+	authorisationMock "github.com/ONSdigital/dp-authorisation/v2/authorisation/mock"
 	"github.com/ONSdigital/dp-healthcheck/healthcheck"
-	"github.com/ONSdigital/dp-upload-service/aws"
-	mock_aws "github.com/ONSdigital/dp-upload-service/aws/mock"
 	"github.com/ONSdigital/dp-upload-service/config"
 	"github.com/ONSdigital/dp-upload-service/service"
 	mock_service "github.com/ONSdigital/dp-upload-service/service/mock"
+	"github.com/ONSdigital/dp-upload-service/storage"
+	mock_storage "github.com/ONSdigital/dp-upload-service/storage/mock"
 
 	"github.com/pkg/errors"
 
@@ -26,11 +29,12 @@ var (
 )
 
 var (
-	errS3Uploaded  = errors.New("S3 uploaded error")
-	errHealthcheck = errors.New("healthCheck error")
+	errS3Uploaded     = errors.New("S3 uploaded error")
+	errHealthcheck    = errors.New("healthCheck error")
+	errAuthMiddleware = errors.New("authorisation middleware error")
 )
 
-var funcDoS3UploadedErr = func(ctx context.Context, cfg *config.Config) (aws.S3Clienter, error) {
+var funcDoS3UploadedErr = func(ctx context.Context, cfg *config.Config) (storage.Driver, error) {
 	return nil, errS3Uploaded
 }
 
@@ -42,10 +46,23 @@ var funcDoGetHTTPServerNil = func(bindAddr string, router http.Handler) service.
 	return nil
 }
 
+var funcDoGetAuthorisationMiddlewareErr = func(ctx context.Context, authorisationConfig *authorisation.Config) (authorisation.Middleware, error) {
+	return nil, errAuthMiddleware
+}
+
+func newAuthMiddlewareMock() *authorisationMock.MiddlewareMock {
+	return &authorisationMock.MiddlewareMock{
+		RequireWithAttributesFunc: func(permission string, handlerFunc http.HandlerFunc, getAttributes authorisation.GetAttributesFromRequest) http.HandlerFunc {
+			return handlerFunc
+		},
+		CloseFunc: func(ctx context.Context) error { return nil },
+	}
+}
+
 func TestRun(t *testing.T) {
 
 	Convey("Given a set of mocked dependencies", t, func() {
-		s3UploadedMock := &mock_aws.S3ClienterMock{
+		s3UploadedMock := &mock_storage.DriverMock{
 			CheckerFunc: func(ctx context.Context, state *healthcheck.CheckState) error { return nil },
 		}
 
@@ -62,7 +79,7 @@ func TestRun(t *testing.T) {
 			},
 		}
 
-		funcDoGetS3UploadedOk := func(ctx context.Context, cfg *config.Config) (aws.S3Clienter, error) {
+		funcDoGetS3UploadedOk := func(ctx context.Context, cfg *config.Config) (storage.Driver, error) {
 			return s3UploadedMock, nil
 		}
 
@@ -74,6 +91,11 @@ func TestRun(t *testing.T) {
 			return serverMock
 		}
 
+		authMiddlewareMock := newAuthMiddlewareMock()
+		funcDoGetAuthorisationMiddlewareOk := func(ctx context.Context, authorisationConfig *authorisation.Config) (authorisation.Middleware, error) {
+			return authMiddlewareMock, nil
+		}
+
 		Convey("When initialising s3 uploaded bucket that returns an error", func() {
 			initMock := &mock_service.InitialiserMock{
 				DoGetHTTPServerFunc:           funcDoGetHTTPServerNil,
@@ -113,10 +135,11 @@ func TestRun(t *testing.T) {
 		Convey("When all dependencies are successfully initialised", func() {
 
 			initMock := &mock_service.InitialiserMock{
-				DoGetHTTPServerFunc:           funcDoGetHTTPServer,
-				DoGetHealthCheckFunc:          funcDoGetHealthcheckOk,
-				DoGetS3UploadedFunc:           funcDoGetS3UploadedOk,
-				DoGetStaticFileS3UploaderFunc: funcDoGetS3UploadedOk,
+				DoGetHTTPServerFunc:              funcDoGetHTTPServer,
+				DoGetHealthCheckFunc:             funcDoGetHealthcheckOk,
+				DoGetS3UploadedFunc:              funcDoGetS3UploadedOk,
+				DoGetStaticFileS3UploaderFunc:    funcDoGetS3UploadedOk,
+				DoGetAuthorisationMiddlewareFunc: funcDoGetAuthorisationMiddlewareOk,
 			}
 
 			svcErrors := make(chan error, 1)
@@ -140,6 +163,88 @@ func TestRun(t *testing.T) {
 				serverWg.Wait() // Wait for HTTP server go-routine to finish
 				So(len(serverMock.ListenAndServeCalls()), ShouldEqual, 1)
 			})
+
+			Convey("Every route other than the health check requires a static files permission", func() {
+				permissions := map[string]int{}
+				for _, call := range authMiddlewareMock.RequireWithAttributesCalls() {
+					So(call.GetAttributes, ShouldNotBeNil)
+					permissions[call.Permission]++
+				}
+				So(permissions, ShouldResemble, map[string]int{
+					"static-files:create": 6,
+					"static-files:read":   7,
+					"static-files:update": 2,
+				})
+			})
+		})
+
+		Convey("When initialising the authorisation middleware returns an error", func() {
+			initMock := &mock_service.InitialiserMock{
+				DoGetHTTPServerFunc:              funcDoGetHTTPServerNil,
+				DoGetHealthCheckFunc:             funcDoGetHealthcheckOk,
+				DoGetS3UploadedFunc:              funcDoGetS3UploadedOk,
+				DoGetStaticFileS3UploaderFunc:    funcDoGetS3UploadedOk,
+				DoGetAuthorisationMiddlewareFunc: funcDoGetAuthorisationMiddlewareErr,
+			}
+			svcErrors := make(chan error, 1)
+			svcList := service.NewServiceList(initMock)
+			_, err := service.Run(ctx, svcList, testBuildTime, testGitCommit, testVersion, svcErrors)
+
+			Convey("Then service Run fails with the same error", func() {
+				So(err, ShouldResemble, errAuthMiddleware)
+			})
+		})
+
+		Convey("When authorisation is enabled", func() {
+			os.Setenv("AUTHORISATION_ENABLED", "true")
+			defer os.Unsetenv("AUTHORISATION_ENABLED")
+
+			initMock := &mock_service.InitialiserMock{
+				DoGetHTTPServerFunc:              funcDoGetHTTPServer,
+				DoGetHealthCheckFunc:             funcDoGetHealthcheckOk,
+				DoGetS3UploadedFunc:              funcDoGetS3UploadedOk,
+				DoGetStaticFileS3UploaderFunc:    funcDoGetS3UploadedOk,
+				DoGetAuthorisationMiddlewareFunc: funcDoGetAuthorisationMiddlewareOk,
+			}
+			svcErrors := make(chan error, 1)
+			svcList := service.NewServiceList(initMock)
+			serverWg.Add(1)
+
+			_, err := service.Run(ctx, svcList, testBuildTime, testGitCommit, testVersion, svcErrors)
+			serverWg.Wait()
+
+			Convey("Then the middleware is created with authorisation enabled and its checkers are registered", func() {
+				So(err, ShouldBeNil)
+				So(initMock.DoGetAuthorisationMiddlewareCalls()[0].AuthorisationConfig.Enabled, ShouldBeTrue)
+				So(len(hcMock.AddCheckCalls()), ShouldEqual, 3)
+				So(hcMock.AddCheckCalls()[1].Name, ShouldResemble, "permissions cache health check")
+				So(hcMock.AddCheckCalls()[2].Name, ShouldResemble, "jwt keys state health check")
+			})
+		})
+
+		Convey("When a ClamAV address is configured", func() {
+			os.Setenv("CLAMAV_ADDR", "localhost:3310")
+			defer os.Unsetenv("CLAMAV_ADDR")
+
+			initMock := &mock_service.InitialiserMock{
+				DoGetHTTPServerFunc:              funcDoGetHTTPServer,
+				DoGetHealthCheckFunc:             funcDoGetHealthcheckOk,
+				DoGetS3UploadedFunc:              funcDoGetS3UploadedOk,
+				DoGetStaticFileS3UploaderFunc:    funcDoGetS3UploadedOk,
+				DoGetAuthorisationMiddlewareFunc: funcDoGetAuthorisationMiddlewareOk,
+			}
+			svcErrors := make(chan error, 1)
+			svcList := service.NewServiceList(initMock)
+			serverWg.Add(1)
+
+			_, err := service.Run(ctx, svcList, testBuildTime, testGitCommit, testVersion, svcErrors)
+			serverWg.Wait()
+
+			Convey("Then the ClamAV checker is registered", func() {
+				So(err, ShouldBeNil)
+				So(len(hcMock.AddCheckCalls()), ShouldEqual, 2)
+				So(hcMock.AddCheckCalls()[1].Name, ShouldResemble, "ClamAV")
+			})
 		})
 
 		Convey("When the Checkers cannot be registered", func() {
@@ -155,8 +260,9 @@ func TestRun(t *testing.T) {
 				DoGetHealthCheckFunc: func(cfg *config.Config, buildTime string, gitCommit string, version string) (service.HealthChecker, error) {
 					return hcMockAddFail, nil
 				},
-				DoGetS3UploadedFunc:           funcDoGetS3UploadedOk,
-				DoGetStaticFileS3UploaderFunc: funcDoGetS3UploadedOk,
+				DoGetS3UploadedFunc:              funcDoGetS3UploadedOk,
+				DoGetStaticFileS3UploaderFunc:    funcDoGetS3UploadedOk,
+				DoGetAuthorisationMiddlewareFunc: funcDoGetAuthorisationMiddlewareOk,
 			}
 			svcErrors := make(chan error, 1)
 			svcList := service.NewServiceList(initMock)
@@ -180,7 +286,7 @@ func TestClose(t *testing.T) {
 
 		hcStopped := false
 
-		s3UploadedMock := &mock_aws.S3ClienterMock{
+		s3UploadedMock := &mock_storage.DriverMock{
 			CheckerFunc: func(ctx context.Context, state *healthcheck.CheckState) error { return nil },
 		}
 
@@ -191,6 +297,8 @@ func TestClose(t *testing.T) {
 			StopFunc:     func() { hcStopped = true },
 		}
 
+		authMiddlewareMock := newAuthMiddlewareMock()
+
 		// server Shutdown will fail if healthcheck is not stopped
 		serverMock := &mock_service.HTTPServerMock{
 			ListenAndServeFunc: func() error { return nil },
@@ -206,11 +314,14 @@ func TestClose(t *testing.T) {
 
 			initMock := &mock_service.InitialiserMock{
 				DoGetHTTPServerFunc:           func(bindAddr string, router http.Handler) service.HTTPServer { return serverMock },
-				DoGetS3UploadedFunc:           func(ctx context.Context, cfg *config.Config) (aws.S3Clienter, error) { return s3UploadedMock, nil },
-				DoGetStaticFileS3UploaderFunc: func(ctx context.Context, cfg *config.Config) (aws.S3Clienter, error) { return s3UploadedMock, nil },
+				DoGetS3UploadedFunc:           func(ctx context.Context, cfg *config.Config) (storage.Driver, error) { return s3UploadedMock, nil },
+				DoGetStaticFileS3UploaderFunc: func(ctx context.Context, cfg *config.Config) (storage.Driver, error) { return s3UploadedMock, nil },
 				DoGetHealthCheckFunc: func(cfg *config.Config, buildTime string, gitCommit string, version string) (service.HealthChecker, error) {
 					return hcMock, nil
 				},
+				DoGetAuthorisationMiddlewareFunc: func(ctx context.Context, authorisationConfig *authorisation.Config) (authorisation.Middleware, error) {
+					return authMiddlewareMock, nil
+				},
 			}
 
 			svcErrors := make(chan error, 1)
@@ -222,6 +333,7 @@ func TestClose(t *testing.T) {
 				So(err, ShouldBeNil)
 				So(len(hcMock.StopCalls()), ShouldEqual, 1)
 				So(len(serverMock.ShutdownCalls()), ShouldEqual, 1)
+				So(len(authMiddlewareMock.CloseCalls()), ShouldEqual, 1)
 			})
 		})
 
@@ -236,11 +348,14 @@ func TestClose(t *testing.T) {
 
 			initMock := &mock_service.InitialiserMock{
 				DoGetHTTPServerFunc:           func(bindAddr string, router http.Handler) service.HTTPServer { return failingserverMock },
-				DoGetS3UploadedFunc:           func(ctx context.Context, cfg *config.Config) (aws.S3Clienter, error) { return s3UploadedMock, nil },
-				DoGetStaticFileS3UploaderFunc: func(ctx context.Context, cfg *config.Config) (aws.S3Clienter, error) { return s3UploadedMock, nil },
+				DoGetS3UploadedFunc:           func(ctx context.Context, cfg *config.Config) (storage.Driver, error) { return s3UploadedMock, nil },
+				DoGetStaticFileS3UploaderFunc: func(ctx context.Context, cfg *config.Config) (storage.Driver, error) { return s3UploadedMock, nil },
 				DoGetHealthCheckFunc: func(cfg *config.Config, buildTime string, gitCommit string, version string) (service.HealthChecker, error) {
 					return hcMock, nil
 				},
+				DoGetAuthorisationMiddlewareFunc: func(ctx context.Context, authorisationConfig *authorisation.Config) (authorisation.Middleware, error) {
+					return authMiddlewareMock, nil
+				},
 			}
 
 			svcErrors := make(chan error, 1)
@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/ONSdigital/dp-upload-service/config"
+)
+
+var ErrUnknownDriver = errors.New("unknown storage driver")
+
+// Factory creates the Driver for the named bucket from the service configuration
+type Factory func(ctx context.Context, cfg *config.Config, bucketName string) (Driver, error)
+
+// Registry holds the factories of the available storage drivers by name, so that the driver used can be chosen in
+// config with STORAGE_BACKEND
+type Registry struct {
+	mu        sync.RWMutex
+	factories map[string]Factory
+}
+
+// NewRegistry creates a Registry with no drivers
+func NewRegistry() *Registry {
+	return &Registry{factories: make(map[string]Factory)}
+}
+
+// Register makes the driver created by the factory available with the name, replacing any driver already registered
+// with the name
+func (r *Registry) Register(name string, factory Factory) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.factories[name] = factory
+}
+
+// Names returns the names of the registered drivers in alphabetical order
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.factories))
+	for name := range r.factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New creates the Driver for the named bucket using the driver configured with STORAGE_BACKEND
+func (r *Registry) New(ctx context.Context, cfg *config.Config, bucketName string) (Driver, error) {
+	r.mu.RLock()
+	factory, ok := r.factories[cfg.StorageBackend]
+	r.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w %q, expected one of %v", ErrUnknownDriver, cfg.StorageBackend, r.Names())
+	}
+	return factory(ctx, cfg, bucketName)
+}
@@ -0,0 +1,64 @@
+package storage_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ONSdigital/dp-upload-service/config"
+	"github.com/ONSdigital/dp-upload-service/storage"
+	mock_storage "github.com/ONSdigital/dp-upload-service/storage/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type RegistrySuite struct {
+	suite.Suite
+	registry *storage.Registry
+	driver   *mock_storage.DriverMock
+	buckets  []string
+}
+
+func TestRegistry(t *testing.T) {
+	suite.Run(t, new(RegistrySuite))
+}
+
+func (s *RegistrySuite) SetupTest() {
+	s.driver = &mock_storage.DriverMock{}
+	s.buckets = nil
+	s.registry = storage.NewRegistry()
+	s.registry.Register("memory", func(ctx context.Context, cfg *config.Config, bucketName string) (storage.Driver, error) {
+		s.buckets = append(s.buckets, bucketName)
+		return s.driver, nil
+	})
+}
+
+func (s *RegistrySuite) TestNewUsesConfiguredDriver() {
+	driver, err := s.registry.New(context.Background(), &config.Config{StorageBackend: "memory"}, "bucket")
+
+	s.NoError(err)
+	s.Same(s.driver, driver)
+	s.Equal([]string{"bucket"}, s.buckets)
+}
+
+func (s *RegistrySuite) TestNewWithUnknownDriver() {
+	s.registry.Register("disk", nil)
+
+	driver, err := s.registry.New(context.Background(), &config.Config{StorageBackend: "tape"}, "bucket")
+
+	s.Nil(driver)
+	s.ErrorIs(err, storage.ErrUnknownDriver)
+	s.EqualError(err, `unknown storage driver "tape", expected one of [disk memory]`)
+	s.Empty(s.buckets)
+}
+
+func (s *RegistrySuite) TestRegisterReplacesDriverWithSameName() {
+	replacement := &mock_storage.DriverMock{}
+	s.registry.Register("memory", func(ctx context.Context, cfg *config.Config, bucketName string) (storage.Driver, error) {
+		return replacement, nil
+	})
+
+	driver, err := s.registry.New(context.Background(), &config.Config{StorageBackend: "memory"}, "bucket")
+
+	s.NoError(err)
+	s.Same(replacement, driver)
+	s.Equal([]string{"memory"}, s.registry.Names())
+}
@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"path"
+	"strings"
+)
+
+// The prefixes of the records that the service keeps in a bucket alongside the objects it uploads. Nothing can be
+// uploaded under them, so that a record can never be replaced, or read back, by uploading a file.
+const (
+	MultipartUploadPrefix = ".multipart-uploads/"
+	VerificationPrefix    = ".verifications/"
+	RunningChecksumPrefix = ".running-checksums/"
+	PublicCopyPrefix      = ".public-copies/"
+	OwnerPrefix           = ".owners/"
+	UploadOwnerPrefix     = ".upload-owners/"
+)
+
+var reservedPrefixes = []string{
+	MultipartUploadPrefix,
+	VerificationPrefix,
+	RunningChecksumPrefix,
+	PublicCopyPrefix,
+	OwnerPrefix,
+	UploadOwnerPrefix,
+}
+
+// IsReserved reports whether the key is under one of the prefixes reserved for the records of the service, or under
+// any of the other prefixes given, such as the prefix that infected files are quarantined under. The key is cleaned
+// first, so that a key such as "data/../.verifications/file.csv" cannot be used to get around the check.
+func IsReserved(key string, prefixes ...string) bool {
+	cleaned := strings.TrimPrefix(path.Clean("/"+key), "/")
+	if strings.HasSuffix(key, "/") {
+		cleaned += "/"
+	}
+
+	for _, set := range [][]string{reservedPrefixes, prefixes} {
+		for _, prefix := range set {
+			if prefix != "" && strings.HasPrefix(cleaned, prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,43 @@
+package storage_test
+
+import (
+	"testing"
+
+	"github.com/ONSdigital/dp-upload-service/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsReserved(t *testing.T) {
+	for _, key := range []string{
+		".multipart-uploads/data/file.csv",
+		".verifications/data/file.csv",
+		".running-checksums/data/file.csv",
+		".public-copies/data/file.csv",
+		".owners/data/file.csv",
+		".upload-owners/data/file.csv",
+		".verifications/",
+		"/.verifications/data/file.csv",
+		"./.verifications/data/file.csv",
+		"data/../.verifications/file.csv",
+	} {
+		assert.True(t, storage.IsReserved(key), key)
+	}
+
+	for _, key := range []string{
+		"data/file.csv",
+		"data/.verifications/file.csv",
+		".verifications",
+		"verifications/file.csv",
+		"quarantine/data/file.csv",
+	} {
+		assert.False(t, storage.IsReserved(key), key)
+	}
+}
+
+func TestIsReservedWithOtherPrefixes(t *testing.T) {
+	assert.True(t, storage.IsReserved("quarantine/data/file.csv", "quarantine/"))
+	assert.True(t, storage.IsReserved("quarantine/", "quarantine/"))
+	assert.True(t, storage.IsReserved("data/../quarantine/file.csv", "quarantine/"))
+	assert.False(t, storage.IsReserved("quarantined/file.csv", "quarantine/"))
+	assert.False(t, storage.IsReserved("data/file.csv", ""))
+}
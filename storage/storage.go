@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/ONSdigital/dp-healthcheck/healthcheck"
+)
+
+//go:generate moq -out mock/driver.go -pkg mock_storage . Driver
+
+// MinPartSize is the smallest size allowed for every part of a multipart upload except the last
+const MinPartSize = 5 * 1024 * 1024
+
+// Errors returned by every Driver, wrapping any error from the underlying storage, so that callers can handle them
+// with errors.Is whichever driver is in use
+var (
+	ErrNotFound            = errors.New("object not found")
+	ErrNotUploaded         = errors.New("no multipart upload in progress")
+	ErrUploadInProgress    = errors.New("multipart upload already in progress")
+	ErrAlreadyUploaded     = errors.New("object has already been uploaded")
+	ErrPartNotFound        = errors.New("part has not been uploaded")
+	ErrPartTooSmall        = errors.New("part is smaller than the minimum part size")
+	ErrPresignNotSupported = errors.New("storage driver does not support presigned URLs")
+)
+
+// PartRequest describes a part of a multipart upload
+type PartRequest struct {
+	Key         string
+	ContentType string
+	PartNumber  int32
+	TotalParts  int
+	FileName    string
+	// Size is the number of bytes in the part, or 0 if it is not known until the part has been read
+	Size int64
+}
+
+// PartResponse describes a part that has been uploaded, and whether it completed the multipart upload
+type PartResponse struct {
+	ETag             string
+	AllPartsUploaded bool
+}
+
+// ObjectInfo describes a stored object. The ETag is not quoted. The Checksum is the base64 encoded SHA256 checksum kept
+// by S3 for the object, or empty if it was stored without one; the checksum of an object uploaded in parts is the
+// checksum of the checksums of its parts, followed by "-" and the number of parts.
+type ObjectInfo struct {
+	SizeInBytes  int64
+	ETag         string
+	Checksum     string
+	ContentType  string
+	LastModified time.Time
+}
+
+// ObjectSummary describes an object listed in a bucket
+type ObjectSummary struct {
+	Key          string
+	LastModified time.Time
+}
+
+// MultipartUpload describes a multipart upload that has been started but not completed or aborted
+type MultipartUpload struct {
+	Key       string
+	UploadID  string
+	Initiated time.Time
+}
+
+// UploadedPart describes a part that has been uploaded to an in progress multipart upload
+type UploadedPart struct {
+	PartNumber   int32
+	SizeInBytes  int64
+	ETag         string
+	LastModified time.Time
+}
+
+// MultipartUploadParts describes the parts uploaded so far to an in progress multipart upload
+type MultipartUploadParts struct {
+	UploadID string
+	Parts    []UploadedPart
+}
+
+// Driver stores the objects of a single bucket. Objects are written as multipart uploads, which are completed once
+// every part has been uploaded, either by UploadPart or, for parts uploaded directly to presigned URLs, by
+// CompleteMultipartUpload. Several instances of the service can share a bucket, so drivers coordinate through the
+// storage itself to make sure that each key has a single multipart upload in progress, which is completed only once.
+// A part for a key whose object has already been stored is rejected by UploadPart with ErrAlreadyUploaded, rather than
+// starting another multipart upload that would replace it.
+// Put and List are only used for the small records that the service keeps alongside the objects it uploads.
+type Driver interface {
+	UploadPart(ctx context.Context, req *PartRequest, payload io.Reader) (PartResponse, error)
+	CreateMultipartUpload(ctx context.Context, key, contentType string) (string, error)
+	PresignUploadPart(ctx context.Context, key, uploadID string, partNumber int32, expires time.Duration) (string, error)
+	CompleteMultipartUpload(ctx context.Context, key, uploadID string) error
+	CheckPartUploaded(ctx context.Context, req *PartRequest) (bool, error)
+	PartExists(ctx context.Context, req *PartRequest) (bool, error)
+	ListUploadedParts(ctx context.Context, key string) (MultipartUploadParts, bool, error)
+	AbortMultipartUpload(ctx context.Context, key string) (bool, error)
+	AbortMultipartUploadByID(ctx context.Context, key, uploadID string) error
+	ListMultipartUploads(ctx context.Context) ([]MultipartUpload, error)
+	Head(ctx context.Context, key string) (ObjectInfo, error)
+	Get(ctx context.Context, key string) (io.ReadCloser, *int64, error)
+	Put(ctx context.Context, key, contentType string, content io.Reader) error
+	List(ctx context.Context, prefix string) ([]ObjectSummary, error)
+	Delete(ctx context.Context, key string) error
+	CopyFrom(ctx context.Context, sourceBucket, sourceKey, key string) (string, error)
+	URL(key string) (string, error)
+	PresignGet(ctx context.Context, key string, expires time.Duration, contentDisposition string) (string, error)
+	Checker(ctx context.Context, state *healthcheck.CheckState) error
+}
+
+// Bucket is a named bucket stored by a Driver
+type Bucket struct {
+	name string
+	Driver
+}
+
+// NewBucket creates a Bucket with the name, stored by the driver
+func NewBucket(name string, driver Driver) *Bucket {
+	return &Bucket{
+		name:   name,
+		Driver: driver,
+	}
+}
+
+// Name returns the name of the bucket
+func (b *Bucket) Name() string {
+	return b.name
+}
@@ -1,13 +1,19 @@
 package upload
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"io"
+	"mime"
 	"net/http"
+	"strings"
+	"time"
 
-	s3client "github.com/ONSdigital/dp-s3/v3"
-	"github.com/ONSdigital/dp-upload-service/aws"
+	"github.com/ONSdigital/dp-net/v3/request"
+	"github.com/ONSdigital/dp-upload-service/mediatype"
+	"github.com/ONSdigital/dp-upload-service/storage"
 	"github.com/ONSdigital/log.go/v2/log"
 	"github.com/gorilla/mux"
 	"github.com/gorilla/schema"
@@ -15,6 +21,18 @@ import (
 
 var decoder = schema.NewDecoder()
 
+const (
+	// ownerPrefix is where the collection that each object was uploaded for is recorded
+	ownerPrefix = storage.OwnerPrefix
+	// collectionIDAttribute is the attribute that permissions are scoped to collections with
+	collectionIDAttribute = "collection_id"
+)
+
+// owner records the collection that an object was uploaded for, which requests to download it are authorised against
+type owner struct {
+	CollectionID string `json:"collection_id"`
+}
+
 // Resumable represents resumable js upload query pararmeters
 type Resumable struct {
 	ChunkNumber      int    `schema:"resumableChunkNumber" validate:"required"`
@@ -29,27 +47,35 @@ type Resumable struct {
 	AliasName        string `schema:"aliasName" validate:"required"`
 }
 
-// createS3Request creates a S3 UploadRequest struct from a Resumable struct
-func (resum *Resumable) createS3Request() *s3client.UploadPartRequest {
+// createS3Request creates a storage PartRequest struct from a Resumable struct
+func (resum *Resumable) createS3Request() *storage.PartRequest {
 	log.Info(context.Background(), "calling function s3 request", log.Data{"resumeidentifier": resum.Identifier})
-	return &s3client.UploadPartRequest{
-		UploadKey:   resum.Identifier,
-		Type:        resum.Type,
-		ChunkNumber: int32(resum.ChunkNumber),
-		TotalChunks: resum.TotalChunks,
+	return &storage.PartRequest{
+		Key:         resum.Identifier,
+		ContentType: resum.Type,
+		PartNumber:  int32(resum.ChunkNumber),
+		TotalParts:  resum.TotalChunks,
 		FileName:    resum.FileName,
 	}
 }
 
 // Uploader represents the necessary configuration for uploading a file
 type Uploader struct {
-	bucket *aws.Bucket
+	bucket             *storage.Bucket
+	presignedURLExpiry time.Duration
+	mediaTypes         mediatype.Policy
+	maxFileSize        int64
 }
 
-// New returns a new Uploader from the provided clients
-func New(bucket *aws.Bucket) *Uploader {
+// New returns a new Uploader from the provided clients, whose presigned download URLs expire after presignedURLExpiry
+// and which only accepts files with the media types allowed by the policy that are no larger than maxFileSize bytes.
+// A maxFileSize of 0 allows files of any size.
+func New(bucket *storage.Bucket, presignedURLExpiry time.Duration, mediaTypes mediatype.Policy, maxFileSize int64) *Uploader {
 	return &Uploader{
-		bucket: bucket,
+		bucket:             bucket,
+		presignedURLExpiry: presignedURLExpiry,
+		mediaTypes:         mediaTypes,
+		maxFileSize:        maxFileSize,
 	}
 }
 
@@ -100,7 +126,10 @@ func (u *Uploader) CheckUploaded(w http.ResponseWriter, req *http.Request) {
 // @Param        request formData Resumable false "Resumable"
 // @Success      200
 // @Failure      400
+// @Failure      403
 // @Failure      404
+// @Failure      413
+// @Failure      415
 // @Failure      500
 // @Router       /upload [post]
 func (u *Uploader) Upload(w http.ResponseWriter, req *http.Request) {
@@ -118,6 +147,39 @@ func (u *Uploader) Upload(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	// the records kept alongside the objects, such as who each was uploaded for, cannot be replaced by uploading over them
+	if storage.IsReserved(resum.Identifier) {
+		log.Warn(req.Context(), "upload is under a reserved prefix", log.Data{"uid": resum.Identifier})
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if !u.mediaTypes.Allows(resum.Type) {
+		log.Warn(req.Context(), "file type is not allowed", log.Data{"uid": resum.Identifier, "type": resum.Type})
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		return
+	}
+
+	if u.maxFileSize > 0 && int64(resum.TotalSize) > u.maxFileSize {
+		log.Warn(req.Context(), "file is larger than the maximum file size", log.Data{"uid": resum.Identifier, "size": resum.TotalSize, "max_file_size": u.maxFileSize})
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	// an upload in progress for another collection cannot be taken over by sending it more chunks
+	collectionID := requestCollectionID(req)
+	recorded, err := u.owner(req.Context(), resum.Identifier)
+	if err != nil {
+		log.Error(req.Context(), "error reading owner of upload", err, log.Data{"uid": resum.Identifier})
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if recorded != nil && recorded.CollectionID != collectionID {
+		log.Warn(req.Context(), "upload is for another collection", log.Data{"uid": resum.Identifier, "collection_id": collectionID})
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
 	content, _, err := req.FormFile("file")
 	if err != nil {
 		log.Error(req.Context(), "error getting file from form", err)
@@ -131,11 +193,21 @@ func (u *Uploader) Upload(w http.ResponseWriter, req *http.Request) {
 		}
 	}()
 
-	payload, err := io.ReadAll(content)
-	if err != nil {
-		log.Error(req.Context(), "error reading file", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
+	// the type of the file is sniffed from the start of its first chunk
+	var payload io.Reader = content
+	if resum.ChunkNumber == 1 && u.mediaTypes.SniffsContent(resum.Type) {
+		head, sniffed, err := mediatype.Sniff(content)
+		if err != nil {
+			log.Error(req.Context(), "error reading file from form", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if !mediatype.Matches(resum.Type, head) {
+			log.Warn(req.Context(), "file content does not match its type", log.Data{"uid": resum.Identifier, "type": resum.Type})
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		payload = sniffed
 	}
 
 	// Perform upload
@@ -144,9 +216,74 @@ func (u *Uploader) Upload(w http.ResponseWriter, req *http.Request) {
 		w.WriteHeader(statusCodeFromS3Error(err))
 		return
 	}
+
+	if resum.ChunkNumber == 1 && recorded == nil && collectionID != "" {
+		if err := u.recordOwner(req.Context(), resum.Identifier, owner{CollectionID: collectionID}); err != nil {
+			log.Error(req.Context(), "error recording owner of upload", err, log.Data{"uid": resum.Identifier})
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
 	w.WriteHeader(http.StatusOK)
 }
 
+// ObjectAttributes returns the collection that the object requested by the id path parameter was uploaded for as the
+// attributes a request to download it is authorised for. It can be used with authorisation.Middleware
+// RequireWithAttributes so that only callers who may read the collection are given a URL for the object. Objects
+// uploaded without a collection have no attributes, so only permissions that are not scoped to a collection allow the
+// request.
+func (u *Uploader) ObjectAttributes(req *http.Request) (map[string]string, error) {
+	recorded, err := u.owner(req.Context(), objectKey(req))
+	if err != nil {
+		return nil, err
+	}
+
+	attributes := map[string]string{}
+	if recorded != nil {
+		attributes[collectionIDAttribute] = recorded.CollectionID
+	}
+	return attributes, nil
+}
+
+// owner returns the recorded owner of the object with the key, or nil if none was recorded
+func (u *Uploader) owner(ctx context.Context, key string) (*owner, error) {
+	body, _, err := u.bucket.Get(ctx, ownerPrefix+key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := body.Close(); err != nil {
+			log.Error(ctx, "error closing s3 object body", err, log.Data{"key": ownerPrefix + key})
+		}
+	}()
+
+	recorded := new(owner)
+	if err := json.NewDecoder(body).Decode(recorded); err != nil {
+		return nil, err
+	}
+	return recorded, nil
+}
+
+func (u *Uploader) recordOwner(ctx context.Context, key string, recorded owner) error {
+	content, err := json.Marshal(recorded)
+	if err != nil {
+		return err
+	}
+	return u.bucket.Put(ctx, ownerPrefix+key, "application/json", bytes.NewReader(content))
+}
+
+// requestCollectionID returns the collection that the request was authorised for, from the Collection-Id header or,
+// if there isn't one, the collectionId query parameter
+func requestCollectionID(req *http.Request) string {
+	if collectionID := req.Header.Get(request.CollectionIDHeaderKey); collectionID != "" {
+		return collectionID
+	}
+	return req.URL.Query().Get("collectionId")
+}
+
 // GetS3URL godoc
 // @Description  returns an S3 URL for a requested path, and the client's region and bucket name
 // @Tags         upload
@@ -159,16 +296,9 @@ func (u *Uploader) Upload(w http.ResponseWriter, req *http.Request) {
 // @Failure      500
 // @Router       /upload/{id} [get]
 func (u *Uploader) GetS3URL(w http.ResponseWriter, req *http.Request) {
-	param := req.URL.Query().Get(":id")
-	path := mux.Vars(req)["id"]
-
-	// Florence historically sent the query param, but this is being removed. Where
-	// it is provided, it should be used by preference for now.
-	if len(param) > 0 {
-		path = param
-	}
+	path := objectKey(req)
 
-	url, err := u.bucket.GetS3URL(path)
+	url, err := u.bucket.URL(path)
 	if err != nil {
 		log.Error(req.Context(), "error getting S3 URL", err)
 		w.WriteHeader(http.StatusInternalServerError)
@@ -193,15 +323,97 @@ func (u *Uploader) GetS3URL(w http.ResponseWriter, req *http.Request) {
 	w.Write(b) //nolint
 }
 
+// PresignedURL is a URL that an object can be downloaded from until it expires
+type PresignedURL struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// GetPresignedURL godoc
+// @Description  returns a presigned URL that the object with the requested path can be downloaded from until it expires. If a filename is given, the object is downloaded as an attachment with that name
+// @Tags         upload
+// @Accept       json
+// @Produce      json
+// @Param        id        path      string  true   "S3 object key"
+// @Param        filename  query     string  false  "Name the object is downloaded as"
+// @Success      200  {object}  PresignedURL
+// @Failure      401
+// @Failure      403
+// @Failure      404
+// @Failure      500
+// @Failure      501
+// @Router       /upload/{id}/presigned [get]
+func (u *Uploader) GetPresignedURL(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	path := objectKey(req)
+	logData := log.Data{"path": path}
+
+	// the records kept alongside the objects are never handed out
+	if strings.HasPrefix(path, ".") {
+		log.Warn(ctx, "presigned URL requested for a record", logData)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if _, err := u.bucket.Head(ctx, path); err != nil {
+		log.Error(ctx, "error checking object exists", err, logData)
+		if errors.Is(err, storage.ErrNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	var contentDisposition string
+	if filename := req.URL.Query().Get("filename"); filename != "" {
+		contentDisposition = mime.FormatMediaType("attachment", map[string]string{"filename": filename})
+	}
+
+	expiresAt := time.Now().UTC().Add(u.presignedURLExpiry)
+	url, err := u.bucket.PresignGet(ctx, path, u.presignedURLExpiry, contentDisposition)
+	if err != nil {
+		log.Error(ctx, "error presigning S3 URL", err, logData)
+		if errors.Is(err, storage.ErrPresignNotSupported) {
+			w.WriteHeader(http.StatusNotImplemented)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	b, err := json.Marshal(PresignedURL{URL: url, ExpiresAt: expiresAt})
+	if err != nil {
+		log.Error(ctx, "error marshalling json", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(b) //nolint
+}
+
+// objectKey returns the key of the object requested by the id path parameter
+func objectKey(req *http.Request) string {
+	param := req.URL.Query().Get(":id")
+	path := mux.Vars(req)["id"]
+
+	// Florence historically sent the query param, but this is being removed. Where
+	// it is provided, it should be used by preference for now.
+	if len(param) > 0 {
+		path = param
+	}
+
+	return path
+}
+
 // handleError decides the HTTP status according to the provided error
 func statusCodeFromS3Error(err error) int {
-	//nolint
-	switch err.(type) {
-	case *s3client.ErrNotUploaded:
-		return http.StatusNotFound
-	case *s3client.ErrListParts:
+	switch {
+	case errors.Is(err, storage.ErrNotUploaded):
 		return http.StatusNotFound
-	case *s3client.ErrChunkNumberNotFound:
+	case errors.Is(err, storage.ErrPartNotFound):
 		return http.StatusNotFound
 	default:
 		return http.StatusInternalServerError
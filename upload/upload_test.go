@@ -3,16 +3,23 @@ package upload_test
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"io"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	s3client "github.com/ONSdigital/dp-s3/v3"
 	"github.com/ONSdigital/dp-upload-service/aws"
-	mock_aws "github.com/ONSdigital/dp-upload-service/aws/mock"
+	"github.com/ONSdigital/dp-upload-service/mediatype"
+	"github.com/ONSdigital/dp-upload-service/storage"
+	mock_storage "github.com/ONSdigital/dp-upload-service/storage/mock"
 	"github.com/ONSdigital/dp-upload-service/upload"
+	"github.com/gorilla/mux"
 
 	. "github.com/smartystreets/goconvey/convey"
 )
@@ -38,22 +45,22 @@ func TestGetUpload(t *testing.T) {
 			addQueryParams(req, "1", "1")
 
 			// S3 client returns ErrNotUploaded if uploadID cannot be found
-			s3 := &mock_aws.S3ClienterMock{
-				CheckPartUploadedFunc: func(ctx context.Context, req *s3client.UploadPartRequest) (bool, error) {
-					return false, s3client.NewErrNotUploaded(errors.New("Not working"), nil)
+			s3 := &mock_storage.DriverMock{
+				CheckPartUploadedFunc: func(ctx context.Context, req *storage.PartRequest) (bool, error) {
+					return false, s3client.NewError(storage.ErrNotUploaded, nil)
 				},
 			}
-			bucket := aws.NewBucket(s3Region, s3Bucket, s3)
-			up := upload.New(bucket)
+			bucket := storage.NewBucket(s3Bucket, s3)
+			up := upload.New(bucket, time.Minute, mediatype.NewPolicy(nil), 0)
 			up.CheckUploaded(w, req)
 
 			// Validations
 			So(len(s3.CheckPartUploadedCalls()), ShouldEqual, 1)
-			So(s3.CheckPartUploadedCalls()[0].Req, ShouldResemble, &s3client.UploadPartRequest{
-				UploadKey:   "12345",
-				Type:        "text/plain",
-				ChunkNumber: 1,
-				TotalChunks: 1,
+			So(s3.CheckPartUploadedCalls()[0].Req, ShouldResemble, &storage.PartRequest{
+				Key:         "12345",
+				ContentType: "text/plain",
+				PartNumber:  1,
+				TotalParts:  1,
 				FileName:    "helloworld",
 			})
 			So(w.Code, ShouldEqual, 404)
@@ -64,22 +71,22 @@ func TestGetUpload(t *testing.T) {
 			addQueryParams(req, "1", "2")
 
 			// S3 client returns true if upload could be found and chunk was already uploaded
-			s3 := &mock_aws.S3ClienterMock{
-				CheckPartUploadedFunc: func(ctx context.Context, req *s3client.UploadPartRequest) (bool, error) {
+			s3 := &mock_storage.DriverMock{
+				CheckPartUploadedFunc: func(ctx context.Context, req *storage.PartRequest) (bool, error) {
 					return true, nil
 				},
 			}
-			bucket := aws.NewBucket(s3Region, s3Bucket, s3)
-			up := upload.New(bucket)
+			bucket := storage.NewBucket(s3Bucket, s3)
+			up := upload.New(bucket, time.Minute, mediatype.NewPolicy(nil), 0)
 			up.CheckUploaded(w, req)
 
 			// Validations
 			So(len(s3.CheckPartUploadedCalls()), ShouldEqual, 1)
-			So(s3.CheckPartUploadedCalls()[0].Req, ShouldResemble, &s3client.UploadPartRequest{
-				UploadKey:   "12345",
-				Type:        "text/plain",
-				ChunkNumber: 1,
-				TotalChunks: 2,
+			So(s3.CheckPartUploadedCalls()[0].Req, ShouldResemble, &storage.PartRequest{
+				Key:         "12345",
+				ContentType: "text/plain",
+				PartNumber:  1,
+				TotalParts:  2,
 				FileName:    "helloworld",
 			})
 			So(w.Code, ShouldEqual, 200)
@@ -90,22 +97,22 @@ func TestGetUpload(t *testing.T) {
 			addQueryParams(req, "1", "1")
 
 			// S3 client returns generic error if ListMultipartUploads fails
-			s3 := &mock_aws.S3ClienterMock{
-				CheckPartUploadedFunc: func(ctx context.Context, req *s3client.UploadPartRequest) (bool, error) {
+			s3 := &mock_storage.DriverMock{
+				CheckPartUploadedFunc: func(ctx context.Context, req *storage.PartRequest) (bool, error) {
 					return false, errors.New("could not list uploads")
 				},
 			}
-			bucket := aws.NewBucket(s3Region, s3Bucket, s3)
-			up := upload.New(bucket)
+			bucket := storage.NewBucket(s3Bucket, s3)
+			up := upload.New(bucket, time.Minute, mediatype.NewPolicy(nil), 0)
 			up.CheckUploaded(w, req)
 
 			// Validations
 			So(len(s3.CheckPartUploadedCalls()), ShouldEqual, 1)
-			So(s3.CheckPartUploadedCalls()[0].Req, ShouldResemble, &s3client.UploadPartRequest{
-				UploadKey:   "12345",
-				Type:        "text/plain",
-				ChunkNumber: 1,
-				TotalChunks: 1,
+			So(s3.CheckPartUploadedCalls()[0].Req, ShouldResemble, &storage.PartRequest{
+				Key:         "12345",
+				ContentType: "text/plain",
+				PartNumber:  1,
+				TotalParts:  1,
 				FileName:    "helloworld",
 			})
 			So(w.Code, ShouldEqual, 500)
@@ -125,25 +132,28 @@ func TestPostUpload(t *testing.T) {
 			addQueryParams(req, "1", "1")
 
 			// S3 client returns generic error if ListMultipartUploads fails
-			s3 := &mock_aws.S3ClienterMock{
-				UploadPartFunc: func(ctx context.Context, req *s3client.UploadPartRequest, payload []byte) (s3client.MultipartUploadResponse, error) {
-					return s3client.MultipartUploadResponse{}, nil
+			var uploadedPayload []byte
+			s3 := &mock_storage.DriverMock{
+				GetFunc: ownerNotRecorded,
+				UploadPartFunc: func(ctx context.Context, req *storage.PartRequest, payload io.Reader) (storage.PartResponse, error) {
+					uploadedPayload, _ = io.ReadAll(payload)
+					return storage.PartResponse{}, nil
 				},
 			}
-			bucket := aws.NewBucket(s3Region, s3Bucket, s3)
-			up := upload.New(bucket)
+			bucket := storage.NewBucket(s3Bucket, s3)
+			up := upload.New(bucket, time.Minute, mediatype.NewPolicy(nil), 0)
 			up.Upload(w, req)
 
 			// Validations
 			So(len(s3.UploadPartCalls()), ShouldEqual, 1)
-			So(s3.UploadPartCalls()[0].Req, ShouldResemble, &s3client.UploadPartRequest{
-				UploadKey:   "12345",
-				Type:        "text/plain",
-				ChunkNumber: 1,
-				TotalChunks: 1,
+			So(s3.UploadPartCalls()[0].Req, ShouldResemble, &storage.PartRequest{
+				Key:         "12345",
+				ContentType: "text/plain",
+				PartNumber:  1,
+				TotalParts:  1,
 				FileName:    "helloworld",
 			})
-			So(s3.UploadPartCalls()[0].Payload, ShouldResemble, expectedPayload)
+			So(uploadedPayload, ShouldResemble, expectedPayload)
 			So(w.Code, ShouldEqual, 200)
 
 		})
@@ -152,28 +162,181 @@ func TestPostUpload(t *testing.T) {
 			addQueryParams(req, "1", "1")
 
 			// S3 client returns generic error if ListMultipartUploads fails
-			s3 := &mock_aws.S3ClienterMock{
-				UploadPartFunc: func(ctx context.Context, req *s3client.UploadPartRequest, payload []byte) (s3client.MultipartUploadResponse, error) {
-					return s3client.MultipartUploadResponse{}, errors.New("could not list uploads")
+			s3 := &mock_storage.DriverMock{
+				GetFunc: ownerNotRecorded,
+				UploadPartFunc: func(ctx context.Context, req *storage.PartRequest, payload io.Reader) (storage.PartResponse, error) {
+					return storage.PartResponse{}, errors.New("could not list uploads")
 				},
 			}
-			bucket := aws.NewBucket(s3Region, s3Bucket, s3)
-			up := upload.New(bucket)
+			bucket := storage.NewBucket(s3Bucket, s3)
+			up := upload.New(bucket, time.Minute, mediatype.NewPolicy(nil), 0)
 			up.Upload(w, req)
 
 			// Validations
 			So(len(s3.UploadPartCalls()), ShouldEqual, 1)
-			So(s3.UploadPartCalls()[0].Req, ShouldResemble, &s3client.UploadPartRequest{
-				UploadKey:   "12345",
-				Type:        "text/plain",
-				ChunkNumber: 1,
-				TotalChunks: 1,
+			So(s3.UploadPartCalls()[0].Req, ShouldResemble, &storage.PartRequest{
+				Key:         "12345",
+				ContentType: "text/plain",
+				PartNumber:  1,
+				TotalParts:  1,
 				FileName:    "helloworld",
 			})
 			So(w.Code, ShouldEqual, 500)
 
 		})
 
+		Convey("test 400 status returned if the upload is under a reserved prefix", func() {
+			addQueryParams(req, "1", "1")
+			q := req.URL.Query()
+			q.Set("resumableIdentifier", ".owners/12345")
+			req.URL.RawQuery = q.Encode()
+
+			s3 := &mock_storage.DriverMock{}
+			bucket := storage.NewBucket(s3Bucket, s3)
+			up := upload.New(bucket, time.Minute, mediatype.NewPolicy(nil), 0)
+			up.Upload(w, req)
+
+			// Validations
+			So(len(s3.UploadPartCalls()), ShouldEqual, 0)
+			So(w.Code, ShouldEqual, 400)
+		})
+
+		Convey("test 415 status returned if the file type is not allowed", func() {
+			addQueryParams(req, "1", "1")
+
+			s3 := &mock_storage.DriverMock{}
+			bucket := storage.NewBucket(s3Bucket, s3)
+			up := upload.New(bucket, time.Minute, mediatype.NewPolicy([]string{"text/csv"}), 0)
+			up.Upload(w, req)
+
+			// Validations
+			So(len(s3.UploadPartCalls()), ShouldEqual, 0)
+			So(w.Code, ShouldEqual, 415)
+		})
+
+		Convey("test 413 status returned if the file is larger than the maximum file size", func() {
+			addQueryParams(req, "1", "1")
+
+			s3 := &mock_storage.DriverMock{}
+			bucket := storage.NewBucket(s3Bucket, s3)
+			up := upload.New(bucket, time.Minute, mediatype.NewPolicy(nil), 5242879)
+			up.Upload(w, req)
+
+			// Validations
+			So(len(s3.UploadPartCalls()), ShouldEqual, 0)
+			So(w.Code, ShouldEqual, 413)
+		})
+
+	})
+
+	Convey("given a POST /upload request whose content does not match its type", t, func() {
+
+		w := httptest.NewRecorder()
+		req, err := createTestFileUploadPart([]byte("%PDF-1.7\n"))
+		So(err, ShouldBeNil)
+
+		s3 := &mock_storage.DriverMock{
+			GetFunc: ownerNotRecorded,
+			UploadPartFunc: func(ctx context.Context, req *storage.PartRequest, payload io.Reader) (storage.PartResponse, error) {
+				return storage.PartResponse{}, nil
+			},
+		}
+		up := upload.New(storage.NewBucket(s3Bucket, s3), time.Minute, mediatype.NewPolicy([]string{"text/plain"}), 0)
+
+		Convey("test 415 status returned for the first chunk", func() {
+			addQueryParams(req, "1", "2")
+			up.Upload(w, req)
+
+			So(len(s3.UploadPartCalls()), ShouldEqual, 0)
+			So(w.Code, ShouldEqual, 415)
+		})
+
+		Convey("test later chunks are not sniffed", func() {
+			addQueryParams(req, "2", "2")
+			up.Upload(w, req)
+
+			So(len(s3.UploadPartCalls()), ShouldEqual, 1)
+			So(w.Code, ShouldEqual, 200)
+		})
+
+		Convey("test 415 status returned for the first chunk when every type is allowed", func() {
+			addQueryParams(req, "1", "2")
+			up := upload.New(storage.NewBucket(s3Bucket, s3), time.Minute, mediatype.NewPolicy(nil), 0)
+			up.Upload(w, req)
+
+			So(len(s3.UploadPartCalls()), ShouldEqual, 0)
+			So(w.Code, ShouldEqual, 415)
+		})
+	})
+
+	Convey("given a POST /upload request for a collection", t, func() {
+
+		w := httptest.NewRecorder()
+		req, err := createTestFileUploadPart(expectedPayload)
+		So(err, ShouldBeNil)
+		req.Header.Set("Collection-Id", "collection-1")
+
+		s3 := &mock_storage.DriverMock{
+			GetFunc: ownerNotRecorded,
+			PutFunc: func(ctx context.Context, key, contentType string, content io.Reader) error {
+				return nil
+			},
+			UploadPartFunc: func(ctx context.Context, req *storage.PartRequest, payload io.Reader) (storage.PartResponse, error) {
+				return storage.PartResponse{}, nil
+			},
+		}
+		up := upload.New(storage.NewBucket(s3Bucket, s3), time.Minute, mediatype.NewPolicy(nil), 0)
+
+		Convey("test the collection is recorded with the first chunk", func() {
+			addQueryParams(req, "1", "2")
+			up.Upload(w, req)
+
+			So(w.Code, ShouldEqual, 200)
+			So(len(s3.PutCalls()), ShouldEqual, 1)
+			So(s3.PutCalls()[0].Key, ShouldEqual, ".owners/12345")
+			recorded, _ := io.ReadAll(s3.PutCalls()[0].Content)
+			So(string(recorded), ShouldEqual, `{"collection_id":"collection-1"}`)
+		})
+
+		Convey("test the collection is not recorded again with later chunks", func() {
+			addQueryParams(req, "2", "2")
+			up.Upload(w, req)
+
+			So(w.Code, ShouldEqual, 200)
+			So(s3.PutCalls(), ShouldBeEmpty)
+		})
+
+		Convey("test 403 status returned if the upload is for another collection", func() {
+			s3.GetFunc = ownerRecorded("collection-2")
+			addQueryParams(req, "1", "2")
+			up.Upload(w, req)
+
+			So(w.Code, ShouldEqual, 403)
+			So(s3.UploadPartCalls(), ShouldBeEmpty)
+			So(s3.PutCalls(), ShouldBeEmpty)
+		})
+
+		Convey("test later chunks for the recorded collection are uploaded", func() {
+			s3.GetFunc = ownerRecorded("collection-1")
+			addQueryParams(req, "2", "2")
+			up.Upload(w, req)
+
+			So(w.Code, ShouldEqual, 200)
+			So(len(s3.UploadPartCalls()), ShouldEqual, 1)
+		})
+
+		Convey("test 400 status returned if the upload would replace the collection recorded for another object", func() {
+			addQueryParams(req, "1", "1")
+			q := req.URL.Query()
+			q.Set("resumableIdentifier", ".owners/173849-helloworldtxt")
+			req.URL.RawQuery = q.Encode()
+			up.Upload(w, req)
+
+			So(w.Code, ShouldEqual, 400)
+			So(s3.GetCalls(), ShouldBeEmpty)
+			So(s3.UploadPartCalls(), ShouldBeEmpty)
+			So(s3.PutCalls(), ShouldBeEmpty)
+		})
 	})
 
 }
@@ -186,8 +349,16 @@ func TestGetS3Url(t *testing.T) {
 		So(err, ShouldBeNil)
 
 		Convey("A 200 OK status is returned, with the fully qualified s3 url for the region, bucket and s3 key", func() {
-			bucket := aws.NewBucket(s3Region, s3Bucket, &mock_aws.S3ClienterMock{})
-			up := upload.New(bucket)
+			bucket := storage.NewBucket(s3Bucket, &mock_storage.DriverMock{
+				URLFunc: func(key string) (string, error) {
+					s3Url, err := s3client.NewURL(s3Region, s3Bucket, key)
+					if err != nil {
+						return "", err
+					}
+					return s3Url.String(aws.PathStyle)
+				},
+			})
+			up := upload.New(bucket, time.Minute, mediatype.NewPolicy(nil), 0)
 			up.GetS3URL(w, req)
 
 			// Validations
@@ -195,9 +366,167 @@ func TestGetS3Url(t *testing.T) {
 			So(w.Body.String(), ShouldEqual, `{"url":"https://s3-eu-west-2.amazonaws.com/test-bucket/173849-helloworldtxt"}`)
 			So(w.Header().Get("Content-Type"), ShouldEqual, "application/json")
 		})
+
+		Convey("A 500 status is returned if the storage driver cannot create a URL", func() {
+			bucket := storage.NewBucket(s3Bucket, &mock_storage.DriverMock{
+				URLFunc: func(key string) (string, error) {
+					return "", errors.New("no URL")
+				},
+			})
+			up := upload.New(bucket, time.Minute, mediatype.NewPolicy(nil), 0)
+			up.GetS3URL(w, req)
+
+			So(w.Code, ShouldEqual, 500)
+		})
+	})
+}
+
+func TestObjectAttributes(t *testing.T) {
+
+	Convey("Given a request for an object", t, func() {
+		req, err := http.NewRequest("GET", "/upload/173849-helloworldtxt/presigned", nil)
+		So(err, ShouldBeNil)
+		req = mux.SetURLVars(req, map[string]string{"id": "173849-helloworldtxt"})
+		s3 := &mock_storage.DriverMock{GetFunc: ownerRecorded("collection-1")}
+		up := upload.New(storage.NewBucket(s3Bucket, s3), time.Minute, mediatype.NewPolicy(nil), 0)
+
+		Convey("The attributes are the collection the object was uploaded for", func() {
+			attributes, err := up.ObjectAttributes(req)
+
+			So(err, ShouldBeNil)
+			So(attributes, ShouldResemble, map[string]string{"collection_id": "collection-1"})
+			So(s3.GetCalls()[0].Key, ShouldEqual, ".owners/173849-helloworldtxt")
+		})
+
+		Convey("There are no attributes if the object was uploaded without a collection", func() {
+			s3.GetFunc = ownerNotRecorded
+			attributes, err := up.ObjectAttributes(req)
+
+			So(err, ShouldBeNil)
+			So(attributes, ShouldBeEmpty)
+		})
+
+		Convey("An error is returned if the owner cannot be read", func() {
+			s3.GetFunc = func(ctx context.Context, key string) (io.ReadCloser, *int64, error) {
+				return nil, nil, errors.New("get failed")
+			}
+			_, err := up.ObjectAttributes(req)
+
+			So(err, ShouldNotBeNil)
+		})
 	})
 }
 
+func TestGetPresignedURL(t *testing.T) {
+
+	Convey("Given a GET /upload/{id}/presigned request", t, func() {
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest("GET", "/upload/173849-helloworldtxt/presigned", nil)
+		So(err, ShouldBeNil)
+		req = mux.SetURLVars(req, map[string]string{"id": "173849-helloworldtxt"})
+
+		s3 := &mock_storage.DriverMock{
+			HeadFunc: func(ctx context.Context, key string) (storage.ObjectInfo, error) {
+				return storage.ObjectInfo{}, nil
+			},
+			PresignGetFunc: func(ctx context.Context, key string, expires time.Duration, contentDisposition string) (string, error) {
+				return "https://test-bucket.s3.eu-west-2.amazonaws.com/" + key + "?X-Amz-Signature=signature", nil
+			},
+		}
+
+		Convey("A 200 OK status is returned, with a presigned URL for the object that expires after the configured time", func() {
+			up := upload.New(storage.NewBucket(s3Bucket, s3), time.Minute, mediatype.NewPolicy(nil), 0)
+			before := time.Now()
+			up.GetPresignedURL(w, req)
+
+			So(w.Code, ShouldEqual, 200)
+			So(w.Header().Get("Content-Type"), ShouldEqual, "application/json")
+
+			var body upload.PresignedURL
+			So(json.Unmarshal(w.Body.Bytes(), &body), ShouldBeNil)
+			So(body.URL, ShouldEqual, "https://test-bucket.s3.eu-west-2.amazonaws.com/173849-helloworldtxt?X-Amz-Signature=signature")
+			So(body.ExpiresAt, ShouldHappenOnOrBetween, before.Add(time.Minute), time.Now().Add(time.Minute))
+
+			So(s3.HeadCalls()[0].Key, ShouldEqual, "173849-helloworldtxt")
+			So(s3.PresignGetCalls()[0].Key, ShouldEqual, "173849-helloworldtxt")
+			So(s3.PresignGetCalls()[0].Expires, ShouldEqual, time.Minute)
+			So(s3.PresignGetCalls()[0].ContentDisposition, ShouldBeEmpty)
+		})
+
+		Convey("The object is downloaded as an attachment when a filename is requested", func() {
+			req.URL.RawQuery = "filename=hello+world.txt"
+			up := upload.New(storage.NewBucket(s3Bucket, s3), time.Minute, mediatype.NewPolicy(nil), 0)
+			up.GetPresignedURL(w, req)
+
+			So(w.Code, ShouldEqual, 200)
+			So(s3.PresignGetCalls()[0].ContentDisposition, ShouldEqual, `attachment; filename="hello world.txt"`)
+		})
+
+		Convey("A 404 status is returned if the object does not exist", func() {
+			s3.HeadFunc = func(ctx context.Context, key string) (storage.ObjectInfo, error) {
+				return storage.ObjectInfo{}, storage.ErrNotFound
+			}
+			up := upload.New(storage.NewBucket(s3Bucket, s3), time.Minute, mediatype.NewPolicy(nil), 0)
+			up.GetPresignedURL(w, req)
+
+			So(w.Code, ShouldEqual, 404)
+			So(s3.PresignGetCalls(), ShouldBeEmpty)
+		})
+
+		Convey("A 404 status is returned for the records kept alongside the objects", func() {
+			req = mux.SetURLVars(req, map[string]string{"id": ".owners/173849-helloworldtxt"})
+			up := upload.New(storage.NewBucket(s3Bucket, s3), time.Minute, mediatype.NewPolicy(nil), 0)
+			up.GetPresignedURL(w, req)
+
+			So(w.Code, ShouldEqual, 404)
+			So(s3.HeadCalls(), ShouldBeEmpty)
+			So(s3.PresignGetCalls(), ShouldBeEmpty)
+		})
+
+		Convey("A 500 status is returned if the object cannot be checked", func() {
+			s3.HeadFunc = func(ctx context.Context, key string) (storage.ObjectInfo, error) {
+				return storage.ObjectInfo{}, errors.New("head failed")
+			}
+			up := upload.New(storage.NewBucket(s3Bucket, s3), time.Minute, mediatype.NewPolicy(nil), 0)
+			up.GetPresignedURL(w, req)
+
+			So(w.Code, ShouldEqual, 500)
+		})
+
+		Convey("A 501 status is returned if the storage driver cannot presign URLs", func() {
+			s3.PresignGetFunc = func(ctx context.Context, key string, expires time.Duration, contentDisposition string) (string, error) {
+				return "", storage.ErrPresignNotSupported
+			}
+			up := upload.New(storage.NewBucket(s3Bucket, s3), time.Minute, mediatype.NewPolicy(nil), 0)
+			up.GetPresignedURL(w, req)
+
+			So(w.Code, ShouldEqual, 501)
+		})
+
+		Convey("A 500 status is returned if the URL cannot be presigned", func() {
+			s3.PresignGetFunc = func(ctx context.Context, key string, expires time.Duration, contentDisposition string) (string, error) {
+				return "", errors.New("presign failed")
+			}
+			up := upload.New(storage.NewBucket(s3Bucket, s3), time.Minute, mediatype.NewPolicy(nil), 0)
+			up.GetPresignedURL(w, req)
+
+			So(w.Code, ShouldEqual, 500)
+		})
+	})
+}
+
+// ownerNotRecorded is a Get of an object for which no owner has been recorded
+func ownerNotRecorded(ctx context.Context, key string) (io.ReadCloser, *int64, error) {
+	return nil, nil, storage.ErrNotFound
+}
+
+// ownerRecorded returns a Get of an object that was uploaded for the collection
+func ownerRecorded(collectionID string) func(ctx context.Context, key string) (io.ReadCloser, *int64, error) {
+	return func(ctx context.Context, key string) (io.ReadCloser, *int64, error) {
+		return io.NopCloser(strings.NewReader(`{"collection_id":"` + collectionID + `"}`)), nil, nil
+	}
+}
+
 // createTestFileUploadPart creates an http Request with the expected body payload
 func createTestFileUploadPart(testPayload []byte) (*http.Request, error) {
 	body := &bytes.Buffer{}
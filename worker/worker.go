@@ -0,0 +1,165 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ONSdigital/log.go/v2/log"
+)
+
+// queueLength is the number of keys that can be waiting to be processed before any more are left for the next sweep
+const queueLength = 1000
+
+// ProcessFunc does the work for the key, returning an error if it failed and should be retried
+type ProcessFunc func(ctx context.Context, key string) error
+
+// PendingFunc lists the keys whose work has not been done yet
+type PendingFunc func(ctx context.Context) ([]string, error)
+
+type job struct {
+	ctx context.Context
+	key string
+}
+
+// Pool processes keys in the background on a fixed number of goroutines. Keys are enqueued as the work for them is
+// created, and every interval the keys that are still pending are enqueued again, so that work that failed, or that
+// was left behind by an instance of the service that stopped, is retried.
+type Pool struct {
+	name     string
+	workers  int
+	interval time.Duration
+	process  ProcessFunc
+	pending  PendingFunc
+
+	jobs   chan job
+	mu     sync.Mutex
+	queued map[string]struct{}
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New creates a Pool that processes keys on the number of workers, enqueuing the pending keys every interval. An
+// interval of zero disables the sweep, so keys are only processed when they are enqueued.
+func New(name string, workers int, interval time.Duration, process ProcessFunc, pending PendingFunc) *Pool {
+	return &Pool{
+		name:     name,
+		workers:  workers,
+		interval: interval,
+		process:  process,
+		pending:  pending,
+		jobs:     make(chan job, queueLength),
+		queued:   make(map[string]struct{}),
+	}
+}
+
+// Start runs the workers and the sweep in the background until Stop is called or the context is done. The pending
+// keys found by the sweep are processed with the values of the context.
+func (p *Pool) Start(ctx context.Context) {
+	p.mu.Lock()
+	p.ctx, p.cancel = context.WithCancel(ctx)
+	p.mu.Unlock()
+
+	for i := 0; i < p.workers; i++ {
+		p.wg.Add(1)
+		go p.work()
+	}
+
+	if p.interval > 0 {
+		p.wg.Add(1)
+		go p.sweep()
+	}
+}
+
+// Stop cancels any work in progress and waits for the workers to finish. Keys that are still queued are left for the
+// next sweep. It does nothing if the pool was not started.
+func (p *Pool) Stop() {
+	p.mu.Lock()
+	cancel := p.cancel
+	p.mu.Unlock()
+
+	if cancel == nil {
+		return
+	}
+	cancel()
+	p.wg.Wait()
+}
+
+// Enqueue adds the key to be processed with the values of the context, such as the caller's auth token, but without
+// its deadline or cancellation, so that the work outlives the request that created it. A key that is already queued
+// or being processed is not added again, and nothing is added once the pool has stopped or while the queue is full,
+// as the key is found by the next sweep instead.
+func (p *Pool) Enqueue(ctx context.Context, key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.ctx == nil || p.ctx.Err() != nil {
+		log.Warn(ctx, "background work not queued as the pool is not running", log.Data{"pool": p.name, "key": key})
+		return
+	}
+	if _, ok := p.queued[key]; ok {
+		return
+	}
+
+	select {
+	case p.jobs <- job{ctx: context.WithoutCancel(ctx), key: key}:
+		p.queued[key] = struct{}{}
+	default:
+		log.Warn(ctx, "background work not queued as the queue is full", log.Data{"pool": p.name, "key": key})
+	}
+}
+
+func (p *Pool) work() {
+	defer p.wg.Done()
+
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case j := <-p.jobs:
+			p.run(j)
+		}
+	}
+}
+
+// run processes the job with a context that is cancelled when the pool stops, then allows the key to be queued again
+func (p *Pool) run(j job) {
+	ctx, cancel := context.WithCancel(j.ctx)
+	stop := context.AfterFunc(p.ctx, cancel)
+	defer func() {
+		stop()
+		cancel()
+
+		p.mu.Lock()
+		delete(p.queued, j.key)
+		p.mu.Unlock()
+	}()
+
+	if err := p.process(ctx, j.key); err != nil {
+		log.Error(ctx, "background work failed", err, log.Data{"pool": p.name, "key": j.key})
+	}
+}
+
+func (p *Pool) sweep() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-ticker.C:
+			keys, err := p.pending(p.ctx)
+			if err != nil {
+				log.Error(p.ctx, "failed to list pending background work", err, log.Data{"pool": p.name})
+				continue
+			}
+			for _, key := range keys {
+				p.Enqueue(p.ctx, key)
+			}
+		}
+	}
+}
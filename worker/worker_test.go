@@ -0,0 +1,185 @@
+package worker_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-upload-service/worker"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type contextKey string
+
+// recorder records the keys processed by a pool, along with the value of the test context key they were processed with
+type recorder struct {
+	mu     sync.Mutex
+	keys   []string
+	values []interface{}
+}
+
+func (r *recorder) process(ctx context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.keys = append(r.keys, key)
+	r.values = append(r.values, ctx.Value(contextKey("token")))
+	return nil
+}
+
+func (r *recorder) processed() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.keys...)
+}
+
+func noPending(ctx context.Context) ([]string, error) {
+	return nil, nil
+}
+
+// eventually reports whether the condition becomes true within a second
+func eventually(condition func() bool) bool {
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if condition() {
+			return true
+		}
+	}
+	return false
+}
+
+func TestEnqueue(t *testing.T) {
+	Convey("Given a running pool", t, func() {
+		r := &recorder{}
+		pool := worker.New("test", 2, 0, r.process, noPending)
+		pool.Start(context.Background())
+		defer pool.Stop()
+
+		Convey("When a key is enqueued by a request that has since been cancelled", func() {
+			ctx, cancel := context.WithCancel(context.WithValue(context.Background(), contextKey("token"), "user-token"))
+			pool.Enqueue(ctx, "data/file.csv")
+			cancel()
+
+			Convey("Then it is processed with the values of the request's context", func() {
+				So(eventually(func() bool { return len(r.processed()) == 1 }), ShouldBeTrue)
+				So(r.processed(), ShouldResemble, []string{"data/file.csv"})
+				r.mu.Lock()
+				defer r.mu.Unlock()
+				So(r.values, ShouldResemble, []interface{}{"user-token"})
+			})
+		})
+	})
+
+	Convey("Given a pool whose worker is processing a key", t, func() {
+		started := make(chan struct{})
+		release := make(chan struct{})
+		var mu sync.Mutex
+		calls := 0
+		pool := worker.New("test", 1, 0, func(ctx context.Context, key string) error {
+			mu.Lock()
+			calls++
+			mu.Unlock()
+			started <- struct{}{}
+			<-release
+			return nil
+		}, noPending)
+		pool.Start(context.Background())
+		defer pool.Stop()
+
+		pool.Enqueue(context.Background(), "data/file.csv")
+		<-started
+
+		Convey("When the same key is enqueued again", func() {
+			pool.Enqueue(context.Background(), "data/file.csv")
+			close(release)
+
+			Convey("Then it is only processed once", func() {
+				time.Sleep(50 * time.Millisecond)
+				mu.Lock()
+				defer mu.Unlock()
+				So(calls, ShouldEqual, 1)
+			})
+		})
+	})
+
+	Convey("Given a pool that has not been started", t, func() {
+		r := &recorder{}
+		pool := worker.New("test", 1, 0, r.process, noPending)
+
+		Convey("When a key is enqueued", func() {
+			pool.Enqueue(context.Background(), "data/file.csv")
+
+			Convey("Then it is not processed", func() {
+				So(r.processed(), ShouldBeEmpty)
+			})
+		})
+	})
+}
+
+func TestSweep(t *testing.T) {
+	Convey("Given a pool with pending keys whose processing fails the first time", t, func() {
+		var mu sync.Mutex
+		attempts := map[string]int{}
+		pool := worker.New("test", 1, 10*time.Millisecond, func(ctx context.Context, key string) error {
+			mu.Lock()
+			defer mu.Unlock()
+			attempts[key]++
+			if attempts[key] == 1 {
+				return errors.New("failed")
+			}
+			return nil
+		}, func(ctx context.Context) ([]string, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if attempts["data/file.csv"] > 1 {
+				return nil, nil
+			}
+			return []string{"data/file.csv"}, nil
+		})
+
+		Convey("When the pool is started", func() {
+			pool.Start(context.Background())
+			defer pool.Stop()
+
+			Convey("Then the pending key is retried by the sweep until it succeeds", func() {
+				So(eventually(func() bool {
+					mu.Lock()
+					defer mu.Unlock()
+					return attempts["data/file.csv"] == 2
+				}), ShouldBeTrue)
+			})
+		})
+	})
+}
+
+func TestStop(t *testing.T) {
+	Convey("Given a pool whose worker is processing a key", t, func() {
+		started := make(chan struct{})
+		var cancelled bool
+		pool := worker.New("test", 1, time.Hour, func(ctx context.Context, key string) error {
+			close(started)
+			<-ctx.Done()
+			cancelled = true
+			return ctx.Err()
+		}, noPending)
+		pool.Start(context.Background())
+		pool.Enqueue(context.Background(), "data/file.csv")
+		<-started
+
+		Convey("When the pool is stopped", func() {
+			pool.Stop()
+
+			Convey("Then the work in progress is cancelled before Stop returns", func() {
+				So(cancelled, ShouldBeTrue)
+			})
+		})
+	})
+
+	Convey("Given a pool that has not been started", t, func() {
+		pool := worker.New("test", 1, time.Hour, nil, noPending)
+
+		Convey("Then it can be stopped", func() {
+			So(pool.Stop, ShouldNotPanic)
+		})
+	})
+}